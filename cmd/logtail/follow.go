@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// follower reads a file like tail -F: at the end of the file it waits for
+// more data instead of returning io.EOF, and reopens the path once the file
+// is rotated or truncated. It returns io.EOF once ctx is done.
+type follower struct {
+	ctx  context.Context
+	path string
+	poll time.Duration
+
+	f      *os.File
+	offset int64
+}
+
+func follow(ctx context.Context, path string, poll time.Duration, fromStart bool) (*follower, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fl := &follower{ctx: ctx, path: path, poll: poll, f: f}
+	if !fromStart {
+		if fl.offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return fl, nil
+}
+
+func (fl *follower) Read(p []byte) (int, error) {
+	for {
+		n, err := fl.f.Read(p)
+		fl.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+
+		// At the end of the file: only now look for a rotation, so the
+		// entries written to the old file before it are read.
+		reopened, err := fl.reopen()
+		if err != nil {
+			return 0, err
+		}
+		if reopened {
+			continue
+		}
+
+		select {
+		case <-fl.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(fl.poll):
+		}
+	}
+}
+
+// reopen switches to the file now at path if it isn't the one being read, and
+// rewinds if the file was truncated. A missing path is waited for.
+func (fl *follower) reopen() (bool, error) {
+	current, err := fl.f.Stat()
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(fl.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(current, info) {
+		f, err := os.Open(fl.path)
+		if err != nil {
+			return false, nil // Not created yet, or not readable yet
+		}
+		fl.f.Close()
+		fl.f, fl.offset = f, 0
+		return true, nil
+	}
+
+	if info.Size() < fl.offset {
+		if _, err := fl.f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		fl.offset = 0
+		return true, nil
+	}
+	return false, nil
+}
+
+func (fl *follower) Close() error {
+	return fl.f.Close()
+}
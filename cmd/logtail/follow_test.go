@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendLine(t *testing.T, path, line string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(line + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestFollowerAcrossRotationAndTruncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLine(t, path, "before")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	fl, err := follow(ctx, path, time.Millisecond, false)
+	require.NoError(t, err)
+	defer fl.Close()
+	lines := bufio.NewScanner(fl)
+
+	next := func() string {
+		require.True(t, lines.Scan())
+		return lines.Text()
+	}
+
+	appendLine(t, path, "appended")
+	assert.Equal(t, "appended", next())
+
+	appendLine(t, path, "last of old file")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendLine(t, path, "first of new file")
+	assert.Equal(t, "last of old file", next())
+	assert.Equal(t, "first of new file", next())
+
+	require.NoError(t, os.Truncate(path, 0))
+	appendLine(t, path, "after truncation")
+	assert.Equal(t, "after truncation", next())
+}
+
+func TestFollowerFromStartStopsWithContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLine(t, path, "existing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fl, err := follow(ctx, path, time.Millisecond, true)
+	require.NoError(t, err)
+	defer fl.Close()
+
+	buf := make([]byte, 64)
+	n, err := fl.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "existing\n", string(buf[:n]))
+
+	cancel()
+	_, err = fl.Read(buf)
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,196 @@
+// Command logtail follows the JSON lines written by the log package as they
+// are written, shows the ones passing its filters like logview does,
+// highlights matches and can report entry rates.
+//
+// Usage:
+//
+//	logtail [flags] [file]
+//
+// It follows file across rotations and truncations, or reads standard input
+// when no file is given, so it also works on a pipe:
+//
+//	kubectl logs -f deploy/api | logtail -level warn -highlight timeout -stats 10s
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Stasky745/go-libs/log"
+	"github.com/Stasky745/go-libs/log/logjson"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, isTerminal(os.Stdout)); err != nil {
+		fmt.Fprintln(os.Stderr, "logtail:", err)
+		os.Exit(2)
+	}
+}
+
+// exprList collects repeated -where flags.
+type exprList []logjson.FieldExpr
+
+func (l *exprList) String() string {
+	return fmt.Sprint(len(*l), " expressions")
+}
+
+func (l *exprList) Set(s string) error {
+	expr, err := logjson.ParseFieldExpr(s)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, expr)
+	return nil
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, color bool) error {
+	flags := flag.NewFlagSet("logtail", flag.ContinueOnError)
+	level := flags.String("level", "debug", "minimum `level` to show")
+	highlight := flags.String("highlight", "", "highlight the matches of `regexp`")
+	statsEvery := flags.Duration("stats", 0, "report entry rates by level and component every `interval`")
+	statsOnly := flags.Bool("stats-only", false, "only report entry rates, don't show entries")
+	componentKey := flags.String("component-key", "logger", "`key` naming the component in -stats")
+	fromStart := flags.Bool("from-start", false, "show the file from its start instead of only new entries")
+	poll := flags.Duration("poll", 250*time.Millisecond, "how often to check the file for new entries")
+	noColor := flags.Bool("no-color", false, "don't color levels and highlights")
+	var keys log.KeyNames
+	flags.StringVar(&keys.Message, "message-key", "", "`key` of the message, if renamed")
+	flags.StringVar(&keys.Level, "level-key", "", "`key` of the level, if renamed")
+	flags.StringVar(&keys.Time, "time-key", "", "`key` of the time, if renamed")
+	var where exprList
+	flags.Var(&where, "where", "show entries where `expr` holds: key=value, key!=value, key~regexp, key>n, key<=n, ... or key; repeatable")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errors.New("follows a single file")
+	}
+	if *statsOnly && *statsEvery <= 0 {
+		*statsEvery = 10 * time.Second
+	}
+
+	filter := logjson.Filter{Fields: where}
+	if err := filter.MinLevel.UnmarshalText([]byte(*level)); err != nil {
+		return err
+	}
+
+	out := &lockedWriter{w: stdout}
+	color = color && !*noColor
+	var w io.Writer = out
+	if *highlight != "" {
+		re, err := regexp.Compile(*highlight)
+		if err != nil {
+			return fmt.Errorf("invalid -highlight: %w", err)
+		}
+		w = &highlighter{w: out, re: re, color: color}
+	}
+	printer := logjson.NewPrinter(w, color)
+
+	r := stdin
+	if flags.NArg() == 1 {
+		fl, err := follow(ctx, flags.Arg(0), *poll, *fromStart)
+		if err != nil {
+			return err
+		}
+		defer fl.Close()
+		r = fl
+	}
+
+	var counts *stats
+	if *statsEvery > 0 {
+		counts = newStats(*componentKey, time.Now())
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(*statsEvery)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case now := <-ticker.C:
+					_ = counts.report(out, now)
+				}
+			}
+		}()
+	}
+
+	d := logjson.NewDecoder(r).WithKeyNames(keys)
+	for {
+		e, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !filter.Match(e) {
+			continue
+		}
+		if counts != nil {
+			counts.add(e)
+		}
+		if *statsOnly {
+			continue
+		}
+		if err := printer.Print(e); err != nil {
+			return err
+		}
+	}
+
+	if counts != nil {
+		return counts.report(out, time.Now())
+	}
+	return nil
+}
+
+// lockedWriter serializes the entries and the stats reports.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(p)
+}
+
+// highlighter marks the matches of re in every entry printed, in reverse video
+// or between >>> and <<< without colors.
+type highlighter struct {
+	w     io.Writer
+	re    *regexp.Regexp
+	color bool
+}
+
+func (h *highlighter) Write(p []byte) (int, error) {
+	repl := []byte(">>>$0<<<")
+	if h.color {
+		repl = []byte("\x1b[7m$0\x1b[27m")
+	}
+	if _, err := h.w.Write(h.re.ReplaceAll(p, repl)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// isTerminal reports whether f is a character device, leaving colors out of
+// pipes and files.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
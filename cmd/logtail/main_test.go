@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const input = `{"level":"debug","ts":"2024-05-01T10:00:00Z","msg":"cache miss","logger":"cache"}
+{"level":"warn","ts":"2024-05-01T10:00:01Z","msg":"upstream timeout","logger":"api","upstream":"billing"}
+{"level":"error","ts":"2024-05-01T10:00:02Z","msg":"request failed","logger":"api"}
+`
+
+func TestRunFiltersAndHighlights(t *testing.T) {
+	var out bytes.Buffer
+	args := []string{"-level", "warn", "-where", "logger=api", "-highlight", "time[a-z]+"}
+	require.NoError(t, run(context.Background(), args, strings.NewReader(input), &out, false))
+
+	assert.Equal(t, "2024-05-01T10:00:01.000Z\tWARN\tupstream >>>timeout<<<\t{\"logger\":\"api\",\"upstream\":\"billing\"}\n"+
+		"2024-05-01T10:00:02.000Z\tERROR\trequest failed\t{\"logger\":\"api\"}\n", out.String())
+
+	out.Reset()
+	require.NoError(t, run(context.Background(), []string{"-highlight", "timeout"}, strings.NewReader(input), &out, true))
+	assert.Contains(t, out.String(), "upstream \x1b[7mtimeout\x1b[27m")
+}
+
+func TestRunStatsOnly(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, run(context.Background(), []string{"-stats-only"}, strings.NewReader(input), &out, false))
+
+	assert.True(t, strings.HasPrefix(out.String(), "stats: "), out.String())
+	assert.Contains(t, out.String(), "| api=")
+	assert.Contains(t, out.String(), "cache=")
+	assert.NotContains(t, out.String(), "cache miss")
+}
+
+func TestRunRejectsBadFlags(t *testing.T) {
+	var out bytes.Buffer
+	ctx := context.Background()
+	assert.Error(t, run(ctx, []string{"-highlight", "("}, strings.NewReader(input), &out, false))
+	assert.Error(t, run(ctx, []string{"a.log", "b.log"}, nil, &out, false))
+	assert.Error(t, run(ctx, []string{"missing.log"}, nil, &out, false))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Stasky745/go-libs/log/logjson"
+)
+
+// stats counts the entries shown since the last report, by level and by
+// component.
+type stats struct {
+	componentKey string
+
+	mu         sync.Mutex
+	levels     map[zapcore.Level]int
+	components map[string]int
+	since      time.Time
+}
+
+func newStats(componentKey string, now time.Time) *stats {
+	return &stats{
+		componentKey: componentKey,
+		levels:       make(map[zapcore.Level]int),
+		components:   make(map[string]int),
+		since:        now,
+	}
+}
+
+func (s *stats) add(e logjson.Entry) {
+	component, _ := e.Fields[s.componentKey].(string)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.levels[e.Level]++
+	if component != "" {
+		s.components[component]++
+	}
+}
+
+// report writes the rates since the last report and resets the counts, like:
+//
+//	stats: 12.5/s info=10.0/s error=2.5/s | api=7.5/s db=5.0/s
+func (s *stats) report(w io.Writer, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := now.Sub(s.since).Seconds()
+	if elapsed <= 0 {
+		return nil
+	}
+	rate := func(n int) string {
+		return fmt.Sprintf("%.1f/s", float64(n)/elapsed)
+	}
+
+	var total int
+	var byLevel []string
+	for lvl := zapcore.DebugLevel; lvl <= zapcore.FatalLevel; lvl++ {
+		if n := s.levels[lvl]; n > 0 {
+			total += n
+			byLevel = append(byLevel, lvl.String()+"="+rate(n))
+		}
+	}
+	line := "stats: " + rate(total)
+	if len(byLevel) > 0 {
+		line += " " + strings.Join(byLevel, " ")
+	}
+
+	if len(s.components) > 0 {
+		names := make([]string, 0, len(s.components))
+		for name := range s.components {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		byComponent := make([]string, len(names))
+		for i, name := range names {
+			byComponent[i] = name + "=" + rate(s.components[name])
+		}
+		line += " | " + strings.Join(byComponent, " ")
+	}
+
+	s.levels = make(map[zapcore.Level]int)
+	s.components = make(map[string]int)
+	s.since = now
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
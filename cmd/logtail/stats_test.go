@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log/logjson"
+)
+
+func TestStatsReport(t *testing.T) {
+	start := time.Now()
+	s := newStats("logger", start)
+	d := logjson.NewDecoder(nil)
+	for _, line := range []string{
+		`{"level":"info","logger":"api","msg":"a"}`,
+		`{"level":"info","logger":"db","msg":"b"}`,
+		`{"level":"error","logger":"api","msg":"c"}`,
+		`{"level":"info","msg":"d"}`,
+	} {
+		s.add(d.Decode([]byte(line)))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, s.report(&buf, start.Add(2*time.Second)))
+	assert.Equal(t, "stats: 2.0/s info=1.5/s error=0.5/s | api=1.0/s db=0.5/s\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, s.report(&buf, start.Add(4*time.Second)))
+	assert.Equal(t, "stats: 0.0/s\n", buf.String(), "counts reset after each report")
+}
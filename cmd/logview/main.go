@@ -0,0 +1,134 @@
+// Command logview pretty-prints the JSON lines written by the log package,
+// laid out and colored like the development console, and filters them by
+// level, time range and field.
+//
+// Usage:
+//
+//	logview [flags] [file ...]
+//
+// It reads standard input when no file is given. For instance, to see the
+// warnings of the last hour about a given user:
+//
+//	logview -level warn -since 1h -where user_id=42 app.log
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Stasky745/go-libs/log"
+	"github.com/Stasky745/go-libs/log/logjson"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, isTerminal(os.Stdout)); err != nil {
+		fmt.Fprintln(os.Stderr, "logview:", err)
+		os.Exit(2)
+	}
+}
+
+// exprList collects repeated -where flags.
+type exprList []logjson.FieldExpr
+
+func (l *exprList) String() string {
+	return fmt.Sprint(len(*l), " expressions")
+}
+
+func (l *exprList) Set(s string) error {
+	expr, err := logjson.ParseFieldExpr(s)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, expr)
+	return nil
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, color bool) error {
+	flags := flag.NewFlagSet("logview", flag.ContinueOnError)
+	level := flags.String("level", "debug", "minimum `level` to show")
+	since := flags.String("since", "", "hide entries before `time` (RFC3339, date or duration ago)")
+	until := flags.String("until", "", "hide entries after `time` (RFC3339, date or duration ago)")
+	noColor := flags.Bool("no-color", false, "don't color levels")
+	var keys log.KeyNames
+	flags.StringVar(&keys.Message, "message-key", "", "`key` of the message, if renamed")
+	flags.StringVar(&keys.Level, "level-key", "", "`key` of the level, if renamed")
+	flags.StringVar(&keys.Time, "time-key", "", "`key` of the time, if renamed")
+	var where exprList
+	flags.Var(&where, "where", "show entries where `expr` holds: key=value, key!=value, key~regexp, key>n, key<=n, ... or key; repeatable")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter := logjson.Filter{Fields: where}
+	if err := filter.MinLevel.UnmarshalText([]byte(*level)); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, bound := range []struct {
+		flag string
+		dst  *time.Time
+	}{{*since, &filter.Since}, {*until, &filter.Until}} {
+		if bound.flag == "" {
+			continue
+		}
+		t, err := logjson.ParseTime(bound.flag, now)
+		if err != nil {
+			return err
+		}
+		*bound.dst = t
+	}
+
+	printer := logjson.NewPrinter(stdout, color && !*noColor)
+	if flags.NArg() == 0 {
+		return view(stdin, keys, filter, printer)
+	}
+	for _, name := range flags.Args() {
+		if err := viewFile(name, stdin, keys, filter, printer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func viewFile(name string, stdin io.Reader, keys log.KeyNames, filter logjson.Filter, printer *logjson.Printer) error {
+	if name == "-" {
+		return view(stdin, keys, filter, printer)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return view(f, keys, filter, printer)
+}
+
+func view(r io.Reader, keys log.KeyNames, filter logjson.Filter, printer *logjson.Printer) error {
+	d := logjson.NewDecoder(r).WithKeyNames(keys)
+	for {
+		e, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !filter.Match(e) {
+			continue
+		}
+		if err := printer.Print(e); err != nil {
+			return err
+		}
+	}
+}
+
+// isTerminal reports whether f is a character device, leaving colors out of
+// pipes and files.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const input = `{"level":"debug","ts":"2024-05-01T10:00:00Z","msg":"cache miss","key":"user:1"}
+{"level":"info","ts":"2024-05-01T10:00:01Z","msg":"request served","status":200}
+{"level":"error","ts":"2024-05-01T10:00:02Z","msg":"request failed","status":503}
+`
+
+func TestRunFiltersStdin(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"-level", "info", "-where", "status>=500", "-where", "msg~failed"}, strings.NewReader(input), &out, false))
+	assert.Equal(t, "2024-05-01T10:00:02.000Z\tERROR\trequest failed\t{\"status\":503}\n", out.String())
+}
+
+func TestRunTimeRangeAndFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(input), 0o600))
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"-no-color", "-since", "2024-05-01T10:00:01Z", "-until", "2024-05-01T10:00:01Z", path}, nil, &out, true))
+	assert.Equal(t, "2024-05-01T10:00:01.000Z\tINFO\trequest served\t{\"status\":200}\n", out.String())
+}
+
+func TestRunRejectsBadFlags(t *testing.T) {
+	var out bytes.Buffer
+	assert.Error(t, run([]string{"-level", "loud"}, strings.NewReader(input), &out, false))
+	assert.Error(t, run([]string{"-since", "soon"}, strings.NewReader(input), &out, false))
+	assert.Error(t, run([]string{"missing.log"}, nil, &out, false))
+}
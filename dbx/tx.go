@@ -0,0 +1,181 @@
+// Package dbx holds database/sql helpers.
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// defaultMaxRetries is how many times WithTx retries a transaction unless
+// told otherwise.
+const defaultMaxRetries = 3
+
+// Beginner starts transactions; *sql.DB and *sql.Conn implement it.
+type Beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// TxOption customizes WithTx.
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	name       string
+	txOptions  *sql.TxOptions
+	maxRetries int
+	backoff    log.Backoff
+	retryable  func(error) bool
+	logger     *log.Logger
+}
+
+// WithName names the transaction in its log entries.
+func WithName(name string) TxOption {
+	return func(o *txOptions) {
+		o.name = name
+	}
+}
+
+// WithTxOptions sets the isolation level and read-only mode.
+func WithTxOptions(opts *sql.TxOptions) TxOption {
+	return func(o *txOptions) {
+		o.txOptions = opts
+	}
+}
+
+// WithMaxRetries sets how many times a transaction is retried after a
+// retryable error, 3 by default. 0 disables retries.
+func WithMaxRetries(n int) TxOption {
+	return func(o *txOptions) {
+		o.maxRetries = n
+	}
+}
+
+// WithBackoff sets the delays between retries, short ones by default: 10ms
+// doubling up to 1s, with 20% jitter.
+func WithBackoff(b log.Backoff) TxOption {
+	return func(o *txOptions) {
+		o.backoff = b
+	}
+}
+
+// WithRetryable replaces IsRetryable to decide which errors are retried.
+func WithRetryable(fn func(error) bool) TxOption {
+	return func(o *txOptions) {
+		o.retryable = fn
+	}
+}
+
+// WithLogger logs through l instead of the global logger.
+func WithLogger(l *log.Logger) TxOption {
+	return func(o *txOptions) {
+		o.logger = l
+	}
+}
+
+// WithTx runs fn in a transaction, committing it if fn returns nil and
+// rolling it back otherwise, or if fn panics. When fn or the commit fail with
+// a serialization failure or a deadlock (see IsRetryable), the whole
+// transaction is retried, so fn must be safe to run again.
+//
+// Every transaction is logged once done, with its duration, the retries it
+// took and its outcome: at Debug when committed, at Warn when rolled back and
+// at Error when the commit failed. Each retry is logged at Info.
+func WithTx(ctx context.Context, db Beginner, fn func(tx *sql.Tx) error, opts ...TxOption) error {
+	o := &txOptions{
+		maxRetries: defaultMaxRetries,
+		backoff:    log.Backoff{Initial: 10 * time.Millisecond, Max: time.Second, Multiplier: 2, Jitter: 0.2},
+		retryable:  IsRetryable,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	logger := o.logger
+	if logger == nil {
+		logger = log.GetLogger()
+	}
+
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		outcome, err := runTx(ctx, db, o.txOptions, fn)
+		if err == nil || attempt >= o.maxRetries || !o.retryable(err) {
+			level := log.DebugLevel
+			switch outcome {
+			case "rolled back":
+				level = log.WarnLevel
+			case "commit failed", "begin failed":
+				level = log.ErrorLevel
+			}
+			keysAndValues := []interface{}{"tx", o.name, "outcome", outcome, "duration", time.Since(start), "retries", attempt}
+			if err != nil {
+				keysAndValues = append(keysAndValues, "error", err)
+			}
+			logger.Log(level, "transaction finished", keysAndValues...)
+			return err
+		}
+
+		delay := o.backoff.Delay(attempt)
+		logger.Info("retrying transaction", "tx", o.name, "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			logger.Warn("transaction finished", "tx", o.name, "outcome", "canceled", "duration", time.Since(start), "retries", attempt, "error", err)
+			return fmt.Errorf("%w (retrying after: %v)", ctx.Err(), err)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runTx runs one attempt and reports how it ended.
+func runTx(ctx context.Context, db Beginner, opts *sql.TxOptions, fn func(tx *sql.Tx) error) (outcome string, err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return "begin failed", err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			return "rolled back", errors.Join(err, fmt.Errorf("rollback failed: %w", rollbackErr))
+		}
+		return "rolled back", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "commit failed", err
+	}
+	return "committed", nil
+}
+
+// sqlStateError is implemented by the errors of the PostgreSQL drivers
+// (pgx, lib/pq) and others reporting SQLSTATE codes.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// Retryable SQLSTATE codes: serialization_failure and deadlock_detected.
+var retryableStates = map[string]bool{"40001": true, "40P01": true}
+
+// IsRetryable reports whether err is a serialization failure or a deadlock,
+// after which the transaction may succeed if retried. It recognizes SQLSTATE
+// codes 40001 and 40P01 from drivers exposing them, and MySQL's deadlock and
+// lock wait timeout errors (1213 and 1205) by their message.
+func IsRetryable(err error) bool {
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		return retryableStates[stateErr.SQLState()]
+	}
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "Error 1213") || strings.Contains(msg, "Error 1205")
+}
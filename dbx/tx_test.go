@@ -0,0 +1,186 @@
+package dbx
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// fakeDriver records the transactions of its connections, failing commits
+// with the errors queued in commitErrs.
+type fakeDriver struct {
+	mu         sync.Mutex
+	commits    int
+	rollbacks  int
+	commitErrs []error
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return &fakeTx{d: c.d}, nil }
+
+type fakeTx struct{ d *fakeDriver }
+
+func (tx *fakeTx) Commit() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+
+	if len(tx.d.commitErrs) > 0 {
+		err := tx.d.commitErrs[0]
+		tx.d.commitErrs = tx.d.commitErrs[1:]
+		return err
+	}
+	tx.d.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+
+	tx.d.rollbacks++
+	return nil
+}
+
+type fakeConnector struct{ d *fakeDriver }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c fakeConnector) Driver() driver.Driver                        { return c.d }
+
+func openFake(t *testing.T, commitErrs ...error) (*sql.DB, *fakeDriver) {
+	d := &fakeDriver{commitErrs: commitErrs}
+	db := sql.OpenDB(fakeConnector{d: d})
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+type pgError struct{ code string }
+
+func (e pgError) Error() string    { return "pg error " + e.code }
+func (e pgError) SQLState() string { return e.code }
+
+func testLogger(buf *bytes.Buffer) *log.Logger {
+	return log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" || a.Key == "delay" || a.Key == slog.SourceKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+}
+
+var noDelay = log.Backoff{Initial: time.Microsecond, Multiplier: 1}
+
+func TestWithTxCommits(t *testing.T) {
+	db, d := openFake(t)
+	var buf bytes.Buffer
+
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error { return nil }, WithName("create user"), WithLogger(testLogger(&buf)))
+	require.NoError(t, err)
+	assert.Equal(t, 1, d.commits)
+	assert.Equal(t, "level=DEBUG msg=\"transaction finished\" tx=\"create user\" outcome=committed retries=0\n", buf.String())
+}
+
+func TestWithTxRollsBack(t *testing.T) {
+	db, d := openFake(t)
+	var buf bytes.Buffer
+	failure := errors.New("user exists")
+
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error { return failure }, WithLogger(testLogger(&buf)))
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 0, d.commits)
+	assert.Equal(t, 1, d.rollbacks)
+	assert.Contains(t, buf.String(), "level=WARN msg=\"transaction finished\" tx=\"\" outcome=\"rolled back\" retries=0 error=\"user exists\"")
+}
+
+func TestWithTxRetriesSerializationFailures(t *testing.T) {
+	db, d := openFake(t, pgError{"40001"})
+	var buf bytes.Buffer
+	calls := 0
+
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		calls++
+		if calls == 1 {
+			return pgError{"40P01"}
+		}
+		return nil
+	}, WithBackoff(noDelay), WithLogger(testLogger(&buf)))
+	require.NoError(t, err)
+
+	// Deadlock in fn, serialization failure on commit, then success.
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 1, d.commits)
+	assert.Equal(t, 2, strings.Count(buf.String(), "msg=\"retrying transaction\""))
+	assert.Contains(t, buf.String(), "outcome=committed retries=2")
+}
+
+func TestWithTxGivesUp(t *testing.T) {
+	db, _ := openFake(t)
+	var buf bytes.Buffer
+	calls := 0
+
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		calls++
+		return pgError{"40001"}
+	}, WithMaxRetries(1), WithBackoff(noDelay), WithLogger(testLogger(&buf)))
+	assert.Equal(t, pgError{"40001"}, err)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	err = WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		calls++
+		return pgError{"23505"} // unique_violation isn't retried
+	}, WithBackoff(noDelay), WithLogger(testLogger(&buf)))
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithTxStopsRetryingWhenCanceled(t *testing.T) {
+	db, _ := openFake(t)
+	var buf bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := WithTx(ctx, db, func(tx *sql.Tx) error {
+		cancel()
+		return pgError{"40001"}
+	}, WithBackoff(log.Backoff{Initial: time.Hour}), WithLogger(testLogger(&buf)))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, buf.String(), "outcome=canceled")
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	db, d := openFake(t)
+	var buf bytes.Buffer
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = WithTx(context.Background(), db, func(tx *sql.Tx) error { panic("boom") }, WithLogger(testLogger(&buf)))
+	})
+	assert.Equal(t, 1, d.rollbacks)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(pgError{"40001"}))
+	assert.True(t, IsRetryable(pgError{"40P01"}))
+	assert.False(t, IsRetryable(pgError{"23505"}))
+	assert.True(t, IsRetryable(errors.New("Error 1213 (40001): Deadlock found when trying to get lock")))
+	assert.False(t, IsRetryable(errors.New("connection refused")))
+	assert.False(t, IsRetryable(nil))
+}
@@ -0,0 +1,143 @@
+// Package execx runs subprocesses, logging their output line by line so it
+// ends up as structured entries instead of raw bytes interleaved with the
+// parent's logs.
+package execx
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// defaultMaxLineBytes caps the lines logged unless told otherwise; longer
+// lines are split.
+const defaultMaxLineBytes = 64 * 1024
+
+// Option customizes Run.
+type Option func(*options)
+
+type options struct {
+	name         string
+	stdoutLevel  log.Level
+	stderrLevel  log.Level
+	maxLineBytes int
+	logger       *log.Logger
+}
+
+// WithName logs the command as name instead of its path and arguments, to
+// keep secrets passed as arguments out of the logs.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithStdoutLevel logs standard output lines at level, Info by default.
+func WithStdoutLevel(level log.Level) Option {
+	return func(o *options) {
+		o.stdoutLevel = level
+	}
+}
+
+// WithStderrLevel logs standard error lines at level, Warn by default.
+func WithStderrLevel(level log.Level) Option {
+	return func(o *options) {
+		o.stderrLevel = level
+	}
+}
+
+// WithMaxLineBytes splits lines longer than n bytes, 64KiB by default.
+func WithMaxLineBytes(n int) Option {
+	return func(o *options) {
+		o.maxLineBytes = n
+	}
+}
+
+// WithLogger logs through l instead of the global logger.
+func WithLogger(l *log.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// Run starts cmd and waits for it, logging each line it writes to standard
+// output and standard error as an entry with the command, its PID and the
+// stream. Once it exits, it logs the duration and exit code: at Info when it
+// succeeded and at Error otherwise. cmd.Stdout and cmd.Stderr must be nil.
+//
+// Run returns the error of cmd.Start or cmd.Wait.
+func Run(cmd *exec.Cmd, opts ...Option) error {
+	o := &options{
+		name:         cmd.String(),
+		stdoutLevel:  log.InfoLevel,
+		stderrLevel:  log.WarnLevel,
+		maxLineBytes: defaultMaxLineBytes,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	logger := o.logger
+	if logger == nil {
+		logger = log.GetLogger()
+	}
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		return errors.New("execx: Stdout and Stderr must be nil")
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		logger.Error("command failed to start", "cmd", o.name, "error", err)
+		return err
+	}
+	fields := []interface{}{"cmd", o.name, "pid", cmd.Process.Pid}
+
+	// The pipes must be drained before Wait, which closes them.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		logLines(logger, stdout, o.stdoutLevel, append(fields, "stream", "stdout"), o.maxLineBytes)
+	}()
+	go func() {
+		defer wg.Done()
+		logLines(logger, stderr, o.stderrLevel, append(fields, "stream", "stderr"), o.maxLineBytes)
+	}()
+	wg.Wait()
+
+	err = cmd.Wait()
+	keysAndValues := append(fields, "duration", time.Since(start), "exit_code", cmd.ProcessState.ExitCode())
+	if err != nil {
+		logger.Error("command failed", append(keysAndValues, "error", err)...)
+		return err
+	}
+	logger.Info("command finished", keysAndValues...)
+	return nil
+}
+
+// logLines logs each line read from r with fields until it's closed.
+func logLines(logger *log.Logger, r io.Reader, level log.Level, fields []interface{}, maxLineBytes int) {
+	br := bufio.NewReaderSize(r, maxLineBytes)
+	for {
+		line, _, err := br.ReadLine()
+		if len(line) > 0 {
+			logger.Log(level, string(line), fields...)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package execx
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// testLogger returns a logger writing JSON lines to buf, at every level.
+func testLogger(buf *bytes.Buffer) *log.Logger {
+	return log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+}
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+func TestRun(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := exec.Command("sh", "-c", "echo hello; echo oops >&2; printf 'no newline'")
+
+	require.NoError(t, Run(cmd, WithLogger(testLogger(&buf))))
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 4)
+	byMsg := map[string]map[string]interface{}{}
+	for _, entry := range logged {
+		byMsg[entry["msg"].(string)] = entry
+	}
+
+	assert.Equal(t, "INFO", byMsg["hello"]["level"])
+	assert.Equal(t, "stdout", byMsg["hello"]["stream"])
+	assert.Equal(t, float64(cmd.Process.Pid), byMsg["hello"]["pid"])
+	assert.Equal(t, cmd.String(), byMsg["hello"]["cmd"])
+	assert.Equal(t, "WARN", byMsg["oops"]["level"])
+	assert.Equal(t, "stderr", byMsg["oops"]["stream"])
+	assert.Contains(t, byMsg, "no newline")
+
+	finished := logged[3]
+	assert.Equal(t, "command finished", finished["msg"])
+	assert.Equal(t, float64(0), finished["exit_code"])
+	assert.Contains(t, finished, "duration")
+}
+
+func TestRunFailure(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := exec.Command("sh", "-c", "exit 3")
+
+	err := Run(cmd, WithName("migrate"), WithLogger(testLogger(&buf)))
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "ERROR", logged[0]["level"])
+	assert.Equal(t, "command failed", logged[0]["msg"])
+	assert.Equal(t, "migrate", logged[0]["cmd"])
+	assert.Equal(t, float64(3), logged[0]["exit_code"])
+}
+
+func TestRunLevelsAndLongLines(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := exec.Command("sh", "-c", "echo 0123456789abcdefXYZ >&2")
+
+	require.NoError(t, Run(cmd, WithStderrLevel(log.ErrorLevel), WithMaxLineBytes(16), WithLogger(testLogger(&buf))))
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 3)
+	assert.Equal(t, "0123456789abcdef", logged[0]["msg"])
+	assert.Equal(t, "XYZ", logged[1]["msg"])
+	assert.Equal(t, "ERROR", logged[0]["level"])
+}
+
+func TestRunStartFailure(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Run(exec.Command("/does/not/exist"), WithLogger(testLogger(&buf)))
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), "command failed to start")
+
+	cmd := exec.Command("true")
+	cmd.Stdout = &bytes.Buffer{}
+	assert.Error(t, Run(cmd))
+}
@@ -0,0 +1,103 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPMiddleware logs every request handled by next through the global
+// logger. See (*Logger).HTTPMiddleware.
+func HTTPMiddleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
+	return newMiddleware(GetLogger, next, opts)
+}
+
+// HTTPMiddleware logs every request handled by next once it completes, with
+// its method, path, status, response size, latency, remote IP and user agent,
+// and its ID if RequestIDMiddleware gave it one.
+// Requests are logged at Info, at Warn for 4xx statuses and at Error for 5xx
+// ones.
+func (l *Logger) HTTPMiddleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
+	return newMiddleware(func() *Logger { return l }, next, opts)
+}
+
+func newMiddleware(logger func() *Logger, next http.Handler, opts []MiddlewareOption) http.Handler {
+	o := &middlewareOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.bodies != nil {
+		keys := o.redactedKeys
+		if keys == nil {
+			keys = DefaultRedactedKeys
+		}
+		o.bodies.redacted = newKeySet(keys)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule, matched := o.rule(r)
+		if matched && rule.skip {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		rec := NewResponseRecorder(w)
+		var requestBody *capturedBody
+		if o.bodies != nil {
+			requestBody = o.bodies.captureRequest(r)
+			rec.body = &capturedBody{max: o.bodies.max}
+		}
+		next.ServeHTTP(rec, r)
+
+		status := rec.Status()
+		level := statusLevel(status)
+		if matched && status < http.StatusInternalServerError {
+			level = rule.level
+		}
+
+		keysAndValues := []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", rec.Bytes(),
+			"latency", time.Since(start),
+			"remote_ip", remoteIP(r),
+			"user_agent", r.UserAgent(),
+		}
+		if o.route != nil {
+			if route := o.route(r); route != "" {
+				keysAndValues = append(keysAndValues, "route", route)
+			}
+		}
+		if id := RequestIDFromContext(r.Context()); id != "" {
+			keysAndValues = append(keysAndValues, RequestIDKey, id)
+		}
+		if o.bodies != nil {
+			keysAndValues = o.bodies.appendBody(keysAndValues, "request_body", requestBody)
+			keysAndValues = o.bodies.appendBody(keysAndValues, "response_body", rec.body)
+		}
+		logger().Log(level, "http request", keysAndValues...)
+	})
+}
+
+func statusLevel(status int) Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return ErrorLevel
+	case status >= http.StatusBadRequest:
+		return WarnLevel
+	default:
+		return InfoLevel
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,35 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	serve(h, "/hello", "curl/8.0")
+	serve(h, "/missing", "curl/8.0")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "info"))
+	assert.Contains(t, lines[0], `"method": "GET", "path": "/hello", "status": 200, "bytes": 5`)
+	assert.Contains(t, lines[0], `"remote_ip": "192.0.2.1", "user_agent": "curl/8.0"`)
+	assert.True(t, strings.HasPrefix(lines[1], "warn"))
+	assert.Contains(t, lines[1], `"status": 404`)
+}
@@ -0,0 +1,231 @@
+//go:build !nozap
+
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AlertRule fires its Notifier once at least Threshold entries at Level or
+// above, carrying all of Fields, are logged within Window. The alert resolves
+// after a full Window passes without another matching entry.
+type AlertRule struct {
+	Name      string
+	Level     Level
+	Fields    map[string]string
+	Threshold int
+	Window    time.Duration
+	Notifier  Notifier
+}
+
+// Alert describes a state change of an AlertRule.
+type Alert struct {
+	Rule   string
+	Firing bool // false once the alert resolves
+	Count  int  // matching entries within the window when it fired
+	At     time.Time
+}
+
+// Notifier delivers alerts somewhere outside the process.
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(alert Alert) error
+
+// Notify calls f(alert).
+func (f NotifierFunc) Notify(alert Alert) error {
+	return f(alert)
+}
+
+// WithAlerts evaluates the given rules against every entry logged. Notifiers
+// run in their own goroutine so a slow notifier never blocks logging, except
+// when a DPanic, Panic or Fatal entry fires the alert: it's notified before
+// the entry is written, as the process may not outlive it.
+func WithAlerts(rules ...AlertRule) Option {
+	return func(o *options) {
+		engine := &alertEngine{}
+		for _, rule := range rules {
+			engine.rules = append(engine.rules, &alertState{rule: rule})
+		}
+		o.alerts = engine
+	}
+}
+
+type alertEngine struct {
+	rootMu sync.Mutex
+	root   zapcore.Core // Replaced when a ConfigWatcher rebuilds the core
+	rules  []*alertState
+}
+
+type alertState struct {
+	rule AlertRule
+
+	mu     sync.Mutex
+	hits   []time.Time
+	firing bool
+	until  time.Time // Resolution time, pushed back by matching entries
+	timer  *time.Timer
+}
+
+func (e *alertEngine) wrap(core zapcore.Core) zapcore.Core {
+	e.rootMu.Lock()
+	e.root = core
+	e.rootMu.Unlock()
+
+	return &alertCore{Core: core, engine: e}
+}
+
+func (e *alertEngine) minLevel() zapcore.Level {
+	lvl := zapcore.FatalLevel
+	for _, s := range e.rules {
+		if level := zapcore.Level(s.rule.Level); level < lvl {
+			lvl = level
+		}
+	}
+	return lvl
+}
+
+func (e *alertEngine) observe(ent zapcore.Entry, fields map[string]string) {
+	for _, s := range e.rules {
+		if ent.Level < zapcore.Level(s.rule.Level) || !matchFields(s.rule.Fields, fields) {
+			continue
+		}
+		e.record(s, ent.Time, ent.Level >= zapcore.DPanicLevel)
+	}
+}
+
+// record registers a matching entry logged at, firing the alert when the
+// threshold is crossed. The alert is delivered before record returns if blocking
+// is set.
+func (e *alertEngine) record(s *alertState, at time.Time, blocking bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.firing {
+		// The timer may be firing already, so resolve arms it again instead of
+		// it being reset here.
+		s.until = time.Now().Add(s.rule.Window)
+		return
+	}
+
+	recent := s.hits[:0]
+	for _, t := range s.hits {
+		if at.Sub(t) < s.rule.Window {
+			recent = append(recent, t)
+		}
+	}
+	s.hits = append(recent, at)
+
+	if len(s.hits) < s.rule.Threshold {
+		return
+	}
+
+	s.firing = true
+	s.until = time.Now().Add(s.rule.Window)
+	s.timer = time.AfterFunc(s.rule.Window, func() { e.resolve(s) })
+	e.notify(s.rule, Alert{Rule: s.rule.Name, Firing: true, Count: len(s.hits), At: at}, blocking)
+	s.hits = nil
+}
+
+// resolve resolves the alert unless a matching entry pushed its resolution
+// back, in which case it's checked again then.
+func (e *alertEngine) resolve(s *alertState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.firing {
+		return
+	}
+	if wait := time.Until(s.until); wait > 0 {
+		s.timer.Reset(wait)
+		return
+	}
+	s.firing = false
+	e.notify(s.rule, Alert{Rule: s.rule.Name, At: time.Now()}, false)
+}
+
+// notify delivers alert with the rule's notifier, in its own goroutine unless
+// blocking is set.
+func (e *alertEngine) notify(rule AlertRule, alert Alert, blocking bool) {
+	if rule.Notifier == nil {
+		return
+	}
+
+	deliver := func() {
+		if err := rule.Notifier.Notify(alert); err != nil {
+			ent := zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Now(), Message: "can't deliver alert"}
+			e.rootMu.Lock()
+			root := e.root
+			e.rootMu.Unlock()
+			if ce := root.Check(ent, nil); ce != nil {
+				ce.Write(zap.String("rule", rule.Name), zap.Bool("firing", alert.Firing), zap.Error(err))
+			}
+		}
+	}
+	if blocking {
+		deliver()
+		return
+	}
+	go deliver()
+}
+
+// alertCore feeds entries to the alert engine; writing is left to the wrapped
+// core.
+type alertCore struct {
+	zapcore.Core
+	engine *alertEngine
+	fields []zapcore.Field
+}
+
+func (c *alertCore) With(fields []zapcore.Field) zapcore.Core {
+	return &alertCore{
+		Core:   c.Core.With(fields),
+		engine: c.engine,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *alertCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= c.engine.minLevel() {
+		ce = ce.AddCore(ent, observer{fn: c.observe})
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *alertCore) observe(ent zapcore.Entry, fields []zapcore.Field) {
+	values := fieldValues(c.fields)
+	for k, v := range fieldValues(fields) {
+		values[k] = v
+	}
+	c.engine.observe(ent, values)
+}
+
+// fieldValues renders fields as strings keyed by field name.
+func fieldValues(fields []zapcore.Field) map[string]string {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	values := make(map[string]string, len(enc.Fields))
+	for k, v := range enc.Fields {
+		values[k] = fmt.Sprint(v)
+	}
+	return values
+}
+
+func matchFields(want, got map[string]string) bool {
+	for k, v := range want {
+		if got[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+//go:build !nozap
+
+package log
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertFiresOnceAndResolves(t *testing.T) {
+	alerts := make(chan Alert, 10)
+	rule := AlertRule{
+		Name:      "payments-errors",
+		Level:     ErrorLevel,
+		Fields:    map[string]string{ComponentKey: "payments"},
+		Threshold: 2,
+		Window:    50 * time.Millisecond,
+		Notifier:  NotifierFunc(func(a Alert) error { alerts <- a; return nil }),
+	}
+	_, cleanup := setupTestLogger(false, WithAlerts(rule))
+	defer cleanup()
+
+	Error("charge failed", ComponentKey, "payments")
+	Error("charge failed", ComponentKey, "shipping") // Doesn't match the rule
+	Warn("charge slow", ComponentKey, "payments")    // Below the rule level
+	Error("charge failed", ComponentKey, "payments")
+	Error("charge failed", ComponentKey, "payments") // Already firing
+
+	select {
+	case a := <-alerts:
+		assert.True(t, a.Firing)
+		assert.Equal(t, "payments-errors", a.Rule)
+		assert.Equal(t, 2, a.Count)
+	case <-time.After(time.Second):
+		t.Fatal("alert did not fire")
+	}
+
+	select {
+	case a := <-alerts:
+		assert.False(t, a.Firing) // Resolved once quiet
+	case <-time.After(time.Second):
+		t.Fatal("alert did not resolve")
+	}
+}
+
+func TestAlertMatchesBoundFields(t *testing.T) {
+	fired := make(chan Alert, 1)
+	rule := AlertRule{
+		Name:      "any-error",
+		Level:     ErrorLevel,
+		Fields:    map[string]string{"tenant": "acme"},
+		Threshold: 1,
+		Window:    time.Minute,
+		Notifier:  NotifierFunc(func(a Alert) error { fired <- a; return nil }),
+	}
+	_, cleanup := setupTestLogger(false, WithAlerts(rule))
+	defer cleanup()
+
+	bound := GetLogger().derive(GetLogger().sugaredLogger.With("tenant", "acme"))
+	bound.Error("tenant failure")
+
+	select {
+	case a := <-fired:
+		assert.True(t, a.Firing)
+	case <-time.After(time.Second):
+		t.Fatal("alert did not fire for bound fields")
+	}
+}
+
+func TestAlertNotifiesBeforePanicking(t *testing.T) {
+	var notified []Alert // Not synchronized: written before Panic returns
+	rule := AlertRule{
+		Name:      "panics",
+		Level:     PanicLevel,
+		Threshold: 1,
+		Window:    time.Minute,
+		Notifier:  NotifierFunc(func(a Alert) error { notified = append(notified, a); return nil }),
+	}
+	_, cleanup := setupTestLogger(false, WithAlerts(rule))
+	defer cleanup()
+
+	assert.Panics(t, func() { Panic("unrecoverable") })
+	if assert.Len(t, notified, 1) {
+		assert.True(t, notified[0].Firing)
+	}
+}
+
+func TestAlertExtendedByEntriesResolvesOnce(t *testing.T) {
+	var mu sync.Mutex
+	var resolved int
+	rule := AlertRule{
+		Name:      "errors",
+		Level:     ErrorLevel,
+		Threshold: 1,
+		Window:    100 * time.Millisecond,
+		Notifier: NotifierFunc(func(a Alert) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if !a.Firing {
+				resolved++
+			}
+			return nil
+		}),
+	}
+	_, cleanup := setupTestLogger(false, WithAlerts(rule))
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		Error("failure")
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	assert.Zero(t, resolved) // Still firing
+	mu.Unlock()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return resolved > 0
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(150 * time.Millisecond) // A stale timer would resolve again
+	mu.Lock()
+	assert.Equal(t, 1, resolved)
+	mu.Unlock()
+}
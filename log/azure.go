@@ -0,0 +1,210 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	azureAPIVersion    = "2023-01-01"
+	azureMonitorScope  = "https://monitor.azure.com//.default"
+	azureAuthorityHost = "https://login.microsoftonline.com"
+)
+
+// AzureMonitorConfig configures a sink for the Azure Monitor Logs ingestion
+// API, which writes entries to a Log Analytics table through a data
+// collection rule (DCR).
+type AzureMonitorConfig struct {
+	// Endpoint is the logs ingestion endpoint of the data collection
+	// endpoint or rule, like "https://my-dce-a1b2.westeurope-1.ingest.monitor.azure.com".
+	Endpoint string
+	RuleID   string // Immutable ID of the rule, "dcr-..."
+	Stream   string // Stream declared by the rule, like "Custom-AppLogs_CL"
+
+	// Credentials of the Microsoft Entra (Azure AD) application allowed to
+	// publish to the rule, used with the client credentials flow.
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+
+	// AuthorityHost replaces https://login.microsoftonline.com, for
+	// sovereign clouds.
+	AuthorityHost string
+
+	// Token, if set, replaces the client credentials, for instance to use a
+	// managed identity through azidentity. It returns a token for the
+	// "https://monitor.azure.com//.default" scope and its expiry.
+	Token func(ctx context.Context) (token string, expires time.Time, err error)
+
+	// Columns maps entry keys to the columns of the stream. It's merged over
+	// the defaults: "ts" to TimeGenerated, "level" to Level, "msg" to Message
+	// and "caller" to Caller. The fields not mapped go into the dynamic
+	// column named by PropertiesColumn, "Properties" by default.
+	Columns          map[string]string
+	PropertiesColumn string
+
+	HTTP  HTTPConfig
+	Batch BatchConfig // MaxBytes is capped to stay under the API's 1MB limit
+}
+
+// NewAzureMonitorSink returns a sink uploading entries to Azure Monitor in
+// batches. Entries must be JSON encoded.
+func NewAzureMonitorSink(config AzureMonitorConfig) (*BatchSink, error) {
+	if config.Endpoint == "" || config.RuleID == "" || config.Stream == "" {
+		return nil, errors.New("azure monitor sink needs an endpoint, a rule ID and a stream")
+	}
+
+	token := config.Token
+	if token == nil {
+		if config.TenantID == "" || config.ClientID == "" || config.ClientSecret == "" {
+			return nil, errors.New("azure monitor sink needs a tenant ID, client ID and client secret, or a Token function")
+		}
+		client, err := config.HTTP.Client()
+		if err != nil {
+			return nil, err
+		}
+		token = (&aadCredential{config: config, client: client}).token
+	}
+
+	endpoint := fmt.Sprintf("%s/dataCollectionRules/%s/streams/%s?api-version=%s",
+		strings.TrimSuffix(config.Endpoint, "/"), url.PathEscape(config.RuleID), url.PathEscape(config.Stream), azureAPIVersion)
+	shipper, err := newHTTPShipper(config.HTTP, endpoint, "application/json", Gzip)
+	if err != nil {
+		return nil, err
+	}
+	cache := &tokenCache{fetch: token}
+	shipper.authorize = func(req *http.Request) error {
+		t, err := cache.get(req.Context())
+		if err != nil {
+			return fmt.Errorf("can't get an azure monitor token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+		return nil
+	}
+
+	columns := map[string]string{"ts": "TimeGenerated", "level": "Level", "msg": "Message", "caller": "Caller"}
+	for key, column := range config.Columns {
+		columns[key] = column
+	}
+	properties := config.PropertiesColumn
+	if properties == "" {
+		properties = "Properties"
+	}
+
+	config.Batch.MaxBytes = capBatchBytes(config.Batch.MaxBytes, oneMBBatchBytes)
+	return newDecodingBatchSink("azure-monitor", config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		rows := make([]map[string]interface{}, len(batch))
+		for i, line := range batch {
+			rows[i] = azureRow(decode(line), columns, properties)
+		}
+		body, err := json.Marshal(rows)
+		if err != nil {
+			return err
+		}
+		return shipper.ship(body)
+	}), nil
+}
+
+// azureRow maps an entry to the columns of the stream.
+func azureRow(e sinkEntry, columns map[string]string, properties string) map[string]interface{} {
+	row := map[string]interface{}{
+		columns["ts"]:     e.Time.UTC().Format(time.RFC3339Nano),
+		columns["level"]:  e.Level,
+		columns["msg"]:    e.Message,
+		columns["caller"]: e.Caller,
+	}
+
+	rest := make(map[string]interface{})
+	for key, value := range e.Fields {
+		if column, ok := columns[key]; ok {
+			row[column] = value
+		} else {
+			rest[key] = value
+		}
+	}
+	if len(rest) > 0 {
+		row[properties] = rest
+	}
+	return row
+}
+
+// aadCredential gets tokens with the client credentials flow.
+type aadCredential struct {
+	config AzureMonitorConfig
+	client *http.Client
+}
+
+func (c *aadCredential) token(ctx context.Context) (string, time.Time, error) {
+	authority := c.config.AuthorityHost
+	if authority == "" {
+		authority = azureAuthorityHost
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.config.ClientID},
+		"client_secret": {c.config.ClientSecret},
+		"scope":         {azureMonitorScope},
+	}
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authority, "/"), url.PathEscape(c.config.TenantID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, &HTTPStatusError{URL: tokenURL, StatusCode: resp.StatusCode}
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid token response: %w", err)
+	}
+	return body.AccessToken, time.Now().Add(time.Duration(body.ExpiresIn) * time.Second), nil
+}
+
+// tokenCache reuses a token until shortly before it expires.
+type tokenCache struct {
+	fetch func(ctx context.Context) (string, time.Time, error)
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// tokenRefreshMargin renews tokens before they expire, so none expires in
+// flight.
+const tokenRefreshMargin = 5 * time.Minute
+
+func (c *tokenCache) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Until(c.expires) > tokenRefreshMargin {
+		return c.token, nil
+	}
+	token, expires, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expires = token, expires
+	return token, nil
+}
@@ -0,0 +1,118 @@
+//go:build !nozap
+
+package log
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureMonitorSink(t *testing.T) {
+	var mu sync.Mutex
+	var tokenRequests int
+	var rows []map[string]interface{}
+	var auth, path string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+			assert.Equal(t, "https://monitor.azure.com//.default", r.PostForm.Get("scope"))
+			tokenRequests++
+			_, _ = w.Write([]byte(`{"access_token":"secret-token","expires_in":3600}`))
+			return
+		}
+
+		auth, path = r.Header.Get("Authorization"), r.URL.RequestURI()
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		var batch []map[string]interface{}
+		require.NoError(t, json.NewDecoder(gz).Decode(&batch))
+		rows = append(rows, batch...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink, err := NewAzureMonitorSink(AzureMonitorConfig{
+		Endpoint:      server.URL,
+		RuleID:        "dcr-123",
+		Stream:        "Custom-AppLogs_CL",
+		TenantID:      "tenant",
+		ClientID:      "client",
+		ClientSecret:  "secret",
+		AuthorityHost: server.URL,
+		Columns:       map[string]string{"request_id": "RequestId"},
+		HTTP:          HTTPConfig{Compression: Gzip},
+		Batch:         BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"error","ts":1714557600.5,"caller":"api/h.go:7","msg":"failed","request_id":"r-1","user":"bob"}` + "\n"))
+	require.NoError(t, sink.Sync())
+	_, _ = sink.Write([]byte(`{"level":"info","msg":"second"}`))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, tokenRequests, "token is cached")
+	assert.Equal(t, "Bearer secret-token", auth)
+	assert.Equal(t, "/dataCollectionRules/dcr-123/streams/Custom-AppLogs_CL?api-version=2023-01-01", path)
+	require.Len(t, rows, 2)
+	assert.Equal(t, map[string]interface{}{
+		"TimeGenerated": "2024-05-01T10:00:00.5Z",
+		"Level":         "error",
+		"Message":       "failed",
+		"Caller":        "api/h.go:7",
+		"RequestId":     "r-1",
+		"Properties":    map[string]interface{}{"user": "bob"},
+	}, rows[0])
+	assert.NotContains(t, rows[1], "Properties")
+}
+
+func TestAzureMonitorSinkConfigErrors(t *testing.T) {
+	_, err := NewAzureMonitorSink(AzureMonitorConfig{Endpoint: "https://dce", RuleID: "dcr-1"})
+	assert.Error(t, err)
+
+	_, err = NewAzureMonitorSink(AzureMonitorConfig{Endpoint: "https://dce", RuleID: "dcr-1", Stream: "Custom-X"})
+	assert.Error(t, err, "credentials are required")
+
+	sink, err := NewAzureMonitorSink(AzureMonitorConfig{
+		Endpoint: "https://dce", RuleID: "dcr-1", Stream: "Custom-X",
+		Token: func(context.Context) (string, time.Time, error) { return "t", time.Now().Add(time.Hour), nil },
+	})
+	require.NoError(t, err)
+	assert.NoError(t, sink.Close())
+}
+
+func TestTokenCacheRefreshesBeforeExpiry(t *testing.T) {
+	var fetches int
+	cache := &tokenCache{fetch: func(context.Context) (string, time.Time, error) {
+		fetches++
+		return "token", time.Now().Add(tokenRefreshMargin + time.Second), nil
+	}}
+
+	for i := 0; i < 3; i++ {
+		token, err := cache.get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token", token)
+	}
+	assert.Equal(t, 1, fetches)
+
+	cache.expires = time.Now().Add(tokenRefreshMargin - time.Second)
+	_, _ = cache.get(context.Background())
+	assert.Equal(t, 2, fetches)
+}
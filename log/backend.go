@@ -0,0 +1,52 @@
+package log
+
+// Level is the severity of an entry, shared by every Backend. The values match
+// zap's levels.
+type Level int8
+
+const (
+	DebugLevel Level = iota - 1
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	PanicLevel Level = iota
+	FatalLevel
+)
+
+// Backend is the engine a Logger writes its entries through. zap is the
+// default one; others can be plugged in with NewLoggerWithBackend or
+// UseBackend. Builds with the nozap tag have no zap backend; the default one
+// is then slog, see NewSlogBackend.
+type Backend interface {
+	// Log writes an entry with alternating keys and values. After a
+	// PanicLevel entry it panics with msg, and after a FatalLevel one it exits
+	// the process; see Terminate.
+	Log(level Level, msg string, keysAndValues []interface{})
+
+	// With returns a Backend adding keysAndValues to every entry.
+	With(keysAndValues []interface{}) Backend
+
+	// Enabled reports whether entries at level are written.
+	Enabled(level Level) bool
+
+	// Sync flushes any buffered entries.
+	Sync() error
+}
+
+// UseBackend replaces the global logger with one writing through b.
+func UseBackend(b Backend) {
+	logger.Store(NewLoggerWithBackend(b))
+}
+
+// Terminate finishes a PanicLevel or FatalLevel entry the way the zap backend
+// does: it panics with msg after a PanicLevel entry and runs the OnFatal and
+// OnExit hooks before exiting after a FatalLevel one. Other backends call it
+// once they've written such an entry.
+func Terminate(level Level, msg string) {
+	switch level {
+	case PanicLevel:
+		panic(msg)
+	case FatalLevel:
+		runFatalHooks(defaultExitTimeout)
+	}
+}
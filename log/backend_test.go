@@ -0,0 +1,103 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+// recordingBackend keeps the entries logged through it.
+type recordingBackend struct {
+	mu      sync.Mutex
+	level   Level
+	context []interface{}
+	entries *[]string
+}
+
+func newRecordingBackend(level Level) *recordingBackend {
+	return &recordingBackend{level: level, entries: new([]string)}
+}
+
+func (b *recordingBackend) Log(level Level, msg string, keysAndValues []interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	*b.entries = append(*b.entries, fmt.Sprint(level, " ", msg, " ", append(b.context, keysAndValues...)))
+	Terminate(level, msg)
+}
+
+func (b *recordingBackend) With(keysAndValues []interface{}) Backend {
+	return &recordingBackend{level: b.level, context: append(b.context, keysAndValues...), entries: b.entries}
+}
+
+func (b *recordingBackend) Enabled(level Level) bool { return level >= b.level }
+
+func (b *recordingBackend) Sync() error { return nil }
+
+func (b *recordingBackend) logged() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string{}, *b.entries...)
+}
+
+func TestLoggerWithBackend(t *testing.T) {
+	backend := newRecordingBackend(InfoLevel)
+	l := NewLoggerWithBackend(backend)
+
+	l.Info("hello", "key", "value")
+	l.Debugf("formatted %d", 1) // Disabled, never formatted
+	l.Warnf("formatted %d", 2)
+	assert.Panics(t, func() { l.Panic("boom") })
+
+	assert.Equal(t, []string{
+		"0 hello [key value]",
+		"1 formatted 2 []",
+		"4 boom []",
+	}, backend.logged())
+}
+
+func TestFeaturesWriteThroughBackend(t *testing.T) {
+	backend := newRecordingBackend(DebugLevel)
+	l := NewLoggerWithBackend(backend)
+
+	req := l.NewRequestBuffer(10, 0)
+	req.Debug("buffered", "attempt", 1)
+	assert.Empty(t, backend.logged()) // Held until the request ends
+	req.End(assert.AnError)
+
+	assert.Equal(t, []string{"-1 buffered [attempt 1]"}, backend.logged())
+}
+
+func TestUseBackend(t *testing.T) {
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	backend := newRecordingBackend(InfoLevel)
+	UseBackend(backend)
+
+	Error("global", "n", 2)
+	assert.Equal(t, []string{"2 global [n 2]"}, backend.logged())
+}
+
+func TestZapBackendReportsCaller(t *testing.T) {
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	l, err := NewLogger(true, WithSink(zapcore.AddSync(&buf)))
+	require.NoError(t, err)
+	logger.Store(l)
+
+	l.Info("through the method")
+	Info("through the package function")
+
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("backend_test.go")))
+}
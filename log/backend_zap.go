@@ -0,0 +1,84 @@
+//go:build !nozap
+
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewLoggerWithBackend returns a Logger writing through b. The package's
+// helpers work as with the zap backend, but the Options configuring zap cores
+// (sinks, escalation, alerts, ...) don't apply.
+func NewLoggerWithBackend(b Backend) *Logger {
+	return &Logger{sugaredLogger: zap.New(&backendCore{backend: b}).Sugar(), backend: b}
+}
+
+// zapBackend is the default Backend.
+type zapBackend struct {
+	sugared *zap.SugaredLogger
+	core    zapcore.Core
+}
+
+func newZapBackend(sugared *zap.SugaredLogger) *zapBackend {
+	return &zapBackend{sugared: sugared, core: sugared.Desugar().Core()}
+}
+
+func (b *zapBackend) Log(level Level, msg string, keysAndValues []interface{}) {
+	b.sugared.Logw(zapcore.Level(level), msg, keysAndValues...)
+}
+
+func (b *zapBackend) With(keysAndValues []interface{}) Backend {
+	return newZapBackend(b.sugared.With(keysAndValues...))
+}
+
+func (b *zapBackend) Enabled(level Level) bool {
+	return b.core.Enabled(zapcore.Level(level))
+}
+
+func (b *zapBackend) Sync() error {
+	return b.sugared.Sync()
+}
+
+// backendCore adapts a Backend to zapcore.Core, so the features built on zap
+// cores, like request buffers and runtime stats, write through any Backend.
+type backendCore struct {
+	backend Backend
+}
+
+func (c *backendCore) Enabled(lvl zapcore.Level) bool {
+	return c.backend.Enabled(Level(lvl))
+}
+
+func (c *backendCore) With(fields []zapcore.Field) zapcore.Core {
+	return &backendCore{backend: c.backend.With(keysAndValuesOf(fields))}
+}
+
+func (c *backendCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *backendCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.backend.Log(Level(ent.Level), ent.Message, keysAndValuesOf(fields))
+	return nil
+}
+
+func (c *backendCore) Sync() error {
+	return c.backend.Sync()
+}
+
+// keysAndValuesOf flattens fields into alternating keys and values, in order.
+func keysAndValuesOf(fields []zapcore.Field) []interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	keysAndValues := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		f.AddTo(enc)
+		if v, ok := enc.Fields[f.Key]; ok {
+			keysAndValues = append(keysAndValues, f.Key, v)
+		}
+	}
+	return keysAndValues
+}
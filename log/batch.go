@@ -0,0 +1,346 @@
+//go:build !nozap
+
+package log
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Defaults of BatchConfig.
+const (
+	defaultBatchEntries  = 500
+	defaultBatchBytes    = 1 << 20
+	defaultFlushInterval = time.Second
+	defaultBatchQueue    = 8
+)
+
+// OverflowPolicy decides what a buffered sink does when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// Block makes the caller wait for room, so nothing is lost but logging
+	// slows down to the pace of the sink.
+	Block OverflowPolicy = iota
+	// DropOldest discards the oldest buffered data to make room.
+	DropOldest
+	// DropNewest discards the data being written.
+	DropNewest
+)
+
+// BatchConfig tunes how a BatchSink groups entries: a batch is sent as soon
+// as it holds MaxEntries entries or MaxBytes bytes, or FlushInterval after its
+// first entry, whichever comes first. Up to QueueSize batches wait for the
+// send function; beyond that Overflow applies. Zero values pick the defaults:
+// 500 entries, 1MiB, 1 second, 8 batches and Block.
+type BatchConfig struct {
+	MaxEntries    int
+	MaxBytes      int
+	FlushInterval time.Duration
+	QueueSize     int
+	Overflow      OverflowPolicy
+}
+
+// oneMBBatchBytes keeps batches under the 1MB payload limit of several
+// ingestion APIs, leaving room for the framing the sinks add.
+const oneMBBatchBytes = 900 << 10
+
+// capBatchBytes returns maxBytes, or limit if maxBytes is unset or above it.
+func capBatchBytes(maxBytes, limit int) int {
+	if maxBytes <= 0 || maxBytes > limit {
+		return limit
+	}
+	return maxBytes
+}
+
+func (c BatchConfig) withDefaults() BatchConfig {
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = defaultBatchEntries
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = defaultBatchBytes
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushInterval
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultBatchQueue
+	}
+	return c
+}
+
+// BatchStats describes the batches a BatchSink sent so far.
+type BatchStats struct {
+	Batches     uint64        // Batches sent successfully
+	Failed      uint64        // Batches the send function failed on
+	Dropped     uint64        // Entries discarded by the overflow policy
+	Entries     uint64        // Entries in the batches sent
+	Bytes       uint64        // Bytes in the batches sent
+	LastEntries int           // Size of the last batch sent
+	LastLatency time.Duration // From the first entry of the last batch to its delivery
+	MaxLatency  time.Duration // Worst latency seen
+}
+
+// BatchSink groups the entries written to it into batches handed to a send
+// function on a background goroutine, so that logging never waits for the
+// network. It is the shared batcher of the HTTP-based sinks.
+type BatchSink struct {
+	name   string
+	config BatchConfig
+	send   func(batch [][]byte) error
+	keys   *sinkKeys // Keys of the entries send decodes, if it does
+
+	mu      sync.Mutex
+	current *batch
+	timer   *time.Timer // Flushes current, reset for each new batch
+	closed  bool
+	syncs   sync.WaitGroup // Syncs sending on queue, which Close waits for
+
+	// Separate from mu, which may be held while waiting for the sender.
+	statsMu   sync.Mutex
+	stats     BatchStats
+	displaced []*batch // Markers DropOldest took off the queue, see enqueue
+
+	queue chan *batch
+	done  chan struct{}
+}
+
+type batch struct {
+	entries [][]byte
+	bytes   int
+	started time.Time
+	flushed chan struct{} // closed once sent, if someone waits for it
+}
+
+// NewBatchSink starts a BatchSink calling send with each batch. Failed sends
+// are reported to OnSinkError callbacks and the batch is discarded; retrying
+// is up to send.
+func NewBatchSink(name string, config BatchConfig, send func(batch [][]byte) error) *BatchSink {
+	config = config.withDefaults()
+	s := &BatchSink{
+		name:   name,
+		config: config,
+		send:   send,
+		queue:  make(chan *batch, config.QueueSize),
+		done:   make(chan struct{}),
+	}
+	s.timer = time.AfterFunc(config.FlushInterval, s.flushOnTimer)
+	s.timer.Stop()
+	go s.run()
+	return s
+}
+
+// newDecodingBatchSink starts a BatchSink calling send with each batch and a
+// function decoding its entries, with the keys of the logger the sink is
+// added to.
+func newDecodingBatchSink(name string, config BatchConfig, send func(batch [][]byte, decode func([]byte) sinkEntry) error) *BatchSink {
+	keys := &sinkKeys{}
+	s := NewBatchSink(name, config, func(batch [][]byte) error {
+		return send(batch, keys.decode)
+	})
+	s.keys = keys
+	return s
+}
+
+func (s *BatchSink) setEntryKeys(keys entryKeys) {
+	if s.keys != nil {
+		s.keys.setEntryKeys(keys)
+	}
+}
+
+// Name identifies the sink in write error reports.
+func (s *BatchSink) Name() string {
+	return s.name
+}
+
+// Write adds a copy of p to the current batch.
+func (s *BatchSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	if s.current == nil {
+		s.current = &batch{started: time.Now()}
+		s.timer.Reset(s.config.FlushInterval)
+	}
+	s.current.entries = append(s.current.entries, append([]byte(nil), p...))
+	s.current.bytes += len(p)
+
+	if len(s.current.entries) >= s.config.MaxEntries || s.current.bytes >= s.config.MaxBytes {
+		s.enqueue()
+	}
+	return len(p), nil
+}
+
+// Sync sends the current batch and waits for every queued batch to be sent.
+// The batch and the marker Sync waits on are queued without holding s.mu, so
+// writers aren't blocked meanwhile, and wait for room whatever the overflow
+// policy, so the entries Sync is meant to flush aren't dropped.
+func (s *BatchSink) Sync() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.syncs.Add(1)
+	defer s.syncs.Done()
+	pending := s.current
+	if pending != nil {
+		s.timer.Stop()
+		s.current = nil
+	}
+	s.mu.Unlock()
+
+	if pending != nil {
+		s.queue <- pending
+	}
+	marker := &batch{flushed: make(chan struct{})}
+	s.queue <- marker
+
+	<-marker.flushed
+	return nil
+}
+
+// Close sends the remaining entries and stops the sink.
+func (s *BatchSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.current != nil {
+		s.enqueue()
+	}
+	s.timer.Stop()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.syncs.Wait()
+	close(s.queue)
+	<-s.done
+	return nil
+}
+
+// Stats returns the batching metrics so far.
+func (s *BatchSink) Stats() BatchStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	return s.stats
+}
+
+// flushOnTimer sends the current batch once it's FlushInterval old. Ticks
+// for a batch sent meanwhile, which stopping the timer came too late for,
+// find a younger batch and leave it to the tick the timer was reset for.
+func (s *BatchSink) flushOnTimer() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && !s.closed && time.Since(s.current.started) >= s.config.FlushInterval {
+		s.enqueue()
+	}
+}
+
+// enqueue hands the current batch to the sender, applying the overflow
+// policy if the queue is full. The caller must hold s.mu.
+func (s *BatchSink) enqueue() {
+	s.timer.Stop()
+	b := s.current
+	s.current = nil
+
+	for {
+		select {
+		case s.queue <- b:
+			return
+		default:
+		}
+
+		switch s.config.Overflow {
+		case DropNewest:
+			s.countDropped(b)
+			return
+		case DropOldest:
+			select {
+			case oldest := <-s.queue:
+				if oldest.flushed != nil {
+					// Flush markers aren't dropped: the batches before this one
+					// were all taken by the sender, which releases it once done
+					// with the batch it's on.
+					s.statsMu.Lock()
+					s.displaced = append(s.displaced, oldest)
+					s.statsMu.Unlock()
+				} else {
+					s.countDropped(oldest)
+				}
+			default:
+			}
+		default:
+			s.queue <- b
+			return
+		}
+	}
+}
+
+func (s *BatchSink) countDropped(b *batch) {
+	recordDropped(DropOverflow, uint64(len(b.entries)))
+	s.statsMu.Lock()
+	s.stats.Dropped += uint64(len(b.entries))
+	s.statsMu.Unlock()
+}
+
+func (s *BatchSink) run() {
+	defer close(s.done)
+	defer s.releaseDisplaced()
+
+	for b := range s.queue {
+		s.handle(b)
+		s.releaseDisplaced()
+	}
+}
+
+// releaseDisplaced releases the flush markers DropOldest took off the queue.
+func (s *BatchSink) releaseDisplaced() {
+	s.statsMu.Lock()
+	displaced := s.displaced
+	s.displaced = nil
+	s.statsMu.Unlock()
+
+	for _, marker := range displaced {
+		close(marker.flushed)
+	}
+}
+
+// handle sends b, or releases the Sync waiting on it if it's a flush marker.
+func (s *BatchSink) handle(b *batch) {
+	if b.flushed != nil {
+		close(b.flushed)
+		return
+	}
+
+	err := s.send(b.entries)
+	latency := time.Since(b.started)
+
+	s.statsMu.Lock()
+	if err != nil {
+		s.stats.Failed++
+	} else {
+		s.stats.Batches++
+		s.stats.Entries += uint64(len(b.entries))
+		s.stats.Bytes += uint64(b.bytes)
+		s.stats.LastEntries = len(b.entries)
+		s.stats.LastLatency = latency
+		if latency > s.stats.MaxLatency {
+			s.stats.MaxLatency = latency
+		}
+	}
+	s.statsMu.Unlock()
+
+	if err != nil {
+		recordDropped(DropSinkError, uint64(len(b.entries)))
+		reportSinkError(s.name, err)
+	}
+}
@@ -0,0 +1,292 @@
+//go:build !nozap
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecorder collects the batches sent by a BatchSink.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (r *batchRecorder) send(batch [][]byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	var entries []string
+	for _, entry := range batch {
+		entries = append(entries, string(entry))
+	}
+	r.batches = append(r.batches, entries)
+	return nil
+}
+
+func (r *batchRecorder) sent() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([][]string(nil), r.batches...)
+}
+
+func TestBatchSinkFlushesOnMaxEntries(t *testing.T) {
+	rec := &batchRecorder{}
+	sink := NewBatchSink("test", BatchConfig{MaxEntries: 2, FlushInterval: time.Hour}, rec.send)
+	defer sink.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := sink.Write([]byte(fmt.Sprint(i)))
+		require.NoError(t, err)
+	}
+	require.NoError(t, sink.Sync())
+
+	assert.Equal(t, [][]string{{"0", "1"}, {"2", "3"}, {"4"}}, rec.sent())
+	stats := sink.Stats()
+	assert.Equal(t, uint64(3), stats.Batches)
+	assert.Equal(t, uint64(5), stats.Entries)
+	assert.Equal(t, 1, stats.LastEntries)
+	assert.Positive(t, stats.MaxLatency)
+}
+
+func TestBatchSinkFlushesOnMaxBytes(t *testing.T) {
+	rec := &batchRecorder{}
+	sink := NewBatchSink("test", BatchConfig{MaxBytes: 10, FlushInterval: time.Hour}, rec.send)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte("123456"))
+	_, _ = sink.Write([]byte("789012")) // Reaches 10 bytes
+	_, _ = sink.Write([]byte("3"))
+	require.NoError(t, sink.Sync())
+
+	assert.Equal(t, [][]string{{"123456", "789012"}, {"3"}}, rec.sent())
+}
+
+func TestBatchSinkFlushesOnInterval(t *testing.T) {
+	rec := &batchRecorder{}
+	sink := NewBatchSink("test", BatchConfig{FlushInterval: 5 * time.Millisecond}, rec.send)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte("lonely entry"))
+
+	assert.Eventually(t, func() bool { return len(rec.sent()) == 1 }, time.Second, time.Millisecond)
+}
+
+func TestBatchSinkIgnoresStaleTicks(t *testing.T) {
+	rec := &batchRecorder{}
+	sink := NewBatchSink("test", BatchConfig{MaxEntries: 2, FlushInterval: time.Hour}, rec.send)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte("1"))
+	_, _ = sink.Write([]byte("2")) // Sent on size, before its tick
+	_, _ = sink.Write([]byte("3"))
+	sink.flushOnTimer() // The tick of the first batch, too late to be stopped
+
+	sink.mu.Lock()
+	assert.Len(t, sink.current.entries, 1) // Left for its own tick
+	sink.mu.Unlock()
+	require.NoError(t, sink.Sync())
+	assert.Equal(t, [][]string{{"1", "2"}, {"3"}}, rec.sent())
+}
+
+func TestBatchSinkCloseFlushes(t *testing.T) {
+	rec := &batchRecorder{}
+	sink := NewBatchSink("test", BatchConfig{FlushInterval: time.Hour}, rec.send)
+
+	_, _ = sink.Write([]byte("last words"))
+	require.NoError(t, sink.Close())
+
+	assert.Equal(t, [][]string{{"last words"}}, rec.sent())
+	_, err := sink.Write([]byte("too late"))
+	assert.Error(t, err)
+}
+
+func TestBatchSinkReportsFailures(t *testing.T) {
+	reported := captureSinkErrors(t)
+	rec := &batchRecorder{err: errors.New("backend down")}
+	sink := NewBatchSink("loki", BatchConfig{FlushInterval: time.Hour}, rec.send)
+
+	_, _ = sink.Write([]byte("entry"))
+	require.NoError(t, sink.Close())
+
+	assert.Equal(t, []string{"loki: backend down"}, *reported)
+	assert.Equal(t, uint64(1), sink.Stats().Failed)
+}
+
+// slowSender blocks every send until released, counting the entries it got.
+type slowSender struct {
+	release chan struct{}
+	mu      sync.Mutex
+	entries int
+}
+
+func (s *slowSender) send(batch [][]byte) error {
+	<-s.release
+	s.mu.Lock()
+	s.entries += len(batch)
+	s.mu.Unlock()
+	return nil
+}
+
+// writeUnderLoad writes total single-entry batches from several goroutines.
+func writeUnderLoad(sink *BatchSink, writers, perWriter int) {
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				_, _ = sink.Write([]byte("entry"))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBatchSinkOverflowDropPolicies(t *testing.T) {
+	for _, policy := range []OverflowPolicy{DropOldest, DropNewest} {
+		sender := &slowSender{release: make(chan struct{})}
+		sink := NewBatchSink("test", BatchConfig{MaxEntries: 1, QueueSize: 4, Overflow: policy}, sender.send)
+
+		writeUnderLoad(sink, 8, 100) // Never blocks although nothing is sent
+		close(sender.release)
+		require.NoError(t, sink.Close())
+
+		stats := sink.Stats()
+		assert.Positive(t, stats.Dropped)
+		assert.Equal(t, uint64(800), stats.Entries+stats.Dropped, "policy %d", policy)
+	}
+}
+
+func TestBatchSinkOverflowDropOldestKeepsNewest(t *testing.T) {
+	rec := &batchRecorder{}
+	release := make(chan struct{})
+	sink := NewBatchSink("test", BatchConfig{MaxEntries: 1, QueueSize: 2, Overflow: DropOldest}, func(batch [][]byte) error {
+		<-release
+		return rec.send(batch)
+	})
+
+	for i := 0; i < 10; i++ {
+		_, _ = sink.Write([]byte(fmt.Sprint(i)))
+	}
+	close(release)
+	require.NoError(t, sink.Close())
+
+	sent := rec.sent()
+	assert.Equal(t, []string{"9"}, sent[len(sent)-1])
+}
+
+func TestBatchSinkOverflowBlock(t *testing.T) {
+	sender := &slowSender{release: make(chan struct{})}
+	sink := NewBatchSink("test", BatchConfig{MaxEntries: 1, QueueSize: 2, Overflow: Block}, sender.send)
+
+	done := make(chan struct{})
+	go func() {
+		writeUnderLoad(sink, 8, 100)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("writers weren't blocked by the full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(sender.release)
+	<-done
+	require.NoError(t, sink.Close())
+
+	stats := sink.Stats()
+	assert.Zero(t, stats.Dropped)
+	assert.Equal(t, uint64(800), stats.Entries)
+}
+
+func TestBatchSinkSyncWithFullQueue(t *testing.T) {
+	rec := &batchRecorder{}
+	sending, release := make(chan struct{}, 1), make(chan struct{})
+	sink := NewBatchSink("test", BatchConfig{MaxEntries: 2, QueueSize: 1, FlushInterval: time.Hour, Overflow: DropNewest}, func(batch [][]byte) error {
+		select {
+		case sending <- struct{}{}:
+		default:
+		}
+		<-release
+		return rec.send(batch)
+	})
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte("0"))
+	_, _ = sink.Write([]byte("1"))
+	<-sending
+	for _, entry := range []string{"2", "3", "last"} { // One batch queued, one current
+		_, _ = sink.Write([]byte(entry))
+	}
+
+	synced := make(chan struct{})
+	go func() {
+		_ = sink.Sync()
+		close(synced)
+	}()
+	time.Sleep(10 * time.Millisecond) // Let Sync take the current batch
+
+	written := make(chan struct{})
+	go func() {
+		_, _ = sink.Write([]byte("meanwhile"))
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("Sync blocked a writer while waiting for the sender")
+	}
+
+	close(release)
+	<-synced
+	assert.Contains(t, rec.sent(), []string{"last"}) // Not dropped although the queue was full
+	assert.Zero(t, sink.Stats().Dropped)
+}
+
+func TestBatchSinkDropOldestKeepsSyncMarkers(t *testing.T) {
+	release := make(chan struct{})
+	sink := NewBatchSink("test", BatchConfig{MaxEntries: 1, QueueSize: 1, FlushInterval: time.Hour, Overflow: DropOldest}, func([][]byte) error {
+		<-release
+		return nil
+	})
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte("sending"))
+	time.Sleep(10 * time.Millisecond) // Let the sender take it
+
+	synced := make(chan struct{})
+	go func() {
+		_ = sink.Sync()
+		close(synced)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	_, _ = sink.Write([]byte("displaces the marker"))
+
+	select {
+	case <-synced:
+		t.Fatal("Sync returned before the batch being sent was")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-synced
+}
+
+func TestCapBatchBytes(t *testing.T) {
+	assert.Equal(t, 100, capBatchBytes(0, 100))
+	assert.Equal(t, 100, capBatchBytes(500, 100))
+	assert.Equal(t, 50, capBatchBytes(50, 100))
+}
@@ -0,0 +1,150 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// defaultBodyContentTypes are the bodies WithBodies logs unless told
+// otherwise.
+var defaultBodyContentTypes = []string{"application/json", "application/x-www-form-urlencoded"}
+
+// WithBodies logs the request and response bodies of the given content
+// types, JSON and URL-encoded forms by default, up to maxBytes each. The
+// values of DefaultRedactedKeys, or of the keys set with WithRedactedKeys, are
+// redacted from JSON and form bodies. JSON bodies longer than maxBytes can't be
+// redacted, so they are left out. Bodies of other types are logged as is.
+//
+// Bodies may hold personal data: this is meant for debugging, such as webhook
+// integrations in staging.
+func WithBodies(maxBytes int, contentTypes ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		if len(contentTypes) == 0 {
+			contentTypes = defaultBodyContentTypes
+		}
+		o.bodies = &bodyOptions{max: maxBytes, types: contentTypes}
+	}
+}
+
+// WithRedactedKeys replaces DefaultRedactedKeys for the bodies logged with
+// WithBodies.
+func WithRedactedKeys(keys ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.redactedKeys = keys
+	}
+}
+
+type bodyOptions struct {
+	max      int
+	types    []string
+	redacted keySet // Set once all the options are applied
+}
+
+// capturedBody holds the first bytes of a body, one more than the cap so
+// truncation can be told apart.
+type capturedBody struct {
+	contentType string
+	data        []byte
+	max         int
+}
+
+func (b *capturedBody) capture(contentType string, p []byte) {
+	if b.contentType == "" {
+		b.contentType = contentType
+	}
+	if room := b.max + 1 - len(b.data); room > 0 {
+		b.data = append(b.data, p[:min(room, len(p))]...)
+	}
+}
+
+// captureRequest reads the start of the request body, if its content type is
+// selected, and puts it back in front of the rest for the handler.
+func (o *bodyOptions) captureRequest(r *http.Request) *capturedBody {
+	contentType := r.Header.Get("Content-Type")
+	if r.Body == nil || r.Body == http.NoBody || !o.selected(contentType) {
+		return nil
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(r.Body, int64(o.max)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), errReader{err}, r.Body), r.Body}
+
+	return &capturedBody{contentType: contentType, data: prefix, max: o.max}
+}
+
+// appendBody adds the body under key if one was captured.
+func (o *bodyOptions) appendBody(keysAndValues []interface{}, key string, b *capturedBody) []interface{} {
+	if b == nil || len(b.data) == 0 || !o.selected(b.contentType) {
+		return keysAndValues
+	}
+	return append(keysAndValues, key, o.render(b))
+}
+
+func (o *bodyOptions) render(b *capturedBody) string {
+	data, truncated := b.data, len(b.data) > o.max
+	if truncated {
+		data = data[:o.max]
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(b.contentType)
+	switch {
+	case isJSON(mediaType):
+		if truncated {
+			return "[JSON body over the size cap, left out as it can't be redacted]"
+		}
+		redacted, err := redactJSON(data, o.redacted)
+		if err != nil {
+			return "[invalid JSON body]"
+		}
+		data = redacted
+	case mediaType == "application/x-www-form-urlencoded":
+		redacted, err := redactForm(data, o.redacted)
+		if err != nil {
+			return "[invalid form body]"
+		}
+		data = redacted
+	}
+
+	if truncated {
+		return string(data) + "...[truncated]"
+	}
+	return string(data)
+}
+
+func (o *bodyOptions) selected(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range o.types {
+		if mediaType == t || (t == "application/json" && isJSON(mediaType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSON matches application/json and its +json variants.
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// errReader returns err, if any, once the captured prefix is consumed, so the
+// handler sees the error reading the body caused.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
@@ -0,0 +1,71 @@
+//go:build !nozap
+
+package log
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func echoHandler(contentType string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(body)
+	})
+}
+
+func post(h http.Handler, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBodiesLoggedAndRedacted(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	h := HTTPMiddleware(echoHandler("application/json"), WithBodies(1024))
+	rec := post(h, "application/json; charset=utf-8", `{"event":"paid","token":"s3cr3t"}`)
+
+	assert.Equal(t, `{"event":"paid","token":"s3cr3t"}`, rec.Body.String()) // The handler sees the whole body
+	assert.Contains(t, buf.String(), `"request_body": "{\"event\":\"paid\",\"token\":\"[REDACTED]\"}"`)
+	assert.Contains(t, buf.String(), `"response_body": "{\"event\":\"paid\",\"token\":\"[REDACTED]\"}"`)
+	assert.NotContains(t, buf.String(), "s3cr3t")
+}
+
+func TestBodiesCapped(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	h := HTTPMiddleware(echoHandler("text/plain"), WithBodies(8, "application/x-www-form-urlencoded", "application/json"))
+
+	rec := post(h, "application/x-www-form-urlencoded", "a=1&b=2&password=x")
+	assert.Equal(t, "a=1&b=2&password=x", rec.Body.String())
+	assert.Contains(t, buf.String(), `"request_body": "a=1&b=2...[truncated]"`)
+	assert.NotContains(t, buf.String(), "response_body") // text/plain isn't selected
+
+	post(h, "application/json", `{"password":"hunter2"}`)
+	assert.Contains(t, buf.String(), "left out as it can't be redacted")
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestBodiesOtherTypesAndKeys(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	h := HTTPMiddleware(echoHandler("application/json"), WithRedactedKeys("card"), WithBodies(1024))
+	post(h, "application/xml", "<a/>")
+	post(h, "application/json", `{"card":"4111","password":"kept"}`)
+
+	assert.NotContains(t, buf.String(), "<a/>")
+	assert.Contains(t, buf.String(), `\"card\":\"[REDACTED]\"`)
+	assert.Contains(t, buf.String(), `\"password\":\"kept\"`)
+}
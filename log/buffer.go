@@ -0,0 +1,142 @@
+//go:build !nozap
+
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RequestBuffer is a request-scoped logger whose Debug entries are kept in
+// memory instead of being written. When the request ends they are flushed to
+// the sinks if it failed or was too slow, and dropped otherwise. Entries at
+// Info and above are written immediately.
+type RequestBuffer struct {
+	*Logger
+	ring      *entryRing
+	start     time.Time
+	threshold time.Duration
+}
+
+// NewRequestBuffer starts a RequestBuffer on top of the global logger.
+// See (*Logger).NewRequestBuffer.
+func NewRequestBuffer(size int, threshold time.Duration) *RequestBuffer {
+	return GetLogger().NewRequestBuffer(size, threshold)
+}
+
+// NewRequestBuffer starts a RequestBuffer that keeps at most size Debug
+// entries, overwriting the oldest ones once full. If threshold is positive,
+// requests lasting longer than it also get their entries flushed.
+func (l *Logger) NewRequestBuffer(size int, threshold time.Duration) *RequestBuffer {
+	l = l.resolved()
+	if size < 1 {
+		size = 1
+	}
+
+	ring := &entryRing{entries: make([]bufferedEntry, size)}
+	sugared := l.sugaredLogger.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &bufferCore{Core: core, ring: ring}
+	})).Sugar()
+
+	return &RequestBuffer{
+		Logger:    l.derive(sugared),
+		ring:      ring,
+		start:     time.Now(),
+		threshold: threshold,
+	}
+}
+
+// End finishes the request. The buffered entries are written if err is not
+// nil or the request exceeded the latency threshold, and discarded otherwise.
+// It reports whether the entries were written.
+func (b *RequestBuffer) End(err error) bool {
+	entries := b.ring.drain()
+
+	if err == nil && (b.threshold <= 0 || time.Since(b.start) <= b.threshold) {
+		recordDropped(DropBuffer, uint64(len(entries)))
+		return false
+	}
+
+	for _, e := range entries {
+		_ = e.core.Write(e.entry, e.fields)
+	}
+	_ = b.backend.Sync()
+	return true
+}
+
+type bufferedEntry struct {
+	core   zapcore.Core
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+// entryRing is a fixed-size ring of entries shared by every core derived from
+// the same RequestBuffer.
+type entryRing struct {
+	mu      sync.Mutex
+	entries []bufferedEntry
+	next    int
+	full    bool
+}
+
+func (r *entryRing) push(e bufferedEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// drain returns the buffered entries from oldest to newest and empties the ring.
+func (r *entryRing) drain() []bufferedEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []bufferedEntry
+	if r.full {
+		out = append(out, r.entries[r.next:]...)
+	}
+	out = append(out, r.entries[:r.next]...)
+
+	for i := range r.entries {
+		r.entries[i] = bufferedEntry{}
+	}
+	r.next, r.full = 0, false
+	return out
+}
+
+// bufferCore diverts Debug entries into the ring and lets everything else
+// through to the wrapped core.
+type bufferCore struct {
+	zapcore.Core
+	ring *entryRing
+}
+
+func (c *bufferCore) Enabled(lvl zapcore.Level) bool {
+	return lvl == zapcore.DebugLevel || c.Core.Enabled(lvl)
+}
+
+func (c *bufferCore) With(fields []zapcore.Field) zapcore.Core {
+	return &bufferCore{Core: c.Core.With(fields), ring: c.ring}
+}
+
+func (c *bufferCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level == zapcore.DebugLevel {
+		return ce.AddCore(ent, c)
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *bufferCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level != zapcore.DebugLevel {
+		return c.Core.Write(ent, fields)
+	}
+	c.ring.push(bufferedEntry{core: c.Core, entry: ent, fields: fields})
+	return nil
+}
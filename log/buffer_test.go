@@ -0,0 +1,70 @@
+//go:build !nozap
+
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestBufferDropsOnSuccess(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	req := NewRequestBuffer(10, 0)
+	req.Debug("buffered debug", "key", "value")
+	req.Info("immediate info")
+
+	assert.NotContains(t, buf.String(), "buffered debug") // Held until End
+	assert.Contains(t, buf.String(), "immediate info")    // Info is not buffered
+
+	assert.False(t, req.End(nil))
+	assert.NotContains(t, buf.String(), "buffered debug")
+}
+
+func TestRequestBufferFlushesOnError(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	req := NewRequestBuffer(10, 0)
+	req.Debug("first debug")
+	req.Debug("second debug")
+
+	assert.True(t, req.End(assert.AnError))
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, "first debug")
+	assert.Contains(t, logOutput, "second debug")
+	assert.Less(t, strings.Index(logOutput, "first debug"), strings.Index(logOutput, "second debug"))
+}
+
+func TestRequestBufferFlushesWhenSlow(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	req := NewRequestBuffer(10, time.Nanosecond)
+	req.Debug("slow request debug")
+	time.Sleep(time.Millisecond)
+
+	assert.True(t, req.End(nil))
+	assert.Contains(t, buf.String(), "slow request debug")
+}
+
+func TestRequestBufferKeepsNewestEntries(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	req := NewRequestBuffer(2, 0)
+	req.Debug("entry one")
+	req.Debug("entry two")
+	req.Debug("entry three")
+	req.End(assert.AnError)
+
+	logOutput := buf.String()
+	assert.NotContains(t, logOutput, "entry one") // Overwritten by the ring
+	assert.Contains(t, logOutput, "entry two")
+	assert.Contains(t, logOutput, "entry three")
+}
@@ -0,0 +1,231 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Compression is a Content-Encoding applied to the payloads of HTTP sinks.
+type Compression string
+
+// Supported compressions. Zstd is only available once registered, by
+// importing log/logzstd.
+const (
+	NoCompression Compression = ""
+	Gzip          Compression = "gzip"
+	Zstd          Compression = "zstd"
+	Deflate       Compression = "deflate" // zlib format, as HTTP's deflate encoding is
+)
+
+// CompressWriter returns a writer compressing to w, flushed by Close.
+type CompressWriter func(w io.Writer) (io.WriteCloser, error)
+
+var (
+	compressorsMu sync.RWMutex
+	compressors   = map[Compression]CompressWriter{
+		Gzip: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		Deflate: func(w io.Writer) (io.WriteCloser, error) {
+			return zlib.NewWriter(w), nil
+		},
+	}
+)
+
+// RegisterCompression makes c available to the sinks, encoded with the
+// writers newWriter returns. It fails if c is already registered.
+func RegisterCompression(c Compression, newWriter CompressWriter) error {
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+
+	if _, ok := compressors[c]; ok || c == NoCompression {
+		return fmt.Errorf("compression %q is already registered", c)
+	}
+	compressors[c] = newWriter
+	return nil
+}
+
+func compressorOf(c Compression) (CompressWriter, bool) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+
+	newWriter, ok := compressors[c]
+	return newWriter, ok
+}
+
+// negotiate returns want if the backend supports it, falling back to the
+// best compression the backend does support, gzip first. Compressions that
+// aren't registered are left out.
+func (want Compression) negotiate(supported ...Compression) Compression {
+	if want == NoCompression {
+		return NoCompression
+	}
+
+	usable := func(c Compression) bool {
+		for _, s := range supported {
+			if s == c {
+				_, ok := compressorOf(c)
+				return ok
+			}
+		}
+		return false
+	}
+	for _, c := range []Compression{want, Gzip, Zstd} {
+		if usable(c) {
+			return c
+		}
+	}
+	return NoCompression
+}
+
+// compress returns body encoded with c.
+func (c Compression) compress(body []byte) ([]byte, error) {
+	if c == NoCompression {
+		return body, nil
+	}
+	newWriter, ok := compressorOf(c)
+	if !ok {
+		return nil, fmt.Errorf("unsupported compression %q", c)
+	}
+
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// httpShipper posts batches of entries to an HTTP backend, compressed with
+// the best encoding both sides support.
+type httpShipper struct {
+	client      *http.Client
+	method      string
+	url         string
+	contentType string
+	header      http.Header
+	compression Compression
+
+	// authorize, if set, adds credentials that may change between requests,
+	// like short-lived bearer tokens.
+	authorize func(req *http.Request) error
+}
+
+// newHTTPShipper prepares a shipper for url, negotiating config.Compression
+// against the encodings the backend accepts.
+func newHTTPShipper(config HTTPConfig, url, contentType string, supported ...Compression) (*httpShipper, error) {
+	client, err := config.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	return &httpShipper{
+		client:      client,
+		method:      http.MethodPost,
+		url:         url,
+		contentType: contentType,
+		header:      make(http.Header),
+		compression: config.Compression.negotiate(supported...),
+	}, nil
+}
+
+// ship sends one batch, failing on any non-2xx response.
+func (s *httpShipper) ship(body []byte) error {
+	_, err := s.post(body)
+	return err
+}
+
+// defaultHTTPRetries is how many times the HTTP sinks that retry send a batch
+// again by default.
+const defaultHTTPRetries = 3
+
+// shipRetrying sends one batch like ship, sending it again following backoff,
+// up to maxRetries times, while it fails with a transient error.
+func (s *httpShipper) shipRetrying(body []byte, maxRetries int, backoff Backoff) error {
+	for attempt := 0; ; attempt++ {
+		err := s.ship(body)
+		if err == nil || !transientHTTPError(err) || attempt >= maxRetries {
+			return err
+		}
+		time.Sleep(backoff.Delay(attempt))
+	}
+}
+
+// transientHTTPError reports whether err may go away by retrying: network
+// errors, 429 and 5xx statuses. Other statuses mean the batch or the
+// credentials are wrong.
+func transientHTTPError(err error) bool {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+	return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+}
+
+// maxResponseSize bounds the responses read by post.
+const maxResponseSize = 1 << 20
+
+// post sends one batch and returns the response body, failing on any non-2xx
+// response.
+func (s *httpShipper) post(body []byte) ([]byte, error) {
+	payload, err := s.compression.compress(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(s.method, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range s.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	if s.compression != NoCompression {
+		req.Header.Set("Content-Encoding", string(s.compression))
+	}
+	if s.authorize != nil {
+		if err := s.authorize(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &HTTPStatusError{URL: s.url, StatusCode: resp.StatusCode}
+	}
+	return respBody, err
+}
+
+// HTTPStatusError is returned when an HTTP backend rejects a batch.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s responded %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
@@ -0,0 +1,100 @@
+//go:build !nozap
+
+package log
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionNegotiate(t *testing.T) {
+	assert.Equal(t, Deflate, Deflate.negotiate(Gzip, Deflate))
+	assert.Equal(t, Gzip, Deflate.negotiate(Gzip))    // Backend lacks deflate
+	assert.Equal(t, Gzip, Zstd.negotiate(Gzip, Zstd)) // Zstd isn't registered
+	assert.Equal(t, NoCompression, Gzip.negotiate(Zstd))
+	assert.Equal(t, NoCompression, Gzip.negotiate()) // Backend can't decompress
+	assert.Equal(t, NoCompression, NoCompression.negotiate(Gzip, Deflate))
+}
+
+func TestRegisterCompression(t *testing.T) {
+	assert.ErrorContains(t, RegisterCompression(Gzip, nil), `"gzip" is already registered`)
+
+	// An identity encoding stands in for a real one.
+	require.NoError(t, RegisterCompression("identity-test", func(w io.Writer) (io.WriteCloser, error) {
+		return nopWriteCloser{w}, nil
+	}))
+	assert.Equal(t, Compression("identity-test"), Compression("identity-test").negotiate(Gzip, "identity-test"))
+	body, err := Compression("identity-test").compress([]byte("raw"))
+	require.NoError(t, err)
+	assert.Equal(t, "raw", string(body))
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestHTTPShipperCompresses(t *testing.T) {
+	for _, compression := range []Compression{NoCompression, Gzip, Deflate} {
+		t.Run(string(compression), func(t *testing.T) {
+			var encoding, body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				encoding = r.Header.Get("Content-Encoding")
+
+				var reader io.Reader = r.Body
+				switch encoding {
+				case "gzip":
+					gz, err := gzip.NewReader(r.Body)
+					require.NoError(t, err)
+					reader = gz
+				case "deflate":
+					zr, err := zlib.NewReader(r.Body)
+					require.NoError(t, err)
+					reader = zr
+				}
+				raw, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				body = string(raw)
+			}))
+			defer server.Close()
+
+			shipper, err := newHTTPShipper(HTTPConfig{Compression: compression}, server.URL, "application/x-ndjson", Gzip, Deflate)
+			require.NoError(t, err)
+			require.NoError(t, shipper.ship([]byte(`{"msg":"batched"}`+"\n")))
+
+			assert.Equal(t, string(compression), encoding)
+			assert.Equal(t, `{"msg":"batched"}`+"\n", body)
+		})
+	}
+}
+
+func TestHTTPShipperRejectedBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	shipper, err := newHTTPShipper(HTTPConfig{}, server.URL, "application/json")
+	require.NoError(t, err)
+
+	err = shipper.ship([]byte("{}"))
+	var statusErr *HTTPStatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+}
+
+func TestTransientHTTPError(t *testing.T) {
+	assert.True(t, transientHTTPError(errors.New("connection reset by peer")))
+	assert.True(t, transientHTTPError(&HTTPStatusError{StatusCode: http.StatusTooManyRequests}))
+	assert.True(t, transientHTTPError(&HTTPStatusError{StatusCode: http.StatusBadGateway}))
+	assert.False(t, transientHTTPError(&HTTPStatusError{StatusCode: http.StatusBadRequest}))
+}
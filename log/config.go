@@ -0,0 +1,240 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Config describes a logger in a file read by LoadConfig, for services whose
+// logging is set up by operations rather than in code.
+type Config struct {
+	// Development selects the development defaults: console encoding, Debug
+	// level and no sampling.
+	Development bool `json:"development" yaml:"development" toml:"development"`
+
+	// Level is the minimum level, such as "debug" or "warn".
+	Level string `json:"level" yaml:"level" toml:"level"`
+
+	// Modules overrides the level of Named loggers by name, see
+	// WithModuleLevels.
+	Modules map[string]string `json:"modules" yaml:"modules" toml:"modules"`
+
+	// Encoding is "json" or "console".
+	Encoding string `json:"encoding" yaml:"encoding" toml:"encoding"`
+
+	// Outputs are the sinks, as URLs opened by OpenSink. They replace the
+	// default stderr output; list "stderr" to keep it.
+	Outputs []string `json:"outputs" yaml:"outputs" toml:"outputs"`
+
+	// Sampling limits identical entries, see WithSampling.
+	Sampling *SamplingConfig `json:"sampling" yaml:"sampling" toml:"sampling"`
+
+	// Fields are added to every entry, see WithFields.
+	Fields map[string]interface{} `json:"fields" yaml:"fields" toml:"fields"`
+
+	// TimeZone is the IANA name of the time zone of timestamps, see
+	// WithTimeZone.
+	TimeZone string `json:"time_zone" yaml:"time_zone" toml:"time_zone"`
+}
+
+// SamplingConfig is the sampling section of a Config.
+type SamplingConfig struct {
+	Initial    int `json:"initial" yaml:"initial" toml:"initial"`
+	Thereafter int `json:"thereafter" yaml:"thereafter" toml:"thereafter"`
+}
+
+// LoadConfig builds a logger from the configuration file at path, see
+// ReadConfig, with opts applied after it. Only JSON is built in: YAML and TOML
+// files need log/logyaml or log/logtoml imported, and fail with an error
+// naming the module to import otherwise:
+//
+//	import _ "github.com/Stasky745/go-libs/log/logyaml"
+func LoadConfig(path string, opts ...Option) (*Logger, error) {
+	config, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Build(opts...)
+}
+
+// ReadConfig reads a Config in the format given by the extension of path:
+// .json, or one registered with RegisterConfigFormat, like .yaml and .yml by
+// importing log/logyaml or .toml by importing log/logtoml. Unknown settings
+// are errors, so typos don't go unnoticed, and so are the YAML and TOML
+// extensions until their module is imported.
+func ReadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return parseConfig(path, data)
+}
+
+// ConfigDecoder decodes a configuration file into config. It must reject
+// unknown settings.
+type ConfigDecoder func(data []byte, config *Config) error
+
+var (
+	configFormatsMu sync.RWMutex
+	configFormats   = map[string]ConfigDecoder{".json": decodeJSONConfig}
+)
+
+// configFormatModules are the modules registering the formats that aren't
+// built in, named by the error of files in those formats when they're not
+// imported.
+var configFormatModules = map[string]string{
+	".yaml": "github.com/Stasky745/go-libs/log/logyaml",
+	".yml":  "github.com/Stasky745/go-libs/log/logyaml",
+	".toml": "github.com/Stasky745/go-libs/log/logtoml",
+}
+
+// RegisterConfigFormat makes ReadConfig, LoadConfig and WatchConfig read the
+// files with extension ext, such as ".yaml", with decode. It fails if the
+// extension is already registered.
+func RegisterConfigFormat(ext string, decode ConfigDecoder) error {
+	configFormatsMu.Lock()
+	defer configFormatsMu.Unlock()
+
+	ext = strings.ToLower(ext)
+	if _, ok := configFormats[ext]; ok {
+		return fmt.Errorf("config format %q is already registered", ext)
+	}
+	configFormats[ext] = decode
+	return nil
+}
+
+func decodeJSONConfig(data []byte, config *Config) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(config)
+}
+
+// parseConfig parses data, read from path, in the format given by its
+// extension.
+func parseConfig(path string, data []byte) (Config, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	configFormatsMu.RLock()
+	decode, ok := configFormats[ext]
+	configFormatsMu.RUnlock()
+	if !ok {
+		if module, known := configFormatModules[ext]; known {
+			return Config{}, fmt.Errorf("log config %s: unsupported format %q, import %s to read it", path, ext, module)
+		}
+		return Config{}, fmt.Errorf("log config %s: unsupported format %q", path, ext)
+	}
+
+	var config Config
+	if err := decode(data, &config); err != nil {
+		return Config{}, fmt.Errorf("log config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Build returns a logger configured by c, with opts applied after it.
+func (c Config) Build(opts ...Option) (*Logger, error) {
+	options, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+	return NewLogger(c.Development, append(options, opts...)...)
+}
+
+// build returns the logger configured by c, with opts applied after it,
+// without starting the background work of its options, along with the
+// options and the sinks opened for c's outputs.
+func (c Config) build(opts []Option) (*Logger, *options, []zapcore.WriteSyncer, error) {
+	configOpts, err := c.options()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	o := newOptions(configOpts)
+	outputs := o.sinks[:len(o.sinks):len(o.sinks)]
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	l, err := newLogger(c.Development, o)
+	if err != nil {
+		closeSinks(outputs)
+		return nil, nil, nil, err
+	}
+	return l, o, outputs, nil
+}
+
+// options translates c into Options.
+func (c Config) options() ([]Option, error) {
+	var opts []Option
+
+	if c.Level != "" {
+		level, err := ParseLevel(c.Level)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithLevel(level))
+	}
+
+	if len(c.Modules) > 0 {
+		levels := make(map[string]Level, len(c.Modules))
+		for name, levelName := range c.Modules {
+			level, err := ParseLevel(levelName)
+			if err != nil {
+				return nil, fmt.Errorf("level of module %q: %w", name, err)
+			}
+			levels[name] = level
+		}
+		opts = append(opts, func(o *options) { o.moduleLevels = levels })
+	}
+
+	switch c.Encoding {
+	case "":
+	case "json", "console":
+		opts = append(opts, func(o *options) { o.encoding = c.Encoding })
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", c.Encoding)
+	}
+
+	if len(c.Outputs) > 0 {
+		opts = append(opts, func(o *options) { o.noStderr = true })
+		for _, output := range c.Outputs {
+			opts = append(opts, WithOutput(output))
+		}
+	}
+
+	if c.Sampling != nil {
+		opts = append(opts, WithSampling(c.Sampling.Initial, c.Sampling.Thereafter))
+	}
+
+	if len(c.Fields) > 0 {
+		keys := make([]string, 0, len(c.Fields))
+		for key := range c.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var keysAndValues []interface{}
+		for _, key := range keys {
+			keysAndValues = append(keysAndValues, key, c.Fields[key])
+		}
+		opts = append(opts, WithFields(keysAndValues...))
+	}
+
+	if c.TimeZone != "" {
+		loc, err := time.LoadLocation(c.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTimeZone(loc))
+	}
+
+	return opts, nil
+}
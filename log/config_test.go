@@ -0,0 +1,83 @@
+//go:build !nozap
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "app.log")
+	l, err := LoadConfig(writeConfig(t, "log.json", `{
+  "level": "warn",
+  "modules": {"db": "debug"},
+  "outputs": ["`+out+`"],
+  "sampling": {"initial": 0},
+  "fields": {"service": "api", "version": 3},
+  "time_zone": "Europe/Madrid"
+}`))
+	require.NoError(t, err)
+	assert.Equal(t, WarnLevel, l.GetLevel())
+
+	l.Info("hidden")
+	l.Warn("shown")
+	l.Named("db").Debug("db debug")
+
+	logged, err := os.ReadFile(out)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(logged)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"msg":"shown"`)
+	assert.Contains(t, lines[0], `"service":"api","version":3`)
+	assert.Regexp(t, `"ts":\d+`, lines[0])
+	assert.Contains(t, lines[1], `"msg":"db debug"`)
+}
+
+func TestRegisterConfigFormat(t *testing.T) {
+	err := RegisterConfigFormat(".conf", func(data []byte, config *Config) error {
+		config.Level = strings.TrimSpace(string(data))
+		return nil
+	})
+	require.NoError(t, err)
+
+	config, err := ReadConfig(writeConfig(t, "log.CONF", "debug\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "debug", config.Level)
+
+	assert.ErrorContains(t, RegisterConfigFormat(".json", decodeJSONConfig), `".json" is already registered`)
+}
+
+func TestReadConfigErrors(t *testing.T) {
+	_, err := ReadConfig(writeConfig(t, "log.json", `{"levle": "debug"}`))
+	assert.ErrorContains(t, err, "levle")
+	_, err = ReadConfig(writeConfig(t, "log.yaml", ""))
+	assert.ErrorContains(t, err, `unsupported format ".yaml", import github.com/Stasky745/go-libs/log/logyaml`) // Without logyaml
+	_, err = ReadConfig(writeConfig(t, "log.toml", ""))
+	assert.ErrorContains(t, err, "import github.com/Stasky745/go-libs/log/logtoml")
+	_, err = ReadConfig(writeConfig(t, "log.ini", ""))
+	assert.ErrorContains(t, err, "unsupported format")
+	assert.NotContains(t, err.Error(), "import")
+
+	for _, config := range []Config{
+		{Level: "loud"},
+		{Modules: map[string]string{"db": "loud"}},
+		{Encoding: "xml"},
+		{TimeZone: "Mars/Olympus"},
+		{Outputs: []string{"nope://x"}},
+	} {
+		_, err := config.Build()
+		assert.Error(t, err, "%+v", config)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !nozap
+
+package log
+
+import "context"
+
+type loggerKey struct{}
+
+// WithContext returns a copy of ctx carrying l, for FromContext. It lets a
+// request-scoped logger, holding request and user IDs, travel down the call
+// stack with the context.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the global logger if
+// there's none.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+	return GetLogger()
+}
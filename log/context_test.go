@@ -0,0 +1,28 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	assert.Same(t, GetLogger(), FromContext(context.Background()))
+
+	backend := newRecordingBackend(InfoLevel)
+	l := NewLoggerWithBackend(backend)
+	ctx := WithContext(context.Background(), l)
+	assert.Same(t, l, FromContext(ctx))
+
+	FromContext(ctx).Info("scoped", "request_id", "r1")
+	assert.Equal(t, []string{"0 scoped [request_id r1]"}, backend.logged())
+
+	// A nil logger falls back to the global one.
+	assert.Same(t, GetLogger(), FromContext(WithContext(ctx, nil)))
+}
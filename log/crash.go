@@ -0,0 +1,162 @@
+//go:build !nozap
+
+package log
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// recentErrorCount is how many error fingerprints are kept for crash reports.
+const recentErrorCount = 10
+
+// WithoutCrashReport stops Panic and Fatal entries from carrying a
+// RuntimeSnapshot and the fingerprints of the latest Error entries, as the
+// runtime and recent_errors fields. They do by default; the snapshot is only
+// taken when such an entry is written.
+func WithoutCrashReport() Option {
+	return func(o *options) {
+		o.noCrashReport = true
+	}
+}
+
+// WithGoroutineDump writes the stacks of all goroutines to sink whenever a
+// Panic or Fatal entry is logged, right after the entry itself. A nil sink
+// means os.Stderr.
+func WithGoroutineDump(sink io.Writer) Option {
+	return func(o *options) {
+		if sink == nil {
+			sink = os.Stderr
+		}
+		o.crashReporter().dumpTo = sink
+	}
+}
+
+// crashReporter handles Panic and Fatal entries for crash reports and
+// WithGoroutineDump. For the former, it remembers the fingerprints of the
+// latest Error entries.
+type crashReporter struct {
+	snapshot bool
+	dumpTo   io.Writer
+
+	mu           sync.Mutex
+	fingerprints []string
+	next         int
+	full         bool
+}
+
+func newCrashReporter(size int) *crashReporter {
+	return &crashReporter{fingerprints: make([]string, size)}
+}
+
+func (r *crashReporter) wrap(core zapcore.Core) zapcore.Core {
+	return &crashCore{Core: core, reporter: r}
+}
+
+func (r *crashReporter) record(ent zapcore.Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fingerprints[r.next] = fingerprint(ent)
+	r.next = (r.next + 1) % len(r.fingerprints)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// recent returns the remembered fingerprints from oldest to newest.
+func (r *crashReporter) recent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []string
+	if r.full {
+		out = append(out, r.fingerprints[r.next:]...)
+	}
+	return append(out, r.fingerprints[:r.next]...)
+}
+
+// crashReporter returns the crash reporter of the options, adding one the
+// first time.
+func (o *options) crashReporter() *crashReporter {
+	if o.crash == nil {
+		o.crash = newCrashReporter(recentErrorCount)
+	}
+	return o.crash
+}
+
+// crashCore records Error entries and enriches Panic and Fatal ones.
+type crashCore struct {
+	zapcore.Core
+	reporter *crashReporter
+}
+
+func (c *crashCore) With(fields []zapcore.Field) zapcore.Core {
+	return &crashCore{Core: c.Core.With(fields), reporter: c.reporter}
+}
+
+func (c *crashCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < zapcore.ErrorLevel {
+		return c.Core.Check(ent, ce)
+	}
+
+	if ent.Level >= zapcore.PanicLevel {
+		ce = c.checkCrash(ent, ce)
+		if c.reporter.dumpTo != nil {
+			ce = ce.AddCore(ent, observer{fn: c.reporter.dump})
+		}
+		return ce
+	}
+	if !c.reporter.snapshot {
+		return c.Core.Check(ent, ce)
+	}
+
+	record := observer{fn: func(ent zapcore.Entry, _ []zapcore.Field) { c.reporter.record(ent) }}
+	return c.Core.Check(ent, ce.AddCore(ent, record))
+}
+
+// checkCrash checks a Panic or Fatal entry. With crash reports on, the
+// wrapped core is checked on its own and written by an observer adding the
+// crash fields, so that the snapshot is taken at Write, not Check.
+func (c *crashCore) checkCrash(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.reporter.snapshot {
+		return c.Core.Check(ent, ce)
+	}
+
+	crash := c.Core.Check(ent, nil)
+	if crash == nil {
+		return ce
+	}
+	return ce.AddCore(ent, observer{fn: func(ent zapcore.Entry, fields []zapcore.Field) {
+		crash.Entry = ent // With the caller and stack added since Check
+		crash.Write(append(fields,
+			zap.Object("runtime", TakeRuntimeSnapshot()),
+			zap.Strings("recent_errors", c.reporter.recent()),
+		)...)
+	}})
+}
+
+// dump writes every goroutine's stack to the crash sink.
+func (r *crashReporter) dump(ent zapcore.Entry, _ []zapcore.Field) {
+	_, _ = fmt.Fprintf(r.dumpTo, "%s\t%s\t%s\n%s\n",
+		ent.Time.Format(time.RFC3339Nano), ent.Level.CapitalString(), ent.Message, goroutineStacks())
+}
+
+// fingerprint identifies entries logged with the same message from the same
+// place, ignoring their fields.
+func fingerprint(ent zapcore.Entry) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(ent.LoggerName))
+	_, _ = h.Write([]byte(ent.Message))
+	if ent.Caller.Defined {
+		_, _ = fmt.Fprintf(h, "%s:%d", ent.Caller.File, ent.Caller.Line)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,77 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPanicCarriesRuntimeSnapshot(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	Error("first failure")
+	Error("second failure")
+	assert.Panics(t, func() { Panic("giving up") })
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, "giving up")
+	assert.Contains(t, logOutput, "goroutines")
+	assert.Contains(t, logOutput, "uptime")
+	assert.Contains(t, logOutput, "recent_errors")
+	panicked := logOutput[strings.Index(logOutput, "giving up"):]
+	assert.Contains(t, panicked, "crash_test.go:") // Keeps the stack added after Check
+	assert.Len(t, GetLogger().sugaredLogger.Desugar().Core().(*crashCore).reporter.recent(), 2)
+}
+
+func TestErrorDoesNotCarryRuntimeSnapshot(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	Error("plain failure")
+
+	assert.NotContains(t, buf.String(), "goroutines")
+}
+
+func TestPanicWithoutCrashReport(t *testing.T) {
+	buf, cleanup := setupTestLogger(false, WithoutCrashReport())
+	defer cleanup()
+
+	assert.Panics(t, func() { Panic("giving up") })
+
+	assert.Contains(t, buf.String(), "giving up")
+	assert.NotContains(t, buf.String(), "goroutines")
+	assert.NotContains(t, buf.String(), "recent_errors")
+}
+
+func TestCrashReporterKeepsLatestFingerprints(t *testing.T) {
+	r := newCrashReporter(2)
+	r.record(zapcore.Entry{Message: "a"})
+	r.record(zapcore.Entry{Message: "b"})
+	r.record(zapcore.Entry{Message: "c"})
+
+	assert.Equal(t, []string{
+		fingerprint(zapcore.Entry{Message: "b"}),
+		fingerprint(zapcore.Entry{Message: "c"}),
+	}, r.recent())
+}
+
+func TestGoroutineDumpOnPanic(t *testing.T) {
+	var dump bytes.Buffer
+	_, cleanup := setupTestLogger(false, WithGoroutineDump(&dump))
+	defer cleanup()
+
+	Error("not dumped")
+	assert.Zero(t, dump.Len())
+
+	assert.Panics(t, func() { Panic("deadlocked") })
+
+	assert.Contains(t, dump.String(), "PANIC\tdeadlocked")
+	assert.Contains(t, dump.String(), "goroutine ")
+	assert.Contains(t, dump.String(), "TestGoroutineDumpOnPanic")
+}
@@ -0,0 +1,71 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Keys of the fields added by CtxFields.
+const (
+	CtxErrKey        = "ctx.err"         // ctx.Err(), if ctx is done
+	CtxTimeoutKey    = "ctx.timeout"     // Whether the deadline was exceeded
+	CtxCauseKey      = "ctx.cause"       // context.Cause(ctx), if it says more than ctx.Err()
+	CtxDeadlineInKey = "ctx.deadline_in" // Time left until the deadline, negative once past
+)
+
+// CtxFields describes the state of ctx as key-value pairs: why it is done,
+// if it is, whether that was a timeout, the cause given to the cancel
+// function, and how long is left until its deadline. It answers "was it a
+// timeout?" when logging an operation that failed.
+func CtxFields(ctx context.Context) []interface{} {
+	var keysAndValues []interface{}
+	if err := ctx.Err(); err != nil {
+		keysAndValues = append(keysAndValues,
+			CtxErrKey, err.Error(),
+			CtxTimeoutKey, errors.Is(err, context.DeadlineExceeded),
+		)
+		if cause := context.Cause(ctx); cause != nil && cause != err {
+			keysAndValues = append(keysAndValues, CtxCauseKey, cause.Error())
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		keysAndValues = append(keysAndValues, CtxDeadlineInKey, time.Until(deadline))
+	}
+	return keysAndValues
+}
+
+// LogIfCtxDone logs through the global logger if ctx is done.
+// See (*Logger).LogIfCtxDone.
+func LogIfCtxDone(ctx context.Context, msg string, keysAndValues ...interface{}) bool {
+	level, done := ctxDoneLevel(ctx)
+	if done {
+		GetLogger().log(level, msg, append(keysAndValues[:len(keysAndValues):len(keysAndValues)], CtxFields(ctx)...))
+	}
+	return done
+}
+
+// LogIfCtxDone logs msg with keysAndValues and CtxFields(ctx) if ctx is
+// done, at Warn if its deadline was exceeded and at Info if it was canceled,
+// which usually means the caller went away. It reports whether ctx was done.
+func (l *Logger) LogIfCtxDone(ctx context.Context, msg string, keysAndValues ...interface{}) bool {
+	level, done := ctxDoneLevel(ctx)
+	if done {
+		l.log(level, msg, append(keysAndValues[:len(keysAndValues):len(keysAndValues)], CtxFields(ctx)...))
+	}
+	return done
+}
+
+func ctxDoneLevel(ctx context.Context) (Level, bool) {
+	err := ctx.Err()
+	switch {
+	case err == nil:
+		return 0, false
+	case errors.Is(err, context.DeadlineExceeded):
+		return WarnLevel, true
+	default:
+		return InfoLevel, true
+	}
+}
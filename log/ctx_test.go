@@ -0,0 +1,79 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCtxFields(t *testing.T) {
+	assert.Empty(t, CtxFields(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	fields := CtxFields(ctx)
+	require.Len(t, fields, 2)
+	assert.Equal(t, CtxDeadlineInKey, fields[0])
+	assert.InDelta(t, time.Hour, fields[1], float64(time.Minute))
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	fields = CtxFields(ctx)
+	assert.Equal(t, []interface{}{CtxErrKey, "context deadline exceeded", CtxTimeoutKey, true}, fields[:4])
+	assert.Equal(t, CtxDeadlineInKey, fields[4])
+	assert.Negative(t, fields[5])
+
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(errors.New("client disconnected"))
+	assert.Equal(t, []interface{}{
+		CtxErrKey, "context canceled",
+		CtxTimeoutKey, false,
+		CtxCauseKey, "client disconnected",
+	}, CtxFields(ctx))
+}
+
+func TestLogIfCtxDone(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	assert.False(t, LogIfCtxDone(context.Background(), "never logged"))
+	assert.Empty(t, buf.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	assert.True(t, LogIfCtxDone(ctx, "query abandoned", "table", "users"))
+	assert.Contains(t, buf.String(), "warn\t")
+	assert.Contains(t, buf.String(), "query abandoned\t{\"table\": \"users\", \"ctx.err\": \"context deadline exceeded\", \"ctx.timeout\": true")
+
+	buf.Reset()
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	assert.True(t, GetLogger().LogIfCtxDone(ctx, "request canceled"))
+	assert.Contains(t, buf.String(), "info\t")
+	assert.Contains(t, buf.String(), "request canceled\t{\"ctx.err\": \"context canceled\", \"ctx.timeout\": false}")
+}
+
+func TestLogIfCtxDoneReportsCaller(t *testing.T) {
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	l, err := NewLogger(false, WithSink(zapcore.AddSync(&buf)))
+	require.NoError(t, err)
+	logger.Store(l)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.LogIfCtxDone(ctx, "through the method")
+	LogIfCtxDone(ctx, "through the package function")
+
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("ctx_test.go")))
+}
@@ -0,0 +1,138 @@
+//go:build !nozap
+
+package log
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CurlKey is the key CheckErr logs curl commands under.
+const CurlKey = "curl"
+
+// sensitiveHeaders are always redacted from curl commands, on top of the
+// headers named like DefaultRedactedKeys.
+var sensitiveHeaders = newKeySet([]string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"})
+
+// WithCurlCommands makes CheckErr log a curl command reproducing the failed
+// request under CurlKey, when its key-value pairs hold an *http.Request or an
+// *http.Response carrying its request. Request bodies are cut at
+// maxBodyBytes. See CurlCommand for what is redacted.
+func WithCurlCommands(maxBodyBytes int) Option {
+	return func(o *options) {
+		o.curlBodyBytes = maxBodyBytes
+		o.curl = true
+	}
+}
+
+// CurlCommand returns a copy-pasteable curl command line reproducing req.
+// Sensitive headers, like Authorization and Cookie, the password of the URL
+// and the values of DefaultRedactedKeys in its query and in JSON and form
+// bodies are redacted. The body is read
+// through req.GetBody, leaving req.Body untouched, and cut at maxBodyBytes;
+// it's left out if the request can't replay it.
+func CurlCommand(req *http.Request, maxBodyBytes int) string {
+	redacted := newKeySet(DefaultRedactedKeys)
+	args := []string{"curl"}
+	if req.Method != "" && req.Method != http.MethodGet {
+		args = append(args, "-X", req.Method)
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			if sensitiveHeaders.has(name) || redacted.has(name) {
+				value = Redacted
+			}
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+	if req.Host != "" && req.URL != nil && req.Host != req.URL.Host {
+		args = append(args, "-H", shellQuote("Host: "+req.Host))
+	}
+
+	if body, ok := curlBody(req, maxBodyBytes, redacted); ok {
+		args = append(args, "--data-raw", shellQuote(body))
+	}
+
+	if req.URL != nil {
+		args = append(args, shellQuote(redactURL(req.URL, redacted)))
+	}
+	return strings.Join(args, " ")
+}
+
+func curlBody(req *http.Request, maxBodyBytes int, redacted keySet) (string, bool) {
+	if req.GetBody == nil || req.Body == nil || req.Body == http.NoBody {
+		return "", false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", false
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, int64(maxBodyBytes)+1))
+	if err != nil || len(data) == 0 {
+		return "", false
+	}
+	o := &bodyOptions{max: maxBodyBytes, redacted: redacted}
+	return o.render(&capturedBody{contentType: req.Header.Get("Content-Type"), data: data, max: maxBodyBytes}), true
+}
+
+// redactURL returns u as a string with its password and the values of the
+// query parameters in redacted replaced. The query keeps its order.
+func redactURL(u *url.URL, redacted keySet) string {
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+			if name, err := url.QueryUnescape(key); err == nil && redacted.has(name) {
+				params[i] = key + "=" + url.QueryEscape(Redacted)
+			}
+		}
+		copied := *u
+		copied.RawQuery = strings.Join(params, "&")
+		u = &copied
+	}
+	return u.Redacted()
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// appendCurlCommand adds to the key-value pairs logged by CheckErr the curl
+// command reproducing the first request among the values of keysAndValues,
+// if l logs them and they don't hold one already.
+func (l *Logger) appendCurlCommand(logged, keysAndValues []interface{}) []interface{} {
+	if !l.curl || hasKey(logged, CurlKey) {
+		return logged
+	}
+	for i := 1; i < len(keysAndValues); i += 2 {
+		if req := requestOf(keysAndValues[i]); req != nil {
+			return append(logged, CurlKey, CurlCommand(req, l.curlBodyBytes))
+		}
+	}
+	return logged
+}
+
+// requestOf returns the request held by an *http.Request or *http.Response.
+func requestOf(value interface{}) *http.Request {
+	switch v := value.(type) {
+	case *http.Request:
+		return v
+	case *http.Response:
+		if v != nil {
+			return v.Request
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+//go:build !nozap
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// HandleDiagnostics logs a diagnostic bundle through the global logger each
+// time one of sigs is received. See (*Logger).HandleDiagnostics.
+func HandleDiagnostics(sigs ...os.Signal) (stop func()) {
+	return GetLogger().HandleDiagnostics(sigs...)
+}
+
+// HandleDiagnostics logs a diagnostic bundle (see LogDiagnostics) each time
+// one of sigs is received, SIGQUIT when none are given. While it is active Go's
+// own stderr dump and exit on SIGQUIT are suppressed. Calling stop restores the
+// default signal handling once any dump in progress is written.
+func (l *Logger) HandleDiagnostics(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGQUIT}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-ch:
+				l.LogDiagnostics("signal", sig.String())
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+		<-exited
+	}
+}
+
+// LogDiagnostics logs the stacks of every goroutine, a RuntimeSnapshot and
+// the logger's configuration and minimum level, under min_level so it does
+// not clash with the entry's own level, in a single Info entry.
+func (l *Logger) LogDiagnostics(keysAndValues ...interface{}) {
+	l = l.resolved()
+	keysAndValues = append(keysAndValues,
+		zap.Object("runtime", TakeRuntimeSnapshot()),
+		zap.Stringer("min_level", zapcore.LevelOf(l.sugaredLogger.Desugar().Core())),
+		zap.Object("config", zapcore.ObjectMarshalerFunc(l.marshalConfig)),
+		zap.String("stacks", goroutineStacks()),
+	)
+	l.log(InfoLevel, "diagnostic dump", keysAndValues)
+}
+
+func (l *Logger) marshalConfig(enc zapcore.ObjectEncoder) error {
+	enc.AddBool("development", l.config.Development)
+	enc.AddString("encoding", l.config.Encoding)
+	enc.AddBool("sampling", l.config.Sampling != nil)
+	if err := enc.AddArray("output_paths", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, path := range l.config.OutputPaths {
+			arr.AppendString(path)
+		}
+		return nil
+	})); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogDiagnostics(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	GetLogger().LogDiagnostics("reason", "manual")
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, "diagnostic dump")
+	assert.Contains(t, logOutput, `"reason": "manual"`)
+	assert.Contains(t, logOutput, `"min_level": "info"`)
+	assert.Contains(t, logOutput, `"encoding": "json"`)
+	assert.Contains(t, logOutput, "goroutines")
+	assert.Contains(t, logOutput, "TestLogDiagnostics") // Part of the stacks
+}
+
+func TestLogDiagnosticsKeys(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLogger(false, WithTee(TeeSink{Sink: zapcore.AddSync(&buf)}))
+	assert.NoError(t, err)
+
+	l.LogDiagnostics()
+
+	// Decode the top-level keys one by one, as a map would hide duplicates
+	dec := json.NewDecoder(&buf)
+	_, err = dec.Token()
+	assert.NoError(t, err)
+	keys := map[string]int{}
+	for dec.More() {
+		key, err := dec.Token()
+		assert.NoError(t, err)
+		keys[key.(string)]++
+		var value json.RawMessage
+		assert.NoError(t, dec.Decode(&value))
+	}
+
+	for _, key := range []string{"level", "min_level", "msg", "runtime", "config", "stacks"} {
+		assert.Equal(t, 1, keys[key], key)
+	}
+}
+
+func TestHandleDiagnosticsOnSignal(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	stop := HandleDiagnostics(syscall.SIGUSR1)
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	time.Sleep(50 * time.Millisecond)
+	stop() // Waits for the handler, so the buffer is safe to read
+
+	assert.Contains(t, buf.String(), `"signal": "user defined signal 1"`)
+}
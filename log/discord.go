@@ -0,0 +1,200 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of DiscordConfig, and limits of the Discord API.
+const (
+	defaultDiscordRateLimit   = 20
+	defaultDiscordRateWindow  = time.Minute
+	defaultDiscordBurstWindow = time.Minute
+	discordMaxEmbeds          = 10
+	discordMaxFields          = 25
+	discordMaxTitle           = 256
+	discordMaxDescription     = 4096
+	discordMaxFieldName       = 256
+	discordMaxFieldValue      = 1024
+)
+
+// Embed colors by level.
+var discordColors = map[string]int{
+	"error":  0xe74c3c,
+	"dpanic": 0xc0392b,
+	"panic":  0x8e44ad,
+	"fatal":  0x2c0b0e,
+}
+
+// DiscordConfig configures a sink posting entries to a Discord webhook.
+type DiscordConfig struct {
+	WebhookURL string
+
+	// Username and AvatarURL override those of the webhook.
+	Username  string
+	AvatarURL string
+
+	// KeyFields are the fields shown as embed fields, in order. By default
+	// all fields are, sorted by key, up to Discord's limit of 25.
+	KeyFields []string
+
+	// Repeats of an entry, with the same level, message and caller, are
+	// suppressed within BurstWindow of its last post, a minute by default.
+	// The next post of the entry says how many were.
+	BurstWindow time.Duration
+
+	// At most RateLimit messages are posted per RateWindow, 20 per minute by
+	// default, under the 30 Discord allows a webhook.
+	RateLimit  int
+	RateWindow time.Duration
+
+	HTTP  HTTPConfig
+	Batch BatchConfig
+}
+
+// NewDiscordSink returns a sink posting the Error, Panic and Fatal entries
+// written to it to Discord, as embeds colored by level with the message as
+// title, the caller and fields below. Lower entries are skipped, so the sink
+// can take the logger's whole output; entries must be JSON encoded.
+func NewDiscordSink(config DiscordConfig) (*BatchSink, error) {
+	if config.WebhookURL == "" {
+		return nil, errors.New("discord sink needs a webhook URL")
+	}
+	if config.BurstWindow <= 0 {
+		config.BurstWindow = defaultDiscordBurstWindow
+	}
+	if config.RateLimit <= 0 {
+		config.RateLimit = defaultDiscordRateLimit
+	}
+	if config.RateWindow <= 0 {
+		config.RateWindow = defaultDiscordRateWindow
+	}
+
+	shipper, err := newHTTPShipper(config.HTTP, config.WebhookURL, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	dedup := newNotifyDedup(config.BurstWindow, "")
+	limiter := newNotifyLimiter(config.RateLimit, config.RateWindow)
+
+	return newDecodingBatchSink("discord", config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		now := time.Now()
+		var embeds []discordEmbed
+		left := 0
+		for _, e := range notifyEntries(batch, zapcore.ErrorLevel, decode) {
+			ok, repeats := dedup.allow(notifyKey(e), now)
+			switch {
+			case !ok:
+			case len(embeds) == discordMaxEmbeds:
+				left++
+			default:
+				embeds = append(embeds, config.embed(e, repeats))
+			}
+		}
+		if len(embeds) == 0 {
+			return nil
+		}
+		ok, suppressed := limiter.allow(now)
+		if !ok {
+			return nil
+		}
+
+		msg := discordMessage{Username: config.Username, AvatarURL: config.AvatarURL, Embeds: embeds}
+		var notes []string
+		if left > 0 {
+			notes = append(notes, fmt.Sprintf("…and %d more", left))
+		}
+		if suppressed > 0 {
+			notes = append(notes, fmt.Sprintf("%d earlier messages were suppressed by rate limiting", suppressed))
+		}
+		msg.Content = strings.Join(notes, "\n")
+
+		body, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		return shipper.ship(body)
+	}), nil
+}
+
+type discordMessage struct {
+	Content   string         `json:"content,omitempty"`
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Timestamp   string         `json:"timestamp"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+func (c DiscordConfig) embed(e NotifyEntry, repeats int) discordEmbed {
+	embed := discordEmbed{
+		Title:     truncateRunes(strings.ToUpper(e.Level)+": "+e.Message, discordMaxTitle),
+		Color:     discordColors[e.Level],
+		Timestamp: e.Time.UTC().Format(time.RFC3339Nano),
+	}
+	if e.Caller != "" {
+		embed.Description = truncateRunes("`"+e.Caller+"`", discordMaxDescription)
+	}
+
+	keys := c.KeyFields
+	if len(keys) == 0 {
+		keys = e.fieldKeys()
+	}
+	for _, key := range keys {
+		v, ok := e.Fields[key]
+		if !ok {
+			continue
+		}
+		if len(embed.Fields) == discordMaxFields {
+			break
+		}
+		embed.Fields = append(embed.Fields, discordField{
+			Name:   truncateRunes(key, discordMaxFieldName),
+			Value:  truncateRunes(discordValue(v), discordMaxFieldValue),
+			Inline: true,
+		})
+	}
+
+	if repeats > 0 {
+		embed.Footer = &discordFooter{Text: fmt.Sprintf("%d repeats suppressed since the last post", repeats)}
+	}
+	return embed
+}
+
+// discordValue formats a field value, as JSON unless it's a string. Discord
+// rejects empty values.
+func discordValue(v interface{}) string {
+	s, ok := v.(string)
+	if !ok {
+		b, _ := json.Marshal(v)
+		s = string(b)
+	}
+	if s == "" {
+		return "\u200b" // Zero width space
+	}
+	return s
+}
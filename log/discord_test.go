@@ -0,0 +1,73 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscordSink(t *testing.T) {
+	var mu sync.Mutex
+	var messages []discordMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var msg discordMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		messages = append(messages, msg)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink, err := NewDiscordSink(DiscordConfig{
+		WebhookURL: server.URL,
+		KeyFields:  []string{"order", "user"},
+		Batch:      BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	line := `{"level":"error","ts":"2024-05-01T10:00:00Z","msg":"payment failed","caller":"pay.go:12","order":42,"user":"ana","ignored":true}` + "\n"
+	_, _ = sink.Write([]byte(`{"level":"warn","msg":"ignored"}` + "\n"))
+	for i := 0; i < 3; i++ {
+		_, _ = sink.Write([]byte(line))
+	}
+	require.NoError(t, sink.Sync())
+	_, _ = sink.Write([]byte(line))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, messages, 1, "repeats within the burst window are suppressed")
+	require.Len(t, messages[0].Embeds, 1)
+	embed := messages[0].Embeds[0]
+	assert.Equal(t, "ERROR: payment failed", embed.Title)
+	assert.Equal(t, "`pay.go:12`", embed.Description)
+	assert.Equal(t, discordColors["error"], embed.Color)
+	assert.Equal(t, "2024-05-01T10:00:00Z", embed.Timestamp)
+	assert.Equal(t, []discordField{{Name: "order", Value: "42", Inline: true}, {Name: "user", Value: "ana", Inline: true}}, embed.Fields)
+	assert.Nil(t, embed.Footer)
+}
+
+func TestDiscordEmbed(t *testing.T) {
+	config := DiscordConfig{}
+	embed := config.embed(NotifyEntry{Level: "fatal", Message: strings.Repeat("x", 300), Fields: map[string]interface{}{"b": "", "a": []interface{}{1.0}}}, 4)
+
+	assert.Len(t, []rune(embed.Title), discordMaxTitle)
+	assert.Empty(t, embed.Description)
+	assert.Equal(t, []discordField{{Name: "a", Value: "[1]", Inline: true}, {Name: "b", Value: "\u200b", Inline: true}}, embed.Fields)
+	assert.Equal(t, "4 repeats suppressed since the last post", embed.Footer.Text)
+
+	_, err := NewDiscordSink(DiscordConfig{})
+	assert.Error(t, err)
+}
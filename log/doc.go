@@ -0,0 +1,26 @@
+// Package log is the structured logger of these libraries. Entries go
+// through a Backend: zap by default, which the Options of NewLogger and
+// InitLogger configure, or another one, such as slog or zerolog, plugged in
+// with NewLoggerWithBackend or UseBackend. The adapters and sinks built on
+// other libraries, like loggrpc or logkafka, are modules of their own, so
+// depending on log doesn't pull in those libraries.
+//
+// Building with the nozap tag leaves zap out, along with everything built on
+// its cores, for programs that want nothing beyond the standard library: the
+// entry points, CheckErr, the exit hooks and the backends remain, NewLogger
+// and InitLogger log through slog and WithSequence is the only Option.
+//
+//	go build -tags nozap
+//
+// Call InitLogger once at startup, then log through the package functions,
+// which use the global logger, or through a *Logger. Entries take a message
+// followed by alternating keys and values:
+//
+//	log.InitLogger(false)
+//	log.Info("user created", "user", id)
+//
+// CheckErr is the one error helper: CheckErr(err, panic, message,
+// keysAndValues...) returns false if err is nil, and otherwise logs message
+// with err under "error" at Error, or at Panic if panic is true, and returns
+// true.
+package log
@@ -0,0 +1,89 @@
+//go:build !nozap
+
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Reasons entries get dropped, as reported by DroppedEntries.
+const (
+	DropSampling  = "sampling"   // Discarded by the sampler
+	DropBuffer    = "buffer"     // Debug entries of a RequestBuffer that ended fine
+	DropOverflow  = "overflow"   // A full buffer, queue or spool made room
+	DropSinkError = "sink_error" // A sink failed to write or send them
+)
+
+var dropped = struct {
+	mu       sync.Mutex
+	total    map[string]uint64
+	reported map[string]uint64 // totals at the last summary
+}{total: make(map[string]uint64), reported: make(map[string]uint64)}
+
+// DroppedEntries returns how many entries were dropped since the process
+// started, per reason. Metrics integrations export it as counters.
+func DroppedEntries() map[string]uint64 {
+	dropped.mu.Lock()
+	defer dropped.mu.Unlock()
+
+	counts := make(map[string]uint64, len(dropped.total))
+	for reason, n := range dropped.total {
+		counts[reason] = n
+	}
+	return counts
+}
+
+func recordDropped(reason string, n uint64) {
+	if n == 0 {
+		return
+	}
+
+	dropped.mu.Lock()
+	dropped.total[reason] += n
+	dropped.mu.Unlock()
+
+	if reason == DropOverflow {
+		reportInternal("log entries dropped", zap.String("reason", reason), zap.Uint64("count", n))
+	}
+}
+
+// WithDropReport logs a Warn summary of the entries dropped in the last
+// interval, every interval, whenever any were.
+func WithDropReport(every time.Duration) Option {
+	return func(o *options) {
+		o.dropReportEvery = every
+	}
+}
+
+func (l *Logger) reportDropped(every time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			l.logDropSummary(every)
+		}
+	}
+}
+
+func (l *Logger) logDropSummary(interval time.Duration) {
+	dropped.mu.Lock()
+	keysAndValues := []interface{}{"interval", interval}
+	for reason, n := range dropped.total {
+		if delta := n - dropped.reported[reason]; delta > 0 {
+			keysAndValues = append(keysAndValues, reason, delta)
+		}
+		dropped.reported[reason] = n
+	}
+	dropped.mu.Unlock()
+
+	if len(keysAndValues) > 2 {
+		l.log(WarnLevel, "log entries dropped", keysAndValues)
+	}
+}
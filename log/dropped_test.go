@@ -0,0 +1,71 @@
+//go:build !nozap
+
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetDropped clears the dropped entry counters for the test.
+func resetDropped(t *testing.T) {
+	reset := func() {
+		dropped.mu.Lock()
+		defer dropped.mu.Unlock()
+
+		dropped.total = make(map[string]uint64)
+		dropped.reported = make(map[string]uint64)
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestDroppedEntriesCountsEachReason(t *testing.T) {
+	resetDropped(t)
+	captureSinkErrors(t)
+	_, cleanup := setupTestLogger(false, WithSink(&failingWriter{failing: true}))
+	defer cleanup()
+
+	req := NewRequestBuffer(10, 0)
+	req.Debug("dropped with the buffer")
+	req.Debug("dropped with the buffer")
+	req.End(nil)
+
+	Info("lost by the failing sink")
+
+	sink := NewBatchSink("test", BatchConfig{MaxEntries: 1, QueueSize: 1, Overflow: DropNewest}, func([][]byte) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	for i := 0; i < 5; i++ {
+		_, _ = sink.Write([]byte("entry"))
+	}
+	require.NoError(t, sink.Close())
+
+	counts := DroppedEntries()
+	assert.Equal(t, uint64(2), counts[DropBuffer])
+	assert.Equal(t, uint64(1), counts[DropSinkError])
+	assert.Equal(t, sink.Stats().Dropped, counts[DropOverflow])
+}
+
+func TestDropSummaryLogsDeltas(t *testing.T) {
+	resetDropped(t)
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	GetLogger().logDropSummary(time.Minute)
+	assert.Empty(t, buf.String()) // Nothing dropped, nothing logged
+
+	recordDropped(DropSampling, 40)
+	GetLogger().logDropSummary(time.Minute)
+	assert.Contains(t, buf.String(), "log entries dropped")
+	assert.Contains(t, buf.String(), `"sampling": 40`)
+
+	buf.Reset()
+	recordDropped(DropSampling, 2)
+	GetLogger().logDropSummary(time.Minute)
+	assert.Contains(t, buf.String(), `"sampling": 2`) // Only what's new since the last summary
+}
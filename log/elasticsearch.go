@@ -0,0 +1,193 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Defaults of ElasticsearchConfig.
+const (
+	defaultElasticsearchIndex   = "logs-{date}"
+	defaultElasticsearchDate    = "2006.01.02"
+	defaultElasticsearchRetries = 3
+)
+
+// ElasticsearchConfig configures a sink indexing entries with the
+// Elasticsearch bulk API. It works with OpenSearch too.
+type ElasticsearchConfig struct {
+	// URL of the cluster, as in https://es.example.com:9200.
+	URL string
+
+	// Index names the index of each entry. {date} is replaced by the entry
+	// date, UTC, formatted with DateLayout, so each day gets its own index,
+	// to be matched by an index template. It defaults to logs-{date}.
+	Index string
+
+	// DateLayout formats {date}, 2006.01.02 by default.
+	DateLayout string
+
+	// APIKey, or else Username and Password, authenticate the requests.
+	APIKey   string
+	Username string
+	Password string
+
+	// MaxRetries is how many times a batch, or the entries of it the cluster
+	// rejected with 429 Too Many Requests, is sent again, following Backoff.
+	// It defaults to 3; a negative value disables retries.
+	MaxRetries int
+	Backoff    Backoff // DefaultBackoff if unset
+
+	HTTP  HTTPConfig
+	Batch BatchConfig
+}
+
+// NewElasticsearchSink returns a sink indexing entries in batches, one _bulk
+// request per batch. Entries must be JSON encoded: ts becomes @timestamp, msg
+// message, and the other fields are indexed as they are. Entries the cluster
+// rejects for other reasons than 429 aren't retried, and fail the batch.
+func NewElasticsearchSink(config ElasticsearchConfig) (*BatchSink, error) {
+	if config.URL == "" {
+		return nil, errors.New("elasticsearch sink needs a URL")
+	}
+	if config.Index == "" {
+		config.Index = defaultElasticsearchIndex
+	}
+	if config.DateLayout == "" {
+		config.DateLayout = defaultElasticsearchDate
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultElasticsearchRetries
+	}
+	if config.Backoff == (Backoff{}) {
+		config.Backoff = DefaultBackoff
+	}
+
+	endpoint := strings.TrimSuffix(config.URL, "/") + "/_bulk"
+	shipper, err := newHTTPShipper(config.HTTP, endpoint, "application/x-ndjson", Gzip)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case config.APIKey != "":
+		shipper.header.Set("Authorization", "ApiKey "+config.APIKey)
+	case config.Username != "":
+		shipper.authorize = func(req *http.Request) error {
+			req.SetBasicAuth(config.Username, config.Password)
+			return nil
+		}
+	}
+
+	return newDecodingBatchSink("elasticsearch "+config.URL, config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		return config.index(shipper, batch, decode)
+	}), nil
+}
+
+// index sends batch, retrying what was rejected with 429.
+func (c ElasticsearchConfig) index(shipper *httpShipper, batch [][]byte, decode func([]byte) sinkEntry) error {
+	actions := make([][]byte, len(batch))
+	for i, line := range batch {
+		action, err := c.action(decode(line))
+		if err != nil {
+			return err
+		}
+		actions[i] = action
+	}
+
+	var rejected error
+	for attempt := 0; ; attempt++ {
+		var body bytes.Buffer
+		for _, action := range actions {
+			body.Write(action)
+		}
+
+		resp, err := shipper.post(body.Bytes())
+		var statusErr *HTTPStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests && attempt < c.MaxRetries {
+			time.Sleep(c.Backoff.Delay(attempt))
+			continue
+		}
+		if err != nil {
+			return errors.Join(rejected, err)
+		}
+
+		throttled, err := elasticsearchRejected(resp, actions)
+		rejected = errors.Join(rejected, err)
+		if len(throttled) == 0 {
+			return rejected
+		}
+		if attempt >= c.MaxRetries {
+			return errors.Join(rejected, fmt.Errorf("elasticsearch throttled %d entries", len(throttled)))
+		}
+		actions = throttled
+		time.Sleep(c.Backoff.Delay(attempt))
+	}
+}
+
+// action returns the bulk action line and document line indexing e.
+func (c ElasticsearchConfig) action(e sinkEntry) ([]byte, error) {
+	doc := e.Fields
+	doc["@timestamp"] = e.Time.UTC().Format(time.RFC3339Nano)
+	doc["message"] = e.Message
+	doc["level"] = e.Level
+	if e.Caller != "" {
+		doc["caller"] = e.Caller
+	}
+	source, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	index := strings.ReplaceAll(c.Index, "{date}", e.Time.UTC().Format(c.DateLayout))
+	meta, err := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": index}})
+	if err != nil {
+		return nil, err
+	}
+
+	action := make([]byte, 0, len(meta)+len(source)+2)
+	action = append(append(action, meta...), '\n')
+	return append(append(action, source...), '\n'), nil
+}
+
+// elasticsearchRejected reads a bulk response, returning the actions rejected
+// with 429, to retry, and an error describing the other rejections.
+func elasticsearchRejected(resp []byte, actions [][]byte) ([][]byte, error) {
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil || !result.Errors || len(result.Items) != len(actions) {
+		return nil, nil // Nothing to learn from an unexpected response
+	}
+
+	var throttled [][]byte
+	var rejected int
+	var reason string
+	for i, item := range result.Items {
+		for _, outcome := range item {
+			switch {
+			case outcome.Status == http.StatusTooManyRequests:
+				throttled = append(throttled, actions[i])
+			case outcome.Status < 200 || outcome.Status > 299:
+				rejected++
+				reason = outcome.Error.Type + ": " + outcome.Error.Reason
+			}
+		}
+	}
+	if rejected > 0 {
+		return throttled, fmt.Errorf("elasticsearch rejected %d of %d entries: %s", rejected, len(actions), reason)
+	}
+	return throttled, nil
+}
@@ -0,0 +1,158 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeElasticsearch answers bulk requests with the statuses of respond, called
+// with the request number and the documents, and keeps the documents indexed.
+type fakeElasticsearch struct {
+	mu       sync.Mutex
+	requests int
+	indexed  []map[string]interface{}
+	indices  []string
+	auth     string
+	respond  func(request int, docs []map[string]interface{}) (status int, items []int)
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.auth = r.Header.Get("Authorization")
+	var docs []map[string]interface{}
+	var indices []string
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var meta map[string]map[string]string
+		_ = json.Unmarshal(scanner.Bytes(), &meta)
+		scanner.Scan()
+		var doc map[string]interface{}
+		_ = json.Unmarshal(scanner.Bytes(), &doc)
+		docs = append(docs, doc)
+		indices = append(indices, meta["index"]["_index"])
+	}
+
+	f.requests++
+	status, statuses := f.respond(f.requests, docs)
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+	var items []map[string]interface{}
+	var errors bool
+	for i, s := range statuses {
+		item := map[string]interface{}{"status": s}
+		if s == http.StatusCreated {
+			f.indexed = append(f.indexed, docs[i])
+			f.indices = append(f.indices, indices[i])
+		} else {
+			errors = true
+			item["error"] = map[string]string{"type": "mapper_parsing_exception", "reason": "failed to parse"}
+		}
+		items = append(items, map[string]interface{}{"index": item})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": errors, "items": items})
+}
+
+func created(n int) []int {
+	statuses := make([]int, n)
+	for i := range statuses {
+		statuses[i] = http.StatusCreated
+	}
+	return statuses
+}
+
+func newTestElasticsearchSink(t *testing.T, es *fakeElasticsearch, config ElasticsearchConfig) *BatchSink {
+	server := httptest.NewServer(es)
+	t.Cleanup(server.Close)
+
+	config.URL = server.URL
+	config.Backoff = Backoff{Initial: time.Millisecond, Multiplier: 1}
+	config.Batch = BatchConfig{FlushInterval: time.Hour}
+	sink, err := NewElasticsearchSink(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { sink.Close() })
+	return sink
+}
+
+func TestElasticsearchSink(t *testing.T) {
+	es := &fakeElasticsearch{respond: func(_ int, docs []map[string]interface{}) (int, []int) {
+		return http.StatusOK, created(len(docs))
+	}}
+	sink := newTestElasticsearchSink(t, es, ElasticsearchConfig{Index: "api-{date}", APIKey: "key"})
+
+	_, _ = sink.Write([]byte(`{"level":"info","ts":1714557600,"msg":"one","user":7}`))
+	_, _ = sink.Write([]byte(`{"level":"warn","ts":1714608000,"caller":"api/user.go:42","msg":"two"}`))
+	require.NoError(t, sink.Sync())
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	assert.Equal(t, "ApiKey key", es.auth)
+	assert.Equal(t, []string{"api-2024.05.01", "api-2024.05.02"}, es.indices)
+	assert.Equal(t, []map[string]interface{}{
+		{"@timestamp": "2024-05-01T10:00:00Z", "message": "one", "level": "info", "user": float64(7)},
+		{"@timestamp": "2024-05-02T00:00:00Z", "message": "two", "level": "warn", "caller": "api/user.go:42"},
+	}, es.indexed)
+}
+
+func TestElasticsearchSinkRetriesThrottled(t *testing.T) {
+	captureInternal(t)
+	es := &fakeElasticsearch{respond: func(request int, docs []map[string]interface{}) (int, []int) {
+		switch request {
+		case 1:
+			return http.StatusTooManyRequests, nil
+		case 2:
+			// Only the second entry is throttled; the third is malformed.
+			return http.StatusOK, []int{http.StatusCreated, http.StatusTooManyRequests, http.StatusBadRequest}
+		default:
+			return http.StatusOK, created(len(docs))
+		}
+	}}
+	sink := newTestElasticsearchSink(t, es, ElasticsearchConfig{})
+
+	for _, msg := range []string{"one", "two", "three"} {
+		_, _ = sink.Write([]byte(`{"msg":"` + msg + `"}`))
+	}
+	require.NoError(t, sink.Sync())
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	assert.Equal(t, 3, es.requests)
+	require.Len(t, es.indexed, 2)
+	assert.Equal(t, "one", es.indexed[0]["message"])
+	assert.Equal(t, "two", es.indexed[1]["message"])
+	assert.Equal(t, uint64(1), sink.Stats().Failed, "the malformed entry fails the batch")
+}
+
+func TestElasticsearchSinkGivesUp(t *testing.T) {
+	captureInternal(t)
+	es := &fakeElasticsearch{respond: func(int, []map[string]interface{}) (int, []int) {
+		return http.StatusTooManyRequests, nil
+	}}
+	sink := newTestElasticsearchSink(t, es, ElasticsearchConfig{MaxRetries: 2})
+
+	_, _ = sink.Write([]byte(`{"msg":"lost"}`))
+	require.NoError(t, sink.Sync())
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	assert.Equal(t, 3, es.requests)
+	assert.Equal(t, uint64(1), sink.Stats().Failed)
+}
+
+func TestElasticsearchSinkConfig(t *testing.T) {
+	_, err := NewElasticsearchSink(ElasticsearchConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,326 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of EmailConfig.
+const (
+	defaultEmailContextLines = 100
+	defaultEmailTimeout      = 10 * time.Second
+	defaultEmailRateLimit    = 10
+	defaultEmailRateWindow   = time.Hour
+)
+
+// SMTPSecurity selects how the connection to the SMTP server is secured.
+type SMTPSecurity int
+
+const (
+	// SMTPStartTLS upgrades the connection with STARTTLS, failing if the
+	// server doesn't offer it. Usually on port 587.
+	SMTPStartTLS SMTPSecurity = iota
+	// SMTPImplicitTLS speaks TLS from the start, usually on port 465.
+	SMTPImplicitTLS
+	// SMTPPlain sends in clear text. Only meant for relays on the same host
+	// or network.
+	SMTPPlain
+)
+
+// EmailConfig configures a sink emailing critical entries.
+type EmailConfig struct {
+	Addr     string // host:port of the SMTP server
+	Security SMTPSecurity
+	TLS      TLSConfig // ServerName defaults to the host of Addr
+
+	// Username and Password authenticate with PLAIN, if set.
+	Username string
+	Password string
+
+	From    string
+	To      []string
+	Subject string // Prefix of the subjects, "[<hostname>] Critical log entry" by default
+
+	// ContextLines is how many of the last lines written to the sink, at any
+	// level, are attached to the email, 100 by default.
+	ContextLines int
+
+	// At most RateLimit emails are sent per RateWindow, 10 per hour by
+	// default, so that a panic recovered in a loop doesn't flood inboxes.
+	RateLimit  int
+	RateWindow time.Duration
+
+	Timeout time.Duration // Of a whole delivery, 10 seconds by default
+}
+
+// EmailSink emails the Panic and Fatal entries written to it, attaching the
+// lines written before them for context. Sends run in the background; Sync
+// waits for them, which the logger does before panicking or exiting.
+type EmailSink struct {
+	sinkKeys
+	config   EmailConfig
+	tls      *tls.Config
+	hostname string
+	limiter  *notifyLimiter
+
+	mu    sync.Mutex
+	lines [][]byte // Ring of the last ContextLines lines
+	next  int
+
+	pending sync.WaitGroup
+}
+
+// NewEmailSink returns a sink emailing critical entries, which must be JSON
+// encoded. It takes the logger's whole output to keep context lines:
+//
+//	email, err := log.NewEmailSink(log.EmailConfig{Addr: "smtp.example.com:587", ...})
+//	...
+//	log.NewLogger(false, log.WithSink(email))
+func NewEmailSink(config EmailConfig) (*EmailSink, error) {
+	if config.Addr == "" || config.From == "" || len(config.To) == 0 {
+		return nil, errors.New("email sink needs a server address, a sender and recipients")
+	}
+	host, _, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp address: %w", err)
+	}
+	if config.TLS.ServerName == "" {
+		config.TLS.ServerName = host
+	}
+	tlsConfig, err := config.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	if config.Subject == "" {
+		config.Subject = fmt.Sprintf("[%s] Critical log entry", hostname)
+	}
+	if config.ContextLines <= 0 {
+		config.ContextLines = defaultEmailContextLines
+	}
+	if config.RateLimit <= 0 {
+		config.RateLimit = defaultEmailRateLimit
+	}
+	if config.RateWindow <= 0 {
+		config.RateWindow = defaultEmailRateWindow
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultEmailTimeout
+	}
+
+	return &EmailSink{
+		config:   config,
+		tls:      tlsConfig,
+		hostname: hostname,
+		limiter:  newNotifyLimiter(config.RateLimit, config.RateWindow),
+		lines:    make([][]byte, 0, config.ContextLines),
+	}, nil
+}
+
+// Name identifies the sink in SinkErrorCounts.
+func (s *EmailSink) Name() string {
+	return "email " + s.config.Addr
+}
+
+// Write keeps p as a context line and, if it's a Panic or Fatal entry,
+// starts emailing it.
+func (s *EmailSink) Write(p []byte) (int, error) {
+	line := append([]byte(nil), trimNewline(p)...)
+
+	s.mu.Lock()
+	if len(s.lines) < s.config.ContextLines {
+		s.lines = append(s.lines, line)
+	} else {
+		s.lines[s.next] = line
+		s.next = (s.next + 1) % len(s.lines)
+	}
+	var recent [][]byte
+	entries := notifyEntries([][]byte{line}, zapcore.DPanicLevel, s.decode)
+	if len(entries) > 0 {
+		recent = append(append(recent, s.lines[s.next:]...), s.lines[:s.next]...)
+	}
+	s.mu.Unlock()
+
+	if len(entries) > 0 {
+		if ok, suppressed := s.limiter.allow(time.Now()); ok {
+			s.pending.Add(1)
+			go func() {
+				defer s.pending.Done()
+				if err := s.send(entries[0], recent, suppressed); err != nil {
+					reportSinkError(s.Name(), err)
+				}
+			}()
+		}
+	}
+	return len(p), nil
+}
+
+// Sync waits for the emails being sent.
+func (s *EmailSink) Sync() error {
+	s.pending.Wait()
+	return nil
+}
+
+// Close waits for the emails being sent.
+func (s *EmailSink) Close() error {
+	return s.Sync()
+}
+
+func (s *EmailSink) send(e NotifyEntry, recent [][]byte, suppressed int) error {
+	msg, err := s.message(e, recent, suppressed)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	var conn net.Conn
+	if s.config.Security == SMTPImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.config.Addr, s.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", s.config.Addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(s.config.Timeout))
+
+	c, err := smtp.NewClient(conn, s.tls.ServerName)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.config.Security == SMTPStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server doesn't support STARTTLS")
+		}
+		if err := c.StartTLS(s.tls); err != nil {
+			return err
+		}
+	}
+	if s.config.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.tls.ServerName)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.config.From); err != nil {
+		return err
+	}
+	for _, to := range s.config.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message builds the email: the entry as text, with the recent lines as an
+// attachment.
+func (s *EmailSink) message(e NotifyEntry, recent [][]byte, suppressed int) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	subject := s.config.Subject + ": " + e.Message
+	fmt.Fprintf(&buf, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(s.config.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(part, "%s: %s\r\n\r\n", strings.ToUpper(e.Level), e.Message)
+	fmt.Fprintf(part, "Time: %s\r\nHost: %s\r\n", e.Time.Format(time.RFC3339Nano), s.hostname)
+	if e.Caller != "" {
+		fmt.Fprintf(part, "Caller: %s\r\n", e.Caller)
+	}
+	for _, key := range e.fieldKeys() {
+		fmt.Fprintf(part, "%s: %v\r\n", key, e.Fields[key])
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(part, "\r\n%d earlier emails were suppressed by rate limiting.\r\n", suppressed)
+	}
+	fmt.Fprintf(part, "\r\nThe last %d log lines are attached.\r\n", len(recent))
+
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Disposition":       {`attachment; filename="context.log"`},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, &lineBreaker{w: part})
+	for _, line := range recent {
+		_, _ = enc.Write(line)
+		_, _ = enc.Write([]byte("\n"))
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// lineBreaker breaks base64 output into lines of 76 characters, as MIME
+// requires.
+type lineBreaker struct {
+	w   io.Writer
+	col int
+}
+
+func (b *lineBreaker) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		chunk := p
+		if room := 76 - b.col; len(chunk) > room {
+			chunk = chunk[:room]
+		}
+		if _, err := b.w.Write(chunk); err != nil {
+			return 0, err
+		}
+		b.col += len(chunk)
+		p = p[len(chunk):]
+		if b.col == 76 {
+			if _, err := b.w.Write([]byte("\r\n")); err != nil {
+				return 0, err
+			}
+			b.col = 0
+		}
+	}
+	return n, nil
+}
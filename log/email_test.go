@@ -0,0 +1,159 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP accepts mail in clear text and records the messages.
+type fakeSMTP struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	messages []string
+	rcpts    []string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTP{ln: ln}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-fake")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var msg strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				msg.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmailSink(t *testing.T) {
+	server := newFakeSMTP(t)
+	sink, err := NewEmailSink(EmailConfig{
+		Addr:         server.ln.Addr().String(),
+		Security:     SMTPPlain,
+		From:         "app@example.com",
+		To:           []string{"oncall@example.com", "dev@example.com"},
+		Subject:      "[api] Critical",
+		ContextLines: 2,
+	})
+	require.NoError(t, err)
+
+	_, _ = sink.Write([]byte(`{"level":"info","msg":"starting"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"warn","msg":"db slow"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"error","msg":"not critical"}` + "\n"))
+	require.NoError(t, sink.Sync())
+	server.mu.Lock()
+	assert.Empty(t, server.messages)
+	server.mu.Unlock()
+
+	_, _ = sink.Write([]byte(`{"level":"fatal","msg":"db unreachable","caller":"main.go:9","db":"main"}` + "\n"))
+	require.NoError(t, sink.Close())
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, []string{"oncall@example.com", "dev@example.com"}, server.rcpts)
+	require.Len(t, server.messages, 1)
+
+	msg, err := mail.ReadMessage(strings.NewReader(server.messages[0]))
+	require.NoError(t, err)
+	assert.Equal(t, "[api] Critical: db unreachable", msg.Header.Get("Subject"))
+	assert.Equal(t, "app@example.com", msg.Header.Get("From"))
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	part, err := mr.NextPart()
+	require.NoError(t, err)
+	body, _ := io.ReadAll(part)
+	assert.Contains(t, string(body), "FATAL: db unreachable")
+	assert.Contains(t, string(body), "Caller: main.go:9\r\ndb: main\r\n")
+
+	part, err = mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "context.log", part.FileName())
+	attachment, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"error","msg":"not critical"}`+"\n"+`{"level":"fatal","msg":"db unreachable","caller":"main.go:9","db":"main"}`+"\n", string(attachment))
+}
+
+func TestLineBreaker(t *testing.T) {
+	var b strings.Builder
+	w := &lineBreaker{w: &b}
+	_, _ = w.Write([]byte(strings.Repeat("a", 100)))
+	_, _ = w.Write([]byte(strings.Repeat("b", 60)))
+	assert.Equal(t, strings.Repeat("a", 76)+"\r\n"+strings.Repeat("a", 24)+strings.Repeat("b", 52)+"\r\n"+strings.Repeat("b", 8), b.String())
+}
+
+func TestNewEmailSink(t *testing.T) {
+	_, err := NewEmailSink(EmailConfig{Addr: "smtp.example.com:587", From: "app@example.com"})
+	assert.Error(t, err)
+	_, err = NewEmailSink(EmailConfig{Addr: "smtp.example.com", From: "app@example.com", To: []string{"a@example.com"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,136 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// sinkEntry is an entry written to a sink, decoded back from its JSON
+// encoding so sinks can map it to the schema of their backend.
+type sinkEntry struct {
+	Time    time.Time
+	Level   string
+	Message string
+	Caller  string
+	Fields  map[string]interface{} // The other fields
+}
+
+// entryKeys are the keys of the time, level, message and caller of encoded
+// entries. An empty key leaves the part out.
+type entryKeys struct {
+	time, level, message, caller string
+}
+
+// defaultEntryKeys are the keys of zap's production encoder.
+var defaultEntryKeys = entryKeys{time: "ts", level: "level", message: "msg", caller: "caller"}
+
+func entryKeysOf(config zapcore.EncoderConfig) entryKeys {
+	return entryKeys{time: config.TimeKey, level: config.LevelKey, message: config.MessageKey, caller: config.CallerKey}
+}
+
+// entryDecoder is implemented by the sinks decoding the entries written to
+// them, which are told the keys of the encoder writing to them when added to
+// a logger.
+type entryDecoder interface {
+	setEntryKeys(keys entryKeys)
+}
+
+// entryEncoder is implemented by the sinks writing entries of their own, which
+// encode them with the config of the encoder writing to them.
+type entryEncoder interface {
+	setEncoderConfig(config zapcore.EncoderConfig)
+}
+
+// useEntryKeys tells sink, if it's an entryDecoder, the keys of the entries
+// encoded with config, and config itself if it's an entryEncoder.
+func useEntryKeys(sink zapcore.WriteSyncer, config zapcore.EncoderConfig) {
+	if d, ok := sink.(entryDecoder); ok {
+		d.setEntryKeys(entryKeysOf(config))
+	}
+	if e, ok := sink.(entryEncoder); ok {
+		e.setEncoderConfig(config)
+	}
+}
+
+// sinkKeys holds the entry keys of a sink, defaultEntryKeys until set. It's
+// safe for concurrent use.
+type sinkKeys struct {
+	keys atomic.Pointer[entryKeys]
+}
+
+func (k *sinkKeys) setEntryKeys(keys entryKeys) {
+	k.keys.Store(&keys)
+}
+
+// decode decodes line with the keys set.
+func (k *sinkKeys) decode(line []byte) sinkEntry {
+	if keys := k.keys.Load(); keys != nil {
+		return decodeSinkEntry(line, *keys)
+	}
+	return decodeSinkEntry(line, defaultEntryKeys)
+}
+
+// decodeSinkEntry decodes an entry encoded as JSON with keys. Entries that
+// aren't JSON, like those of a console-encoded logger, become a message
+// written now at info level.
+func decodeSinkEntry(line []byte, keys entryKeys) sinkEntry {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return sinkEntry{Time: time.Now(), Level: "info", Message: string(trimNewline(line)), Fields: map[string]interface{}{}}
+	}
+
+	e := sinkEntry{Time: time.Now(), Level: "info", Fields: fields}
+	if v, ok := fields[keys.time]; ok && keys.time != "" {
+		if t, ok := parseEntryTime(v); ok {
+			e.Time = t
+		}
+		delete(fields, keys.time)
+	}
+	for key, dst := range map[string]*string{keys.level: &e.Level, keys.message: &e.Message, keys.caller: &e.Caller} {
+		if v, ok := fields[key].(string); ok && key != "" {
+			*dst = v
+			delete(fields, key)
+		}
+	}
+	return e
+}
+
+// parseEntryTime reads the time encodings zap offers: epoch seconds, millis
+// or nanos, and ISO8601 or RFC3339 strings.
+func parseEntryTime(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case float64:
+		switch {
+		case v > 1e17:
+			return time.Unix(0, int64(v)), true
+		case v > 1e11:
+			return time.UnixMilli(int64(v)), true
+		default:
+			sec := int64(v)
+			return time.Unix(sec, int64((v-float64(sec))*1e9)), true
+		}
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000Z0700"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+		if sec, err := strconv.ParseFloat(v, 64); err == nil {
+			return parseEntryTime(sec)
+		}
+	}
+	return time.Time{}, false
+}
+
+func trimNewline(line []byte) []byte {
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+	return line
+}
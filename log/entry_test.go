@@ -0,0 +1,69 @@
+//go:build !nozap
+
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeSinkEntry(t *testing.T) {
+	e := decodeSinkEntry([]byte(`{"level":"warn","ts":"2024-05-01T10:00:00.000Z","caller":"a.go:1","msg":"slow","ms":12}`+"\n"), defaultEntryKeys)
+	assert.True(t, e.Time.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "warn", e.Level)
+	assert.Equal(t, "slow", e.Message)
+	assert.Equal(t, "a.go:1", e.Caller)
+	assert.Equal(t, map[string]interface{}{"ms": float64(12)}, e.Fields)
+
+	e = decodeSinkEntry([]byte("2024-05-01T10:00:00.000Z\tINFO\tconsole line\n"), defaultEntryKeys)
+	assert.Equal(t, "info", e.Level)
+	assert.Equal(t, "2024-05-01T10:00:00.000Z\tINFO\tconsole line", e.Message)
+	assert.WithinDuration(t, time.Now(), e.Time, time.Minute)
+}
+
+func TestSinkEntryKeysFollowLogger(t *testing.T) {
+	decoded := func(development bool, opts ...Option) []sinkEntry {
+		var entries []sinkEntry
+		sink := newDecodingBatchSink("test", BatchConfig{}, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+			for _, line := range batch {
+				entries = append(entries, decode(line))
+			}
+			return nil
+		})
+		l, err := NewLogger(development, append(opts, WithTee(TeeSink{Sink: sink}))...)
+		require.NoError(t, err)
+		l.Warn("slow", "ms", 12)
+		require.NoError(t, sink.Close())
+		return entries
+	}
+
+	for name, entries := range map[string][]sinkEntry{
+		"key names":   decoded(false, WithKeyNames(KeyNames{Message: "message", Level: "severity", Time: "@timestamp", Caller: "source"})),
+		"development": decoded(true), // T, L, M and C keys
+	} {
+		require.Len(t, entries, 1, name)
+		e := entries[0]
+		assert.Equal(t, "slow", e.Message, name)
+		assert.Equal(t, "warn", strings.ToLower(e.Level), name)
+		assert.Contains(t, e.Caller, "log/entry_test.go:", name)
+		assert.WithinDuration(t, time.Now(), e.Time, time.Minute, name)
+		assert.Equal(t, float64(12), e.Fields["ms"], name)
+		assert.NotContains(t, e.Fields, "message", name)
+		assert.NotContains(t, e.Fields, "M", name)
+	}
+}
+
+func TestParseEntryTime(t *testing.T) {
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, v := range []interface{}{float64(want.Unix()), float64(want.UnixMilli()), float64(want.UnixNano()), "2024-05-01T12:00:00+02:00", "1714557600"} {
+		got, ok := parseEntryTime(v)
+		assert.True(t, ok)
+		assert.True(t, got.Equal(want), "%v", v)
+	}
+	_, ok := parseEntryTime(true)
+	assert.False(t, ok)
+}
@@ -0,0 +1,62 @@
+package log
+
+import "errors"
+
+// ErrorCodeKey is the key CheckErr logs error codes under, so alerting can
+// match on codes rather than on messages.
+const ErrorCodeKey = "error.code"
+
+// Coder is implemented by errors carrying a stable, machine-readable code,
+// like "db.conn_refused". Errors from packages such as errx satisfy it.
+type Coder interface {
+	Code() string
+}
+
+// WithErrorCode returns err annotated with code, for errors that don't carry
+// one themselves. It returns nil if err is nil.
+func WithErrorCode(err error, code string) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{err: err, code: code}
+}
+
+// ErrorCode returns the code of the first error in err's tree implementing
+// Coder with a non-empty code, or "" if there is none.
+func ErrorCode(err error) string {
+	for err != nil {
+		var coder Coder
+		if !errors.As(err, &coder) {
+			return ""
+		}
+		if code := coder.Code(); code != "" {
+			return code
+		}
+		// An empty code: keep looking below the error carrying it.
+		next, ok := coder.(interface{ Unwrap() error })
+		if !ok {
+			return ""
+		}
+		err = next.Unwrap()
+	}
+	return ""
+}
+
+type codedError struct {
+	err  error
+	code string
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+func (e *codedError) Code() string  { return e.code }
+
+// hasKey reports whether key is among the keys of keysAndValues.
+func hasKey(keysAndValues []interface{}, key string) bool {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if keysAndValues[i] == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+//go:build !nozap
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCodedError struct{ code string }
+
+func (e testCodedError) Error() string { return "coded" }
+func (e testCodedError) Code() string  { return e.code }
+
+func TestErrorCode(t *testing.T) {
+	assert.Empty(t, ErrorCode(nil))
+	assert.Empty(t, ErrorCode(errors.New("plain")))
+	assert.Nil(t, WithErrorCode(nil, "unused"))
+
+	err := fmt.Errorf("loading user: %w", WithErrorCode(errors.New("connection refused"), "db.conn_refused"))
+	assert.Equal(t, "db.conn_refused", ErrorCode(err))
+	assert.Equal(t, "loading user: connection refused", err.Error())
+
+	// The outermost code wins, and empty codes are skipped.
+	err = WithErrorCode(WithErrorCode(testCodedError{code: "inner"}, ""), "outer")
+	assert.Equal(t, "outer", ErrorCode(err))
+	assert.Equal(t, "inner", ErrorCode(WithErrorCode(testCodedError{code: "inner"}, "")))
+	assert.Equal(t, "joined", ErrorCode(errors.Join(errors.New("first"), testCodedError{code: "joined"})))
+}
+
+func TestCheckErrLogsErrorCode(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	CheckErr(WithErrorCode(assert.AnError, "payment.declined"), false, "charge failed", "order", 42)
+	assert.Contains(t, buf.String(), `"order": 42, "error.code": "payment.declined"`)
+
+	buf.Reset()
+	CheckErr(testCodedError{code: "from.error"}, false, "explicit code", ErrorCodeKey, "from.caller")
+	assert.Contains(t, buf.String(), `"error.code": "from.caller"`)
+	assert.NotContains(t, buf.String(), "from.error")
+
+	buf.Reset()
+	CheckErr(assert.AnError, false, "no code")
+	assert.NotContains(t, buf.String(), ErrorCodeKey)
+}
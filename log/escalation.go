@@ -0,0 +1,199 @@
+//go:build !nozap
+
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ComponentKey is the field used to tell which component an entry belongs to.
+const ComponentKey = "component"
+
+// WithEscalation enables Debug entries for a component once it logs at least
+// threshold Error entries within window. The component's Debug entries keep
+// flowing until cooldown has passed without being extended, after which the
+// original level applies again. Escalations and restorations are logged.
+//
+// Components are identified by the ComponentKey field, either bound to the
+// logger or passed with the entry.
+func WithEscalation(threshold int, window, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.escalation = &escalator{
+			threshold: threshold,
+			window:    window,
+			cooldown:  cooldown,
+			errors:    make(map[string][]time.Time),
+			escalated: make(map[string]*escalation),
+		}
+	}
+}
+
+// escalator keeps the error history and escalation state shared by every
+// core derived from the logger.
+type escalator struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	root      zapcore.Core
+	errors    map[string][]time.Time
+	escalated map[string]*escalation
+}
+
+// escalation is the escalation of a component, lasting until the cooldown
+// after its last error. Its timer isn't reset when an error extends it, as
+// the timer may be firing already: restore arms it again instead.
+type escalation struct {
+	timer *time.Timer
+	until time.Time
+}
+
+func (e *escalator) wrap(core zapcore.Core) zapcore.Core {
+	e.mu.Lock()
+	e.root = core
+	e.mu.Unlock()
+
+	return &escalationCore{Core: core, esc: e}
+}
+
+// recordError registers an Error entry for component and escalates it when
+// the threshold is crossed.
+func (e *escalator) recordError(component string, at time.Time) {
+	if component == "" {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	recent := e.errors[component][:0]
+	for _, t := range e.errors[component] {
+		if at.Sub(t) < e.window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, at)
+	e.errors[component] = recent
+
+	if len(recent) < e.threshold {
+		return
+	}
+
+	if esc, ok := e.escalated[component]; ok {
+		esc.until = time.Now().Add(e.cooldown)
+		return
+	}
+
+	esc := &escalation{until: time.Now().Add(e.cooldown)}
+	esc.timer = time.AfterFunc(e.cooldown, func() { e.restore(component, esc) })
+	e.escalated[component] = esc
+	e.log(zapcore.WarnLevel, "log level escalated to debug",
+		zap.String(ComponentKey, component),
+		zap.Int("errors", len(recent)),
+		zap.Duration("window", e.window),
+		zap.Duration("cooldown", e.cooldown),
+	)
+}
+
+// restore ends esc unless an error extended it, in which case it's checked
+// again at its new end.
+func (e *escalator) restore(component string, esc *escalation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.escalated[component] != esc {
+		return
+	}
+	if wait := time.Until(esc.until); wait > 0 {
+		esc.timer.Reset(wait)
+		return
+	}
+	delete(e.escalated, component)
+	delete(e.errors, component)
+	e.log(zapcore.InfoLevel, "log level restored", zap.String(ComponentKey, component))
+}
+
+func (e *escalator) isEscalated(component string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, ok := e.escalated[component]
+	return ok
+}
+
+func (e *escalator) anyEscalated() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.escalated) > 0
+}
+
+// log writes an entry about the escalator itself. The caller must hold e.mu.
+func (e *escalator) log(lvl zapcore.Level, msg string, fields ...zapcore.Field) {
+	ent := zapcore.Entry{Level: lvl, Time: time.Now(), Message: msg}
+	if ce := e.root.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// escalationCore counts Error entries per component and lets Debug entries of
+// escalated components through even when the wrapped core would reject them.
+type escalationCore struct {
+	zapcore.Core
+	esc       *escalator
+	component string
+}
+
+func (c *escalationCore) Enabled(lvl zapcore.Level) bool {
+	return c.Core.Enabled(lvl) || (lvl == zapcore.DebugLevel && c.esc.anyEscalated())
+}
+
+func (c *escalationCore) With(fields []zapcore.Field) zapcore.Core {
+	return &escalationCore{
+		Core:      c.Core.With(fields),
+		esc:       c.esc,
+		component: componentOf(fields, c.component),
+	}
+}
+
+func (c *escalationCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.ErrorLevel {
+		ce = ce.AddCore(ent, observer{fn: func(ent zapcore.Entry, fields []zapcore.Field) {
+			c.esc.recordError(componentOf(fields, c.component), ent.Time)
+		}})
+	}
+
+	if c.Core.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+
+	if ent.Level == zapcore.DebugLevel && c.esc.anyEscalated() {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *escalationCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Core.Enabled(ent.Level) {
+		component := componentOf(fields, c.component)
+		if component == "" || !c.esc.isEscalated(component) {
+			return nil
+		}
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// componentOf returns the value of the last ComponentKey field, or def.
+func componentOf(fields []zapcore.Field, def string) string {
+	for _, f := range fields {
+		if f.Key == ComponentKey && f.Type == zapcore.StringType {
+			def = f.String
+		}
+	}
+	return def
+}
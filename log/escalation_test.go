@@ -0,0 +1,79 @@
+//go:build !nozap
+
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestEscalationEnablesDebugForFailingComponent(t *testing.T) {
+	buf, cleanup := setupTestLogger(false, WithEscalation(2, time.Minute, time.Hour))
+	defer cleanup()
+
+	Debug("before escalation", ComponentKey, "payments")
+	Error("charge failed", ComponentKey, "payments")
+	Error("charge failed", ComponentKey, "payments")
+	Debug("after escalation", ComponentKey, "payments")
+	Debug("other component", ComponentKey, "shipping")
+
+	logOutput := buf.String()
+	assert.NotContains(t, logOutput, "before escalation")
+	assert.Contains(t, logOutput, "log level escalated to debug")
+	assert.Contains(t, logOutput, "after escalation")
+	assert.NotContains(t, logOutput, "other component") // Only the failing component is escalated
+}
+
+func TestEscalationRestoresAfterCooldown(t *testing.T) {
+	buf, cleanup := setupTestLogger(false, WithEscalation(1, time.Minute, 10*time.Millisecond))
+	defer cleanup()
+
+	Error("charge failed", ComponentKey, "payments")
+	assert.Eventually(t, func() bool {
+		return !GetLogger().sugaredLogger.Desugar().Core().Enabled(zapcore.DebugLevel)
+	}, time.Second, 5*time.Millisecond)
+
+	Debug("after cooldown", ComponentKey, "payments")
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, "log level restored")
+	assert.NotContains(t, logOutput, "after cooldown")
+}
+
+func TestEscalationIgnoresErrorsOutsideWindow(t *testing.T) {
+	buf, cleanup := setupTestLogger(false, WithEscalation(2, time.Nanosecond, time.Hour))
+	defer cleanup()
+
+	Error("charge failed", ComponentKey, "payments")
+	time.Sleep(time.Millisecond)
+	Error("charge failed", ComponentKey, "payments")
+
+	assert.NotContains(t, buf.String(), "log level escalated")
+}
+
+func TestEscalationExtendedByErrorsRestoresOnce(t *testing.T) {
+	buf, cleanup := setupTestLogger(false, WithEscalation(1, time.Minute, 30*time.Millisecond))
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		Error("charge failed", ComponentKey, "payments")
+		time.Sleep(10 * time.Millisecond)
+	}
+	Debug("still escalated", ComponentKey, "payments")
+	assert.Eventually(t, func() bool {
+		return !GetLogger().sugaredLogger.Desugar().Core().Enabled(zapcore.DebugLevel)
+	}, time.Second, 5*time.Millisecond)
+	// Give a stale timer the time to restore again, then lock the escalator so
+	// that what it wrote is visible.
+	time.Sleep(50 * time.Millisecond)
+	GetLogger().sugaredLogger.Desugar().Core().Enabled(zapcore.DebugLevel)
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, "still escalated")
+	assert.Equal(t, 1, strings.Count(logOutput, "log level escalated"))
+	assert.Equal(t, 1, strings.Count(logOutput, "log level restored"))
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultExitTimeout bounds how long exit hooks may delay the exit.
+const defaultExitTimeout = 5 * time.Second
+
+type exitHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+var (
+	hooksMu    sync.Mutex
+	fatalHooks []func()
+	exitHooks  []exitHook
+
+	// exit is replaced in tests.
+	exit = os.Exit
+)
+
+// OnFatal registers fn to run when a Fatal entry is logged, before the
+// process exits. Hooks run in registration order and share the timeout set by
+// WithFatalTimeout; the process exits once they finish or the timeout expires,
+// whichever comes first. OnExit hooks run after them.
+func OnFatal(fn func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	fatalHooks = append(fatalHooks, fn)
+}
+
+// OnExit registers a named hook run on every exit path this package controls:
+// Fatal, Exit and ExitOnSignal. Hooks run in registration order and each one
+// is logged with its duration and error, if any. The context expires when the
+// exit timeout does.
+func OnExit(name string, fn func(ctx context.Context) error) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	exitHooks = append(exitHooks, exitHook{name: name, fn: fn})
+}
+
+// RunExitHooks runs the OnExit hooks without exiting, for shutdown paths that
+// manage the exit themselves. It stops early if ctx expires and returns the
+// hooks' errors joined.
+func RunExitHooks(ctx context.Context) error {
+	hooksMu.Lock()
+	hooks := append([]exitHook{}, exitHooks...)
+	hooksMu.Unlock()
+
+	l := exitLogger()
+	var errs []error
+	for _, hook := range hooks {
+		if err := ctx.Err(); err != nil {
+			l.Warn("skipping exit hook", "hook", hook.name, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+
+		start := time.Now()
+		err := hook.fn(ctx)
+		if err != nil {
+			l.Error("exit hook failed", "hook", hook.name, "duration", time.Since(start), "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		l.Info("exit hook finished", "hook", hook.name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// Exit runs the OnExit hooks, giving them up to 5 seconds, flushes the global
+// logger and exits the process with code.
+func Exit(code int) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultExitTimeout)
+	defer cancel()
+
+	runWithin(ctx, func() { _ = RunExitHooks(ctx) })
+	_ = exitLogger().backend.Sync()
+	exit(code)
+}
+
+// ExitOnSignal calls Exit(0) when one of sigs is received, SIGINT and SIGTERM
+// when none are given. Calling stop restores the default signal handling.
+func ExitOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		select {
+		case <-done:
+		case sig := <-ch:
+			exitLogger().Info("shutting down", "signal", sig.String())
+			Exit(0)
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// runFatalHooks runs the OnFatal hooks, then the OnExit ones, flushes the
+// global logger and exits with code 1, once they finish or timeout expires.
+func runFatalHooks(timeout time.Duration) {
+	hooksMu.Lock()
+	hooks := append([]func(){}, fatalHooks...)
+	hooksMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	runWithin(ctx, func() {
+		for _, fn := range hooks {
+			fn()
+		}
+		_ = RunExitHooks(ctx)
+		_ = exitLogger().backend.Sync()
+	})
+	exit(1)
+}
+
+// runWithin runs fn and waits until it returns or ctx expires.
+func runWithin(ctx context.Context, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// exitLogger returns the global logger, or one discarding everything if it
+// was never initialized, so exit paths never dereference nil.
+func exitLogger() *Logger {
+	if l := GetLogger(); l != nil {
+		return l
+	}
+	return nopLogger()
+}
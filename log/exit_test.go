@@ -0,0 +1,175 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureExit replaces exit for the duration of the test and returns the
+// codes it was called with.
+func captureExit(t *testing.T) *[]int {
+	var codes []int
+	exit = func(code int) { codes = append(codes, code) }
+	t.Cleanup(func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+
+		exit = os.Exit
+		fatalHooks = nil
+		exitHooks = nil
+	})
+	return &codes
+}
+
+func TestOnFatalRunsHooksBeforeExit(t *testing.T) {
+	codes := captureExit(t)
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	var ran []string
+	OnFatal(func() { ran = append(ran, "close db") })
+	OnFatal(func() { ran = append(ran, "flush sinks") })
+
+	Fatal("can't continue")
+
+	assert.Equal(t, []string{"close db", "flush sinks"}, ran)
+	assert.Equal(t, []int{1}, *codes)
+}
+
+func TestOnFatalTimeout(t *testing.T) {
+	codes := captureExit(t)
+	sink := &opsSink{}
+	l, err := NewLogger(false, WithTee(TeeSink{Sink: sink}), WithFatalTimeout(10*time.Millisecond))
+	assert.NoError(t, err)
+	previous := logger.Load()
+	logger.Store(l)
+	defer logger.Store(previous)
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	OnExit("stuck", func(context.Context) error { // Never finishes on its own
+		close(entered)
+		<-release
+		return nil
+	})
+
+	start := time.Now()
+	l.Fatal("can't continue")
+	<-entered
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, []int{1}, *codes)
+
+	// Let the abandoned hooks finish, so their final sync doesn't land on the
+	// logger of another test
+	close(release)
+	assert.Eventually(t, func() bool { return sink.count("sync") == 2 }, time.Second, time.Millisecond)
+}
+
+func TestFatalRunsExitHooksAfterFatalHooks(t *testing.T) {
+	captureExit(t)
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	var ran []string
+	OnExit("release lock", func(context.Context) error { ran = append(ran, "exit"); return nil })
+	OnFatal(func() { ran = append(ran, "fatal") })
+
+	Fatal("can't continue")
+
+	assert.Equal(t, []string{"fatal", "exit"}, ran)
+}
+
+// opsSink records the writes and syncs it gets, in order.
+type opsSink struct {
+	mu  sync.Mutex
+	ops []string
+}
+
+func (s *opsSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops = append(s.ops, "write")
+	return len(p), nil
+}
+
+func (s *opsSink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops = append(s.ops, "sync")
+	return nil
+}
+
+func (s *opsSink) count(op string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, o := range s.ops {
+		if o == op {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFatalSyncsAfterHooks(t *testing.T) {
+	codes := captureExit(t)
+	sink := &opsSink{}
+	l, err := NewLogger(false, WithTee(TeeSink{Sink: sink}))
+	assert.NoError(t, err)
+	previous := logger.Load()
+	logger.Store(l)
+	defer logger.Store(previous)
+
+	OnExit("log", func(context.Context) error { l.Info("released lock"); return nil })
+
+	l.Fatal("can't continue")
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	// The hook's entry and the one timing it come after the Fatal's own sync
+	assert.Equal(t, []string{"write", "sync", "write", "write", "sync"}, sink.ops)
+	assert.Equal(t, []int{1}, *codes)
+}
+
+func TestExitRunsHooksAndLogsTiming(t *testing.T) {
+	codes := captureExit(t)
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	OnExit("close db", func(context.Context) error { return nil })
+	OnExit("flush queue", func(context.Context) error { return errors.New("queue unreachable") })
+
+	Exit(3)
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, "exit hook finished")
+	assert.Contains(t, logOutput, `"hook": "close db"`)
+	assert.Contains(t, logOutput, "exit hook failed")
+	assert.Contains(t, logOutput, "queue unreachable")
+	assert.Contains(t, logOutput, `"duration"`)
+	assert.Equal(t, []int{3}, *codes)
+}
+
+func TestRunExitHooksSkipsAfterDeadline(t *testing.T) {
+	captureExit(t)
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	OnExit("cancel", func(context.Context) error { cancel(); return nil })
+	OnExit("never runs", func(context.Context) error { t.Error("hook ran after deadline"); return nil })
+
+	err := RunExitHooks(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, buf.String(), "skipping exit hook")
+}
@@ -0,0 +1,33 @@
+//go:build !nozap
+
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithFatalTimeout sets how long OnFatal and OnExit hooks may run after a
+// Fatal entry before the process exits anyway. It defaults to 5 seconds.
+func WithFatalTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.fatalTimeout = timeout
+	}
+}
+
+// fatalHook replaces zap's default os.Exit so the hooks get to run and what
+// they log is flushed, like Exit does.
+type fatalHook struct {
+	timeout time.Duration
+}
+
+func (h fatalHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	runFatalHooks(h.timeout)
+}
+
+// nopLogger returns a logger discarding everything.
+func nopLogger() *Logger {
+	return newZapLogger(zap.NewNop().Sugar(), zap.Config{})
+}
@@ -0,0 +1,119 @@
+//go:build !nozap
+
+package log
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fallbackRetryInterval is how long a FallbackSink stays on its fallback
+// before trying the primary sink again.
+const fallbackRetryInterval = 30 * time.Second
+
+// FallbackSink writes to a primary sink and degrades to a fallback one when
+// the primary fails (disk full, closed pipe...). Failures of the primary are
+// reported to OnSinkError callbacks even though writes succeed. Every switch
+// is announced on the fallback with an entry of its own, encoded as JSON like
+// the logger's entries, and the primary is retried periodically so logging
+// returns to it once it recovers.
+type FallbackSink struct {
+	primary  zapcore.WriteSyncer
+	fallback zapcore.WriteSyncer
+
+	mu       sync.Mutex
+	degraded bool
+	retryAt  time.Time
+	encoder  zapcore.EncoderConfig // Of the logger, for the announcements
+}
+
+// Name identifies the sink in write error reports.
+func (s *FallbackSink) Name() string {
+	return "fallback:" + sinkName(s.primary)
+}
+
+// NewFallbackSink wraps primary so failed writes go to fallback instead, or
+// to os.Stderr if fallback is nil.
+func NewFallbackSink(primary, fallback zapcore.WriteSyncer) *FallbackSink {
+	if fallback == nil {
+		fallback = zapcore.Lock(os.Stderr)
+	}
+	return &FallbackSink{primary: primary, fallback: fallback, encoder: zap.NewProductionEncoderConfig()}
+}
+
+func (s *FallbackSink) setEncoderConfig(config zapcore.EncoderConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.encoder = config
+}
+
+func (s *FallbackSink) setEntryKeys(keys entryKeys) {
+	for _, sink := range []zapcore.WriteSyncer{s.primary, s.fallback} {
+		if d, ok := sink.(entryDecoder); ok {
+			d.setEntryKeys(keys)
+		}
+	}
+}
+
+// Write writes p to the primary sink, or to the fallback one while the
+// primary is failing.
+func (s *FallbackSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.degraded && now.Before(s.retryAt) {
+		return s.fallback.Write(p)
+	}
+
+	n, err := s.primary.Write(p)
+	if err == nil {
+		if s.degraded {
+			s.degraded = false
+			s.announce(zapcore.InfoLevel, "log sink recovered", nil)
+		}
+		return n, nil
+	}
+
+	reportSinkError(sinkName(s.primary), err)
+	if !s.degraded {
+		s.degraded = true
+		s.announce(zapcore.ErrorLevel, "log sink failed, writing to fallback", err)
+	}
+	s.retryAt = now.Add(fallbackRetryInterval)
+	return s.fallback.Write(p)
+}
+
+// Sync flushes whichever sink is in use.
+func (s *FallbackSink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.degraded {
+		return s.fallback.Sync()
+	}
+	return s.primary.Sync()
+}
+
+// announce writes an entry about the sink itself to the fallback. The sink
+// can't go through the logger here, as that would write to itself. The caller
+// holds s.mu.
+func (s *FallbackSink) announce(level zapcore.Level, msg string, err error) {
+	var fields []zapcore.Field
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+
+	entry := zapcore.Entry{Level: level, Time: time.Now(), Message: msg}
+	buf, encodeErr := zapcore.NewJSONEncoder(s.encoder).EncodeEntry(entry, fields)
+	if encodeErr != nil {
+		return
+	}
+	_, _ = s.fallback.Write(buf.Bytes())
+	buf.Free()
+}
@@ -0,0 +1,92 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+// failingWriter fails every write while failing is set.
+type failingWriter struct {
+	bytes.Buffer
+	failing bool
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.failing {
+		return 0, errors.New("no space left on device")
+	}
+	return w.Buffer.Write(p)
+}
+
+func (w *failingWriter) Sync() error { return nil }
+
+func TestFallbackSinkDegradesAndAnnounces(t *testing.T) {
+	primary := &failingWriter{failing: true}
+	var fallback bytes.Buffer
+	sink := NewFallbackSink(primary, zapcore.AddSync(&fallback))
+
+	_, err := sink.Write([]byte("first entry\n"))
+	assert.NoError(t, err)
+	_, err = sink.Write([]byte("second entry\n"))
+	assert.NoError(t, err)
+
+	assert.Contains(t, fallback.String(), "log sink failed, writing to fallback")
+	assert.Contains(t, fallback.String(), "no space left on device")
+	assert.Contains(t, fallback.String(), "first entry")
+	assert.Contains(t, fallback.String(), "second entry")
+	assert.Equal(t, 1, bytes.Count(fallback.Bytes(), []byte("log sink failed"))) // Announced once
+}
+
+func TestFallbackSinkReturnsToPrimary(t *testing.T) {
+	primary := &failingWriter{failing: true}
+	var fallback bytes.Buffer
+	sink := NewFallbackSink(primary, zapcore.AddSync(&fallback))
+
+	_, _ = sink.Write([]byte("lost entry\n"))
+	primary.failing = false
+	sink.retryAt = time.Now() // Skip the retry interval
+
+	_, err := sink.Write([]byte("recovered entry\n"))
+	assert.NoError(t, err)
+
+	assert.Contains(t, primary.String(), "recovered entry")
+	assert.Contains(t, fallback.String(), "log sink recovered")
+}
+
+func TestFallbackSinkAnnouncesWithLoggerKeys(t *testing.T) {
+	var fallback bytes.Buffer
+	sink := NewFallbackSink(&failingWriter{failing: true}, zapcore.AddSync(&fallback))
+	l, err := NewLogger(false, WithSink(sink), WithKeyNames(KeyNames{Time: "@timestamp", Level: "severity", Message: "message"}))
+	require.NoError(t, err)
+
+	l.Info("entry")
+
+	line, _, _ := bytes.Cut(fallback.Bytes(), []byte("\n"))
+	var notice map[string]interface{}
+	require.NoError(t, json.Unmarshal(line, &notice))
+	assert.Equal(t, "log sink failed, writing to fallback", notice["message"])
+	assert.Equal(t, "error", notice["severity"])
+	assert.Contains(t, notice, "@timestamp")
+	assert.NotContains(t, notice, "msg")
+}
+
+func TestFallbackSinkHealthyPrimary(t *testing.T) {
+	primary := &failingWriter{}
+	var fallback bytes.Buffer
+	sink := NewFallbackSink(primary, zapcore.AddSync(&fallback))
+
+	_, err := sink.Write([]byte("entry\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "entry\n", primary.String())
+	assert.Zero(t, fallback.Len())
+}
@@ -0,0 +1,202 @@
+//go:build !nozap
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Modes of the log files and directories created by FileSink.
+const (
+	defaultFileMode os.FileMode = 0o644
+	defaultDirMode  os.FileMode = 0o755
+)
+
+// FileSink appends log entries to a file, optionally rotating it by size
+// and cleaning up the rotated files like lumberjack does, see WithMaxSize. It
+// is safe for concurrent use and can be passed to WithSink.
+type FileSink struct {
+	path string
+	opts fileOptions
+
+	mu   sync.Mutex
+	file *os.File
+	size int64 // Bytes in file
+
+	mill     chan struct{} // Requests a cleanup of the rotated files
+	millDone chan struct{}
+}
+
+// FileOption customizes a FileSink.
+type FileOption func(*fileOptions)
+
+type fileOptions struct {
+	mode       os.FileMode
+	dirMode    os.FileMode
+	uid, gid   int
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+}
+
+// WithFileMode sets the permissions of the log file, 0644 by default. It is
+// enforced on existing files too, regardless of the umask.
+func WithFileMode(mode os.FileMode) FileOption {
+	return func(o *fileOptions) {
+		o.mode = mode
+	}
+}
+
+// WithDirMode sets the permissions of the directories created for the log
+// file, 0755 by default. Existing directories are left alone.
+func WithDirMode(mode os.FileMode) FileOption {
+	return func(o *fileOptions) {
+		o.dirMode = mode
+	}
+}
+
+// WithOwner sets the owner and group of the log file; -1 leaves either
+// unchanged. It only takes effect on Unix when running as root.
+func WithOwner(uid, gid int) FileOption {
+	return func(o *fileOptions) {
+		o.uid, o.gid = uid, gid
+	}
+}
+
+// NewFileSink opens path for appending, creating it and any missing parent
+// directories if needed.
+func NewFileSink(path string, opts ...FileOption) (*FileSink, error) {
+	s := &FileSink{
+		path: path,
+		opts: fileOptions{mode: defaultFileMode, dirMode: defaultDirMode, uid: -1, gid: -1},
+	}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+
+	file, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("can't stat log file %q: %w", path, err)
+	}
+	s.file, s.size = file, info.Size()
+
+	if s.opts.maxSize > 0 {
+		s.mill = make(chan struct{}, 1)
+		s.millDone = make(chan struct{})
+		go s.runMill()
+	}
+
+	openFiles.mu.Lock()
+	openFiles.sinks[s] = struct{}{}
+	openFiles.mu.Unlock()
+	return s, nil
+}
+
+func (s *FileSink) open() (*os.File, error) {
+	if err := mkdirAll(filepath.Dir(s.path), s.opts.dirMode); err != nil {
+		return nil, fmt.Errorf("can't create directory for log file %q: %w", s.path, err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, s.opts.mode)
+	if err != nil {
+		return nil, fmt.Errorf("can't open log file %q: %w", s.path, err)
+	}
+
+	if err := file.Chmod(s.opts.mode); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("can't set mode of log file %q: %w", s.path, err)
+	}
+
+	// Geteuid is -1 on platforms without file ownership.
+	if (s.opts.uid >= 0 || s.opts.gid >= 0) && os.Geteuid() == 0 {
+		if err := file.Chown(s.opts.uid, s.opts.gid); err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("can't set owner of log file %q: %w", s.path, err)
+		}
+	}
+	return file, nil
+}
+
+// Name identifies the sink in write error reports.
+func (s *FileSink) Name() string {
+	return "file:" + s.path
+}
+
+// Write appends p to the file, rotating it first if p would take it over
+// the maximum size.
+func (s *FileSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.opts.maxSize > 0 && s.size > 0 && s.size+int64(len(p)) > s.opts.maxSize {
+		if err := s.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+// Sync flushes the file to disk.
+func (s *FileSink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Sync()
+}
+
+// Close closes the file, after any cleanup of rotated files in progress.
+func (s *FileSink) Close() error {
+	openFiles.mu.Lock()
+	delete(openFiles.sinks, s)
+	openFiles.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mill != nil {
+		close(s.mill)
+		<-s.millDone
+		s.mill = nil
+	}
+	return s.file.Close()
+}
+
+// mkdirAll is os.MkdirAll, except the directories it creates get exactly mode
+// regardless of the umask.
+func mkdirAll(dir string, mode os.FileMode) error {
+	info, err := os.Stat(dir)
+	if err == nil {
+		if !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: dir, Err: errors.New("not a directory")}
+		}
+		return nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if parent := filepath.Dir(dir); parent != dir {
+		if err := mkdirAll(parent, mode); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Mkdir(dir, mode); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return os.Chmod(dir, mode)
+}
@@ -0,0 +1,82 @@
+//go:build !nozap
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSinkMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	sink, err := NewFileSink(path, WithFileMode(0o600))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestFileSinkModeEnforcedOnExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, nil, 0o666))
+
+	sink, err := NewFileSink(path, WithFileMode(0o640))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+}
+
+func TestFileSinkReceivesEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	buf, cleanup := setupTestLogger(false, WithSink(sink))
+	defer cleanup()
+
+	Info("to both outputs", "key", "value")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"msg":"to both outputs"`)
+	assert.Contains(t, buf.String(), "to both outputs")
+}
+
+func TestFileSinkCreatesDirectories(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "var", "log", "app.log")
+
+	sink, err := NewFileSink(path, WithDirMode(0o700))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	for _, dir := range []string{filepath.Join(root, "var"), filepath.Join(root, "var", "log")} {
+		info, err := os.Stat(dir)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	}
+}
+
+func TestFileSinkDirectoryErrorNamesPath(t *testing.T) {
+	root := t.TempDir()
+	blocker := filepath.Join(root, "blocker")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o644)) // A file where a directory is needed
+
+	path := filepath.Join(blocker, "app.log")
+	_, err := NewFileSink(path)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path)
+	assert.Contains(t, err.Error(), "not a directory")
+}
@@ -0,0 +1,30 @@
+//go:build unix && !nozap
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSinkOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner requires root")
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	sink, err := NewFileSink(path, WithOwner(65534, 65534))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	stat := info.Sys().(*syscall.Stat_t)
+	assert.Equal(t, uint32(65534), stat.Uid)
+	assert.Equal(t, uint32(65534), stat.Gid)
+}
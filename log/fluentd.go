@@ -0,0 +1,250 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// defaultFluentdPort is used when FluentdConfig.Addr has no port.
+const defaultFluentdPort = "24224"
+
+// FluentdConfig configures a sink speaking the Fluentd forward protocol, as
+// accepted by fluentd's and fluent-bit's forward inputs.
+type FluentdConfig struct {
+	Addr string // host:port of the aggregator; the port defaults to 24224
+	Tag  string // Tag of the events, used by the aggregator for routing
+
+	TLS *TLSConfig // Optional, see TLSConfig
+
+	// SharedKey enables the shared key handshake of the forward protocol's
+	// security section. Hostname identifies this client in it, os.Hostname
+	// by default.
+	SharedKey string
+	Hostname  string
+
+	// Username and Password authenticate the client if the aggregator
+	// requires user authentication on top of the shared key.
+	Username string
+	Password string
+
+	// RequireAck waits for the aggregator to acknowledge each batch, which
+	// otherwise may be lost with the connection.
+	RequireAck bool
+
+	Timeout time.Duration // For dialing, writing and acknowledgements, 10 seconds by default
+	Batch   BatchConfig
+}
+
+// FluentdSink sends entries to Fluentd in batches, one forward-mode message
+// per batch. Entries must be JSON encoded; their ts field becomes the event
+// time and the other fields the record.
+type FluentdSink struct {
+	*BatchSink
+	forwarder *fluentdForwarder
+}
+
+// NewFluentdSink returns a FluentdSink. It connects lazily, so an unreachable
+// aggregator doesn't prevent the logger from starting; a batch whose send
+// fails is retried once over a new connection.
+func NewFluentdSink(config FluentdConfig) (*FluentdSink, error) {
+	if config.Addr == "" || config.Tag == "" {
+		return nil, errors.New("fluentd sink needs an address and a tag")
+	}
+	if _, _, err := net.SplitHostPort(config.Addr); err != nil {
+		config.Addr = net.JoinHostPort(config.Addr, defaultFluentdPort)
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultDialTimeout
+	}
+
+	f := &fluentdForwarder{config: config}
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		f.tls = tlsConfig
+	}
+
+	return &FluentdSink{
+		BatchSink: newDecodingBatchSink("fluentd "+config.Addr, config.Batch, f.send),
+		forwarder: f,
+	}, nil
+}
+
+// Close sends the remaining entries and closes the connection.
+func (s *FluentdSink) Close() error {
+	err := s.BatchSink.Close()
+	s.forwarder.disconnect()
+	return err
+}
+
+// fluentdForwarder owns the connection. Only the BatchSink's sender goroutine
+// uses it, until Close.
+type fluentdForwarder struct {
+	config FluentdConfig
+	tls    *tls.Config
+
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (f *fluentdForwarder) send(batch [][]byte, decode func([]byte) sinkEntry) error {
+	entries := make([]interface{}, len(batch))
+	for i, line := range batch {
+		e := decode(line)
+		record := e.Fields
+		record["level"] = e.Level
+		record["msg"] = e.Message
+		if e.Caller != "" {
+			record["caller"] = e.Caller
+		}
+		entries[i] = []interface{}{eventTime(e.Time), record}
+	}
+
+	var chunk string
+	option := map[string]interface{}{"size": len(entries)}
+	if f.config.RequireAck {
+		id := make([]byte, 16)
+		_, _ = rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+		option["chunk"] = chunk
+	}
+	message := appendMsgpack(nil, []interface{}{f.config.Tag, entries, option})
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if f.conn == nil {
+			if err = f.connect(); err != nil {
+				continue
+			}
+		}
+		if err = f.write(message, chunk); err == nil {
+			return nil
+		}
+		f.disconnect()
+	}
+	return err
+}
+
+func (f *fluentdForwarder) write(message []byte, chunk string) error {
+	_ = f.conn.SetDeadline(time.Now().Add(f.config.Timeout))
+	if _, err := f.conn.Write(message); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	resp, err := readMsgpack(f.r)
+	if err != nil {
+		return fmt.Errorf("no acknowledgement from fluentd: %w", err)
+	}
+	if ack, _ := resp.(map[string]interface{}); ack["ack"] != chunk {
+		return fmt.Errorf("fluentd acknowledged %v instead of chunk %s", ack["ack"], chunk)
+	}
+	return nil
+}
+
+func (f *fluentdForwarder) connect() error {
+	dialer := &net.Dialer{Timeout: f.config.Timeout}
+	var conn net.Conn
+	var err error
+	if f.tls != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", f.config.Addr, f.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", f.config.Addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	f.conn, f.r = conn, bufio.NewReader(conn)
+	if f.config.SharedKey != "" {
+		if err := f.handshake(); err != nil {
+			f.disconnect()
+			return fmt.Errorf("fluentd handshake failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// handshake authenticates both ends with the shared key: the server sends
+// HELO with a nonce, the client answers PING with a digest of the key, and
+// the server proves it knows the key too in its PONG.
+func (f *fluentdForwarder) handshake() error {
+	_ = f.conn.SetDeadline(time.Now().Add(f.config.Timeout))
+
+	helo, err := readMsgpack(f.r)
+	if err != nil {
+		return err
+	}
+	fields, _ := helo.([]interface{})
+	if len(fields) < 2 || fields[0] != "HELO" {
+		return fmt.Errorf("expected HELO, got %v", helo)
+	}
+	options, _ := fields[1].(map[string]interface{})
+	nonce, _ := options["nonce"].(string)
+	auth, _ := options["auth"].(string)
+
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+	passwordDigest := ""
+	if auth != "" {
+		passwordDigest = sha512Hex(auth, f.config.Username, f.config.Password)
+	}
+	ping := []interface{}{
+		"PING", f.config.Hostname, string(salt),
+		sha512Hex(string(salt), f.config.Hostname, nonce, f.config.SharedKey),
+		f.config.Username, passwordDigest,
+	}
+	if _, err := f.conn.Write(appendMsgpack(nil, ping)); err != nil {
+		return err
+	}
+
+	pong, err := readMsgpack(f.r)
+	if err != nil {
+		return err
+	}
+	fields, _ = pong.([]interface{})
+	if len(fields) < 5 || fields[0] != "PONG" {
+		return fmt.Errorf("expected PONG, got %v", pong)
+	}
+	if ok, _ := fields[1].(bool); !ok {
+		return fmt.Errorf("rejected: %v", fields[2])
+	}
+	serverHostname, _ := fields[3].(string)
+	if fields[4] != sha512Hex(string(salt), serverHostname, nonce, f.config.SharedKey) {
+		return errors.New("server doesn't know the shared key")
+	}
+	return nil
+}
+
+func (f *fluentdForwarder) disconnect() {
+	if f.conn != nil {
+		_ = f.conn.Close()
+		f.conn, f.r = nil, nil
+	}
+}
+
+func sha512Hex(parts ...string) string {
+	h := sha512.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
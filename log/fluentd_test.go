@@ -0,0 +1,150 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFluentd accepts forward-protocol connections, checking the shared key
+// handshake if sharedKey is set and acknowledging chunks.
+type fakeFluentd struct {
+	t         *testing.T
+	ln        net.Listener
+	sharedKey string
+
+	mu       sync.Mutex
+	messages [][]interface{}
+	rejected bool
+}
+
+func newFakeFluentd(t *testing.T, sharedKey string) *fakeFluentd {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeFluentd{t: t, ln: ln, sharedKey: sharedKey}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeFluentd) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeFluentd) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	if f.sharedKey != "" {
+		nonce := "server-nonce"
+		_, _ = conn.Write(appendMsgpack(nil, []interface{}{"HELO", map[string]interface{}{"nonce": nonce, "auth": "", "keepalive": true}}))
+		v, err := readMsgpack(r)
+		if err != nil {
+			return
+		}
+		ping := v.([]interface{})
+		hostname, salt := ping[1].(string), ping[2].(string)
+		if ping[3] != sha512Hex(salt, hostname, nonce, f.sharedKey) {
+			f.mu.Lock()
+			f.rejected = true
+			f.mu.Unlock()
+			_, _ = conn.Write(appendMsgpack(nil, []interface{}{"PONG", false, "shared key mismatch", "", ""}))
+			return
+		}
+		_, _ = conn.Write(appendMsgpack(nil, []interface{}{"PONG", true, "", "aggregator", sha512Hex(salt, "aggregator", nonce, f.sharedKey)}))
+	}
+
+	for {
+		v, err := readMsgpack(r)
+		if err != nil {
+			return
+		}
+		message := v.([]interface{})
+		f.mu.Lock()
+		f.messages = append(f.messages, message)
+		f.mu.Unlock()
+
+		if option, _ := message[2].(map[string]interface{}); option["chunk"] != nil {
+			_, _ = conn.Write(appendMsgpack(nil, map[string]interface{}{"ack": option["chunk"]}))
+		}
+	}
+}
+
+func (f *fakeFluentd) received() [][]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([][]interface{}(nil), f.messages...)
+}
+
+func TestFluentdSinkForwardsBatches(t *testing.T) {
+	server := newFakeFluentd(t, "s3cret")
+	sink, err := NewFluentdSink(FluentdConfig{
+		Addr:       server.ln.Addr().String(),
+		Tag:        "app.api",
+		SharedKey:  "s3cret",
+		RequireAck: true,
+		Batch:      BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"warn","ts":1714557600,"msg":"slow","ms":250}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"info","ts":1714557601,"msg":"done"}` + "\n"))
+	require.NoError(t, sink.Sync())
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "app.api", messages[0][0])
+	entries := messages[0][1].([]interface{})
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"level": "warn", "msg": "slow", "ms": int64(250)}, entries[0].([]interface{})[1])
+	assert.Equal(t, int64(2), messages[0][2].(map[string]interface{})["size"])
+	assert.Equal(t, uint64(1), sink.Stats().Batches, "acknowledged")
+}
+
+func TestFluentdSinkRejectedSharedKey(t *testing.T) {
+	resetDropped(t)
+	captureInternal(t)
+	server := newFakeFluentd(t, "right")
+	sink, err := NewFluentdSink(FluentdConfig{
+		Addr:      server.ln.Addr().String(),
+		Tag:       "app",
+		SharedKey: "wrong",
+		Batch:     BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"msg":"lost"}`))
+	require.NoError(t, sink.Sync())
+
+	assert.Empty(t, server.received())
+	server.mu.Lock()
+	assert.True(t, server.rejected)
+	server.mu.Unlock()
+	assert.Equal(t, uint64(1), sink.Stats().Failed)
+}
+
+func TestFluentdSinkConfig(t *testing.T) {
+	_, err := NewFluentdSink(FluentdConfig{Addr: "localhost"})
+	assert.Error(t, err)
+
+	sink, err := NewFluentdSink(FluentdConfig{Addr: "localhost", Tag: "app"})
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:24224", sink.forwarder.config.Addr)
+	assert.NoError(t, sink.Close())
+}
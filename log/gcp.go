@@ -0,0 +1,240 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGCPLoggingHost = "https://logging.googleapis.com"
+	defaultGCPMetadata    = "metadata.google.internal"
+	defaultGCPLogID       = "app"
+)
+
+// GCPResource is the monitored resource the entries are attached to, like
+// {Type: "k8s_container", Labels: {"cluster_name": ..., "namespace_name":
+// ..., "pod_name": ..., "container_name": ..., "location": ...}}. See
+// https://cloud.google.com/logging/docs/api/v2/resource-list.
+type GCPResource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// GCPLoggingConfig configures a sink writing to Google Cloud Logging with the
+// entries.write API.
+type GCPLoggingConfig struct {
+	ProjectID string
+	LogID     string // Name of the log within the project, "app" by default
+
+	// Resource defaults to the global resource of the project.
+	Resource *GCPResource
+
+	// Labels are added to every entry.
+	Labels map[string]string
+
+	// TraceIDKey and SpanIDKey name the fields carrying the trace and span
+	// IDs, "trace_id" and "span_id" by default. They're sent as the trace
+	// and spanId of the entries, which Cloud Logging uses to show entries
+	// alongside their trace in Cloud Trace.
+	TraceIDKey string
+	SpanIDKey  string
+
+	// Token returns an OAuth2 access token with the logging.write scope and
+	// its expiry. By default tokens come from the metadata server, as on
+	// GCE, GKE, Cloud Run and App Engine; GCE_METADATA_HOST overrides its
+	// address.
+	Token func(ctx context.Context) (token string, expires time.Time, err error)
+
+	// APIHost replaces https://logging.googleapis.com, for instance with a
+	// private endpoint.
+	APIHost string
+
+	HTTP  HTTPConfig
+	Batch BatchConfig // MaxBytes is capped to stay under the API's limits
+}
+
+// NewGCPLoggingSink returns a sink writing entries to Cloud Logging in
+// batches. Entries must be JSON encoded: levels map to severities, msg and
+// the other fields make up the JSON payload, and caller the source location.
+// Batches are written with partial success, so an invalid entry doesn't fail
+// the others.
+func NewGCPLoggingSink(config GCPLoggingConfig) (*BatchSink, error) {
+	if config.ProjectID == "" {
+		return nil, errors.New("gcp logging sink needs a project ID")
+	}
+	if config.LogID == "" {
+		config.LogID = defaultGCPLogID
+	}
+	if config.Resource == nil {
+		config.Resource = &GCPResource{Type: "global", Labels: map[string]string{"project_id": config.ProjectID}}
+	}
+	if config.TraceIDKey == "" {
+		config.TraceIDKey = "trace_id"
+	}
+	if config.SpanIDKey == "" {
+		config.SpanIDKey = "span_id"
+	}
+	host := config.APIHost
+	if host == "" {
+		host = defaultGCPLoggingHost
+	}
+
+	token := config.Token
+	if token == nil {
+		client, err := HTTPConfig{DisableProxy: true, Timeout: config.HTTP.Timeout}.Client()
+		if err != nil {
+			return nil, err
+		}
+		token = (&gcpMetadataCredential{client: client}).token
+	}
+
+	shipper, err := newHTTPShipper(config.HTTP, strings.TrimSuffix(host, "/")+"/v2/entries:write", "application/json", Gzip)
+	if err != nil {
+		return nil, err
+	}
+	cache := &tokenCache{fetch: token}
+	shipper.authorize = func(req *http.Request) error {
+		t, err := cache.get(req.Context())
+		if err != nil {
+			return fmt.Errorf("can't get a gcp token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+		return nil
+	}
+
+	logName := fmt.Sprintf("projects/%s/logs/%s", config.ProjectID, url.PathEscape(config.LogID))
+	config.Batch.MaxBytes = capBatchBytes(config.Batch.MaxBytes, oneMBBatchBytes)
+	return newDecodingBatchSink("gcp-logging "+config.LogID, config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		request := gcpWriteRequest{
+			LogName:        logName,
+			Resource:       config.Resource,
+			Labels:         config.Labels,
+			PartialSuccess: true,
+			Entries:        make([]gcpEntry, len(batch)),
+		}
+		for i, line := range batch {
+			request.Entries[i] = config.entry(decode(line))
+		}
+		body, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		return shipper.ship(body)
+	}), nil
+}
+
+type gcpWriteRequest struct {
+	LogName        string            `json:"logName"`
+	Resource       *GCPResource      `json:"resource"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	PartialSuccess bool              `json:"partialSuccess"`
+	Entries        []gcpEntry        `json:"entries"`
+}
+
+type gcpEntry struct {
+	Timestamp      string                 `json:"timestamp"`
+	Severity       string                 `json:"severity"`
+	JSONPayload    map[string]interface{} `json:"jsonPayload"`
+	Trace          string                 `json:"trace,omitempty"`
+	SpanID         string                 `json:"spanId,omitempty"`
+	SourceLocation *gcpSourceLocation     `json:"sourceLocation,omitempty"`
+}
+
+type gcpSourceLocation struct {
+	File string `json:"file"`
+	Line string `json:"line,omitempty"` // int64, which the API encodes as a string
+}
+
+func (c GCPLoggingConfig) entry(e sinkEntry) gcpEntry {
+	entry := gcpEntry{
+		Timestamp:   e.Time.UTC().Format(time.RFC3339Nano),
+		Severity:    gcpSeverity(e.Level),
+		JSONPayload: e.Fields,
+	}
+	e.Fields["message"] = e.Message
+
+	if id, ok := e.Fields[c.TraceIDKey].(string); ok {
+		delete(e.Fields, c.TraceIDKey)
+		entry.Trace = fmt.Sprintf("projects/%s/traces/%s", c.ProjectID, id)
+	}
+	if id, ok := e.Fields[c.SpanIDKey].(string); ok {
+		delete(e.Fields, c.SpanIDKey)
+		entry.SpanID = id
+	}
+	if e.Caller != "" {
+		entry.SourceLocation = &gcpSourceLocation{File: e.Caller}
+		if i := strings.LastIndexByte(e.Caller, ':'); i > 0 {
+			entry.SourceLocation.File, entry.SourceLocation.Line = e.Caller[:i], e.Caller[i+1:]
+		}
+	}
+	return entry
+}
+
+// gcpSeverity maps zap levels to Cloud Logging severities.
+func gcpSeverity(level string) string {
+	switch level {
+	case "debug":
+		return "DEBUG"
+	case "info":
+		return "INFO"
+	case "warn":
+		return "WARNING"
+	case "error":
+		return "ERROR"
+	case "dpanic":
+		return "CRITICAL"
+	case "panic":
+		return "ALERT"
+	case "fatal":
+		return "EMERGENCY"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// gcpMetadataCredential gets the tokens of the default service account from
+// the metadata server.
+type gcpMetadataCredential struct {
+	client *http.Client
+}
+
+func (c *gcpMetadataCredential) token(ctx context.Context) (string, time.Time, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = defaultGCPMetadata
+	}
+	tokenURL := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, &HTTPStatusError{URL: tokenURL, StatusCode: resp.StatusCode}
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid token response: %w", err)
+	}
+	return body.AccessToken, time.Now().Add(time.Duration(body.ExpiresIn) * time.Second), nil
+}
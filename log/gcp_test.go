@@ -0,0 +1,95 @@
+//go:build !nozap
+
+package log
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCPLoggingSink(t *testing.T) {
+	var mu sync.Mutex
+	var tokenRequests int
+	var request gcpWriteRequest
+	var auth string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/computeMetadata/v1/instance/service-accounts/default/token" {
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			tokenRequests++
+			_, _ = w.Write([]byte(`{"access_token":"secret-token","expires_in":3600}`))
+			return
+		}
+
+		assert.Equal(t, "/v2/entries:write", r.URL.Path)
+		auth = r.Header.Get("Authorization")
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(gz).Decode(&request))
+	}))
+	defer server.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	sink, err := NewGCPLoggingSink(GCPLoggingConfig{
+		ProjectID: "my-project",
+		LogID:     "api",
+		Labels:    map[string]string{"env": "prod"},
+		APIHost:   server.URL,
+		HTTP:      HTTPConfig{Compression: Gzip},
+		Batch:     BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"warn","ts":1714557600,"caller":"api/user.go:42","msg":"slow","ms":250,"trace_id":"abc","span_id":"def"}`))
+	_, _ = sink.Write([]byte(`{"level":"dpanic","ts":1714557601,"msg":"impossible"}`))
+	require.NoError(t, sink.Sync())
+	_, _ = sink.Write([]byte(`{"msg":"again"}`))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, tokenRequests, "token reused")
+	assert.Equal(t, "Bearer secret-token", auth)
+	assert.Equal(t, "projects/my-project/logs/api", request.LogName)
+	assert.Equal(t, &GCPResource{Type: "global", Labels: map[string]string{"project_id": "my-project"}}, request.Resource)
+	assert.Equal(t, map[string]string{"env": "prod"}, request.Labels)
+	assert.True(t, request.PartialSuccess)
+	require.Len(t, request.Entries, 1)
+	assert.Equal(t, "again", request.Entries[0].JSONPayload["message"])
+}
+
+func TestGCPLoggingEntry(t *testing.T) {
+	config := GCPLoggingConfig{ProjectID: "my-project", TraceIDKey: "trace_id", SpanIDKey: "span_id"}
+
+	entry := config.entry(decodeSinkEntry([]byte(`{"level":"warn","ts":1714557600,"caller":"api/user.go:42","msg":"slow","ms":250,"trace_id":"abc","span_id":"def"}`), defaultEntryKeys))
+	assert.Equal(t, gcpEntry{
+		Timestamp:      "2024-05-01T10:00:00Z",
+		Severity:       "WARNING",
+		JSONPayload:    map[string]interface{}{"message": "slow", "ms": float64(250)},
+		Trace:          "projects/my-project/traces/abc",
+		SpanID:         "def",
+		SourceLocation: &gcpSourceLocation{File: "api/user.go", Line: "42"},
+	}, entry)
+
+	for level, severity := range map[string]string{"debug": "DEBUG", "error": "ERROR", "dpanic": "CRITICAL", "panic": "ALERT", "fatal": "EMERGENCY", "trace": "DEFAULT"} {
+		assert.Equal(t, severity, gcpSeverity(level), level)
+	}
+}
+
+func TestGCPLoggingSinkConfig(t *testing.T) {
+	_, err := NewGCPLoggingSink(GCPLoggingConfig{})
+	assert.Error(t, err)
+}
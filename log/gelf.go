@@ -0,0 +1,357 @@
+//go:build !nozap
+
+package log
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Defaults of GelfConfig.
+const (
+	defaultGelfPort      = "12201"
+	defaultGelfChunkSize = 1420 // Fits an Ethernet MTU with the IP and UDP headers
+)
+
+// GELF chunking limits: a chunk starts with the magic bytes, an 8-byte message
+// ID, its sequence number and the chunk count, and a message has at most 128
+// chunks.
+const (
+	gelfChunkHeader = 12
+	gelfMaxChunks   = 128
+)
+
+// GelfConfig configures a sink sending GELF 1.1 messages to Graylog.
+type GelfConfig struct {
+	Addr    string // host:port of the GELF input; the port defaults to 12201
+	Network string // "udp", the default, or "tcp"
+
+	// Host is the host field of the messages, os.Hostname by default.
+	Host string
+
+	// Compression applies to UDP messages, and may be Gzip or Deflate (zlib).
+	// Graylog's TCP inputs don't decompress, so it must be unset for TCP.
+	Compression Compression
+
+	// ChunkSize is the largest UDP datagram sent, 1420 bytes by default.
+	// Longer messages are split into up to 128 chunks; longer still, they're
+	// dropped.
+	ChunkSize int
+
+	TLS *TLSConfig // Optional, TCP only, see TLSConfig
+
+	Timeout time.Duration // For dialing and writing, 10 seconds by default
+	Batch   BatchConfig
+
+	// Reconnect tunes the backoff and retention of the ReconnectingSink
+	// messages go through over TCP.
+	Reconnect []ReconnectOption
+}
+
+// GelfSink sends entries to Graylog as GELF messages, in batches: over UDP as
+// one datagram, or a series of chunks, per entry, and over TCP as
+// null-terminated messages. Entries must be JSON encoded: the msg field
+// becomes short_message, ts the timestamp, level the syslog level, stacktrace
+// the full_message, and the other fields additional fields, nested objects
+// flattened with dots.
+type GelfSink struct {
+	*BatchSink
+	sender *gelfSender
+}
+
+// NewGelfSink returns a GelfSink. It connects lazily, so an unreachable input
+// doesn't prevent the logger from starting. Over TCP, messages go through a
+// ReconnectingSink, which keeps them while the input is unreachable and
+// redials with backoff.
+func NewGelfSink(config GelfConfig) (*GelfSink, error) {
+	if config.Addr == "" {
+		return nil, errors.New("gelf sink needs an address")
+	}
+	if _, _, err := net.SplitHostPort(config.Addr); err != nil {
+		config.Addr = net.JoinHostPort(config.Addr, defaultGelfPort)
+	}
+	if config.Network == "" {
+		config.Network = "udp"
+	}
+	switch config.Network {
+	case "udp":
+		if config.TLS != nil {
+			return nil, errors.New("gelf sink supports TLS over tcp only")
+		}
+		switch config.Compression {
+		case NoCompression, Gzip, Deflate:
+		default:
+			return nil, fmt.Errorf("gelf sink doesn't support compression %q", config.Compression)
+		}
+	case "tcp":
+		if config.Compression != NoCompression {
+			return nil, errors.New("gelf sink supports compression over udp only")
+		}
+	default:
+		return nil, fmt.Errorf("gelf sink doesn't support network %q", config.Network)
+	}
+	if config.Host == "" {
+		config.Host, _ = os.Hostname()
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = defaultGelfChunkSize
+	}
+	if config.ChunkSize <= gelfChunkHeader {
+		return nil, fmt.Errorf("gelf chunk size %d leaves no room for data", config.ChunkSize)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultDialTimeout
+	}
+
+	s := &gelfSender{config: config}
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		s.tls = tlsConfig
+	}
+	if config.Network == "tcp" {
+		s.tcp = NewReconnectingSink("gelf tcp://"+config.Addr, s.dialTCP, config.Reconnect...)
+	}
+
+	return &GelfSink{
+		BatchSink: newDecodingBatchSink("gelf "+config.Network+"://"+config.Addr, config.Batch, s.send),
+		sender:    s,
+	}, nil
+}
+
+// Close sends the remaining entries and closes the connection.
+func (s *GelfSink) Close() error {
+	err := s.BatchSink.Close()
+	s.sender.disconnect()
+	if s.sender.tcp != nil {
+		_ = s.sender.tcp.Close()
+	}
+	return err
+}
+
+// gelfSender owns the connection. Only the BatchSink's sender goroutine uses
+// it, until Close.
+type gelfSender struct {
+	config GelfConfig
+	tls    *tls.Config
+	conn   net.Conn          // Over UDP
+	tcp    *ReconnectingSink // Over TCP
+}
+
+func (s *gelfSender) send(batch [][]byte, decode func([]byte) sinkEntry) error {
+	messages := make([][]byte, 0, len(batch))
+	for _, line := range batch {
+		message, err := gelfMessage(s.config.Host, decode(line))
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	if s.config.Network == "udp" {
+		return s.sendUDP(messages)
+	}
+
+	// The ReconnectingSink reports its own failures.
+	for _, message := range messages {
+		_, _ = s.tcp.Write(append(message, 0))
+	}
+	return nil
+}
+
+// sendUDP sends each message as a datagram, chunked if needed. Messages too
+// large even for 128 chunks are skipped, and reported once the others are
+// sent.
+func (s *gelfSender) sendUDP(messages [][]byte) error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+
+	var tooLarge int
+	for _, message := range messages {
+		payload, err := s.config.Compression.compress(message)
+		if err != nil {
+			return err
+		}
+		datagrams, err := gelfChunks(payload, s.config.ChunkSize)
+		if err != nil {
+			tooLarge++
+			continue
+		}
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.config.Timeout))
+		for _, datagram := range datagrams {
+			if _, err := s.conn.Write(datagram); err != nil {
+				s.disconnect()
+				return err
+			}
+		}
+	}
+	if tooLarge > 0 {
+		return fmt.Errorf("dropped %d gelf messages over %d chunks of %d bytes", tooLarge, gelfMaxChunks, s.config.ChunkSize)
+	}
+	return nil
+}
+
+func (s *gelfSender) connect() error {
+	conn, err := net.DialTimeout("udp", s.config.Addr, s.config.Timeout)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// dialTCP opens the connections of the ReconnectingSink, with a write
+// deadline so a stalled input fails the write instead of hanging it.
+func (s *gelfSender) dialTCP() (io.WriteCloser, error) {
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	var conn net.Conn
+	var err error
+	if s.tls != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.config.Addr, s.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", s.config.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deadlineConn{Conn: conn, timeout: s.config.Timeout}, nil
+}
+
+// deadlineConn sets a write deadline of timeout before each write.
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c deadlineConn) Write(p []byte) (int, error) {
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Write(p)
+}
+
+func (s *gelfSender) disconnect() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// gelfChunks splits payload into datagrams of at most size bytes, with the
+// chunk header if it doesn't fit in one.
+func gelfChunks(payload []byte, size int) ([][]byte, error) {
+	if len(payload) <= size {
+		return [][]byte{payload}, nil
+	}
+
+	data := size - gelfChunkHeader
+	count := (len(payload) + data - 1) / data
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("gelf message of %d bytes needs %d chunks", len(payload), count)
+	}
+
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	chunks := make([][]byte, 0, count)
+	for seq := 0; seq < count; seq++ {
+		end := min((seq+1)*data, len(payload))
+		chunk := make([]byte, 0, gelfChunkHeader+end-seq*data)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(seq), byte(count))
+		chunks = append(chunks, append(chunk, payload[seq*data:end]...))
+	}
+	return chunks, nil
+}
+
+// gelfMessage maps e to a GELF 1.1 message.
+func gelfMessage(host string, e sinkEntry) ([]byte, error) {
+	message := map[string]interface{}{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": e.Message,
+		"timestamp":     math.Round(float64(e.Time.UnixNano())/1e6) / 1e3,
+		"level":         gelfLevel(e.Level),
+	}
+	if message["short_message"] == "" {
+		message["short_message"] = "-" // GELF requires a non-empty message
+	}
+	if stack, ok := e.Fields["stacktrace"].(string); ok {
+		message["full_message"] = stack
+		delete(e.Fields, "stacktrace")
+	}
+	if e.Caller != "" {
+		e.Fields["caller"] = e.Caller
+	}
+
+	fields := make(map[string]interface{}, len(e.Fields))
+	flatten(fields, "", e.Fields)
+	for key, value := range fields {
+		message[gelfFieldName(key)] = gelfFieldValue(value)
+	}
+	return json.Marshal(message)
+}
+
+// gelfLevel maps zap levels to syslog severities.
+func gelfLevel(level string) int {
+	switch level {
+	case "debug":
+		return 7
+	case "info":
+		return 6
+	case "warn":
+		return 4
+	case "error":
+		return 3
+	case "dpanic", "panic", "fatal":
+		return 2
+	default:
+		return 6
+	}
+}
+
+// gelfFieldName returns the name of an additional field: key prefixed with an
+// underscore, with the characters GELF doesn't allow replaced. The reserved
+// _id is renamed _id_.
+func gelfFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name == "id" {
+		name = "id_"
+	}
+	return "_" + name
+}
+
+// gelfFieldValue returns value as a string or number, the only types GELF
+// allows for additional fields. Other values are JSON encoded.
+func gelfFieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string, float64:
+		return v
+	case bool:
+		return fmt.Sprint(v)
+	case nil:
+		return ""
+	default:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+}
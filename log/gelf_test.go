@@ -0,0 +1,219 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readGelfUDP reads one GELF message from conn, reassembling chunks and
+// decompressing it if needed.
+func readGelfUDP(t *testing.T, conn net.PacketConn) map[string]interface{} {
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 65536)
+
+	var payload []byte
+	chunks := map[byte][]byte{}
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		datagram := append([]byte(nil), buf[:n]...)
+		if len(datagram) < 2 || datagram[0] != 0x1e || datagram[1] != 0x0f {
+			payload = datagram
+			break
+		}
+		chunks[datagram[10]] = datagram[12:]
+		if count := int(datagram[11]); len(chunks) == count {
+			for seq := 0; seq < count; seq++ {
+				payload = append(payload, chunks[byte(seq)]...)
+			}
+			break
+		}
+	}
+
+	if len(payload) > 0 && payload[0] == 0x78 {
+		zr, err := zlib.NewReader(bytes.NewReader(payload))
+		require.NoError(t, err)
+		payload, err = io.ReadAll(zr)
+		require.NoError(t, err)
+	}
+	var message map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &message))
+	return message
+}
+
+func TestGelfSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewGelfSink(GelfConfig{
+		Addr:  conn.LocalAddr().String(),
+		Host:  "web-1",
+		Batch: BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"warn","ts":1714557600.5,"caller":"api/user.go:42","msg":"slow","ms":250,"id":7,"http":{"method":"GET"},"ok":true}` + "\n"))
+	require.NoError(t, sink.Sync())
+
+	assert.Equal(t, map[string]interface{}{
+		"version":       "1.1",
+		"host":          "web-1",
+		"short_message": "slow",
+		"timestamp":     1714557600.5,
+		"level":         float64(4),
+		"_caller":       "api/user.go:42",
+		"_ms":           float64(250),
+		"_id_":          float64(7),
+		"_http.method":  "GET",
+		"_ok":           "true",
+	}, readGelfUDP(t, conn))
+}
+
+func TestGelfSinkUDPChunksAndCompresses(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewGelfSink(GelfConfig{
+		Addr:        conn.LocalAddr().String(),
+		Compression: Deflate,
+		ChunkSize:   64,
+		Batch:       BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	// Random-looking data, so compression leaves it long enough to chunk.
+	var stack strings.Builder
+	for i := 0; i < 40; i++ {
+		stack.WriteString(newSpanID())
+	}
+	_, _ = sink.Write([]byte(`{"level":"error","msg":"boom","stacktrace":"` + stack.String() + `"}`))
+	require.NoError(t, sink.Sync())
+
+	message := readGelfUDP(t, conn)
+	assert.Equal(t, "boom", message["short_message"])
+	assert.Equal(t, stack.String(), message["full_message"])
+	assert.Equal(t, float64(3), message["level"])
+}
+
+func TestGelfSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	received := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			message, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			received <- strings.TrimSuffix(message, "\x00")
+		}
+	}()
+
+	sink, err := NewGelfSink(GelfConfig{
+		Addr:    ln.Addr().String(),
+		Network: "tcp",
+		Batch:   BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"info","msg":"one"}`))
+	_, _ = sink.Write([]byte(`{"level":"debug","msg":"two"}`))
+	require.NoError(t, sink.Sync())
+
+	for _, want := range []string{"one", "two"} {
+		var message map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(<-received), &message))
+		assert.Equal(t, want, message["short_message"])
+	}
+}
+
+func TestGelfSinkTCPRetainsDuringOutage(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close()) // The input is down at first
+
+	sink, err := NewGelfSink(GelfConfig{
+		Addr:      addr,
+		Network:   "tcp",
+		Batch:     BatchConfig{FlushInterval: time.Hour},
+		Reconnect: []ReconnectOption{WithBackoff(fastBackoff)},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"info","msg":"kept"}`))
+	require.NoError(t, sink.Sync())
+	time.Sleep(10 * time.Millisecond) // A few failed dials
+
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer ln.Close()
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	message, err := bufio.NewReader(conn).ReadString(0)
+	require.NoError(t, err)
+	assert.Contains(t, message, `"short_message":"kept"`)
+}
+
+func TestGelfChunksLimit(t *testing.T) {
+	chunks, err := gelfChunks(make([]byte, 100), 100)
+	require.NoError(t, err)
+	assert.Len(t, chunks, 1)
+
+	chunks, err = gelfChunks(make([]byte, 100), 50)
+	require.NoError(t, err)
+	assert.Len(t, chunks, 3)
+	assert.Equal(t, chunks[0][2:10], chunks[2][2:10], "same message ID")
+	assert.Equal(t, []byte{2, 3}, chunks[2][10:12])
+
+	_, err = gelfChunks(make([]byte, 129*38+1), 50)
+	assert.Error(t, err)
+}
+
+func TestGelfSinkConfig(t *testing.T) {
+	for _, config := range []GelfConfig{
+		{},
+		{Addr: "localhost", Network: "sctp"},
+		{Addr: "localhost", Network: "tcp", Compression: Gzip},
+		{Addr: "localhost", Compression: Zstd},
+		{Addr: "localhost", TLS: &TLSConfig{}},
+		{Addr: "localhost", ChunkSize: 12},
+	} {
+		_, err := NewGelfSink(config)
+		assert.Error(t, err, "%+v", config)
+	}
+
+	sink, err := NewGelfSink(GelfConfig{Addr: "localhost"})
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:12201", sink.sender.config.Addr)
+	assert.Equal(t, "udp", sink.sender.config.Network)
+	assert.NoError(t, sink.Close())
+}
@@ -0,0 +1,143 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultHoneycombHost = "https://api.honeycomb.io"
+
+// HoneycombConfig configures a sink sending entries as Honeycomb events.
+type HoneycombConfig struct {
+	APIKey  string
+	Dataset string
+
+	// APIHost replaces https://api.honeycomb.io, for instance with
+	// https://api.eu1.honeycomb.io for the EU region.
+	APIHost string
+
+	// TraceIDKey and SpanIDKey name the fields carrying the trace and span
+	// IDs, "trace_id" and "span_id" by default. They're sent as
+	// trace.trace_id and trace.parent_id, which Honeycomb uses to show
+	// entries alongside their trace.
+	TraceIDKey string
+	SpanIDKey  string
+
+	// SampleRate tells Honeycomb that each event stands for that many, if
+	// entries were sampled before reaching the sink.
+	SampleRate uint
+
+	HTTP  HTTPConfig
+	Batch BatchConfig
+}
+
+// NewHoneycombSink returns a sink sending entries to a Honeycomb dataset in
+// batches. Entries must be JSON encoded. Nested objects are flattened into
+// dotted attributes, like http.status, as Honeycomb columns are flat.
+func NewHoneycombSink(config HoneycombConfig) (*BatchSink, error) {
+	if config.APIKey == "" || config.Dataset == "" {
+		return nil, errors.New("honeycomb sink needs an API key and a dataset")
+	}
+	host := config.APIHost
+	if host == "" {
+		host = defaultHoneycombHost
+	}
+	if config.TraceIDKey == "" {
+		config.TraceIDKey = "trace_id"
+	}
+	if config.SpanIDKey == "" {
+		config.SpanIDKey = "span_id"
+	}
+
+	endpoint := strings.TrimSuffix(host, "/") + "/1/batch/" + url.PathEscape(config.Dataset)
+	shipper, err := newHTTPShipper(config.HTTP, endpoint, "application/json", Gzip, Zstd)
+	if err != nil {
+		return nil, err
+	}
+	shipper.header.Set("X-Honeycomb-Team", config.APIKey)
+
+	return newDecodingBatchSink("honeycomb "+config.Dataset, config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		events := make([]honeycombEvent, len(batch))
+		for i, line := range batch {
+			events[i] = config.event(decode(line))
+		}
+		body, err := json.Marshal(events)
+		if err != nil {
+			return err
+		}
+
+		resp, err := shipper.post(body)
+		if err != nil {
+			return err
+		}
+		return honeycombRejected(resp)
+	}), nil
+}
+
+type honeycombEvent struct {
+	Time       string                 `json:"time"`
+	SampleRate uint                   `json:"samplerate,omitempty"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+func (c HoneycombConfig) event(e sinkEntry) honeycombEvent {
+	data := make(map[string]interface{}, len(e.Fields)+3)
+	flatten(data, "", e.Fields)
+	data["level"] = e.Level
+	data["msg"] = e.Message
+	if e.Caller != "" {
+		data["caller"] = e.Caller
+	}
+
+	for key, attribute := range map[string]string{c.TraceIDKey: "trace.trace_id", c.SpanIDKey: "trace.parent_id"} {
+		if id, ok := data[key]; ok {
+			delete(data, key)
+			data[attribute] = id
+		}
+	}
+
+	return honeycombEvent{Time: e.Time.UTC().Format(time.RFC3339Nano), SampleRate: c.SampleRate, Data: data}
+}
+
+// flatten copies fields into dst, joining the keys of nested objects with
+// dots.
+func flatten(dst map[string]interface{}, prefix string, fields map[string]interface{}) {
+	for key, value := range fields {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(dst, prefix+key+".", nested)
+			continue
+		}
+		dst[prefix+key] = value
+	}
+}
+
+// honeycombRejected reports the events of a batch Honeycomb didn't accept,
+// from the per-event statuses it answers with.
+func honeycombRejected(resp []byte) error {
+	var statuses []struct {
+		Status int    `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(resp, &statuses); err != nil {
+		return nil // Nothing to learn from an unexpected response
+	}
+
+	var rejected int
+	var reason string
+	for _, s := range statuses {
+		if s.Status != 202 {
+			rejected++
+			reason = s.Error
+		}
+	}
+	if rejected > 0 {
+		return fmt.Errorf("honeycomb rejected %d of %d events: %s", rejected, len(statuses), reason)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoneycombSink(t *testing.T) {
+	var mu sync.Mutex
+	var events []honeycombEvent
+	var apiKey, path string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		apiKey, path = r.Header.Get("X-Honeycomb-Team"), r.URL.Path
+		var batch []honeycombEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		events = append(events, batch...)
+		_, _ = w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer server.Close()
+
+	sink, err := NewHoneycombSink(HoneycombConfig{
+		APIKey:     "key",
+		Dataset:    "api logs",
+		APIHost:    server.URL,
+		SampleRate: 10,
+		Batch:      BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"info","ts":1714557600,"msg":"served","http":{"status":200,"route":"/users"},"trace_id":"abc","span_id":"def"}`))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "key", apiKey)
+	assert.Equal(t, "/1/batch/api logs", path)
+	require.Len(t, events, 1)
+	assert.Equal(t, "2024-05-01T10:00:00Z", events[0].Time)
+	assert.Equal(t, uint(10), events[0].SampleRate)
+	assert.Equal(t, map[string]interface{}{
+		"level":           "info",
+		"msg":             "served",
+		"http.status":     float64(200),
+		"http.route":      "/users",
+		"trace.trace_id":  "abc",
+		"trace.parent_id": "def",
+	}, events[0].Data)
+}
+
+func TestHoneycombRejectedEvents(t *testing.T) {
+	assert.NoError(t, honeycombRejected([]byte(`[{"status":202},{"status":202}]`)))
+	assert.NoError(t, honeycombRejected([]byte(`not json`)))
+	assert.EqualError(t, honeycombRejected([]byte(`[{"status":202},{"status":400,"error":"event too large"}]`)),
+		"honeycomb rejected 1 of 2 events: event too large")
+}
+
+func TestHoneycombSinkConfig(t *testing.T) {
+	_, err := NewHoneycombSink(HoneycombConfig{Dataset: "logs"})
+	assert.Error(t, err)
+}
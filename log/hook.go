@@ -0,0 +1,113 @@
+//go:build !nozap
+
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// HookEntry is an entry as handed to hooks, with its fields resolved.
+type HookEntry struct {
+	Level   Level
+	Time    time.Time
+	Message string
+	Logger  string // Name of the logger, see Named
+	Caller  string // file:line, empty if the caller isn't recorded
+	Stack   string // Stack trace, if the logger records one at this level
+
+	// Fingerprint identifies the entries with the same logger, message and
+	// caller, as in crash reports. It's stable across restarts of the same
+	// binary.
+	Fingerprint string
+
+	// Fields holds the fields of the entry and of its logger, as they'd be
+	// encoded in JSON. Err is the error logged under "error", if any.
+	Fields map[string]interface{}
+	Err    error
+}
+
+// Hook is called with entries logged at the level it was added for or above.
+type Hook func(entry HookEntry)
+
+type hookConfig struct {
+	level Level
+	hook  Hook
+}
+
+// WithHook calls hook with every entry logged at level or above, to forward
+// them to error trackers or notifiers. Hooks run synchronously as the entry is
+// written, before Panic and Fatal entries panic or exit, so they must hand
+// slow work to another goroutine except when they can't wait, like for the
+// last entry of the process.
+func WithHook(level Level, hook Hook) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hookConfig{level: level, hook: hook})
+	}
+}
+
+// hookCore adds an observer calling the hooks to the entries they want. It
+// keeps the fields added with With, which the observer doesn't see.
+type hookCore struct {
+	zapcore.Core
+	hooks  []hookConfig
+	fields []zapcore.Field
+}
+
+func wrapHooks(hooks []hookConfig) func(zapcore.Core) zapcore.Core {
+	return func(core zapcore.Core) zapcore.Core {
+		return &hookCore{Core: core, hooks: hooks}
+	}
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{
+		Core:   c.Core.With(fields),
+		hooks:  c.hooks,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	for _, h := range c.hooks {
+		if ent.Level >= zapcore.Level(h.level) {
+			ce = ce.AddCore(ent, observer{fn: c.fire})
+			break
+		}
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// fire calls the hooks wanting ent.
+func (c *hookCore) fire(ent zapcore.Entry, fields []zapcore.Field) {
+	entry := HookEntry{
+		Level:   Level(ent.Level),
+		Time:    ent.Time,
+		Message: ent.Message,
+		Logger:  ent.LoggerName,
+		Stack:   ent.Stack,
+
+		Fingerprint: fingerprint(ent),
+	}
+	if ent.Caller.Defined {
+		entry.Caller = ent.Caller.TrimmedPath()
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, fs := range [][]zapcore.Field{c.fields, fields} {
+		for _, f := range fs {
+			f.AddTo(enc)
+			if err, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType && f.Key == "error" {
+				entry.Err = err
+			}
+		}
+	}
+	entry.Fields = enc.Fields
+
+	for _, h := range c.hooks {
+		if ent.Level >= zapcore.Level(h.level) {
+			h.hook(entry)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWithHook(t *testing.T) {
+	var buf bytes.Buffer
+	var entries []HookEntry
+	l, err := NewLogger(false,
+		WithSink(zapcore.AddSync(&buf)),
+		WithFields("app", "api"),
+		WithHook(ErrorLevel, func(e HookEntry) { entries = append(entries, e) }),
+	)
+	require.NoError(t, err)
+
+	failure := errors.New("connection refused")
+	l.Warn("retrying", "attempt", 1)
+	l.With("user", 7).Named("db").Error("query failed", "error", failure, "table", "users")
+
+	require.Len(t, entries, 1)
+	e := entries[0]
+	assert.Equal(t, ErrorLevel, e.Level)
+	assert.Equal(t, "query failed", e.Message)
+	assert.Equal(t, "db", e.Logger)
+	assert.Contains(t, e.Caller, "log/hook_test.go:")
+	assert.Contains(t, e.Stack, "TestWithHook")
+	assert.Len(t, e.Fingerprint, 16)
+	assert.Equal(t, failure, e.Err)
+	assert.Equal(t, "api", e.Fields["app"])
+	assert.Equal(t, int64(7), e.Fields["user"])
+	assert.Equal(t, "users", e.Fields["table"])
+	assert.Equal(t, "connection refused", e.Fields["error"])
+	assert.Contains(t, buf.String(), "query failed", "entries are still written")
+}
+
+func TestWithHookLevels(t *testing.T) {
+	var warnings, errs int
+	l, err := NewLogger(false,
+		WithSink(zapcore.AddSync(&bytes.Buffer{})),
+		WithHook(WarnLevel, func(HookEntry) { warnings++ }),
+		WithHook(ErrorLevel, func(HookEntry) { errs++ }),
+	)
+	require.NoError(t, err)
+
+	l.Info("ignored")
+	l.Warn("warning")
+	l.Error("error")
+
+	assert.Equal(t, 2, warnings)
+	assert.Equal(t, 1, errs)
+}
@@ -0,0 +1,62 @@
+//go:build !nozap
+
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultHTTPTimeout bounds each request made by the HTTP-based sinks.
+const defaultHTTPTimeout = 30 * time.Second
+
+// HTTPConfig holds the settings shared by every HTTP-based sink.
+type HTTPConfig struct {
+	// Proxy is the URL of the proxy to go through. When empty the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+	Proxy string
+
+	// DisableProxy ignores both Proxy and the environment.
+	DisableProxy bool
+
+	TLS     *TLSConfig    // Optional, see TLSConfig
+	Timeout time.Duration // Per request, 30 seconds by default
+
+	// Compression of the batches sent. If the backend doesn't support it, or
+	// it isn't registered, the best one available is used instead.
+	Compression Compression
+}
+
+// Client builds the *http.Client described by the configuration.
+func (c HTTPConfig) Client() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	switch {
+	case c.DisableProxy:
+		transport.Proxy = nil
+	case c.Proxy != "":
+		proxy, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", c.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	default:
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+
+	if c.TLS != nil {
+		tlsConfig, err := c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
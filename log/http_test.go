@@ -0,0 +1,53 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPConfigExplicitProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String() // Proxies receive the absolute target URL
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	client, err := HTTPConfig{Proxy: proxy.URL}.Client()
+	require.NoError(t, err)
+
+	resp, err := client.Get("http://loki.internal/loki/api/v1/push")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "http://loki.internal/loki/api/v1/push", proxied)
+}
+
+func TestHTTPConfigDisableProxy(t *testing.T) {
+	client, err := HTTPConfig{Proxy: "http://proxy.internal:3128", DisableProxy: true}.Client()
+	require.NoError(t, err)
+
+	assert.Nil(t, client.Transport.(*http.Transport).Proxy)
+}
+
+func TestHTTPConfigErrors(t *testing.T) {
+	_, err := HTTPConfig{Proxy: "://bad"}.Client()
+	assert.ErrorContains(t, err, "invalid proxy URL")
+
+	_, err = HTTPConfig{TLS: &TLSConfig{CertFile: "client.crt"}}.Client()
+	assert.Error(t, err)
+}
+
+func TestHTTPConfigDefaults(t *testing.T) {
+	client, err := HTTPConfig{}.Client()
+	require.NoError(t, err)
+
+	assert.Equal(t, defaultHTTPTimeout, client.Timeout)
+	assert.NotNil(t, client.Transport.(*http.Transport).Proxy) // From the environment
+}
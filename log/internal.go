@@ -0,0 +1,90 @@
+//go:build !nozap
+
+package log
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// InternalKey is set on the entries the logging stack writes about its own
+// problems, so they can't be mistaken for application entries.
+const InternalKey = "log_internal"
+
+// internalReportInterval is the minimum time between two identical internal
+// reports; the ones in between are counted and summed into the next.
+const internalReportInterval = time.Second
+
+var internal = struct {
+	mu         sync.Mutex
+	core       zapcore.Core
+	last       map[string]time.Time
+	suppressed map[string]uint64
+}{
+	core:       newInternalCore(os.Stderr),
+	last:       make(map[string]time.Time),
+	suppressed: make(map[string]uint64),
+}
+
+// SetInternalOutput sets where the logging stack reports its own errors:
+// failing sinks, entries dropped on overflow and zap's encoding errors. It is
+// stderr by default, independent of any configured sink, so these problems
+// stay visible when the sinks are what's broken. Reports are JSON lines with
+// InternalKey set, and repeats of the same report are limited to one per
+// second.
+func SetInternalOutput(w io.Writer) {
+	internal.mu.Lock()
+	defer internal.mu.Unlock()
+
+	internal.core = newInternalCore(w)
+}
+
+func newInternalCore(w io.Writer) zapcore.Core {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.Lock(zapcore.AddSync(w)), zapcore.DebugLevel)
+	return core.With([]zapcore.Field{zap.Bool(InternalKey, true)})
+}
+
+// reportInternal writes an internal error report, unless an identical one was
+// written less than internalReportInterval ago. Reports are identical when
+// their message and string fields match, error messages aside.
+func reportInternal(msg string, fields ...zapcore.Field) {
+	key := msg
+	for _, f := range fields {
+		if f.Type == zapcore.StringType && f.Key != "error" {
+			key += "\x00" + f.String
+		}
+	}
+
+	internal.mu.Lock()
+	defer internal.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(internal.last[key]) < internalReportInterval {
+		internal.suppressed[key]++
+		return
+	}
+	internal.last[key] = now
+
+	if n := internal.suppressed[key]; n > 0 {
+		fields = append(fields, zap.Uint64("suppressed", n))
+		delete(internal.suppressed, key)
+	}
+	_ = internal.core.Write(zapcore.Entry{Level: zapcore.ErrorLevel, Time: now, Message: msg}, fields)
+}
+
+// internalErrorOutput receives zap's own error messages, such as encoding
+// failures, and reports them.
+type internalErrorOutput struct{}
+
+func (internalErrorOutput) Write(p []byte) (int, error) {
+	reportInternal("zap error", zap.String("error", strings.TrimSpace(string(p))))
+	return len(p), nil
+}
+
+func (internalErrorOutput) Sync() error { return nil }
@@ -0,0 +1,61 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureInternal sends internal reports to a buffer for the test.
+func captureInternal(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	reset := func() {
+		internal.last = make(map[string]time.Time)
+		internal.suppressed = make(map[string]uint64)
+	}
+	reset()
+	SetInternalOutput(&buf)
+	t.Cleanup(func() {
+		reset()
+		SetInternalOutput(io.Discard)
+	})
+	return &buf
+}
+
+func TestSinkErrorsReportedInternally(t *testing.T) {
+	captureSinkErrors(t)
+	out := captureInternal(t)
+	_, cleanup := setupTestLogger(false, WithSink(&failingWriter{failing: true}))
+	defer cleanup()
+
+	Info("first")
+	Info("second")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2) // The repeats are suppressed
+	for _, line := range lines {
+		assert.Contains(t, line, `"log_internal":true`)
+	}
+	assert.Contains(t, lines[0], `"msg":"log sink failed","log_internal":true,"sink":"*log.failingWriter","error":"no space left on device"`)
+	assert.Contains(t, lines[1], `"msg":"zap error"`)
+}
+
+func TestInternalReportsCountSuppressedRepeats(t *testing.T) {
+	out := captureInternal(t)
+
+	recordDropped(DropOverflow, 3)
+	recordDropped(DropOverflow, 4)
+	internal.last = make(map[string]time.Time) // As if the interval had passed
+	recordDropped(DropOverflow, 5)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"count":3`)
+	assert.Contains(t, lines[1], `"count":5,"suppressed":1`)
+}
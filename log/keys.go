@@ -0,0 +1,38 @@
+//go:build !nozap
+
+package log
+
+import "go.uber.org/zap/zapcore"
+
+// KeyNames renames the keys of the fields zap adds to entries, to match an
+// existing log schema. Empty names keep zap's defaults.
+type KeyNames struct {
+	Message    string // "msg" by default
+	Level      string // "level" by default
+	Time       string // "ts" in production, "T" in development by default
+	Caller     string // "caller" in production, "C" in development by default
+	Stacktrace string // "stacktrace" in production, "S" in development by default
+}
+
+// WithKeyNames renames the message, level, time, caller and stacktrace keys,
+// for instance to "message" and "@timestamp". Sinks added with WithSink use the
+// same names, and those decoding entries to map them to their backend, like
+// NewSplunkSink's, read them by these names.
+func WithKeyNames(names KeyNames) Option {
+	return func(o *options) {
+		o.keyNames = names
+	}
+}
+
+func (n KeyNames) apply(config *zapcore.EncoderConfig) {
+	rename := func(key *string, name string) {
+		if name != "" {
+			*key = name
+		}
+	}
+	rename(&config.MessageKey, n.Message)
+	rename(&config.LevelKey, n.Level)
+	rename(&config.TimeKey, n.Time)
+	rename(&config.CallerKey, n.Caller)
+	rename(&config.StacktraceKey, n.Stacktrace)
+}
@@ -0,0 +1,40 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestKeyNames(t *testing.T) {
+	var sink bytes.Buffer
+	_, cleanup := setupTestLogger(false,
+		WithKeyNames(KeyNames{Message: "message", Level: "severity", Time: "@timestamp"}),
+		WithSink(zapcore.AddSync(&sink)),
+	)
+	defer cleanup()
+
+	Info("renamed")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(sink.Bytes(), &entry))
+	assert.Equal(t, "renamed", entry["message"])
+	assert.Equal(t, "info", entry["severity"])
+	assert.Contains(t, entry, "@timestamp")
+	assert.NotContains(t, entry, "msg")
+	assert.NotContains(t, entry, "level")
+}
+
+func TestKeyNamesKeepDefaults(t *testing.T) {
+	config := zapcore.EncoderConfig{MessageKey: "msg", CallerKey: "caller"}
+	KeyNames{Message: "message"}.apply(&config)
+
+	assert.Equal(t, "message", config.MessageKey)
+	assert.Equal(t, "caller", config.CallerKey)
+}
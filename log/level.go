@@ -0,0 +1,55 @@
+//go:build !nozap
+
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SetLevel sets the minimum level of the global logger.
+// See (*Logger).SetLevel.
+func SetLevel(level Level) {
+	GetLogger().SetLevel(level)
+}
+
+// GetLevel returns the minimum level of the global logger.
+func GetLevel() Level {
+	return GetLogger().GetLevel()
+}
+
+// SetLevel changes the minimum level of l at runtime, for instance to debug
+// a running service without restarting it. The level is shared by l, the
+// loggers derived from it with With, Named and the like, and its sinks.
+//
+// Loggers made with NewLoggerWithBackend leave levels to their backend, so
+// SetLevel does nothing on them.
+func (l *Logger) SetLevel(level Level) {
+	l = l.resolved()
+	if l.config.Level == (zap.AtomicLevel{}) {
+		return
+	}
+	l.levelOf().SetLevel(zapcore.Level(level))
+}
+
+// GetLevel returns the minimum level of l: the lowest one its backend has
+// enabled for loggers made with NewLoggerWithBackend.
+func (l *Logger) GetLevel() Level {
+	l = l.resolved()
+	if l.config.Level != (zap.AtomicLevel{}) {
+		return Level(l.levelOf().Level())
+	}
+	for level := DebugLevel; level < FatalLevel; level++ {
+		if l.backend.Enabled(level) {
+			return level
+		}
+	}
+	return FatalLevel
+}
+
+// AtomicLevel returns the zap level behind SetLevel and GetLevel, which also
+// serves as an http.Handler reading and changing it. It is the zero
+// AtomicLevel for loggers made with NewLoggerWithBackend.
+func (l *Logger) AtomicLevel() zap.AtomicLevel {
+	return l.resolved().levelOf()
+}
@@ -0,0 +1,39 @@
+//go:build !nozap
+
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLevel(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	child := With("component", "db")
+	assert.Equal(t, InfoLevel, GetLevel())
+
+	child.Debug("hidden")
+	SetLevel(DebugLevel)
+	assert.Equal(t, DebugLevel, GetLevel())
+	child.Debug("shown")
+	Debug("shown too")
+
+	SetLevel(ErrorLevel)
+	Warn("hidden again")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+	assert.Contains(t, buf.String(), "shown too")
+	assert.Equal(t, "error", GetLogger().AtomicLevel().String())
+}
+
+func TestSetLevelOtherBackends(t *testing.T) {
+	backend := newRecordingBackend(WarnLevel)
+	l := NewLoggerWithBackend(backend)
+
+	l.SetLevel(DebugLevel) // Left to the backend
+	assert.Equal(t, WarnLevel, l.GetLevel())
+}
@@ -0,0 +1,105 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelHandler serves the levels of the global logger, whichever it is at
+// the time of the request. See (*Logger).LevelHandler.
+func LevelHandler() http.Handler {
+	return newLevelHandler(GetLogger)
+}
+
+// LevelHandler returns a handler reading and changing the levels of l at
+// runtime, meant to be mounted at /loglevel. It serves zap's AtomicLevel API:
+// GET returns {"level":"info"} and PUT sets the level from a JSON body like
+// {"level":"debug"} or a form value level=debug.
+//
+// With a logger query parameter, as in /loglevel?logger=db, it serves the
+// level of the loggers with that Named name instead, as set with
+// WithModuleLevels. A PUT with a valid level adds an override if there's none,
+// which applies to the loggers with that name and their sub-loggers, those
+// created before included. A GET without the parameter also returns the
+// overrides, under "modules".
+func (l *Logger) LevelHandler() http.Handler {
+	return newLevelHandler(func() *Logger { return l })
+}
+
+func newLevelHandler(logger func() *Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := logger()
+		if l != nil {
+			l = l.resolved()
+		}
+		if l == nil || l.config.Level == (zap.AtomicLevel{}) || l.modules == nil {
+			http.Error(w, "levels are left to the logging backend", http.StatusNotImplemented)
+			return
+		}
+
+		name := r.URL.Query().Get("logger")
+		switch {
+		case name != "" && r.Method == http.MethodPut:
+			// Only a valid level adds an override.
+			level, err := decodeLevel(r)
+			if err != nil {
+				writeLevelJSON(w, http.StatusBadRequest, "error", err.Error())
+				return
+			}
+			l.modules.get(name, level).SetLevel(level)
+			writeLevelJSON(w, http.StatusOK, "level", level.String())
+		case name != "":
+			level := l.config.Level
+			if _, override, ok := l.modules.lookup(name); ok {
+				level = override
+			}
+			level.ServeHTTP(w, r)
+		case r.Method == http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(struct {
+				Level   string            `json:"level"`
+				Modules map[string]string `json:"modules,omitempty"`
+			}{l.levelOf().String(), l.modules.snapshot()})
+		default:
+			l.levelOf().ServeHTTP(w, r)
+		}
+	})
+}
+
+// decodeLevel reads the level of a PUT request as zap's AtomicLevel does, from
+// a form value or a JSON body.
+func decodeLevel(r *http.Request) (zapcore.Level, error) {
+	var level zapcore.Level
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		text := r.FormValue("level")
+		if text == "" {
+			return level, errors.New("must specify logging level")
+		}
+		return level, level.UnmarshalText([]byte(text))
+	}
+
+	var body struct {
+		Level *zapcore.Level `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return level, fmt.Errorf("malformed request body: %v", err)
+	}
+	if body.Level == nil {
+		return level, errors.New("must specify logging level")
+	}
+	return *body.Level, nil
+}
+
+// writeLevelJSON writes a one-key JSON object, like zap's AtomicLevel.
+func writeLevelJSON(w http.ResponseWriter, status int, key, value string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{key: value})
+}
@@ -0,0 +1,85 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveLevel(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func TestLevelHandler(t *testing.T) {
+	buf, cleanup := setupTestLogger(false, WithModuleLevels("db=debug"))
+	defer cleanup()
+	h := LevelHandler()
+
+	w := serveLevel(h, http.MethodGet, "/loglevel", "")
+	assert.JSONEq(t, `{"level":"info","modules":{"db":"debug"}}`, w.Body.String())
+
+	w = serveLevel(h, http.MethodPut, "/loglevel", `{"level":"warn"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, WarnLevel, GetLevel())
+
+	db := GetLogger().Named("db")
+	w = serveLevel(h, http.MethodPut, "/loglevel?logger=db", `{"level":"error"}`)
+	assert.JSONEq(t, `{"level":"error"}`, w.Body.String())
+	db.Warn("db warn")
+	assert.NotContains(t, buf.String(), "db warn") // The existing logger follows
+
+	// New overrides apply to the loggers named before, sub-loggers included.
+	api, lazy := GetLogger().Named("api"), Named("api")
+	pool := api.Named("pool")
+	serveLevel(h, http.MethodPut, "/loglevel?logger=api", `{"level":"debug"}`)
+	api.Debug("api debug")
+	lazy.Debug("lazy debug")
+	pool.Debug("pool debug")
+	assert.Contains(t, buf.String(), "api debug")
+	assert.Contains(t, buf.String(), "lazy debug")
+	assert.Contains(t, buf.String(), "pool debug")
+	assert.Equal(t, DebugLevel, pool.GetLevel())
+
+	w = serveLevel(h, http.MethodGet, "/loglevel?logger=db.pool", "")
+	assert.JSONEq(t, `{"level":"error"}`, w.Body.String())
+	w = serveLevel(h, http.MethodGet, "/loglevel?logger=other", "")
+	assert.JSONEq(t, `{"level":"warn"}`, w.Body.String())
+
+	w = serveLevel(h, http.MethodPut, "/loglevel", `{"level":"loud"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Invalid requests and other methods leave the overrides as they are.
+	w = serveLevel(h, http.MethodPut, "/loglevel?logger=cache", `{"level":"loud"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveLevel(h, http.MethodPut, "/loglevel?logger=cache", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveLevel(h, http.MethodPost, "/loglevel?logger=cache", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	w = serveLevel(h, http.MethodGet, "/loglevel", "")
+	assert.JSONEq(t, `{"level":"warn","modules":{"api":"debug","db":"error"}}`, w.Body.String())
+}
+
+func TestLevelHandlerForm(t *testing.T) {
+	_, cleanup := setupTestLogger(false, WithModuleLevels("db=debug"))
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodPut, "/loglevel?logger=db", strings.NewReader("level=warn"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(w, req)
+
+	assert.JSONEq(t, `{"level":"warn"}`, w.Body.String())
+	assert.Equal(t, WarnLevel, Named("db").GetLevel())
+}
+
+func TestLevelHandlerOtherBackends(t *testing.T) {
+	h := NewLoggerWithBackend(newRecordingBackend(InfoLevel)).LevelHandler()
+	assert.Equal(t, http.StatusNotImplemented, serveLevel(h, http.MethodGet, "/loglevel", "").Code)
+}
@@ -5,113 +5,202 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"sync"
-
-	"go.uber.org/zap"
+	"sync/atomic"
 )
 
 var (
-	once   sync.Once
-	logger *Logger
-)
-
-type Logger struct {
-	sugaredLogger *zap.SugaredLogger
-}
-
-func NewLogger(isDevelopment bool) (*Logger, error) {
-	var config zap.Config
+	once sync.Once
 
-	if isDevelopment {
-		config = zap.NewDevelopmentConfig() // Defaults to DebugLevel
-		config.Encoding = "console"         // Pretty-print for dev mode
-	} else {
-		config = zap.NewProductionConfig() // Defaults to InfoLevel
-		config.Encoding = "json"           // JSON for production
-	}
-
-	zapLogger, err := config.Build()
-	if err != nil {
-		return nil, err
-	}
+	// logger is read by every package-level log call, so it's an atomic
+	// pointer rather than a mutex-guarded variable: loads stay as cheap as a
+	// plain read while InitLogger and tests may replace it concurrently.
+	logger atomic.Pointer[Logger]
+)
 
-	return &Logger{sugaredLogger: zapLogger.Sugar()}, nil
+// GetLogger returns the global logger, or nil if InitLogger wasn't called.
+// It's safe to call concurrently with InitLogger.
+func GetLogger() *Logger {
+	return logger.Load()
 }
 
-func InitLogger(isDevelopment bool) {
-	once.Do(func() {
-		var err error
-		logger, err = NewLogger(isDevelopment)
-		if err != nil {
-			panic("failed to initialize logger")
-		}
-	})
+// With returns the global logger with bound fields. See (*Logger).With.
+func With(keysAndValues ...interface{}) *Logger {
+	return GetLogger().With(keysAndValues...)
 }
 
-func GetLogger() *Logger {
-	return logger
+// Enabled reports whether l writes entries at level, to skip building
+// costly fields for nothing.
+func (l *Logger) Enabled(level Level) bool {
+	return l.resolved().backend.Enabled(level)
 }
 
 // Info logs an info message with key-value pairs.
 func Info(msg string, keysAndValues ...interface{}) {
-	GetLogger().sugaredLogger.Infow(msg, keysAndValues...)
+	GetLogger().log(InfoLevel, msg, keysAndValues)
 }
 
 // Debug logs a debug message with key-value pairs.
 func Debug(msg string, keysAndValues ...interface{}) {
-	GetLogger().sugaredLogger.Debugw(msg, keysAndValues...)
+	GetLogger().log(DebugLevel, msg, keysAndValues)
 }
 
 // Warn logs a warning message with key-value pairs.
 func Warn(msg string, keysAndValues ...interface{}) {
-	GetLogger().sugaredLogger.Warnw(msg, keysAndValues...)
+	GetLogger().log(WarnLevel, msg, keysAndValues)
 }
 
 // Error logs an error message with key-value pairs.
 func Error(msg string, keysAndValues ...interface{}) {
-	GetLogger().sugaredLogger.Errorw(msg, keysAndValues...)
+	GetLogger().log(ErrorLevel, msg, keysAndValues)
 }
 
 // Fatal logs a fatal message with key-value pairs and terminates the application.
 func Fatal(msg string, keysAndValues ...interface{}) {
-	GetLogger().sugaredLogger.Fatalw(msg, keysAndValues...)
+	GetLogger().log(FatalLevel, msg, keysAndValues)
 }
 
 // Panic logs a panic message with key-value pairs and panics the application.
 func Panic(msg string, keysAndValues ...interface{}) {
-	GetLogger().sugaredLogger.Panicw(msg, keysAndValues...)
+	GetLogger().log(PanicLevel, msg, keysAndValues)
+}
+
+// Log logs a message with key-value pairs at level, for levels decided at
+// runtime.
+func Log(level Level, msg string, keysAndValues ...interface{}) {
+	GetLogger().log(level, msg, keysAndValues)
 }
 
 // Debugf logs a debug message with formatted text.
 func Debugf(template string, args ...interface{}) {
-	GetLogger().sugaredLogger.Debugf(template, args...)
+	l := GetLogger()
+	if l.Enabled(DebugLevel) {
+		l.log(DebugLevel, fmt.Sprintf(template, args...), nil)
+	}
 }
 
 // Infof logs an info message with formatted text.
 func Infof(template string, args ...interface{}) {
-	GetLogger().sugaredLogger.Infof(template, args...)
+	l := GetLogger()
+	if l.Enabled(InfoLevel) {
+		l.log(InfoLevel, fmt.Sprintf(template, args...), nil)
+	}
 }
 
 // Warnf logs a warning message with formatted text.
 func Warnf(template string, args ...interface{}) {
-	GetLogger().sugaredLogger.Warnf(template, args...)
+	l := GetLogger()
+	if l.Enabled(WarnLevel) {
+		l.log(WarnLevel, fmt.Sprintf(template, args...), nil)
+	}
 }
 
 // Errorf logs an error message with formatted text.
 func Errorf(template string, args ...interface{}) {
-	GetLogger().sugaredLogger.Errorf(template, args...)
+	l := GetLogger()
+	if l.Enabled(ErrorLevel) {
+		l.log(ErrorLevel, fmt.Sprintf(template, args...), nil)
+	}
 }
 
 // Fatalf logs a fatal message with formatted text and terminates the application.
 func Fatalf(template string, args ...interface{}) {
-	GetLogger().sugaredLogger.Fatalf(template, args...)
+	l := GetLogger()
+	if l.Enabled(FatalLevel) {
+		l.log(FatalLevel, fmt.Sprintf(template, args...), nil)
+	}
 }
 
 // Panicf logs a panic message with formatted text and panics the application.
 func Panicf(template string, args ...interface{}) {
-	GetLogger().sugaredLogger.Panicf(template, args...)
+	l := GetLogger()
+	if l.Enabled(PanicLevel) {
+		l.log(PanicLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+// Info logs an info message with key-value pairs.
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(InfoLevel, msg, keysAndValues)
+}
+
+// Debug logs a debug message with key-value pairs.
+func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log(DebugLevel, msg, keysAndValues)
+}
+
+// Warn logs a warning message with key-value pairs.
+func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log(WarnLevel, msg, keysAndValues)
+}
+
+// Error logs an error message with key-value pairs.
+func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
+	l.log(ErrorLevel, msg, keysAndValues)
+}
+
+// Fatal logs a fatal message with key-value pairs and terminates the application.
+func (l *Logger) Fatal(msg string, keysAndValues ...interface{}) {
+	l.log(FatalLevel, msg, keysAndValues)
+}
+
+// Panic logs a panic message with key-value pairs and panics the application.
+func (l *Logger) Panic(msg string, keysAndValues ...interface{}) {
+	l.log(PanicLevel, msg, keysAndValues)
+}
+
+// Log logs a message with key-value pairs at level, for levels decided at
+// runtime.
+func (l *Logger) Log(level Level, msg string, keysAndValues ...interface{}) {
+	l.log(level, msg, keysAndValues)
+}
+
+// Debugf logs a debug message with formatted text.
+func (l *Logger) Debugf(template string, args ...interface{}) {
+	if l.Enabled(DebugLevel) {
+		l.log(DebugLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+// Infof logs an info message with formatted text.
+func (l *Logger) Infof(template string, args ...interface{}) {
+	if l.Enabled(InfoLevel) {
+		l.log(InfoLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+// Warnf logs a warning message with formatted text.
+func (l *Logger) Warnf(template string, args ...interface{}) {
+	if l.Enabled(WarnLevel) {
+		l.log(WarnLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+// Errorf logs an error message with formatted text.
+func (l *Logger) Errorf(template string, args ...interface{}) {
+	if l.Enabled(ErrorLevel) {
+		l.log(ErrorLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+// Fatalf logs a fatal message with formatted text and terminates the application.
+func (l *Logger) Fatalf(template string, args ...interface{}) {
+	if l.Enabled(FatalLevel) {
+		l.log(FatalLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+// Panicf logs a panic message with formatted text and panics the application.
+func (l *Logger) Panicf(template string, args ...interface{}) {
+	if l.Enabled(PanicLevel) {
+		l.log(PanicLevel, fmt.Sprintf(template, args...), nil)
+	}
 }
 
 // CheckErr checks if an error is nil. If not, it logs it and optionally exits the program.
+// The code of the error, set with WithErrorCode or carried by an error
+// implementing Coder, is logged under ErrorCodeKey unless keysAndValues
+// already give one. With WithCurlCommands, a request among keysAndValues is
+// also logged as a curl command reproducing it.
 func CheckErr(parentError error, panic bool, message string, keysAndValues ...interface{}) bool {
 	if parentError == nil {
 		return false
@@ -143,11 +232,17 @@ func CheckErr(parentError error, panic bool, message string, keysAndValues ...in
 
 	// We add the error as the first values within
 	newKeysAndValues = append([]interface{}{"error", parentError}, newKeysAndValues...)
+	if code := ErrorCode(parentError); code != "" && !hasKey(newKeysAndValues, ErrorCodeKey) {
+		newKeysAndValues = append(newKeysAndValues, ErrorCodeKey, code)
+	}
+
+	l := GetLogger()
+	newKeysAndValues = l.appendCurlCommand(newKeysAndValues, keysAndValues)
 
 	if panic {
-		GetLogger().sugaredLogger.Panicw(message, newKeysAndValues) // Use the modified slice with spread operator
+		l.log(PanicLevel, message, newKeysAndValues)
 	}
 
-	GetLogger().sugaredLogger.Errorw(message, newKeysAndValues) // Use the modified slice with spread operator
+	l.log(ErrorLevel, message, newKeysAndValues)
 	return true
 }
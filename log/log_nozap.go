@@ -0,0 +1,105 @@
+//go:build nozap
+
+package log
+
+import (
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Logger writes entries through a Backend. Builds with the nozap tag leave
+// zap and everything built on its cores out, so that the package depends on
+// the standard library only: what remains are the entry points, CheckErr,
+// the exit hooks and the backends.
+type Logger struct {
+	backend  Backend
+	sequence bool // Stamp entries with SequenceKey
+}
+
+// Option configures the loggers built by NewLogger and InitLogger. Builds with
+// the nozap tag only have WithSequence.
+type Option func(*options)
+
+type options struct {
+	sequence bool
+}
+
+// NewLogger returns a Logger writing to stderr through slog: text entries
+// from Debug up if isDevelopment is set, JSON entries from Info up otherwise,
+// like the zap backend's defaults.
+func NewLogger(isDevelopment bool, opts ...Option) (*Logger, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var handler slog.Handler
+	if isDevelopment {
+		handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug})
+	} else {
+		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{AddSource: true})
+	}
+
+	l := NewLoggerWithBackend(NewSlogBackend(handler))
+	l.sequence = o.sequence
+	return l, nil
+}
+
+func InitLogger(isDevelopment bool, opts ...Option) {
+	once.Do(func() {
+		l, err := NewLogger(isDevelopment, opts...)
+		if err != nil {
+			panic("failed to initialize logger")
+		}
+		logger.Store(l)
+	})
+}
+
+// NewLoggerWithBackend returns a Logger writing through b.
+func NewLoggerWithBackend(b Backend) *Logger {
+	return &Logger{backend: b}
+}
+
+// Close does nothing: no option starts background work without zap. It's
+// there so code closing its loggers builds with either backend.
+func (l *Logger) Close() error {
+	return nil
+}
+
+// With returns a logger adding keysAndValues to every entry. l is left
+// unchanged.
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	if len(keysAndValues) == 0 {
+		return l
+	}
+
+	derived := *l
+	derived.backend = l.backend.With(keysAndValues)
+	return &derived
+}
+
+// log writes an entry through the backend. Every entry point calls it
+// directly, so the caller skip is the same for all of them.
+func (l *Logger) log(level Level, msg string, keysAndValues []interface{}) {
+	if l.sequence && l.backend.Enabled(level) {
+		keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)], SequenceKey, nextSequence())
+	}
+	l.backend.Log(level, msg, keysAndValues)
+}
+
+// appendCurlCommand returns logged: curl commands need WithCurlCommands,
+// which builds with the nozap tag don't have.
+func (l *Logger) appendCurlCommand(logged, _ []interface{}) []interface{} {
+	return logged
+}
+
+// resolved returns l: without Named, no logger is resolved lazily.
+func (l *Logger) resolved() *Logger {
+	return l
+}
+
+// nopLogger returns a logger discarding everything.
+func nopLogger() *Logger {
+	return NewLoggerWithBackend(NewSlogBackend(slog.NewTextHandler(io.Discard, nil)))
+}
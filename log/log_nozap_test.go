@@ -0,0 +1,98 @@
+//go:build nozap
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupSlogLogger installs a global logger writing JSON entries from Debug up
+// to the returned buffer, until the test ends.
+func setupSlogLogger(t *testing.T) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug})
+	l := NewLoggerWithBackend(NewSlogBackend(handler))
+
+	previous := logger.Load()
+	logger.Store(l)
+	t.Cleanup(func() { logger.Store(previous) })
+	return l, &buf
+}
+
+// callerFile returns the path of the file calling it.
+func callerFile(t *testing.T) string {
+	_, file, _, ok := runtime.Caller(1)
+	if !ok {
+		t.Fatal("no caller")
+	}
+	return file
+}
+
+func TestLoggerWithoutZap(t *testing.T) {
+	l, buf := setupSlogLogger(t)
+
+	l.With("component", "db").Infof("took %d ms", 12)
+	Debug("cache miss", "key", "user:1")
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, `"msg":"took 12 ms","component":"db"`)
+	assert.Contains(t, logOutput, `"msg":"cache miss","key":"user:1"`)
+	assert.Contains(t, logOutput, `"file":"`+callerFile(t)+`"`) // The caller, not the package
+}
+
+func TestCheckErrWithoutZap(t *testing.T) {
+	_, buf := setupSlogLogger(t)
+
+	assert.False(t, CheckErr(nil, false, "can't connect"))
+	assert.True(t, CheckErr(WithErrorCode(errors.New("refused"), "db.conn_refused"), false, "can't connect", "host", "db1"))
+
+	assert.Contains(t, buf.String(), `"level":"ERROR"`)
+	assert.Contains(t, buf.String(), `"msg":"can't connect","error":"refused","host":"db1","error.code":"db.conn_refused"`)
+}
+
+func TestSequenceWithoutZap(t *testing.T) {
+	l, buf := setupSlogLogger(t)
+	l.sequence = true
+
+	l.Info("first")
+	l.Info("second")
+
+	assert.Regexp(t, `"msg":"first","seq":(\d+)}\n.*"msg":"second","seq":\d+}`, buf.String())
+
+	built, err := NewLogger(false, WithSequence())
+	assert.NoError(t, err)
+	assert.True(t, built.sequence)
+}
+
+func TestFatalWithoutZapRunsHooks(t *testing.T) {
+	var codes []int
+	exit = func(code int) { codes = append(codes, code) }
+	t.Cleanup(func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+
+		exit = os.Exit
+		fatalHooks = nil
+		exitHooks = nil
+	})
+	l, buf := setupSlogLogger(t)
+
+	var ran []string
+	OnFatal(func() { ran = append(ran, "fatal") })
+	OnExit("close db", func(context.Context) error { ran = append(ran, "exit"); return nil })
+
+	l.Fatal("can't continue")
+
+	assert.Equal(t, []string{"fatal", "exit"}, ran)
+	assert.Equal(t, []int{1}, codes)
+	assert.Contains(t, buf.String(), `"msg":"can't continue"`)
+	assert.Contains(t, buf.String(), `"msg":"exit hook finished","hook":"close db"`)
+}
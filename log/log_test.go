@@ -1,15 +1,21 @@
+//go:build !nozap
+
 package log
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func setupTestLogger(isDevelopment bool) (*bytes.Buffer, func()) {
+func setupTestLogger(isDevelopment bool, opts ...Option) (*bytes.Buffer, func()) {
 	var buf bytes.Buffer
 
 	// Create a custom writer syncer to capture logs
@@ -30,6 +36,9 @@ func setupTestLogger(isDevelopment bool) (*bytes.Buffer, func()) {
 	config.OutputPaths = []string{}         // 🛠 Fix: Empty output paths
 	config.EncoderConfig.TimeKey = ""       // 🛠 Fix: Removes timestamp (simplifies testing)
 
+	o := newOptions(opts)
+	o.configure(&config)
+
 	// Build logger with custom writer
 	zapLogger, _ := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
 		return zapcore.NewCore(
@@ -39,8 +48,13 @@ func setupTestLogger(isDevelopment bool) (*bytes.Buffer, func()) {
 		)
 	}))
 
+	// Apply the same features NewLogger would
+	zapLogger = zapLogger.WithOptions(o.zapOptions(config)...)
+
 	// Set global logger
-	logger = &Logger{sugaredLogger: zapLogger.Sugar()}
+	l := newZapLogger(zapLogger.Sugar(), config)
+	o.apply(l)
+	logger.Store(l)
 
 	return &buf, func() { _ = zapLogger.Sync() }
 }
@@ -114,3 +128,110 @@ func TestCheckErr(t *testing.T) {
 	logOutput := buf.String()
 	assert.Contains(t, logOutput, "Error happened") // Should log the error message
 }
+
+// **TEST 6: Global Logger Can Be Replaced While In Use**
+func TestGlobalLoggerConcurrentReplace(t *testing.T) {
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	replacement := GetLogger()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			logger.Store(replacement) // Flagged by -race if reads aren't synchronized
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		Info("concurrent", "i", i)
+	}
+	<-done
+}
+
+// **TEST 7: Log At A Level Decided At Runtime**
+func TestLogLevel(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	Log(WarnLevel, "runtime level", "key", "value")
+	Log(DebugLevel, "filtered out")
+
+	assert.Contains(t, buf.String(), "warn")
+	assert.Contains(t, buf.String(), "runtime level")
+	assert.NotContains(t, buf.String(), "filtered out")
+}
+
+func benchmarkLogger(b *testing.B) {
+	config := zap.NewProductionConfig()
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(config.EncoderConfig), zapcore.AddSync(io.Discard), config.Level)
+	logger.Store(newZapLogger(zap.New(core).Sugar(), config))
+	b.ReportAllocs()
+	b.ResetTimer()
+}
+
+func BenchmarkGetLogger(b *testing.B) {
+	benchmarkLogger(b)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = GetLogger()
+		}
+	})
+}
+
+func BenchmarkInfo(b *testing.B) {
+	benchmarkLogger(b)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Info("benchmark", "key", "value")
+		}
+	})
+}
+
+func BenchmarkDebugDisabled(b *testing.B) {
+	benchmarkLogger(b)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Debug("benchmark", "key", "value")
+		}
+	})
+}
+
+// **TEST 8: Child Loggers Keep Their Bound Fields**
+func TestWith(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	child := With("component", "billing")
+	child.Info("charged", "amount", 10)
+	Info("unscoped")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	assert.Contains(t, string(lines[0]), `{"component": "billing", "amount": 10}`)
+	assert.NotContains(t, string(lines[1]), "billing")
+	assert.Same(t, GetLogger(), With())
+
+	backend := newRecordingBackend(InfoLevel)
+	l := NewLoggerWithBackend(backend).With("tenant", "acme")
+	l.With("user", "ana").Warn("quota")
+	assert.Equal(t, []string{"1 quota [tenant acme user ana]"}, backend.logged())
+}
+
+// logFor logs msg through l on behalf of its caller.
+func logFor(l *Logger, msg string) {
+	l.WithCallerSkip(1).Info(msg)
+}
+
+func TestWithCallerSkip(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLogger(false, WithSink(zapcore.AddSync(&buf)))
+	require.NoError(t, err)
+
+	_, _, line, _ := runtime.Caller(0)
+	logFor(l, "skipped")
+
+	assert.Contains(t, buf.String(), fmt.Sprintf(`"caller":"log/log_test.go:%d"`, line+1))
+	assert.True(t, l.Enabled(InfoLevel))
+	assert.False(t, l.Enabled(DebugLevel))
+}
@@ -0,0 +1,186 @@
+//go:build !nozap
+
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type Logger struct {
+	sugaredLogger *zap.SugaredLogger
+	backend       Backend
+	config        zap.Config
+	sequence      bool // Stamp entries with SequenceKey
+	curl          bool // Let CheckErr log curl commands, see WithCurlCommands
+	curlBodyBytes int
+	name          string        // Dotted name given with Named
+	modules       *moduleLevels // Level overrides by name, see WithModuleLevels
+	lazy          *lazyLogger   // Set for loggers resolved at each entry, see Named
+	background    *background   // Work started by the options, see Close
+}
+
+// background is the work started for a logger by its options, shared by the
+// loggers derived from it.
+type background struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+	running  sync.WaitGroup
+}
+
+func NewLogger(isDevelopment bool, opts ...Option) (*Logger, error) {
+	o := newOptions(opts)
+	l, err := newLogger(isDevelopment, o)
+	if err != nil {
+		return nil, err
+	}
+	l.background = &background{stop: make(chan struct{})}
+	o.start(l, l.background.stop, &l.background.running)
+	return l, nil
+}
+
+// Close stops the background work started by the options of l, like
+// WithRuntimeStats and WithDropReport, and waits for it to return. It
+// applies to the loggers derived from l too, which keep logging, and its
+// sinks are left open. Calling it again does nothing.
+func (l *Logger) Close() error {
+	l = l.resolved()
+	if bg := l.background; bg != nil {
+		bg.stopOnce.Do(func() { close(bg.stop) })
+		bg.running.Wait()
+	}
+	return nil
+}
+
+// newLogger builds the logger configured by o, leaving the background work
+// of its options to o.start.
+func newLogger(isDevelopment bool, o *options) (*Logger, error) {
+	var config zap.Config
+
+	if isDevelopment {
+		config = zap.NewDevelopmentConfig() // Defaults to DebugLevel
+		config.Encoding = "console"         // Pretty-print for dev mode
+	} else {
+		config = zap.NewProductionConfig() // Defaults to InfoLevel
+		config.Encoding = "json"           // JSON for production
+	}
+
+	if o.err != nil {
+		return nil, o.err
+	}
+	if o.timeZone == nil && !isDevelopment {
+		o.timeZone = time.UTC
+	}
+	o.configure(&config)
+
+	// Skip two frames so the caller is the code using this package, not the
+	// wrapper functions below and the backend.
+	zapLogger, err := config.Build(append(o.zapOptions(config), zap.AddCallerSkip(2))...)
+	if err != nil {
+		return nil, err
+	}
+
+	l := newZapLogger(zapLogger.Sugar(), config)
+	o.apply(l)
+	return l, nil
+}
+
+func InitLogger(isDevelopment bool, opts ...Option) {
+	once.Do(func() {
+		l, err := NewLogger(isDevelopment, opts...)
+		if err != nil {
+			panic("failed to initialize logger")
+		}
+		logger.Store(l)
+	})
+}
+
+// newZapLogger returns a Logger on the zap backend.
+func newZapLogger(sugared *zap.SugaredLogger, config zap.Config) *Logger {
+	return &Logger{sugaredLogger: sugared, backend: newZapBackend(sugared), config: config}
+}
+
+// derive returns a copy of l logging through sugared.
+func (l *Logger) derive(sugared *zap.SugaredLogger) *Logger {
+	derived := *l
+	derived.sugaredLogger = sugared
+	derived.backend = newZapBackend(sugared)
+	return &derived
+}
+
+// With returns a logger adding keysAndValues to every entry, like zap's
+// With, so fields such as the component or tenant are given once. l is left
+// unchanged.
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	if len(keysAndValues) == 0 {
+		return l
+	}
+	if l.lazy != nil {
+		return l.lazy.then(l.name, func(parent *Logger) *Logger { return parent.With(keysAndValues...) })
+	}
+	if _, ok := l.backend.(*zapBackend); ok {
+		return l.derive(l.sugaredLogger.With(keysAndValues...))
+	}
+
+	derived := *l
+	derived.backend = l.backend.With(appendFlattened(nil, keysAndValues))
+	derived.sugaredLogger = zap.New(&backendCore{backend: derived.backend}).Sugar()
+	return &derived
+}
+
+// WithCallerSkip returns a logger reporting as the caller of its entries the
+// function skip frames above the one calling it, for adapters logging on
+// behalf of their own callers. Only the zap backend reports callers this way;
+// with others, l is returned.
+func (l *Logger) WithCallerSkip(skip int) *Logger {
+	if l.lazy != nil && skip != 0 {
+		return l.lazy.then(l.name, func(parent *Logger) *Logger { return parent.WithCallerSkip(skip) })
+	}
+	if _, ok := l.backend.(*zapBackend); !ok || skip == 0 {
+		return l
+	}
+	return l.derive(l.sugaredLogger.WithOptions(zap.AddCallerSkip(skip)))
+}
+
+// log writes an entry through the backend. Every entry point calls it
+// directly, so the caller skip is the same for all of them.
+func (l *Logger) log(level Level, msg string, keysAndValues []interface{}) {
+	l = l.resolved()
+	if l.sequence && l.backend.Enabled(level) {
+		keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)], SequenceKey, nextSequence())
+	}
+
+	if _, ok := l.backend.(*zapBackend); ok {
+		l.sugaredLogger.Logw(zapcore.Level(level), msg, keysAndValues...)
+		return
+	}
+	// Pass a copy so keysAndValues doesn't escape through the interface call,
+	// which would cost the zap path an allocation per entry, even disabled.
+	// zap writes the name itself; other backends get it as a field.
+	copied := make([]interface{}, 0, len(keysAndValues)+2)
+	if l.name != "" {
+		copied = append(copied, LoggerNameKey, l.name)
+	}
+	l.backend.Log(level, msg, appendFlattened(copied, keysAndValues))
+}
+
+// appendFlattened appends keysAndValues to dst with the zap.Fields among
+// them, like those of PanicValue, turned into key-value pairs, since only
+// zap understands them.
+func appendFlattened(dst, keysAndValues []interface{}) []interface{} {
+	for i := 0; i < len(keysAndValues); i++ {
+		if f, ok := keysAndValues[i].(zap.Field); ok {
+			dst = append(dst, keysAndValuesOf([]zap.Field{f})...)
+			continue
+		}
+		dst = append(dst, keysAndValues[i])
+		if i+1 < len(keysAndValues) {
+			i++
+			dst = append(dst, keysAndValues[i])
+		}
+	}
+	return dst
+}
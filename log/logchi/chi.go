@@ -0,0 +1,35 @@
+// Package logchi logs the requests of chi routers with their route pattern,
+// like /users/{id}, rather than their path only.
+//
+//	r := chi.NewRouter()
+//	r.Use(logchi.Middleware(logger))
+//	r.Get("/users/{id}", getUser)
+package logchi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// Middleware returns the HTTPMiddleware of l logging the route of each
+// request, for chi's Use.
+func Middleware(l *log.Logger, opts ...log.MiddlewareOption) func(http.Handler) http.Handler {
+	opts = append(opts, log.WithRoute(Route))
+	return func(next http.Handler) http.Handler {
+		return l.HTTPMiddleware(next, opts...)
+	}
+}
+
+// Route returns the pattern chi routed r with, mounted subrouters included,
+// or "" if chi didn't route it. It is complete only once the request is
+// handled.
+func Route(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
@@ -0,0 +1,48 @@
+package logchi
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewJSONHandler(&buf, nil)))
+
+	users := chi.NewRouter()
+	users.Get("/{id}", func(http.ResponseWriter, *http.Request) {})
+	r := chi.NewRouter()
+	r.Use(Middleware(l))
+	r.Mount("/users", users)
+
+	for _, path := range []string{"/users/42", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var logged []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		logged = append(logged, entry)
+	}
+	require.Len(t, logged, 2)
+	assert.Equal(t, "/users/42", logged[0]["path"])
+	assert.Equal(t, "/users/{id}", logged[0]["route"])
+	assert.Equal(t, float64(http.StatusNotFound), logged[1]["status"])
+	assert.NotContains(t, logged[1], "route")
+}
+
+func TestRouteOutsideChi(t *testing.T) {
+	assert.Equal(t, "", Route(httptest.NewRequest(http.MethodGet, "/", nil)))
+}
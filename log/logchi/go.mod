@@ -0,0 +1,19 @@
+module github.com/Stasky745/go-libs/log/logchi
+
+go 1.22.5
+
+require (
+	github.com/Stasky745/go-libs v0.0.0-20261016071848-c5dce97880a8
+	github.com/go-chi/chi/v5 v5.1.0
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel v1.28.0 // indirect
+	go.opentelemetry.io/otel/trace v1.28.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	go.uber.org/zap v1.27.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,20 @@
+module github.com/Stasky745/go-libs/log/loggokit
+
+go 1.22.5
+
+require (
+	github.com/Stasky745/go-libs v0.0.0-20261016071848-c5dce97880a8
+	github.com/go-kit/log v0.2.1
+	github.com/stretchr/testify v1.10.0
+	go.uber.org/zap v1.27.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logfmt/logfmt v0.5.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel v1.28.0 // indirect
+	go.opentelemetry.io/otel/trace v1.28.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,83 @@
+// Package loggokit adapts the log package to go-kit's log.Logger, so services
+// and libraries built on go-kit log through the same logger as the rest of
+// the program.
+//
+//	logger := loggokit.New(log.GetLogger())
+//	level.Info(logger).Log("msg", "listening", "addr", addr)
+package loggokit
+
+import (
+	"fmt"
+
+	kitlog "github.com/go-kit/log"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+const (
+	// MessageKey is the key of the message in go-kit key-value pairs.
+	MessageKey = "msg"
+	// LevelKey is the key of the level, as go-kit's level package sets it.
+	LevelKey = "level"
+
+	// unknownLevelKey keeps the level values ParseLevel rejects.
+	unknownLevelKey = "kit_level"
+)
+
+// Option customizes the adapter.
+type Option func(*logger)
+
+// WithDefaultLevel sets the level of the entries without a level key, Info
+// by default.
+func WithDefaultLevel(level log.Level) Option {
+	return func(a *logger) {
+		a.level = level
+	}
+}
+
+// New returns a go-kit log.Logger writing through l. Each call to its Log
+// method writes an entry: the value of the msg key is its message, the
+// value of the level key, from go-kit's level package or a string ParseLevel
+// accepts, its level, and the other pairs its fields. Levels above Error are
+// logged at Error, so an entry never panics or exits, and unknown levels are
+// kept as the kit_level field of an entry at the default level.
+func New(l *log.Logger, opts ...Option) kitlog.Logger {
+	a := &logger{logger: l, level: log.InfoLevel}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+type logger struct {
+	logger *log.Logger
+	level  log.Level
+}
+
+func (a *logger) Log(keyvals ...interface{}) error {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, kitlog.ErrMissingValue)
+	}
+
+	level := a.level
+	var msg string
+	fields := make([]interface{}, 0, len(keyvals))
+	for i := 0; i < len(keyvals); i += 2 {
+		key, value := fmt.Sprint(keyvals[i]), keyvals[i+1]
+		switch key {
+		case MessageKey:
+			msg = fmt.Sprint(value)
+			continue
+		case LevelKey:
+			if parsed, err := log.ParseLevel(fmt.Sprint(value)); err == nil {
+				level = min(parsed, log.ErrorLevel)
+				continue
+			}
+			key = unknownLevelKey // Would clash with the level of the entry
+		}
+		fields = append(fields, key, value)
+	}
+
+	a.logger.Log(level, msg, fields...)
+	return nil
+}
@@ -0,0 +1,63 @@
+package loggokit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := log.NewLogger(false, log.WithSink(zapcore.AddSync(&buf)))
+	require.NoError(t, err)
+
+	logger := kitlog.With(New(l), "component", "http")
+	require.NoError(t, logger.Log("msg", "listening", "addr", ":8080"))
+	require.NoError(t, level.Warn(logger).Log("msg", "slow", "took_ms", 1200))
+	require.NoError(t, logger.Log("level", "fatal", "msg", "not fatal"))
+	require.NoError(t, logger.Log("msg", "odd", "dangling"))
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 4)
+	assert.Equal(t, "info", logged[0]["level"])
+	assert.Equal(t, "listening", logged[0]["msg"])
+	assert.Equal(t, "http", logged[0]["component"])
+	assert.Equal(t, ":8080", logged[0]["addr"])
+	assert.Equal(t, "warn", logged[1]["level"])
+	assert.Equal(t, float64(1200), logged[1]["took_ms"])
+	assert.Equal(t, "error", logged[2]["level"]) // Never panics or exits
+	assert.Equal(t, kitlog.ErrMissingValue.Error(), logged[3]["dangling"])
+}
+
+func TestWithDefaultLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := log.NewLogger(false, log.WithSink(zapcore.AddSync(&buf)))
+	require.NoError(t, err)
+
+	_ = New(l, WithDefaultLevel(log.WarnLevel)).Log("msg", "unleveled")
+	_ = New(l, WithDefaultLevel(log.WarnLevel)).Log("level", "verbose", "msg", "unknown level")
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 2)
+	assert.Equal(t, "warn", logged[0]["level"])
+	assert.Equal(t, "verbose", logged[1]["kit_level"])
+}
@@ -0,0 +1,149 @@
+package loggrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Stasky745/go-libs/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor logs every outbound unary call once it completes,
+// with its method, target, status code and latency, at the levels
+// UnaryServerInterceptor uses. WithPayloads and WithMethodLevel apply as on
+// the server.
+//
+// Calls carry the request ID of their context, see log.RequestIDFromContext,
+// or a new one, in their x-request-id metadata, so a request can be followed
+// across services. Metadata that already has an x-request-id is left as is.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	redacted := fieldSet(o.redactedFields)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, id := outgoingRequestID(ctx)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+
+		keysAndValues := clientCallFields(method, cc, err, time.Since(start), redacted)
+		if o.logsPayloads(method) {
+			keysAndValues = append(keysAndValues, "request", payload(req, o.payloadMax, redacted))
+			if err == nil {
+				keysAndValues = append(keysAndValues, "response", payload(reply, o.payloadMax, redacted))
+			}
+		}
+		o.log().With(log.RequestIDKey, id).Log(o.level(method, status.Code(err)), "grpc client call", keysAndValues...)
+		return err
+	}
+}
+
+// StreamClientInterceptor logs every outbound streaming call once it ends,
+// like UnaryClientInterceptor, along with how many messages went each way. A
+// stream ends when receiving fails, io.EOF being a success, or when it can't
+// be opened. With WithPayloads, each message is also logged at Debug.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	redacted := fieldSet(o.redactedFields)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, id := outgoingRequestID(ctx)
+		logger := o.log().With(log.RequestIDKey, id)
+
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			logger.Log(o.level(method, status.Code(err)), "grpc client call", clientCallFields(method, cc, err, time.Since(start), redacted)...)
+			return nil, err
+		}
+
+		stream := &loggedClientStream{ClientStream: cs, method: method, opts: o, logger: logger}
+		if o.logsPayloads(method) {
+			stream.redacted = redacted
+		}
+		stream.done = func(err error) {
+			keysAndValues := append(clientCallFields(method, cc, err, time.Since(start), redacted),
+				"messages_received", stream.received,
+				"messages_sent", stream.sent,
+			)
+			logger.Log(o.level(method, status.Code(err)), "grpc client call", keysAndValues...)
+		}
+		return stream, nil
+	}
+}
+
+// loggedClientStream counts the messages of a stream, logs them if redacted
+// is set, and calls done once the stream ends.
+type loggedClientStream struct {
+	grpc.ClientStream
+	method   string
+	opts     *options
+	logger   *log.Logger
+	redacted map[string]bool
+	done     func(error)
+
+	once           sync.Once
+	received, sent int
+}
+
+func (s *loggedClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		s.logMessage("sent", m)
+	}
+	return err
+}
+
+func (s *loggedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.received++
+		s.logMessage("received", m)
+	case errors.Is(err, io.EOF):
+		s.once.Do(func() { s.done(nil) })
+	default:
+		s.once.Do(func() { s.done(err) })
+	}
+	return err
+}
+
+func (s *loggedClientStream) logMessage(direction string, m interface{}) {
+	if s.redacted == nil || !s.logger.Enabled(log.DebugLevel) {
+		return
+	}
+	s.logger.Debug("grpc message",
+		"grpc_method", s.method,
+		"direction", direction,
+		"payload", payload(m, s.opts.payloadMax, s.redacted),
+	)
+}
+
+func clientCallFields(method string, cc *grpc.ClientConn, err error, latency time.Duration, redacted map[string]bool) []interface{} {
+	keysAndValues := callFields(context.Background(), method, err, latency, redacted)
+	if cc != nil {
+		keysAndValues = append(keysAndValues, "target", cc.Target())
+	}
+	return keysAndValues
+}
+
+// outgoingRequestID returns ctx with a request ID in its outgoing metadata,
+// and that ID: the one already there, the one of log.RequestIDFromContext or
+// a new one.
+func outgoingRequestID(ctx context.Context) (context.Context, string) {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(requestIDMetadata); len(values) > 0 {
+			return ctx, values[0]
+		}
+	}
+	id := log.RequestIDFromContext(ctx)
+	if id == "" {
+		id = log.NewRequestID()
+	}
+	return metadata.AppendToOutgoingContext(ctx, requestIDMetadata, id), id
+}
@@ -0,0 +1,95 @@
+package loggrpc
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Stasky745/go-libs/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialHealth serves the health service with the server interceptors logging
+// to serverBuf, and returns a client of it with the client interceptors
+// logging to clientBuf.
+func dialHealth(t *testing.T, serverBuf, clientBuf *bytes.Buffer) healthpb.HealthClient {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(WithLogger(testLogger(serverBuf)))),
+		grpc.StreamInterceptor(StreamServerInterceptor(WithLogger(testLogger(serverBuf)))),
+	)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("db", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(WithLogger(testLogger(clientBuf)))),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(WithLogger(testLogger(clientBuf)))),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	var serverBuf, clientBuf bytes.Buffer
+	client := dialHealth(t, &serverBuf, &clientBuf)
+
+	ctx := log.ContextWithRequestID(context.Background(), "abc-123")
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "db"})
+	require.NoError(t, err)
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "cache"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	logged := entries(t, &clientBuf)
+	require.Len(t, logged, 2)
+	assert.Equal(t, "grpc client call", logged[0]["msg"])
+	assert.Equal(t, "/grpc.health.v1.Health/Check", logged[0]["grpc_method"])
+	assert.Equal(t, "OK", logged[0]["grpc_code"])
+	assert.Equal(t, "passthrough:///bufnet", logged[0]["target"])
+	assert.Equal(t, "abc-123", logged[0]["request_id"])
+	assert.Equal(t, "WARN", logged[1]["level"])
+	assert.Equal(t, "NotFound", logged[1]["grpc_code"])
+	assert.Len(t, logged[1]["request_id"], 32) // A new ID without one in the context
+
+	// The server sees the IDs of the client.
+	served := entries(t, &serverBuf)
+	require.Len(t, served, 2)
+	assert.Equal(t, "abc-123", served[0]["request_id"])
+	assert.Equal(t, logged[1]["request_id"], served[1]["request_id"])
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	var serverBuf, clientBuf bytes.Buffer
+	client := dialHealth(t, &serverBuf, &clientBuf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "db"})
+	require.NoError(t, err)
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	cancel()
+	_, err = stream.Recv()
+	require.Equal(t, codes.Canceled, status.Code(err))
+
+	logged := entries(t, &clientBuf)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "/grpc.health.v1.Health/Watch", logged[0]["grpc_method"])
+	assert.Equal(t, "Canceled", logged[0]["grpc_code"])
+	assert.Equal(t, float64(1), logged[0]["messages_received"])
+	assert.Equal(t, float64(1), logged[0]["messages_sent"])
+}
@@ -0,0 +1,113 @@
+// Package loggrpc logs gRPC calls through the log package.
+package loggrpc
+
+import (
+	"strings"
+
+	"github.com/Stasky745/go-libs/log"
+	"google.golang.org/grpc/codes"
+)
+
+// Option customizes the interceptors.
+type Option func(*options)
+
+type options struct {
+	logger *log.Logger
+
+	payloadMethods []string
+	payloadMax     int
+	redactedFields []string
+
+	methodLevels []methodLevel
+}
+
+type methodLevel struct {
+	level   log.Level
+	methods []string
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{redactedFields: log.DefaultRedactedKeys}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithLogger logs through l instead of the global logger.
+func WithLogger(l *log.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithPayloads logs the messages of the given methods as protojson, up to
+// maxBytes each. Methods are full names like "/pkg.Service/Method";
+// "/pkg.Service/*" matches every method of a service and "*" every method.
+//
+// Fields annotated with the debug_redact option, and fields named like
+// log.DefaultRedactedKeys or the names set with WithRedactedFields, are
+// redacted before the message is encoded, so the size cap never exposes them.
+func WithPayloads(maxBytes int, methods ...string) Option {
+	return func(o *options) {
+		o.payloadMax = maxBytes
+		o.payloadMethods = methods
+	}
+}
+
+// WithRedactedFields replaces log.DefaultRedactedKeys as the names of the
+// fields redacted from logged payloads, on top of those annotated with
+// debug_redact.
+func WithRedactedFields(names ...string) Option {
+	return func(o *options) {
+		o.redactedFields = names
+	}
+}
+
+// WithMethodLevel logs the successful calls of the given methods at level
+// instead of Info, for instance Debug for health checks. Methods are matched
+// as in WithPayloads, and the first WithMethodLevel matching a method wins.
+// Failed calls are logged at the level of their status code regardless.
+func WithMethodLevel(level log.Level, methods ...string) Option {
+	return func(o *options) {
+		o.methodLevels = append(o.methodLevels, methodLevel{level, methods})
+	}
+}
+
+func (o *options) log() *log.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return log.GetLogger()
+}
+
+// logsPayloads reports whether the payloads of fullMethod are logged.
+func (o *options) logsPayloads(fullMethod string) bool {
+	return matchMethod(o.payloadMethods, fullMethod)
+}
+
+// level returns the level of a call to fullMethod that ended with code.
+func (o *options) level(fullMethod string, code codes.Code) log.Level {
+	if code == codes.OK {
+		for _, ml := range o.methodLevels {
+			if matchMethod(ml.methods, fullMethod) {
+				return ml.level
+			}
+		}
+	}
+	return codeLevel(code)
+}
+
+// matchMethod reports whether fullMethod is one of methods, or belongs to a
+// service or wildcard of them.
+func matchMethod(methods []string, fullMethod string) bool {
+	for _, m := range methods {
+		if m == "*" || m == fullMethod {
+			return true
+		}
+		if service, ok := strings.CutSuffix(m, "/*"); ok && strings.HasPrefix(fullMethod, service+"/") {
+			return true
+		}
+	}
+	return false
+}
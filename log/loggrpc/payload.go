@@ -0,0 +1,78 @@
+package loggrpc
+
+import (
+	"strings"
+
+	"github.com/Stasky745/go-libs/log"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// payload encodes msg as protojson for logging, redacted and capped at
+// maxBytes, a negative maxBytes counting as 0.
+func payload(msg interface{}, maxBytes int, redacted map[string]bool) string {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return "[not a protobuf message]"
+	}
+
+	m = proto.Clone(m)
+	redact(m.ProtoReflect(), redacted)
+
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return "[can't encode message: " + err.Error() + "]"
+	}
+	if maxBytes = max(maxBytes, 0); len(data) > maxBytes {
+		return string(data[:maxBytes]) + "...[truncated]"
+	}
+	return string(data)
+}
+
+// redact replaces the values of sensitive fields of m, recursively: strings
+// become log.Redacted and other values are cleared.
+func redact(m protoreflect.Message, redacted map[string]bool) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if isRedacted(fd, redacted) {
+			if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+				m.Set(fd, protoreflect.ValueOfString(log.Redacted))
+			} else {
+				m.Clear(fd)
+			}
+			return true
+		}
+
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				redact(list.Get(i).Message(), redacted)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				redact(mv.Message(), redacted)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			redact(v.Message(), redacted)
+		}
+		return true
+	})
+}
+
+func isRedacted(fd protoreflect.FieldDescriptor, redacted map[string]bool) bool {
+	if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok && opts.GetDebugRedact() {
+		return true
+	}
+	return redacted[strings.ToLower(string(fd.Name()))]
+}
+
+func fieldSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[strings.ToLower(n)] = true
+	}
+	return set
+}
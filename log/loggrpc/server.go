@@ -0,0 +1,193 @@
+package loggrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stasky745/go-libs/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// requestIDMetadata is the metadata key carrying request IDs, the gRPC
+	// counterpart of log.RequestIDHeader.
+	requestIDMetadata = "x-request-id"
+
+	// maxDetailBytes caps each error detail logged.
+	maxDetailBytes = 1024
+)
+
+// UnaryServerInterceptor logs every unary call once it completes, with its
+// method, peer, status code, latency and request ID. Calls are logged at Info
+// when they succeed, or the level set with WithMethodLevel, at Warn when they
+// fail because of the client and at Error otherwise. The details of the
+// status of failed calls, like errdetails.BadRequest, are logged as
+// error_details.
+//
+// The request ID is the one in the x-request-id metadata of the call, if
+// valid as in log.ValidRequestID, or a new one. It is sent back in the
+// x-request-id header, and the context of the handler carries it, for
+// log.RequestIDFromContext, along with a logger adding it to every entry, for
+// log.FromContext.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	redacted := fieldSet(o.redactedFields)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		id := incomingRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+		logger := o.log().With(log.RequestIDKey, id)
+		resp, err := handler(log.WithContext(log.ContextWithRequestID(ctx, id), logger), req)
+
+		keysAndValues := callFields(ctx, info.FullMethod, err, time.Since(start), redacted)
+		if o.logsPayloads(info.FullMethod) {
+			keysAndValues = append(keysAndValues, "request", payload(req, o.payloadMax, redacted))
+			if err == nil {
+				keysAndValues = append(keysAndValues, "response", payload(resp, o.payloadMax, redacted))
+			}
+		}
+		logger.Log(o.level(info.FullMethod, status.Code(err)), "grpc call", keysAndValues...)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs every streaming call once it completes, like
+// UnaryServerInterceptor, along with how many messages went each way. With
+// WithPayloads, each message is also logged at Debug.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	redacted := fieldSet(o.redactedFields)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		id := incomingRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(requestIDMetadata, id))
+		logger := o.log().With(log.RequestIDKey, id)
+
+		stream := &loggedStream{
+			ServerStream: ss,
+			ctx:          log.WithContext(log.ContextWithRequestID(ss.Context(), id), logger),
+			method:       info.FullMethod,
+			opts:         o,
+			logger:       logger,
+		}
+		if o.logsPayloads(info.FullMethod) {
+			stream.redacted = redacted
+		}
+		err := handler(srv, stream)
+
+		keysAndValues := append(callFields(ss.Context(), info.FullMethod, err, time.Since(start), redacted),
+			"messages_received", stream.received,
+			"messages_sent", stream.sent,
+		)
+		logger.Log(o.level(info.FullMethod, status.Code(err)), "grpc call", keysAndValues...)
+		return err
+	}
+}
+
+// loggedStream counts the messages of a stream and logs them if redacted is
+// set.
+type loggedStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	method   string
+	opts     *options
+	logger   *log.Logger
+	redacted map[string]bool
+
+	received, sent int
+}
+
+func (s *loggedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *loggedStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+		s.logMessage("received", m)
+	}
+	return err
+}
+
+func (s *loggedStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		s.logMessage("sent", m)
+	}
+	return err
+}
+
+func (s *loggedStream) logMessage(direction string, m interface{}) {
+	if s.redacted == nil || !s.logger.Enabled(log.DebugLevel) {
+		return
+	}
+	s.logger.Debug("grpc message",
+		"grpc_method", s.method,
+		"direction", direction,
+		"payload", payload(m, s.opts.payloadMax, s.redacted),
+	)
+}
+
+func callFields(ctx context.Context, method string, err error, latency time.Duration, redacted map[string]bool) []interface{} {
+	keysAndValues := []interface{}{
+		"grpc_method", method,
+		"grpc_code", status.Code(err).String(),
+		"latency", latency,
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		keysAndValues = append(keysAndValues, "peer", p.Addr.String())
+	}
+	if err != nil {
+		st := status.Convert(err)
+		keysAndValues = append(keysAndValues, "error", st.Message())
+		if details := errorDetails(st, redacted); len(details) > 0 {
+			keysAndValues = append(keysAndValues, "error_details", details)
+		}
+	}
+	return keysAndValues
+}
+
+// errorDetails encodes the details of st as protojson, redacted like
+// payloads.
+func errorDetails(st *status.Status, redacted map[string]bool) []string {
+	var details []string
+	for _, d := range st.Details() {
+		if err, ok := d.(error); ok {
+			details = append(details, "[can't decode detail: "+err.Error()+"]")
+			continue
+		}
+		details = append(details, payload(d, maxDetailBytes, redacted))
+	}
+	return details
+}
+
+// incomingRequestID returns the request ID in the metadata of ctx, or a new
+// one if it has none or an invalid one.
+func incomingRequestID(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, requestIDMetadata); len(values) > 0 && log.ValidRequestID(values[0]) {
+		return values[0]
+	}
+	return log.NewRequestID()
+}
+
+// codeLevel tells the failures caused by the client, logged at Warn, from the
+// server's own, logged at Error.
+func codeLevel(code codes.Code) log.Level {
+	switch code {
+	case codes.OK:
+		return log.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition, codes.OutOfRange:
+		return log.WarnLevel
+	default:
+		return log.ErrorLevel
+	}
+}
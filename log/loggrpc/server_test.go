@@ -0,0 +1,283 @@
+package loggrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Stasky745/go-libs/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// loginDescriptor describes:
+//
+//	message Login {
+//	  string user = 1;
+//	  string password = 2;
+//	  string pin = 3 [debug_redact = true];
+//	  Login nested = 4;
+//	}
+var loginDescriptor = func() protoreflect.MessageDescriptor {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+	}
+	pin := field("pin", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	pin.Options = &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}
+	nested := field("nested", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	nested.TypeName = proto.String(".test.Login")
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/login.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Login"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("user", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("password", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				pin,
+				nested,
+			},
+		}},
+	}, nil)
+	if err != nil {
+		panic(err)
+	}
+	return file.Messages().Get(0)
+}()
+
+func newLogin(user, password, pin string) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(loginDescriptor)
+	fields := loginDescriptor.Fields()
+	m.Set(fields.ByName("user"), protoreflect.ValueOfString(user))
+	m.Set(fields.ByName("password"), protoreflect.ValueOfString(password))
+	m.Set(fields.ByName("pin"), protoreflect.ValueOfString(pin))
+	return m
+}
+
+// testLogger returns a logger writing JSON lines to buf, at every level.
+func testLogger(buf *bytes.Buffer) *log.Logger {
+	return log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+}
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := UnaryServerInterceptor(WithLogger(testLogger(&buf)))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Auth/Login"}
+
+	_, _ = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no such user")
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "database down")
+	})
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 3)
+	assert.Equal(t, "INFO", logged[0]["level"])
+	assert.Equal(t, "/test.Auth/Login", logged[0]["grpc_method"])
+	assert.Equal(t, "OK", logged[0]["grpc_code"])
+	assert.NotContains(t, logged[0], "request") // Payloads are opt-in
+	assert.Equal(t, "WARN", logged[1]["level"])
+	assert.Equal(t, "no such user", logged[1]["error"])
+	assert.Equal(t, "ERROR", logged[2]["level"])
+}
+
+func TestUnaryServerInterceptorRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := UnaryServerInterceptor(WithLogger(testLogger(&buf)))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Auth/Login"}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 5000}})
+
+	var seen string
+	_, _ = interceptor(metadata.NewIncomingContext(ctx, metadata.Pairs("x-request-id", "abc-123")), nil, info,
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			seen = log.RequestIDFromContext(ctx)
+			log.FromContext(ctx).Info("handling")
+			return nil, nil
+		})
+	_, _ = interceptor(metadata.NewIncomingContext(ctx, metadata.Pairs("x-request-id", "bad id")), nil, info,
+		func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 3)
+	assert.Equal(t, "abc-123", seen)
+	assert.Equal(t, "abc-123", logged[0]["request_id"])
+	assert.Equal(t, "abc-123", logged[1]["request_id"])
+	assert.Equal(t, "10.0.0.7:5000", logged[1]["peer"])
+	assert.Len(t, logged[2]["request_id"], 32) // Invalid IDs are replaced
+}
+
+func TestMethodLevel(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := UnaryServerInterceptor(WithLogger(testLogger(&buf)), WithMethodLevel(log.DebugLevel, "/grpc.health.v1.Health/*"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+
+	_, _ = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "shutting down")
+	})
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 2)
+	assert.Equal(t, "DEBUG", logged[0]["level"])
+	assert.Equal(t, "ERROR", logged[1]["level"]) // Failures keep the level of their code
+}
+
+func TestErrorDetails(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := UnaryServerInterceptor(WithLogger(testLogger(&buf)))
+
+	st, err := status.New(codes.InvalidArgument, "invalid login").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "user", Description: "required"}},
+	})
+	require.NoError(t, err)
+	_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Auth/Login"},
+		func(context.Context, interface{}) (interface{}, error) { return nil, st.Err() })
+
+	logged := entries(t, &buf)[0]
+	details := logged["error_details"].([]interface{})
+	require.Len(t, details, 1)
+	assert.JSONEq(t, `{"fieldViolations":[{"field":"user","description":"required"}]}`, details[0].(string))
+}
+
+func TestPayloadsRedacted(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := UnaryServerInterceptor(WithLogger(testLogger(&buf)), WithPayloads(1024, "/test.Auth/*"))
+
+	req := newLogin("ana", "hunter2", "1234")
+	nested := newLogin("bob", "swordfish", "9999")
+	req.Set(loginDescriptor.Fields().ByName("nested"), protoreflect.ValueOfMessage(nested))
+
+	_, _ = interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/test.Auth/Login"},
+		func(context.Context, interface{}) (interface{}, error) { return newLogin("ana", "", ""), nil })
+
+	logged := entries(t, &buf)[0]
+	assert.JSONEq(t, `{"user":"ana","password":"[REDACTED]","pin":"[REDACTED]","nested":{"user":"bob","password":"[REDACTED]","pin":"[REDACTED]"}}`, logged["request"].(string))
+	assert.JSONEq(t, `{"user":"ana"}`, logged["response"].(string))
+	assert.Equal(t, "hunter2", req.Get(loginDescriptor.Fields().ByName("password")).String()) // The handler's message is untouched
+}
+
+func TestPayloadsAllowlistAndCap(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := UnaryServerInterceptor(WithLogger(testLogger(&buf)), WithPayloads(10, "/test.Auth/Login"), WithRedactedFields())
+	handler := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+
+	_, _ = interceptor(context.Background(), newLogin("ana", "hunter2", ""), &grpc.UnaryServerInfo{FullMethod: "/test.Auth/Login"}, handler)
+	_, _ = interceptor(context.Background(), newLogin("ana", "hunter2", ""), &grpc.UnaryServerInfo{FullMethod: "/test.Auth/Logout"}, handler)
+
+	logged := entries(t, &buf)
+	request := logged[0]["request"].(string)
+	assert.True(t, strings.HasSuffix(request, "...[truncated]"))
+	assert.Len(t, request, 10+len("...[truncated]"))
+	assert.NotContains(t, logged[1], "request")
+}
+
+// fakeStream replays requests and records responses.
+type fakeStream struct {
+	grpc.ServerStream
+	requests []proto.Message
+	sent     int
+}
+
+func (s *fakeStream) Context() context.Context { return context.Background() }
+
+func (s *fakeStream) SetHeader(metadata.MD) error { return nil }
+
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	if len(s.requests) == 0 {
+		return io.EOF
+	}
+	proto.Merge(m.(proto.Message), s.requests[0])
+	s.requests = s.requests[1:]
+	return nil
+}
+
+func (s *fakeStream) SendMsg(interface{}) error {
+	s.sent++
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := StreamServerInterceptor(WithLogger(testLogger(&buf)), WithPayloads(1024, "*"))
+	stream := &fakeStream{requests: []proto.Message{newLogin("ana", "hunter2", ""), newLogin("bob", "", "")}}
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/test.Auth/Chat"}, func(_ interface{}, ss grpc.ServerStream) error {
+		for {
+			m := dynamicpb.NewMessage(loginDescriptor)
+			if err := ss.RecvMsg(m); err != nil {
+				return nil
+			}
+			_ = ss.SendMsg(m)
+		}
+	})
+	require.NoError(t, err)
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 5)
+	assert.Equal(t, "grpc message", logged[0]["msg"])
+	assert.Equal(t, "received", logged[0]["direction"])
+	assert.NotContains(t, logged[0]["payload"], "hunter2")
+	assert.Equal(t, "grpc call", logged[4]["msg"])
+	assert.Equal(t, float64(2), logged[4]["messages_received"])
+	assert.Equal(t, float64(2), logged[4]["messages_sent"])
+}
+
+func TestStreamPayloadsSkippedAboveDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewJSONHandler(&buf, nil)))
+	interceptor := StreamServerInterceptor(WithLogger(logger), WithPayloads(1024, "*"))
+	stream := &fakeStream{requests: []proto.Message{newLogin("ana", "hunter2", "")}}
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/test.Auth/Chat"}, func(_ interface{}, ss grpc.ServerStream) error {
+		return ss.RecvMsg(dynamicpb.NewMessage(loginDescriptor))
+	})
+	require.NoError(t, err)
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "grpc call", logged[0]["msg"])
+}
+
+func TestPayloadNegativeMax(t *testing.T) {
+	assert.Equal(t, "...[truncated]", payload(newLogin("ana", "", ""), -1, fieldSet(nil)))
+}
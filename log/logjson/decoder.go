@@ -0,0 +1,144 @@
+// Package logjson reads the JSON lines written by the log package back into
+// entries, to filter and display them.
+package logjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// maxLineSize bounds the entries a Decoder reads, stack traces included.
+const maxLineSize = 1 << 20
+
+// Entry is a decoded log entry.
+type Entry struct {
+	Time       time.Time // Zero if the entry had none
+	Level      zapcore.Level
+	Message    string
+	Caller     string
+	Stacktrace string
+	Fields     map[string]interface{} // Every other field, nested objects included
+
+	// Raw is the line the entry was decoded from. Lines that aren't JSON
+	// objects are returned as entries with only Raw and Message set.
+	Raw []byte
+}
+
+// Decoder reads entries from a stream of JSON lines.
+type Decoder struct {
+	scanner *bufio.Scanner
+	keys    log.KeyNames
+}
+
+// NewDecoder returns a Decoder reading from r, expecting the keys of a
+// production logger. Use WithKeyNames if they were renamed.
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &Decoder{
+		scanner: scanner,
+		keys:    log.KeyNames{Message: "msg", Level: "level", Time: "ts", Caller: "caller", Stacktrace: "stacktrace"},
+	}
+}
+
+// WithKeyNames sets the keys renamed with log.WithKeyNames. Empty names keep
+// the defaults.
+func (d *Decoder) WithKeyNames(keys log.KeyNames) *Decoder {
+	rename := func(key *string, name string) {
+		if name != "" {
+			*key = name
+		}
+	}
+	rename(&d.keys.Message, keys.Message)
+	rename(&d.keys.Level, keys.Level)
+	rename(&d.keys.Time, keys.Time)
+	rename(&d.keys.Caller, keys.Caller)
+	rename(&d.keys.Stacktrace, keys.Stacktrace)
+	return d
+}
+
+// Next returns the next entry, or io.EOF once the stream is exhausted.
+func (d *Decoder) Next() (Entry, error) {
+	for d.scanner.Scan() {
+		line := d.scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		return d.Decode(line), nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return Entry{}, err
+	}
+	return Entry{}, io.EOF
+}
+
+// Decode decodes a single line.
+func (d *Decoder) Decode(line []byte) Entry {
+	raw := append([]byte(nil), line...)
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return Entry{Level: zapcore.InfoLevel, Message: string(raw), Raw: raw}
+	}
+
+	e := Entry{Fields: fields, Raw: raw}
+	e.Message = takeString(fields, d.keys.Message)
+	e.Caller = takeString(fields, d.keys.Caller)
+	e.Stacktrace = takeString(fields, d.keys.Stacktrace)
+	if err := e.Level.UnmarshalText([]byte(takeString(fields, d.keys.Level))); err != nil {
+		e.Level = zapcore.InfoLevel
+	}
+	if ts, ok := fields[d.keys.Time]; ok {
+		delete(fields, d.keys.Time)
+		e.Time = parseTime(ts)
+	}
+	return e
+}
+
+func takeString(fields map[string]interface{}, key string) string {
+	v, ok := fields[key]
+	if !ok {
+		return ""
+	}
+	delete(fields, key)
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return string(mustJSON(v))
+}
+
+// parseTime reads the time encodings zap offers: epoch seconds, millis or
+// nanos as numbers, and ISO8601 or RFC3339 strings.
+func parseTime(v interface{}) time.Time {
+	switch v := v.(type) {
+	case float64:
+		switch {
+		case v > 1e17:
+			return time.Unix(0, int64(v))
+		case v > 1e11:
+			return time.UnixMilli(int64(v))
+		default:
+			sec := int64(v)
+			return time.Unix(sec, int64((v-float64(sec))*1e9))
+		}
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000Z0700"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func mustJSON(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
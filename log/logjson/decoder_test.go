@@ -0,0 +1,81 @@
+package logjson
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func TestDecoderReadsLoggerOutput(t *testing.T) {
+	var buf bytes.Buffer
+	config := zap.NewProductionEncoderConfig()
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(config), zapcore.AddSync(&buf), zapcore.DebugLevel)
+	zap.New(core, zap.AddCaller()).Sugar().Warnw("disk almost full", "disk", "/dev/sda1", "used", 0.93)
+
+	d := NewDecoder(&buf)
+	e, err := d.Next()
+	require.NoError(t, err)
+
+	assert.Equal(t, zapcore.WarnLevel, e.Level)
+	assert.Equal(t, "disk almost full", e.Message)
+	assert.Contains(t, e.Caller, "decoder_test.go")
+	assert.WithinDuration(t, time.Now(), e.Time, time.Minute)
+	assert.Equal(t, map[string]interface{}{"disk": "/dev/sda1", "used": 0.93}, e.Fields)
+
+	_, err = d.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoderKeepsNonJSONLines(t *testing.T) {
+	d := NewDecoder(strings.NewReader("panic: boom\n\n{\"level\":\"error\",\"msg\":\"failed\"}\n"))
+
+	e, err := d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "panic: boom", e.Message)
+	assert.Nil(t, e.Fields)
+
+	e, err = d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, zapcore.ErrorLevel, e.Level)
+	assert.Equal(t, "failed", e.Message)
+}
+
+func TestDecoderWithKeyNames(t *testing.T) {
+	line := `{"severity":"debug","@timestamp":"2024-05-01T10:00:00.000Z","message":"hello","msg":"kept"}`
+	d := NewDecoder(strings.NewReader(line)).WithKeyNames(log.KeyNames{
+		Message: "message",
+		Level:   "severity",
+		Time:    "@timestamp",
+	})
+
+	e, err := d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, zapcore.DebugLevel, e.Level)
+	assert.Equal(t, "hello", e.Message)
+	assert.True(t, e.Time.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)))
+	assert.Equal(t, map[string]interface{}{"msg": "kept"}, e.Fields)
+}
+
+func TestParseTimeEncodings(t *testing.T) {
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, v := range []interface{}{
+		float64(want.Unix()),
+		float64(want.UnixMilli()),
+		float64(want.UnixNano()),
+		"2024-05-01T10:00:00Z",
+		"2024-05-01T12:00:00.000+0200",
+	} {
+		assert.True(t, parseTime(v).Equal(want), "%v", v)
+	}
+	assert.True(t, parseTime("yesterday").IsZero())
+}
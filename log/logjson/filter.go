@@ -0,0 +1,176 @@
+package logjson
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Filter selects entries. The zero Filter matches every entry.
+type Filter struct {
+	MinLevel zapcore.Level // Entries below it are skipped; 0 is Info, so set DebugLevel to keep everything
+	Since    time.Time     // Entries before it are skipped, unless zero
+	Until    time.Time     // Entries after it are skipped, unless zero
+	Fields   []FieldExpr   // Entries must match all of them
+}
+
+// Match reports whether e passes the filter. Entries without a time pass the
+// time range.
+func (f Filter) Match(e Entry) bool {
+	if e.Level < f.MinLevel {
+		return false
+	}
+	if !e.Time.IsZero() {
+		if !f.Since.IsZero() && e.Time.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && e.Time.After(f.Until) {
+			return false
+		}
+	}
+	for _, expr := range f.Fields {
+		if !expr.Match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldExpr is a condition on a field, parsed by ParseFieldExpr.
+type FieldExpr struct {
+	Key   string
+	Op    string // One of "=", "!=", "~", ">", ">=", "<", "<=" or "" to test the key exists
+	Value string
+
+	re  *regexp.Regexp
+	num float64
+}
+
+// operators lists the two-character operators first, so "!=" wins over "=".
+var operators = []string{"!=", ">=", "<=", "=", "~", ">", "<"}
+
+// ParseFieldExpr parses "key=value", "key!=value", "key~regexp", comparisons
+// with numbers like "key>number" or "key<=number", or a bare "key" that
+// matches entries carrying it. The operator is the first one in s. Keys of
+// nested objects are joined with dots, like "http.status"; "msg" and
+// "caller" address the message and caller.
+func ParseFieldExpr(s string) (FieldExpr, error) {
+	expr := FieldExpr{Key: s}
+	at := len(s)
+	for _, op := range operators {
+		if i := strings.Index(s, op); i > 0 && i < at {
+			expr = FieldExpr{Key: s[:i], Op: op, Value: s[i+len(op):]}
+			at = i
+		}
+	}
+
+	switch expr.Op {
+	case "~":
+		re, err := regexp.Compile(expr.Value)
+		if err != nil {
+			return FieldExpr{}, fmt.Errorf("invalid regexp in %q: %w", s, err)
+		}
+		expr.re = re
+	case ">", ">=", "<", "<=":
+		num, err := strconv.ParseFloat(expr.Value, 64)
+		if err != nil {
+			return FieldExpr{}, fmt.Errorf("%q compares with a non-number", s)
+		}
+		expr.num = num
+	}
+	return expr, nil
+}
+
+// Match reports whether e satisfies the expression.
+func (x FieldExpr) Match(e Entry) bool {
+	v, ok := e.lookup(x.Key)
+	switch x.Op {
+	case "":
+		return ok
+	case "!=":
+		return !ok || render(v) != x.Value
+	}
+	if !ok {
+		return false
+	}
+
+	switch x.Op {
+	case "=":
+		return render(v) == x.Value
+	case "~":
+		return x.re.MatchString(render(v))
+	}
+	num, isNum := v.(float64)
+	if !isNum {
+		var err error
+		if num, err = strconv.ParseFloat(render(v), 64); err != nil {
+			return false
+		}
+	}
+	switch x.Op {
+	case ">":
+		return num > x.num
+	case ">=":
+		return num >= x.num
+	case "<":
+		return num < x.num
+	}
+	return num <= x.num
+}
+
+// lookup finds key among the entry's fields, descending into nested objects
+// at each dot.
+func (e Entry) lookup(key string) (interface{}, bool) {
+	switch key {
+	case "msg", "message":
+		return e.Message, true
+	case "caller":
+		return e.Caller, e.Caller != ""
+	}
+
+	if v, ok := e.Fields[key]; ok {
+		return v, true
+	}
+	var current interface{} = e.Fields
+	for _, part := range strings.Split(key, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// render formats a decoded value the way it appears in the JSON line, without
+// quotes around strings.
+func render(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return string(mustJSON(v))
+	}
+}
+
+// ParseTime parses a time bound given on a command line: an RFC3339 time, a
+// date, or a duration counted back from now, like "15m".
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: want RFC3339, a date or a duration", s)
+}
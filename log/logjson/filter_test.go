@@ -0,0 +1,83 @@
+package logjson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func mustExprs(t *testing.T, exprs ...string) []FieldExpr {
+	t.Helper()
+
+	var out []FieldExpr
+	for _, s := range exprs {
+		expr, err := ParseFieldExpr(s)
+		require.NoError(t, err)
+		out = append(out, expr)
+	}
+	return out
+}
+
+func TestFilterMatch(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	e := NewDecoder(nil).Decode([]byte(`{"level":"warn","ts":1714557600,"msg":"slow query","caller":"db/query.go:42","duration_ms":1250,"db":{"table":"users"},"retry":false}`))
+	require.True(t, e.Time.Equal(at))
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"zero filter", Filter{}, true},
+		{"level below", Filter{MinLevel: zapcore.ErrorLevel}, false},
+		{"level at", Filter{MinLevel: zapcore.WarnLevel}, true},
+		{"since after", Filter{Since: at.Add(time.Second)}, false},
+		{"until before", Filter{Until: at.Add(-time.Second)}, false},
+		{"within range", Filter{Since: at.Add(-time.Hour), Until: at.Add(time.Hour)}, true},
+		{"equal", Filter{Fields: mustExprs(t, "retry=false")}, true},
+		{"not equal", Filter{Fields: mustExprs(t, "retry!=false")}, false},
+		{"not equal missing", Filter{Fields: mustExprs(t, "user!=bob")}, true},
+		{"nested", Filter{Fields: mustExprs(t, "db.table=users")}, true},
+		{"regexp on message", Filter{Fields: mustExprs(t, "msg~^slow")}, true},
+		{"regexp on caller", Filter{Fields: mustExprs(t, "caller~^http/")}, false},
+		{"greater", Filter{Fields: mustExprs(t, "duration_ms>1000")}, true},
+		{"less", Filter{Fields: mustExprs(t, "duration_ms<1000")}, false},
+		{"greater or equal", Filter{Fields: mustExprs(t, "duration_ms>=1250")}, true},
+		{"less or equal", Filter{Fields: mustExprs(t, "duration_ms<=1249")}, false},
+		{"operator in value", Filter{Fields: mustExprs(t, "caller=db/query.go:42")}, true},
+		{"exists", Filter{Fields: mustExprs(t, "db")}, true},
+		{"missing", Filter{Fields: mustExprs(t, "error")}, false},
+		{"all must match", Filter{Fields: mustExprs(t, "db", "error")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(e))
+		})
+	}
+}
+
+func TestParseFieldExprErrors(t *testing.T) {
+	_, err := ParseFieldExpr("msg~(")
+	assert.Error(t, err)
+
+	_, err = ParseFieldExpr("status>high")
+	assert.Error(t, err)
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	got, err := ParseTime("15m", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-15*time.Minute), got)
+
+	got, err = ParseTime("2024-04-30", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC), got)
+
+	_, err = ParseTime("last week", now)
+	assert.Error(t, err)
+}
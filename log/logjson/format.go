@@ -0,0 +1,79 @@
+package logjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// levelColors match zapcore.CapitalColorLevelEncoder.
+var levelColors = map[zapcore.Level]int{
+	zapcore.DebugLevel:  35, // magenta
+	zapcore.InfoLevel:   34, // blue
+	zapcore.WarnLevel:   33, // yellow
+	zapcore.ErrorLevel:  31, // red
+	zapcore.DPanicLevel: 31,
+	zapcore.PanicLevel:  31,
+	zapcore.FatalLevel:  31,
+}
+
+// Printer writes entries laid out like the development console encoder: time,
+// level, caller, message and the remaining fields as JSON, tab-separated, with
+// the stack trace on the following lines.
+type Printer struct {
+	w     io.Writer
+	color bool
+
+	// TimeLayout formats entry times, ISO8601 with milliseconds by default.
+	TimeLayout string
+}
+
+// NewPrinter returns a Printer writing to w, coloring levels if color is set.
+func NewPrinter(w io.Writer, color bool) *Printer {
+	return &Printer{w: w, color: color, TimeLayout: "2006-01-02T15:04:05.000Z0700"}
+}
+
+// Print writes e. Lines that weren't JSON are written unchanged.
+func (p *Printer) Print(e Entry) error {
+	if e.Fields == nil {
+		_, err := fmt.Fprintf(p.w, "%s\n", e.Raw)
+		return err
+	}
+
+	var buf bytes.Buffer
+	if !e.Time.IsZero() {
+		buf.WriteString(e.Time.Format(p.TimeLayout))
+		buf.WriteByte('\t')
+	}
+	if p.color {
+		fmt.Fprintf(&buf, "\x1b[%dm%s\x1b[0m", levelColors[e.Level], e.Level.CapitalString())
+	} else {
+		buf.WriteString(e.Level.CapitalString())
+	}
+	if e.Caller != "" {
+		buf.WriteByte('\t')
+		buf.WriteString(e.Caller)
+	}
+	buf.WriteByte('\t')
+	buf.WriteString(e.Message)
+	if len(e.Fields) > 0 {
+		buf.WriteByte('\t')
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(e.Fields); err != nil {
+			return err
+		}
+		buf.Truncate(buf.Len() - 1) // Encode's newline
+	}
+	buf.WriteByte('\n')
+	if e.Stacktrace != "" {
+		buf.WriteString(e.Stacktrace)
+		buf.WriteByte('\n')
+	}
+
+	_, err := p.w.Write(buf.Bytes())
+	return err
+}
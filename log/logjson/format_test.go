@@ -0,0 +1,27 @@
+package logjson
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrinterLayout(t *testing.T) {
+	e := NewDecoder(nil).Decode([]byte(`{"level":"error","ts":"2024-05-01T10:00:00Z","caller":"api/h.go:7","msg":"failed","url":"/a?b=1&c=2","stacktrace":"main.main\n\tmain.go:3"}`))
+
+	var buf bytes.Buffer
+	require.NoError(t, NewPrinter(&buf, false).Print(e))
+	assert.Equal(t, "2024-05-01T10:00:00.000Z\tERROR\tapi/h.go:7\tfailed\t{\"url\":\"/a?b=1&c=2\"}\nmain.main\n\tmain.go:3\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, NewPrinter(&buf, true).Print(e))
+	assert.Contains(t, buf.String(), "\x1b[31mERROR\x1b[0m")
+}
+
+func TestPrinterPassesThroughRawLines(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewPrinter(&buf, true).Print(NewDecoder(nil).Decode([]byte("goroutine 1 [running]:"))))
+	assert.Equal(t, "goroutine 1 [running]:\n", buf.String())
+}
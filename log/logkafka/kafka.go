@@ -0,0 +1,171 @@
+// Package logkafka publishes log entries to a Kafka topic, for pipelines
+// consuming logs from Kafka rather than from files or collectors.
+//
+//	sink, err := logkafka.New(logkafka.Config{
+//		Brokers:  []string{"kafka-1:9092", "kafka-2:9092"},
+//		Topic:    "logs",
+//		KeyField: "service",
+//	})
+//	...
+//	log.InitLogger(false, log.WithSink(sink))
+package logkafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// Defaults of Config.
+const (
+	defaultBatchEntries = 500
+	defaultBatchBytes   = 1 << 20
+	defaultWriteTimeout = 10 * time.Second
+	defaultMaxRetries   = 3
+
+	// lingerTimeout bounds how long the producer waits for more messages
+	// before sending a partial batch; batching already happened in the
+	// BatchSink.
+	lingerTimeout = 10 * time.Millisecond
+)
+
+// Config configures a Sink.
+type Config struct {
+	Brokers []string // Addresses of the bootstrap brokers, host:port
+	Topic   string
+
+	// KeyField names the field whose value is the message key, like
+	// "service" or "request_id", so the entries sharing it go to the same
+	// partition, in order. Entries without it, or all of them if KeyField is
+	// empty, are spread over the partitions at random.
+	KeyField string
+
+	// Compression of the message batches, none by default.
+	Compression kafka.Compression
+
+	TLS          *log.TLSConfig // Optional, see log.TLSConfig
+	WriteTimeout time.Duration  // For each batch, retries included, 10 seconds by default
+	Batch        log.BatchConfig
+
+	// MaxRetries is how many times a batch the brokers fail to take is sent
+	// again, waiting from Backoff.Initial up to Backoff.Max in between. It
+	// defaults to 3; a negative value disables retries. Meanwhile the next
+	// batches wait in the queue, see log.BatchConfig.
+	MaxRetries int
+	Backoff    log.Backoff // log.DefaultBackoff if unset
+}
+
+// Sink publishes JSON-encoded entries to a topic, one message per entry. It
+// buffers and batches them like a log.BatchSink, so logging doesn't wait for
+// the brokers, and waits for the partition leader to acknowledge each batch.
+type Sink struct {
+	*log.BatchSink
+	writer messageWriter
+}
+
+// messageWriter is the part of *kafka.Writer the sink uses.
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// New returns a Sink. It connects lazily, so unreachable brokers don't prevent
+// the logger from starting.
+func New(config Config) (*Sink, error) {
+	if len(config.Brokers) == 0 || config.Topic == "" {
+		return nil, errors.New("kafka sink needs brokers and a topic")
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = defaultWriteTimeout
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.Backoff == (log.Backoff{}) {
+		config.Backoff = log.DefaultBackoff
+	}
+	batchEntries, batchBytes := config.Batch.MaxEntries, config.Batch.MaxBytes
+	if batchEntries <= 0 {
+		batchEntries = defaultBatchEntries
+	}
+	if batchBytes <= 0 {
+		batchBytes = defaultBatchBytes
+	}
+
+	transport := &kafka.Transport{}
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLS = tlsConfig
+	}
+
+	writer := &kafka.Writer{
+		Addr:            kafka.TCP(config.Brokers...),
+		Topic:           config.Topic,
+		Balancer:        &kafka.Murmur2Balancer{}, // Matches the partitioning of the Java client
+		BatchSize:       batchEntries,
+		BatchBytes:      int64(batchBytes),
+		BatchTimeout:    lingerTimeout,
+		WriteTimeout:    config.WriteTimeout,
+		RequiredAcks:    kafka.RequireOne,
+		MaxAttempts:     config.MaxRetries + 1,
+		WriteBackoffMin: config.Backoff.Initial,
+		WriteBackoffMax: config.Backoff.Max,
+		Compression:     config.Compression,
+		Transport:       transport,
+	}
+	return newSink(config, writer), nil
+}
+
+func newSink(config Config, writer messageWriter) *Sink {
+	return &Sink{
+		BatchSink: log.NewBatchSink("kafka "+config.Topic, config.Batch, func(batch [][]byte) error {
+			msgs := make([]kafka.Message, len(batch))
+			for i, line := range batch {
+				msgs[i] = kafka.Message{Key: messageKey(line, config.KeyField), Value: bytes.TrimRight(line, "\n")}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), config.WriteTimeout)
+			defer cancel()
+			return writer.WriteMessages(ctx, msgs...)
+		}),
+		writer: writer,
+	}
+}
+
+// Close publishes the remaining entries and closes the producer.
+func (s *Sink) Close() error {
+	return errors.Join(s.BatchSink.Close(), s.writer.Close())
+}
+
+// messageKey returns the value of the top-level field key of the entry in
+// line, or nil if there's none.
+func messageKey(line []byte, key string) []byte {
+	if key == "" {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return nil
+	}
+	raw, ok := fields[key]
+	if !ok {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return []byte(s)
+	}
+	return raw // Numbers and the like, as written
+}
@@ -0,0 +1,102 @@
+package logkafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+type fakeWriter struct {
+	mu      sync.Mutex
+	batches [][]kafka.Message
+	err     error
+	closed  bool
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	w.batches = append(w.batches, msgs)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	return nil
+}
+
+func TestSinkPublishesBatches(t *testing.T) {
+	writer := &fakeWriter{}
+	sink := newSink(Config{Topic: "logs", KeyField: "request_id", WriteTimeout: time.Second, Batch: log.BatchConfig{FlushInterval: time.Hour}}, writer)
+
+	lines := []string{
+		`{"level":"info","msg":"one","request_id":"r1"}`,
+		`{"level":"info","msg":"two","request_id":42}`,
+		`{"level":"info","msg":"three"}`,
+	}
+	for _, line := range lines {
+		_, _ = sink.Write([]byte(line + "\n"))
+	}
+	require.NoError(t, sink.Sync())
+	require.NoError(t, sink.Close())
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	require.Len(t, writer.batches, 1)
+	batch := writer.batches[0]
+	require.Len(t, batch, 3)
+	assert.Equal(t, []byte("r1"), batch[0].Key)
+	assert.Equal(t, []byte("42"), batch[1].Key)
+	assert.Nil(t, batch[2].Key)
+	assert.Equal(t, lines[0], string(batch[0].Value))
+	assert.True(t, writer.closed)
+}
+
+func TestSinkFailedBatch(t *testing.T) {
+	writer := &fakeWriter{err: errors.New("leader not available")}
+	sink := newSink(Config{Topic: "logs", WriteTimeout: time.Second, Batch: log.BatchConfig{FlushInterval: time.Hour}}, writer)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"msg":"lost"}`))
+	require.NoError(t, sink.Sync())
+	assert.Equal(t, uint64(1), sink.Stats().Failed)
+}
+
+func TestMessageKey(t *testing.T) {
+	assert.Nil(t, messageKey([]byte(`{"service":"api"}`), ""))
+	assert.Nil(t, messageKey([]byte(`not json`), "service"))
+	assert.Equal(t, []byte("api"), messageKey([]byte(`{"service":"api"}`), "service"))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Topic: "logs"})
+	assert.Error(t, err)
+
+	sink, err := New(Config{Brokers: []string{"localhost:9092"}, Topic: "logs"})
+	require.NoError(t, err)
+	writer := sink.writer.(*kafka.Writer)
+	assert.Equal(t, 4, writer.MaxAttempts) // The first attempt and 3 retries
+	assert.Equal(t, log.DefaultBackoff.Initial, writer.WriteBackoffMin)
+	assert.Equal(t, log.DefaultBackoff.Max, writer.WriteBackoffMax)
+	assert.NoError(t, sink.Close())
+
+	sink, err = New(Config{Brokers: []string{"localhost:9092"}, Topic: "logs", MaxRetries: -1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, sink.writer.(*kafka.Writer).MaxAttempts)
+	assert.NoError(t, sink.Close())
+}
@@ -0,0 +1,90 @@
+// Package loglogr adapts the log package to logr, the logging interface of
+// the Kubernetes ecosystem, so libraries like client-go and
+// controller-runtime log through the same logger as the rest of the program.
+//
+//	ctrl.SetLogger(loglogr.New(log.GetLogger()))
+//	klog.SetLogger(loglogr.New(log.GetLogger(), loglogr.WithVerbosity(2)))
+package loglogr
+
+import (
+	"github.com/go-logr/logr"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// defaultVerbosity logs V(0) and V(1), as zapr does with zap at Debug.
+const defaultVerbosity = 1
+
+// Option customizes the adapter.
+type Option func(*sink)
+
+// WithVerbosity logs the entries of verbosity up to v, V(0) included, and
+// drops the more verbose ones. It's 1 by default.
+func WithVerbosity(v int) Option {
+	return func(s *sink) {
+		s.verbosity = v
+	}
+}
+
+// New returns a logr.Logger writing through l. V(0) entries are logged at
+// Info and the more verbose ones, up to WithVerbosity, at Debug, provided l
+// logs that level. Error entries are logged at Error with the error under
+// "error". Names given with WithName are joined as log.Logger's Named does,
+// and callers are reported through logr's call depth.
+func New(l *log.Logger, opts ...Option) logr.Logger {
+	s := &sink{logger: l, verbosity: defaultVerbosity}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return logr.New(s)
+}
+
+// sink implements logr.LogSink and logr.CallDepthLogSink.
+type sink struct {
+	logger    *log.Logger
+	verbosity int
+	depth     int // Frames between logr's caller and the sink
+}
+
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.depth = info.CallDepth
+}
+
+func (s *sink) Enabled(level int) bool {
+	return level <= s.verbosity && s.logger.Enabled(levelOf(level))
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.logger.WithCallerSkip(s.depth+1).Log(levelOf(level), msg, keysAndValues...)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)], "error", err)
+	s.logger.WithCallerSkip(s.depth+1).Error(msg, keysAndValues...)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	derived := *s
+	derived.logger = s.logger.With(keysAndValues...)
+	return &derived
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	derived := *s
+	derived.logger = s.logger.Named(name)
+	return &derived
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	derived := *s
+	derived.depth += depth
+	return &derived
+}
+
+// levelOf maps logr verbosity levels to log levels.
+func levelOf(v int) log.Level {
+	if v <= 0 {
+		return log.InfoLevel
+	}
+	return log.DebugLevel
+}
@@ -0,0 +1,74 @@
+package loglogr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func newTestLogger(t *testing.T, buf *bytes.Buffer) *log.Logger {
+	l, err := log.NewLogger(false, log.WithSink(zapcore.AddSync(buf)))
+	require.NoError(t, err)
+	l.SetLevel(log.DebugLevel)
+	return l
+}
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(newTestLogger(t, &buf)).WithName("controller").WithValues("namespace", "default")
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("reconciling", "name", "web")
+	logger.V(1).Info("details")
+	logger.V(2).Info("hidden")
+	logger.Error(errors.New("conflict"), "can't update")
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 3)
+	assert.Equal(t, "info", logged[0]["level"])
+	assert.Equal(t, "controller", logged[0]["logger"])
+	assert.Equal(t, "default", logged[0]["namespace"])
+	assert.Equal(t, "web", logged[0]["name"])
+	assert.Equal(t, "loglogr/logr_test.go:"+strconv.Itoa(line+1), logged[0]["caller"])
+	assert.Equal(t, "debug", logged[1]["level"])
+	assert.Equal(t, "error", logged[2]["level"])
+	assert.Equal(t, "conflict", logged[2]["error"])
+}
+
+func TestWithVerbosity(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(newTestLogger(t, &buf), WithVerbosity(4))
+
+	assert.True(t, logger.V(4).Enabled())
+	assert.False(t, logger.V(5).Enabled())
+}
+
+func TestLevelFiltered(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(t, &buf)
+	l.SetLevel(log.InfoLevel)
+
+	logger := New(l)
+	assert.True(t, logger.Enabled())
+	assert.False(t, logger.V(1).Enabled()) // Debug is off
+}
@@ -0,0 +1,180 @@
+// Package logmqtt publishes log entries to an MQTT broker, for embedded and
+// edge devices that already speak MQTT.
+//
+//	sink, err := logmqtt.New(logmqtt.Config{
+//		Broker: "ssl://broker.example.com:8883",
+//		Topic:  "devices/pump-7/logs/{level}",
+//		QoS:    1,
+//	})
+//	...
+//	log.InitLogger(false, log.WithSink(sink))
+package logmqtt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+const (
+	// defaultTimeout bounds how long a batch waits for the broker.
+	defaultTimeout = 10 * time.Second
+
+	// connectionPoll is how often a batch checks whether the client is
+	// connected. Messages published while disconnected would be lost with a
+	// clean session.
+	connectionPoll = 50 * time.Millisecond
+)
+
+// Config configures a Sink.
+type Config struct {
+	// Broker is the URL of the broker: tcp://, ssl://, ws:// or wss://.
+	Broker string
+
+	// ClientID defaults to log-<hostname>-<pid>. Username and Password are
+	// optional.
+	ClientID string
+	Username string
+	Password string
+
+	// Topic of the messages. {level} is replaced by the level of each entry,
+	// as in devices/pump-7/logs/{level}.
+	Topic string
+
+	// QoS is the MQTT quality of service of the messages: 0, at most once,
+	// the default; 1, at least once; or 2, exactly once.
+	QoS byte
+
+	// Retained asks the broker to keep the last message of each topic for
+	// new subscribers, so a dashboard shows a device's last entry at once.
+	Retained bool
+
+	TLS *log.TLSConfig // For ssl:// and wss:// brokers, the system roots if nil
+
+	// Options, if set, is called with the client options before connecting,
+	// to set anything Config doesn't cover, like a will message.
+	Options func(*mqtt.ClientOptions)
+
+	Timeout time.Duration // For each batch, 10 seconds by default
+	Batch   log.BatchConfig
+}
+
+// Sink publishes JSON-encoded entries to an MQTT broker, one message per
+// entry. It buffers and batches them like a log.BatchSink, so logging
+// doesn't wait for the broker.
+type Sink struct {
+	*log.BatchSink
+	client mqtt.Client
+}
+
+// New starts connecting to the broker and returns a Sink. An unreachable
+// broker doesn't prevent the logger from starting: the client connects and
+// reconnects in the background, and batches wait for the connection up to
+// Timeout before failing.
+func New(config Config) (*Sink, error) {
+	if config.Broker == "" || config.Topic == "" {
+		return nil, errors.New("mqtt sink needs a broker and a topic")
+	}
+	if config.QoS > 2 {
+		return nil, fmt.Errorf("invalid mqtt qos %d", config.QoS)
+	}
+	if config.ClientID == "" {
+		hostname, _ := os.Hostname()
+		config.ClientID = fmt.Sprintf("log-%s-%d", hostname, os.Getpid())
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(config.Broker).
+		SetClientID(config.ClientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectTimeout(config.Timeout)
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+	if config.Options != nil {
+		config.Options(opts)
+	}
+
+	client := mqtt.NewClient(opts)
+	client.Connect() // Retries in the background until connected
+
+	return &Sink{
+		BatchSink: log.NewBatchSink("mqtt "+config.Topic, config.Batch, publish(client, config)),
+		client:    client,
+	}, nil
+}
+
+// Close publishes the remaining entries and disconnects.
+func (s *Sink) Close() error {
+	err := s.BatchSink.Close()
+	s.client.Disconnect(250)
+	return err
+}
+
+// publish publishes a batch and waits until the broker has every message, as
+// far as the QoS goes.
+func publish(client mqtt.Client, config Config) func([][]byte) error {
+	return func(batch [][]byte) error {
+		deadline := time.Now().Add(config.Timeout)
+		for !client.IsConnectionOpen() {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("not connected to mqtt broker %s", config.Broker)
+			}
+			time.Sleep(connectionPoll)
+		}
+
+		tokens := make([]mqtt.Token, len(batch))
+		for i, line := range batch {
+			tokens[i] = client.Publish(topic(config.Topic, line), config.QoS, config.Retained, bytes.TrimRight(line, "\n"))
+		}
+
+		var failed int
+		var last error
+		for _, token := range tokens {
+			if !token.WaitTimeout(time.Until(deadline)) {
+				return fmt.Errorf("mqtt broker didn't take messages within %s", config.Timeout)
+			}
+			if err := token.Error(); err != nil {
+				failed++
+				last = err
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("mqtt failed to publish %d of %d messages: %w", failed, len(batch), last)
+		}
+		return nil
+	}
+}
+
+// topic returns pattern with {level} replaced by the level of the entry in
+// line, info if it has none.
+func topic(pattern string, line []byte) string {
+	if !strings.Contains(pattern, "{level}") {
+		return pattern
+	}
+	var entry struct {
+		Level string `json:"level"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil || entry.Level == "" {
+		entry.Level = "info"
+	}
+	return strings.ReplaceAll(pattern, "{level}", entry.Level)
+}
@@ -0,0 +1,120 @@
+package logmqtt
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// fakeBroker accepts connections and publishes, acknowledging QoS 1
+// messages, and records what it receives.
+type fakeBroker struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	clientID string
+	messages []*packets.PublishPacket
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &fakeBroker{ln: ln}
+	go b.serve()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *fakeBroker) url() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *fakeBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			b.mu.Lock()
+			b.clientID = p.ClientIdentifier
+			b.mu.Unlock()
+			_ = packets.NewControlPacket(packets.Connack).Write(conn)
+		case *packets.PublishPacket:
+			b.mu.Lock()
+			b.messages = append(b.messages, p)
+			b.mu.Unlock()
+			if p.Qos == 1 {
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				_ = ack.Write(conn)
+			}
+		case *packets.PingreqPacket:
+			_ = packets.NewControlPacket(packets.Pingresp).Write(conn)
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+func TestSinkPublishes(t *testing.T) {
+	broker := newFakeBroker(t)
+	sink, err := New(Config{
+		Broker:   broker.url(),
+		ClientID: "pump-7",
+		Topic:    "devices/pump-7/logs/{level}",
+		QoS:      1,
+		Retained: true,
+		Timeout:  5 * time.Second,
+		Batch:    log.BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+
+	_, _ = sink.Write([]byte(`{"level":"warn","msg":"pressure high"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"msg":"plain"}` + "\n"))
+	require.NoError(t, sink.Sync())
+	require.NoError(t, sink.Close())
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	assert.Equal(t, "pump-7", broker.clientID)
+	require.Len(t, broker.messages, 2)
+	assert.Equal(t, "devices/pump-7/logs/warn", broker.messages[0].TopicName)
+	assert.Equal(t, `{"level":"warn","msg":"pressure high"}`, string(broker.messages[0].Payload))
+	assert.Equal(t, byte(1), broker.messages[0].Qos)
+	assert.True(t, broker.messages[0].Retain)
+	assert.Equal(t, "devices/pump-7/logs/info", broker.messages[1].TopicName)
+	assert.Equal(t, uint64(1), sink.Stats().Batches)
+}
+
+func TestTopic(t *testing.T) {
+	assert.Equal(t, "logs", topic("logs", []byte(`{"level":"error"}`)))
+	assert.Equal(t, "logs/error", topic("logs/{level}", []byte(`{"level":"error"}`)))
+	assert.Equal(t, "logs/info", topic("logs/{level}", []byte(`not json`)))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Topic: "logs"})
+	assert.Error(t, err)
+	_, err = New(Config{Broker: "tcp://localhost:1883", Topic: "logs", QoS: 3})
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+// Package logmux logs the requests of gorilla/mux routers with their route
+// template, like /users/{id}, rather than their path only.
+//
+//	r := mux.NewRouter()
+//	r.Use(logmux.Middleware(logger))
+//	r.HandleFunc("/users/{id}", getUser)
+package logmux
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// Middleware returns the HTTPMiddleware of l logging the route of each
+// request, for mux's Use. mux runs its middlewares for matched routes only,
+// so requests no route matched aren't logged.
+func Middleware(l *log.Logger, opts ...log.MiddlewareOption) mux.MiddlewareFunc {
+	opts = append(opts, log.WithRoute(Route))
+	return func(next http.Handler) http.Handler {
+		return l.HTTPMiddleware(next, opts...)
+	}
+}
+
+// Route returns the path template of the route mux matched r with, or "" if
+// it matched none.
+func Route(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return template
+}
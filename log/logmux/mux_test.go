@@ -0,0 +1,37 @@
+package logmux
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewJSONHandler(&buf, nil)))
+
+	r := mux.NewRouter()
+	r.Use(Middleware(l))
+	r.HandleFunc("/users/{id:[0-9]+}", func(http.ResponseWriter, *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry)) // A single entry, unmatched requests aren't logged
+	assert.Equal(t, "/users/42", entry["path"])
+	assert.Equal(t, "/users/{id:[0-9]+}", entry["route"])
+}
+
+func TestRouteOutsideMux(t *testing.T) {
+	assert.Equal(t, "", Route(httptest.NewRequest(http.MethodGet, "/", nil)))
+}
@@ -0,0 +1,165 @@
+// Package lognats publishes log entries to NATS, on a core subject or on a
+// JetStream stream, for platforms fanning logs out over NATS.
+//
+//	sink, err := lognats.New(lognats.Config{
+//		URL:       "nats://nats:4222",
+//		Subject:   "logs.api.{level}",
+//		JetStream: true,
+//	})
+//	...
+//	log.InitLogger(false, log.WithSink(sink))
+package lognats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// defaultTimeout bounds how long a batch waits for the server.
+const defaultTimeout = 10 * time.Second
+
+// Config configures a Sink.
+type Config struct {
+	URL string // Comma-separated server URLs, nats.DefaultURL by default
+
+	// Subject of the messages. {level} is replaced by the level of each
+	// entry, as in logs.api.{level}, so subscribers can pick levels.
+	Subject string
+
+	// JetStream publishes to the stream bound to the subject and waits for
+	// it to acknowledge each message, instead of publishing at most once.
+	JetStream bool
+
+	// Options are passed to nats.Connect, for credentials, TLS or the
+	// connection name. The sink retries failed connections forever unless
+	// told otherwise.
+	Options []nats.Option
+
+	Timeout time.Duration // For each batch, 10 seconds by default
+	Batch   log.BatchConfig
+}
+
+// Sink publishes JSON-encoded entries to NATS, one message per entry. It
+// buffers and batches them like a log.BatchSink, so logging doesn't wait for
+// the server.
+type Sink struct {
+	*log.BatchSink
+	conn *nats.Conn
+}
+
+// New connects to NATS and returns a Sink. An unreachable server doesn't
+// prevent the logger from starting: the connection is retried in the
+// background and messages are buffered meanwhile, up to the reconnect buffer
+// of the nats package.
+func New(config Config) (*Sink, error) {
+	if config.Subject == "" {
+		return nil, errors.New("nats sink needs a subject")
+	}
+	if config.URL == "" {
+		config.URL = nats.DefaultURL
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	opts := append([]nats.Option{nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1)}, config.Options...)
+	conn, err := nats.Connect(config.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	publish := publishCore(conn, config)
+	if config.JetStream {
+		js, err := jetstream.New(conn)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		publish = publishJetStream(js, config)
+	}
+
+	return &Sink{
+		BatchSink: log.NewBatchSink("nats "+config.Subject, config.Batch, publish),
+		conn:      conn,
+	}, nil
+}
+
+// Close publishes the remaining entries and closes the connection.
+func (s *Sink) Close() error {
+	err := s.BatchSink.Close()
+	s.conn.Close()
+	return err
+}
+
+// publishCore publishes a batch at most once, flushing it to the server if
+// connected; while reconnecting the nats package buffers it.
+func publishCore(conn *nats.Conn, config Config) func([][]byte) error {
+	return func(batch [][]byte) error {
+		for _, line := range batch {
+			if err := conn.Publish(subject(config.Subject, line), bytes.TrimRight(line, "\n")); err != nil {
+				return err
+			}
+		}
+		if !conn.IsConnected() {
+			return nil
+		}
+		return conn.FlushTimeout(config.Timeout)
+	}
+}
+
+// publishJetStream publishes a batch and waits for every acknowledgement.
+func publishJetStream(js jetstream.JetStream, config Config) func([][]byte) error {
+	return func(batch [][]byte) error {
+		futures := make([]jetstream.PubAckFuture, 0, len(batch))
+		for _, line := range batch {
+			future, err := js.PublishAsync(subject(config.Subject, line), bytes.TrimRight(line, "\n"))
+			if err != nil {
+				return err
+			}
+			futures = append(futures, future)
+		}
+
+		timeout := time.NewTimer(config.Timeout)
+		defer timeout.Stop()
+		var failed int
+		var last error
+		for _, future := range futures {
+			select {
+			case <-future.Ok():
+			case err := <-future.Err():
+				failed++
+				last = err
+			case <-timeout.C:
+				return fmt.Errorf("jetstream didn't acknowledge messages within %s", config.Timeout)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("jetstream rejected %d of %d messages: %w", failed, len(batch), last)
+		}
+		return nil
+	}
+}
+
+// subject returns pattern with {level} replaced by the level of the entry in
+// line, info if it has none.
+func subject(pattern string, line []byte) string {
+	if !strings.Contains(pattern, "{level}") {
+		return pattern
+	}
+	var entry struct {
+		Level string `json:"level"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil || entry.Level == "" {
+		entry.Level = "info"
+	}
+	return strings.ReplaceAll(pattern, "{level}", entry.Level)
+}
@@ -0,0 +1,151 @@
+package lognats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// fakeServer speaks enough of the NATS protocol for a publisher: it records
+// published messages and, in JetStream mode, acknowledges those with a reply
+// subject.
+type fakeServer struct {
+	ln        net.Listener
+	jetStream bool
+
+	mu       sync.Mutex
+	messages []message
+}
+
+type message struct {
+	subject string
+	data    string
+}
+
+func newFakeServer(t *testing.T, jetStream bool) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{ln: ln, jetStream: jetStream}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "nats://" + s.ln.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	var wmu sync.Mutex
+	write := func(format string, args ...interface{}) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		_, _ = fmt.Fprintf(conn, format, args...)
+	}
+	write("INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"headers\":true,\"max_payload\":1048576,\"proto\":1}\r\n")
+
+	subs := map[string]string{} // Subject prefixes of wildcard subscriptions, by sid
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			write("PONG\r\n")
+		case "SUB":
+			subs[fields[len(fields)-1]] = strings.TrimSuffix(fields[1], "*")
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, message{subject: fields[1], data: string(payload[:size])})
+			s.mu.Unlock()
+
+			if s.jetStream && len(fields) == 4 {
+				reply := fields[2]
+				for sid, prefix := range subs {
+					if strings.HasPrefix(reply, prefix) {
+						ack := `{"stream":"LOGS","seq":1}`
+						write("MSG %s %s %d\r\n%s\r\n", reply, sid, len(ack), ack)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (s *fakeServer) received() []message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]message(nil), s.messages...)
+}
+
+func TestSinkPublishes(t *testing.T) {
+	for _, jetStream := range []bool{false, true} {
+		t.Run(fmt.Sprint("jetstream=", jetStream), func(t *testing.T) {
+			server := newFakeServer(t, jetStream)
+			sink, err := New(Config{
+				URL:       server.url(),
+				Subject:   "logs.api.{level}",
+				JetStream: jetStream,
+				Timeout:   5 * time.Second,
+				Batch:     log.BatchConfig{FlushInterval: time.Hour},
+			})
+			require.NoError(t, err)
+			defer sink.Close()
+
+			_, _ = sink.Write([]byte(`{"level":"warn","msg":"slow"}` + "\n"))
+			_, _ = sink.Write([]byte(`{"msg":"plain"}` + "\n"))
+			require.NoError(t, sink.Sync())
+
+			assert.Equal(t, []message{
+				{subject: "logs.api.warn", data: `{"level":"warn","msg":"slow"}`},
+				{subject: "logs.api.info", data: `{"msg":"plain"}`},
+			}, server.received())
+			assert.Equal(t, uint64(1), sink.Stats().Batches)
+		})
+	}
+}
+
+func TestSubject(t *testing.T) {
+	assert.Equal(t, "logs", subject("logs", []byte(`{"level":"error"}`)))
+	assert.Equal(t, "logs.error", subject("logs.{level}", []byte(`{"level":"error"}`)))
+	assert.Equal(t, "logs.info", subject("logs.{level}", []byte(`not json`)))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+}
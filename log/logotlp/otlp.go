@@ -0,0 +1,282 @@
+// Package logotlp exports log entries as OpenTelemetry log records over OTLP,
+// gRPC or HTTP, so they reach OpenTelemetry collectors natively.
+//
+//	sink, err := logotlp.New(logotlp.Config{
+//		Endpoint: "otel-collector:4317",
+//		Insecure: true,
+//		Resource: map[string]string{"service.name": "api"},
+//	})
+//	...
+//	log.InitLogger(false, log.WithSink(sink))
+package logotlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// Protocol is an OTLP transport.
+type Protocol string
+
+// Supported protocols.
+const (
+	GRPC         Protocol = "grpc"
+	HTTPProtobuf Protocol = "http/protobuf"
+)
+
+// Defaults of Config.
+const (
+	defaultGRPCEndpoint = "localhost:4317"
+	defaultHTTPEndpoint = "http://localhost:4318"
+	defaultTimeout      = 10 * time.Second
+	scopeName           = "github.com/Stasky745/go-libs/log"
+)
+
+// Config configures a Sink.
+type Config struct {
+	Protocol Protocol // GRPC by default
+
+	// Endpoint is host:port for gRPC, localhost:4317 by default, and the base
+	// URL for HTTP, http://localhost:4318 by default, to which /v1/logs is
+	// added.
+	Endpoint string
+
+	// Insecure connects over gRPC without TLS. HTTP uses TLS for https URLs.
+	Insecure bool
+
+	// Headers are sent with every export, like API keys of hosted backends.
+	Headers map[string]string
+
+	// Resource holds the resource attributes, like service.name. If it lacks
+	// service.name, OTEL_SERVICE_NAME provides it.
+	Resource map[string]string
+
+	// TraceIDKey and SpanIDKey name the fields carrying the trace and span
+	// IDs, "trace_id" and "span_id" by default. Valid hex IDs become the trace
+	// context of the records instead of attributes.
+	TraceIDKey string
+	SpanIDKey  string
+
+	// HTTP configures the HTTP client, and for both protocols TLS and Gzip
+	// compression.
+	HTTP log.HTTPConfig
+
+	Timeout time.Duration // For each export, 10 seconds by default
+	Batch   log.BatchConfig
+}
+
+// Sink exports JSON-encoded entries as log records, one export request per
+// batch. It buffers and batches them like a log.BatchSink, so logging doesn't
+// wait for the collector. Entries are mapped as the OpenTelemetry log data
+// model suggests: msg becomes the body, level the severity, caller the
+// code.filepath and code.lineno attributes, and the other fields attributes.
+type Sink struct {
+	*log.BatchSink
+	close func() error
+}
+
+// exporter sends one request.
+type exporter func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error)
+
+// New returns a Sink. It connects lazily, so an unreachable collector doesn't
+// prevent the logger from starting.
+func New(config Config) (*Sink, error) {
+	if config.Protocol == "" {
+		config.Protocol = GRPC
+	}
+	if config.TraceIDKey == "" {
+		config.TraceIDKey = "trace_id"
+	}
+	if config.SpanIDKey == "" {
+		config.SpanIDKey = "span_id"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	var export exporter
+	closer := func() error { return nil }
+	switch config.Protocol {
+	case GRPC:
+		conn, err := config.dialGRPC()
+		if err != nil {
+			return nil, err
+		}
+		export, closer = config.grpcExporter(conn), conn.Close
+	case HTTPProtobuf:
+		client, err := config.HTTP.Client()
+		if err != nil {
+			return nil, err
+		}
+		export = config.httpExporter(client)
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol %q", config.Protocol)
+	}
+
+	resource := config.resource()
+	return &Sink{
+		BatchSink: log.NewBatchSink("otlp "+string(config.Protocol), config.Batch, func(batch [][]byte) error {
+			now := time.Now()
+			records := make([]*logspb.LogRecord, len(batch))
+			for i, line := range batch {
+				records[i] = config.record(line, now)
+			}
+			req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+				Resource:  resource,
+				ScopeLogs: []*logspb.ScopeLogs{{Scope: &commonpb.InstrumentationScope{Name: scopeName}, LogRecords: records}},
+			}}}
+
+			ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+			defer cancel()
+			resp, err := export(ctx, req)
+			if err != nil {
+				return err
+			}
+			if partial := resp.GetPartialSuccess(); partial.GetRejectedLogRecords() > 0 {
+				return fmt.Errorf("collector rejected %d of %d log records: %s", partial.GetRejectedLogRecords(), len(records), partial.GetErrorMessage())
+			}
+			return nil
+		}),
+		close: closer,
+	}, nil
+}
+
+// Close exports the remaining entries and closes the connection.
+func (s *Sink) Close() error {
+	return errors.Join(s.BatchSink.Close(), s.close())
+}
+
+func (c Config) resource() *resourcepb.Resource {
+	attributes := make(map[string]string, len(c.Resource)+1)
+	for key, value := range c.Resource {
+		attributes[key] = value
+	}
+	if _, ok := attributes["service.name"]; !ok {
+		if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+			attributes["service.name"] = name
+		}
+	}
+
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resource := &resourcepb.Resource{}
+	for _, key := range keys {
+		resource.Attributes = append(resource.Attributes, &commonpb.KeyValue{Key: key, Value: stringValue(attributes[key])})
+	}
+	return resource
+}
+
+func (c Config) dialGRPC() (*grpc.ClientConn, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = defaultGRPCEndpoint
+	}
+
+	creds := insecure.NewCredentials()
+	if !c.Insecure {
+		tlsConfig := log.TLSConfig{}
+		if c.HTTP.TLS != nil {
+			tlsConfig = *c.HTTP.TLS
+		}
+		built, err := tlsConfig.Build()
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(built)
+	}
+	return grpc.NewClient(endpoint, grpc.WithTransportCredentials(creds))
+}
+
+func (c Config) grpcExporter(conn *grpc.ClientConn) exporter {
+	client := collogspb.NewLogsServiceClient(conn)
+	var callOpts []grpc.CallOption
+	if c.HTTP.Compression == log.Gzip {
+		callOpts = append(callOpts, grpc.UseCompressor(grpcgzip.Name))
+	}
+	return func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+		if len(c.Headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(c.Headers))
+		}
+		return client.Export(ctx, req, callOpts...)
+	}
+}
+
+func (c Config) httpExporter(client *http.Client) exporter {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = defaultHTTPEndpoint
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/logs"
+
+	return func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+		body, err := proto.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		if c.HTTP.Compression == log.Gzip {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			_, _ = gz.Write(body)
+			if err := gz.Close(); err != nil {
+				return nil, err
+			}
+			body = buf.Bytes()
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range c.Headers {
+			httpReq.Header.Set(key, value)
+		}
+		httpReq.Header.Set("Content-Type", "application/x-protobuf")
+		if c.HTTP.Compression == log.Gzip {
+			httpReq.Header.Set("Content-Encoding", "gzip")
+		}
+
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, &log.HTTPStatusError{URL: endpoint, StatusCode: resp.StatusCode}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		exportResp := &collogspb.ExportLogsServiceResponse{}
+		if err := proto.Unmarshal(respBody, exportResp); err != nil {
+			return &collogspb.ExportLogsServiceResponse{}, nil // Nothing to learn from an unexpected response
+		}
+		return exportResp, nil
+	}
+}
@@ -0,0 +1,148 @@
+package logotlp
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+type fakeCollector struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	apiKey   []string
+	rejected int64
+}
+
+func (c *fakeCollector) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.apiKey = md.Get("api-key")
+	c.requests = append(c.requests, req)
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if c.rejected > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: c.rejected, ErrorMessage: "too old"}
+	}
+	return resp, nil
+}
+
+func startCollector(t *testing.T, collector *fakeCollector) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, collector)
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(server.Stop)
+	return ln.Addr().String()
+}
+
+func TestSinkGRPC(t *testing.T) {
+	collector := &fakeCollector{}
+	t.Setenv("OTEL_SERVICE_NAME", "api")
+	sink, err := New(Config{
+		Endpoint: startCollector(t, collector),
+		Insecure: true,
+		Headers:  map[string]string{"api-key": "secret"},
+		Resource: map[string]string{"deployment.environment": "prod"},
+		HTTP:     log.HTTPConfig{Compression: log.Gzip},
+		Batch:    log.BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"info","msg":"one"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"error","msg":"two"}` + "\n"))
+	require.NoError(t, sink.Sync())
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	require.Len(t, collector.requests, 1)
+	assert.Equal(t, []string{"secret"}, collector.apiKey)
+	rl := collector.requests[0].ResourceLogs[0]
+	require.Len(t, rl.Resource.Attributes, 2)
+	assert.Equal(t, "deployment.environment", rl.Resource.Attributes[0].Key)
+	assert.Equal(t, "service.name", rl.Resource.Attributes[1].Key)
+	assert.Equal(t, "api", rl.Resource.Attributes[1].Value.GetStringValue())
+	assert.Equal(t, scopeName, rl.ScopeLogs[0].Scope.Name)
+	records := rl.ScopeLogs[0].LogRecords
+	require.Len(t, records, 2)
+	assert.Equal(t, "two", records[1].Body.GetStringValue())
+}
+
+func TestSinkPartialSuccess(t *testing.T) {
+	collector := &fakeCollector{rejected: 1}
+	sink, err := New(Config{Endpoint: startCollector(t, collector), Insecure: true, Batch: log.BatchConfig{FlushInterval: time.Hour}})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"msg":"old"}`))
+	require.NoError(t, sink.Sync())
+	assert.Equal(t, uint64(1), sink.Stats().Failed)
+}
+
+func TestSinkHTTP(t *testing.T) {
+	var mu sync.Mutex
+	var req collogspb.ExportLogsServiceRequest
+	var path, contentType, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		path, contentType, apiKey = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Api-Key")
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		require.NoError(t, proto.Unmarshal(body, &req))
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	sink, err := New(Config{
+		Protocol: HTTPProtobuf,
+		Endpoint: server.URL,
+		Headers:  map[string]string{"Api-Key": "secret"},
+		HTTP:     log.HTTPConfig{Compression: log.Gzip},
+		Batch:    log.BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"warn","msg":"slow"}`))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "/v1/logs", path)
+	assert.Equal(t, "application/x-protobuf", contentType)
+	assert.Equal(t, "secret", apiKey)
+	assert.Equal(t, "slow", req.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.GetStringValue())
+	assert.Equal(t, uint64(1), sink.Stats().Batches)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Protocol: "http/json"})
+	assert.Error(t, err)
+
+	sink, err := New(Config{})
+	require.NoError(t, err)
+	assert.NoError(t, sink.Close())
+}
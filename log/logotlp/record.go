@@ -0,0 +1,156 @@
+package logotlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// record converts an entry encoded as JSON with the default key names into a
+// log record. Entries that aren't JSON become a record of the line at info.
+func (c Config) record(line []byte, observed time.Time) *logspb.LogRecord {
+	rec := &logspb.LogRecord{
+		TimeUnixNano:         uint64(observed.UnixNano()),
+		ObservedTimeUnixNano: uint64(observed.UnixNano()),
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		rec.SeverityNumber, rec.SeverityText = severity("info")
+		rec.Body = stringValue(strings.TrimRight(string(line), "\r\n"))
+		return rec
+	}
+
+	if t, ok := entryTime(fields["ts"]); ok {
+		rec.TimeUnixNano = uint64(t.UnixNano())
+	}
+	level, _ := fields["level"].(string)
+	if level == "" {
+		level = "info"
+	}
+	rec.SeverityNumber, rec.SeverityText = severity(level)
+	msg, _ := fields["msg"].(string)
+	rec.Body = stringValue(msg)
+	if id, ok := fields[c.TraceIDKey].(string); ok {
+		if b, err := hex.DecodeString(id); err == nil && len(b) == 16 {
+			rec.TraceId = b
+			delete(fields, c.TraceIDKey)
+		}
+	}
+	if id, ok := fields[c.SpanIDKey].(string); ok {
+		if b, err := hex.DecodeString(id); err == nil && len(b) == 8 {
+			rec.SpanId = b
+			delete(fields, c.SpanIDKey)
+		}
+	}
+	if caller, ok := fields["caller"].(string); ok {
+		file, lineNo := caller, ""
+		if i := strings.LastIndexByte(caller, ':'); i > 0 {
+			file, lineNo = caller[:i], caller[i+1:]
+		}
+		rec.Attributes = append(rec.Attributes, &commonpb.KeyValue{Key: "code.filepath", Value: stringValue(file)})
+		if n, err := strconv.ParseInt(lineNo, 10, 64); err == nil {
+			rec.Attributes = append(rec.Attributes, &commonpb.KeyValue{Key: "code.lineno", Value: intValue(n)})
+		}
+	}
+	for _, key := range []string{"ts", "level", "msg", "caller"} {
+		delete(fields, key)
+	}
+
+	rec.Attributes = append(rec.Attributes, keyValues(fields)...)
+	return rec
+}
+
+// severity maps zap levels to OpenTelemetry severities.
+func severity(level string) (logspb.SeverityNumber, string) {
+	switch level {
+	case "debug":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "DEBUG"
+	case "info":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "INFO"
+	case "warn":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN, "WARN"
+	case "error":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "ERROR"
+	case "dpanic":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR2, "DPANIC"
+	case "panic":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL, "PANIC"
+	case "fatal":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL2, "FATAL"
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, strings.ToUpper(level)
+	}
+}
+
+// entryTime reads the ts field as epoch seconds or an RFC3339 string, the
+// time encodings of the production and development encoders.
+func entryTime(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case float64:
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000Z0700"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// keyValues converts fields to attributes, sorted by key.
+func keyValues(fields map[string]interface{}) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]*commonpb.KeyValue, len(keys))
+	for i, key := range keys {
+		kvs[i] = &commonpb.KeyValue{Key: key, Value: anyValue(fields[key])}
+	}
+	return kvs
+}
+
+// anyValue converts a decoded JSON value. Integral numbers become ints.
+func anyValue(v interface{}) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return stringValue(v)
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return intValue(int64(v))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i, elem := range v {
+			values[i] = anyValue(elem)
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]interface{}:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: keyValues(v)}}}
+	default:
+		return &commonpb.AnyValue{} // null
+	}
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func intValue(n int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: n}}
+}
@@ -0,0 +1,75 @@
+package logotlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRecord(t *testing.T) {
+	config := Config{TraceIDKey: "trace_id", SpanIDKey: "span_id"}
+	observed := time.Unix(1714557700, 0)
+
+	rec := config.record([]byte(`{"level":"warn","ts":1714557600.5,"caller":"api/user.go:42","msg":"slow",`+
+		`"ms":250,"ratio":0.5,"ok":true,"tags":["a"],"http":{"route":"/users"},"none":null,`+
+		`"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"}`), observed)
+
+	want := &logspb.LogRecord{
+		TimeUnixNano:         uint64(time.Unix(1714557600, 5e8).UnixNano()),
+		ObservedTimeUnixNano: uint64(observed.UnixNano()),
+		SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+		SeverityText:         "WARN",
+		Body:                 stringValue("slow"),
+		TraceId:              []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanId:               []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		Attributes: []*commonpb.KeyValue{
+			{Key: "code.filepath", Value: stringValue("api/user.go")},
+			{Key: "code.lineno", Value: intValue(42)},
+			{Key: "http", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+				Values: []*commonpb.KeyValue{{Key: "route", Value: stringValue("/users")}},
+			}}}},
+			{Key: "ms", Value: intValue(250)},
+			{Key: "none", Value: &commonpb.AnyValue{}},
+			{Key: "ok", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+			{Key: "ratio", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.5}}},
+			{Key: "tags", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+				Values: []*commonpb.AnyValue{stringValue("a")},
+			}}}},
+		},
+	}
+	assert.True(t, proto.Equal(want, rec), "got %v", rec)
+}
+
+func TestRecordNotJSON(t *testing.T) {
+	observed := time.Unix(1714557700, 0)
+	rec := Config{}.record([]byte("plain text\n"), observed)
+
+	assert.Equal(t, "plain text", rec.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, rec.SeverityNumber)
+	assert.Equal(t, uint64(observed.UnixNano()), rec.TimeUnixNano)
+}
+
+func TestRecordInvalidTraceID(t *testing.T) {
+	rec := Config{TraceIDKey: "trace_id", SpanIDKey: "span_id"}.record([]byte(`{"msg":"x","trace_id":"not-hex"}`), time.Now())
+
+	assert.Empty(t, rec.TraceId)
+	assert.Equal(t, "trace_id", rec.Attributes[0].Key, "kept as an attribute")
+}
+
+func TestSeverity(t *testing.T) {
+	for level, want := range map[string]logspb.SeverityNumber{
+		"debug":  logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+		"error":  logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+		"dpanic": logspb.SeverityNumber_SEVERITY_NUMBER_ERROR2,
+		"panic":  logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+		"fatal":  logspb.SeverityNumber_SEVERITY_NUMBER_FATAL2,
+		"custom": logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED,
+	} {
+		got, _ := severity(level)
+		assert.Equal(t, want, got, level)
+	}
+}
@@ -0,0 +1,197 @@
+// Package logredis logs the commands of go-redis clients through the log
+// package, with their latency and error.
+//
+//	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	rdb.AddHook(logredis.NewHook(logredis.WithSkipCommands("ping")))
+package logredis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// Option customizes a Hook.
+type Option func(*Hook)
+
+// WithLogger logs through l. By default commands are logged through the
+// logger of their context, see log.FromContext, so they carry the fields of
+// the request that ran them.
+func WithLogger(l *log.Logger) Option {
+	return func(h *Hook) {
+		h.logger = l
+	}
+}
+
+// WithLevel logs successful commands at level, Debug by default. Failed
+// commands are logged at Error.
+func WithLevel(level log.Level) Option {
+	return func(h *Hook) {
+		h.level = level
+	}
+}
+
+// WithSlowThreshold logs the successful commands lasting d or more at Warn.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(h *Hook) {
+		h.slowThreshold = d
+	}
+}
+
+// WithSkipCommands stops logging the given commands, like "ping", unless
+// they fail. Names are case insensitive; those of subcommands are written as
+// go-redis names them, like "client setinfo". A pipeline is skipped if all
+// its commands are.
+func WithSkipCommands(names ...string) Option {
+	return func(h *Hook) {
+		for _, name := range names {
+			h.skipped[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// Hook is a redis.Hook logging every command once it completes, with its
+// name, key, latency and error. Pipelines and transactions are logged as a
+// single entry with the names of their commands. Arguments other than the
+// key are left out, since they may hold secrets or large values. redis.Nil,
+// with which commands report missing keys, isn't an error. Failed dials are
+// logged too.
+type Hook struct {
+	logger        *log.Logger
+	level         log.Level
+	slowThreshold time.Duration
+	skipped       map[string]bool
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// NewHook returns a Hook, for the AddHook method of go-redis clients.
+func NewHook(opts ...Option) *Hook {
+	h := &Hook{level: log.DebugLevel, skipped: map[string]bool{}}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		start := time.Now()
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			h.log(ctx, log.ErrorLevel, "redis dial failed", "addr", addr, "latency", time.Since(start), "error", err)
+		}
+		return conn, err
+	}
+}
+
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		latency := time.Since(start)
+
+		failed := failure(err, cmd)
+		if failed == nil && h.skipped[cmd.FullName()] {
+			return err
+		}
+		keysAndValues := []interface{}{"command", cmd.FullName()}
+		if key := commandKey(cmd); key != "" {
+			keysAndValues = append(keysAndValues, "key", key)
+		}
+		keysAndValues = append(keysAndValues, "latency", latency)
+		if failed != nil {
+			keysAndValues = append(keysAndValues, "error", failed)
+		}
+		h.log(ctx, h.levelOf(failed, latency), "redis command", keysAndValues...)
+		return err
+	}
+}
+
+func (h *Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		latency := time.Since(start)
+
+		failed := failure(err, nil)
+		names := make([]string, len(cmds))
+		skipped := true
+		for i, cmd := range cmds {
+			names[i] = cmd.FullName()
+			skipped = skipped && h.skipped[names[i]]
+			if failed == nil {
+				failed = failure(nil, cmd)
+			}
+		}
+		if failed == nil && skipped {
+			return err
+		}
+		keysAndValues := []interface{}{"commands", names, "latency", latency}
+		if failed != nil {
+			keysAndValues = append(keysAndValues, "error", failed)
+		}
+		h.log(ctx, h.levelOf(failed, latency), "redis pipeline", keysAndValues...)
+		return err
+	}
+}
+
+func (h *Hook) levelOf(err error, latency time.Duration) log.Level {
+	switch {
+	case err != nil:
+		return log.ErrorLevel
+	case h.slowThreshold > 0 && latency >= h.slowThreshold:
+		return log.WarnLevel
+	default:
+		return h.level
+	}
+}
+
+func (h *Hook) log(ctx context.Context, level log.Level, msg string, keysAndValues ...interface{}) {
+	l := h.logger
+	if l == nil {
+		l = log.FromContext(ctx)
+	}
+	if l != nil {
+		l.Log(level, msg, keysAndValues...)
+	}
+}
+
+// failure returns err, or else the error of cmd if set, unless it's
+// redis.Nil.
+func failure(err error, cmd redis.Cmder) error {
+	if err == nil && cmd != nil {
+		err = cmd.Err()
+	}
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
+
+// keylessCommands are the commands whose first argument isn't a key, some of
+// them secret, like the password of AUTH.
+var keylessCommands = map[string]bool{
+	"auth": true, "hello": true, "acl": true, "config": true, "migrate": true,
+	"echo": true, "ping": true, "select": true, "eval": true, "evalsha": true,
+	"eval_ro": true, "evalsha_ro": true, "fcall": true, "fcall_ro": true,
+	"script": true, "function": true,
+}
+
+// commandKey returns the first argument of cmd after its name, the key of
+// most commands, or "" if it has none, a subcommand or another first
+// argument.
+func commandKey(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 || strings.Contains(cmd.FullName(), " ") || keylessCommands[cmd.Name()] {
+		return ""
+	}
+	key, _ := args[1].(string)
+	return key
+}
@@ -0,0 +1,121 @@
+package logredis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func testLogger(buf *bytes.Buffer) *log.Logger {
+	return log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+}
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+// process runs cmd through the hook, failing it with err.
+func process(h *Hook, cmd redis.Cmder, err error) {
+	_ = h.ProcessHook(func(_ context.Context, cmd redis.Cmder) error {
+		cmd.SetErr(err)
+		return err
+	})(context.Background(), cmd)
+}
+
+func TestProcessHook(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHook(WithLogger(testLogger(&buf)), WithSkipCommands("PING"))
+	ctx := context.Background()
+
+	process(h, redis.NewStringCmd(ctx, "get", "session:42"), nil)
+	process(h, redis.NewStringCmd(ctx, "get", "missing"), redis.Nil)
+	process(h, redis.NewStatusCmd(ctx, "auth", "hunter2"), errors.New("WRONGPASS invalid password"))
+	process(h, redis.NewStatusCmd(ctx, "ping"), nil)
+	process(h, redis.NewStatusCmd(ctx, "ping"), errors.New("connection refused"))
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 4)
+	assert.Equal(t, "DEBUG", logged[0]["level"])
+	assert.Equal(t, "redis command", logged[0]["msg"])
+	assert.Equal(t, "get", logged[0]["command"])
+	assert.Equal(t, "session:42", logged[0]["key"])
+	assert.Contains(t, logged[0], "latency")
+	assert.Equal(t, "DEBUG", logged[1]["level"]) // redis.Nil isn't an error
+	assert.Equal(t, "ERROR", logged[2]["level"])
+	assert.NotContains(t, logged[2], "key")
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Equal(t, "ping", logged[3]["command"]) // Skipped unless it fails
+	assert.Equal(t, "connection refused", logged[3]["error"])
+}
+
+func TestProcessPipelineHook(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHook(WithLogger(testLogger(&buf)), WithSkipCommands("ping"), WithSlowThreshold(5*time.Millisecond))
+	ctx := context.Background()
+
+	pipeline := func(cmds ...redis.Cmder) {
+		_ = h.ProcessPipelineHook(func(context.Context, []redis.Cmder) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})(ctx, cmds)
+	}
+	pipeline(redis.NewStatusCmd(ctx, "set", "a", "1"), redis.NewIntCmd(ctx, "incr", "b"))
+	pipeline(redis.NewStatusCmd(ctx, "ping"))
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "WARN", logged[0]["level"])
+	assert.Equal(t, "redis pipeline", logged[0]["msg"])
+	assert.Equal(t, []interface{}{"set", "incr"}, logged[0]["commands"])
+}
+
+func TestDialHook(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHook()
+
+	ctx := log.WithContext(context.Background(), testLogger(&buf))
+	_, err := h.DialHook(func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	})(ctx, "tcp", "localhost:6379")
+	require.Error(t, err)
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "redis dial failed", logged[0]["msg"])
+	assert.Equal(t, "localhost:6379", logged[0]["addr"])
+}
+
+func TestClient(t *testing.T) {
+	var buf bytes.Buffer
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer rdb.Close()
+	rdb.AddHook(NewHook(WithLogger(testLogger(&buf))))
+
+	require.Error(t, rdb.Get(context.Background(), "k").Err())
+
+	logged := entries(t, &buf)
+	require.NotEmpty(t, logged)
+	assert.Equal(t, "redis dial failed", logged[0]["msg"])
+	assert.Equal(t, "get", logged[len(logged)-1]["command"])
+}
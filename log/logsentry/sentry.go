@@ -0,0 +1,186 @@
+// Package logsentry forwards Error entries and above to Sentry, with their
+// stack traces and fields, through a log hook.
+//
+//	_ = sentry.Init(sentry.ClientOptions{Dsn: dsn})
+//	log.InitLogger(false, logsentry.WithSentry(logsentry.WithRelease(version)))
+package logsentry
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// defaultFlushTimeout bounds how long Panic and Fatal entries wait for Sentry.
+const defaultFlushTimeout = 2 * time.Second
+
+// Option customizes the hook.
+type Option func(*options)
+
+type options struct {
+	hub          *sentry.Hub
+	level        log.Level
+	sampleRate   float64
+	environment  string
+	release      string
+	flushTimeout time.Duration
+}
+
+// WithHub reports through hub instead of sentry.CurrentHub.
+func WithHub(hub *sentry.Hub) Option {
+	return func(o *options) {
+		o.hub = hub
+	}
+}
+
+// WithLevel forwards entries at level and above, Error by default.
+func WithLevel(level log.Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithSampleRate forwards a random fraction rate of the Error entries, 1 by
+// default. Panic and Fatal entries are always forwarded.
+func WithSampleRate(rate float64) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithEnvironment tags the events with environment, overriding the one of the
+// client options.
+func WithEnvironment(environment string) Option {
+	return func(o *options) {
+		o.environment = environment
+	}
+}
+
+// WithRelease tags the events with release, overriding the one of the client
+// options.
+func WithRelease(release string) Option {
+	return func(o *options) {
+		o.release = release
+	}
+}
+
+// WithFlushTimeout sets how long Panic and Fatal entries wait for their event
+// to be sent before the process goes down, 2 seconds by default.
+func WithFlushTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.flushTimeout = timeout
+	}
+}
+
+// WithSentry returns a log option forwarding entries to Sentry as events: the
+// message as the event message, the error logged under "error" as the
+// exception, the stack trace of the entry as its stack trace, and the other
+// fields as extras. Events are sent asynchronously by the Sentry client,
+// except for Panic and Fatal entries, which wait for them.
+func WithSentry(opts ...Option) log.Option {
+	o := &options{level: log.ErrorLevel, sampleRate: 1, flushTimeout: defaultFlushTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return log.WithHook(o.level, o.capture)
+}
+
+func (o *options) capture(entry log.HookEntry) {
+	critical := entry.Level >= log.PanicLevel
+	if !critical && o.sampleRate < 1 && rand.Float64() >= o.sampleRate {
+		return
+	}
+	hub := o.hub
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+
+	hub.CaptureEvent(o.event(entry))
+	if critical {
+		hub.Flush(o.flushTimeout)
+	}
+}
+
+func (o *options) event(entry log.HookEntry) *sentry.Event {
+	event := sentry.NewEvent()
+	event.Level = level(entry.Level)
+	event.Message = entry.Message
+	event.Timestamp = entry.Time
+	event.Logger = entry.Logger
+	event.Environment = o.environment
+	event.Release = o.release
+	for key, value := range entry.Fields {
+		if key != "error" {
+			event.Extra[key] = value
+		}
+	}
+	if entry.Caller != "" {
+		event.Extra["caller"] = entry.Caller
+	}
+
+	stack := parseStack(entry.Stack)
+	if entry.Err != nil {
+		event.Exception = []sentry.Exception{{
+			Type:       reflect.TypeOf(entry.Err).String(),
+			Value:      entry.Err.Error(),
+			Stacktrace: stack,
+		}}
+	} else if stack != nil {
+		event.Threads = []sentry.Thread{{Stacktrace: stack, Current: true, Crashed: entry.Level >= log.PanicLevel}}
+	}
+	return event
+}
+
+// level maps log levels to Sentry levels.
+func level(l log.Level) sentry.Level {
+	switch {
+	case l > log.ErrorLevel: // DPanic, Panic and Fatal
+		return sentry.LevelFatal
+	case l >= log.ErrorLevel:
+		return sentry.LevelError
+	case l >= log.WarnLevel:
+		return sentry.LevelWarning
+	case l >= log.InfoLevel:
+		return sentry.LevelInfo
+	default:
+		return sentry.LevelDebug
+	}
+}
+
+// parseStack reads a stack trace as zap formats it, a function line followed
+// by a tab-indented file:line line per frame, innermost first. Sentry wants
+// frames outermost first.
+func parseStack(stack string) *sentry.Stacktrace {
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	var frames []sentry.Frame
+	for i := 0; i+1 < len(lines); i += 2 {
+		location := strings.TrimSpace(lines[i+1])
+		colon := strings.LastIndexByte(location, ':')
+		if colon < 0 {
+			continue
+		}
+		line, err := strconv.Atoi(location[colon+1:])
+		if err != nil {
+			continue
+		}
+		frames = append(frames, sentry.NewFrame(runtime.Frame{
+			Function: strings.TrimSpace(lines[i]),
+			File:     location[:colon],
+			Line:     line,
+		}))
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return &sentry.Stacktrace{Frames: frames}
+}
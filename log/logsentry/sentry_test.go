@@ -0,0 +1,115 @@
+package logsentry
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+type fakeTransport struct {
+	mu      sync.Mutex
+	events  []*sentry.Event
+	flushes int
+}
+
+func (t *fakeTransport) Configure(sentry.ClientOptions) {}
+
+func (t *fakeTransport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.events = append(t.events, event)
+}
+
+func (t *fakeTransport) Flush(time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.flushes++
+	return true
+}
+
+func newTestHub(t *testing.T) (*sentry.Hub, *fakeTransport) {
+	transport := &fakeTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Dsn: "https://key@sentry.example.com/1", Transport: transport})
+	require.NoError(t, err)
+	return sentry.NewHub(client, sentry.NewScope()), transport
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "timeout" }
+
+func TestWithSentry(t *testing.T) {
+	hub, transport := newTestHub(t)
+	l, err := log.NewLogger(false,
+		log.WithSink(zapcore.AddSync(&bytes.Buffer{})),
+		WithSentry(WithHub(hub), WithEnvironment("prod"), WithRelease("v1.2.3")),
+	)
+	require.NoError(t, err)
+
+	l.Warn("not forwarded")
+	l.Named("db").Error("query failed", "error", timeoutError{}, "table", "users")
+	l.Error("no error field")
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	require.Len(t, transport.events, 2)
+	event := transport.events[0]
+	assert.Equal(t, sentry.LevelError, event.Level)
+	assert.Equal(t, "query failed", event.Message)
+	assert.Equal(t, "db", event.Logger)
+	assert.Equal(t, "prod", event.Environment)
+	assert.Equal(t, "v1.2.3", event.Release)
+	assert.Equal(t, "users", event.Extra["table"])
+	assert.Contains(t, event.Extra["caller"], "logsentry/sentry_test.go:")
+	assert.NotContains(t, event.Extra, "error")
+	require.Len(t, event.Exception, 1)
+	assert.Equal(t, "logsentry.timeoutError", event.Exception[0].Type)
+	assert.Equal(t, "timeout", event.Exception[0].Value)
+	frames := event.Exception[0].Stacktrace.Frames
+	assert.Equal(t, "TestWithSentry", frames[len(frames)-1].Function, "innermost frame last")
+
+	require.Len(t, transport.events[1].Threads, 1)
+	assert.NotEmpty(t, transport.events[1].Threads[0].Stacktrace.Frames)
+	assert.Zero(t, transport.flushes, "errors aren't waited for")
+}
+
+func TestWithSentryPanicFlushes(t *testing.T) {
+	hub, transport := newTestHub(t)
+	l, err := log.NewLogger(false,
+		log.WithSink(zapcore.AddSync(&bytes.Buffer{})),
+		WithSentry(WithHub(hub), WithSampleRate(0)),
+	)
+	require.NoError(t, err)
+
+	l.Error("sampled out")
+	assert.Panics(t, func() { l.Panic("invariant broken", "error", errors.New("nil map")) })
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	require.Len(t, transport.events, 1, "panics bypass sampling")
+	assert.Equal(t, sentry.LevelFatal, transport.events[0].Level)
+	assert.Equal(t, 1, transport.flushes)
+}
+
+func TestParseStack(t *testing.T) {
+	stack := parseStack("main.handler\n\t/app/main.go:42\nmain.main\n\t/app/main.go:10")
+	require.NotNil(t, stack)
+	require.Len(t, stack.Frames, 2)
+	assert.Equal(t, "main", stack.Frames[0].Function)
+	assert.Equal(t, 10, stack.Frames[0].Lineno)
+	assert.Equal(t, "handler", stack.Frames[1].Function)
+	assert.Equal(t, "/app/main.go", stack.Frames[1].AbsPath)
+
+	assert.Nil(t, parseStack(""))
+}
@@ -0,0 +1,123 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultLogstashPort is used when LogstashConfig.Addr has no port.
+const defaultLogstashPort = "5000"
+
+// LogstashConfig configures a sink writing to a Logstash TCP input with the
+// json_lines codec.
+type LogstashConfig struct {
+	Addr string     // host:port of the input; the port defaults to 5000
+	TLS  *TLSConfig // Optional, see TLSConfig
+
+	// Fields are added to every event unless the entry has a field of the
+	// same name, like a type or environment to route on.
+	Fields map[string]string
+
+	// Reconnect tunes the backoff and retention of the underlying
+	// ReconnectingSink.
+	Reconnect []ReconnectOption
+}
+
+// LogstashSink writes entries to Logstash as newline-delimited JSON events,
+// over a connection redialed with backoff when it fails, see
+// ReconnectingSink. Entries must be JSON encoded: ts becomes @timestamp, msg
+// message, and the other fields are kept as they are.
+type LogstashSink struct {
+	*ReconnectingSink
+	sinkKeys
+	fields map[string]string
+}
+
+// NewLogstashSink returns a LogstashSink. It connects lazily, so an
+// unreachable Logstash doesn't prevent the logger from starting.
+func NewLogstashSink(config LogstashConfig) (*LogstashSink, error) {
+	if config.Addr == "" {
+		return nil, errors.New("logstash sink needs an address")
+	}
+	if _, _, err := net.SplitHostPort(config.Addr); err != nil {
+		config.Addr = net.JoinHostPort(config.Addr, defaultLogstashPort)
+	}
+
+	dial := func() (io.WriteCloser, error) {
+		return net.DialTimeout("tcp", config.Addr, defaultDialTimeout)
+	}
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		dial = TLSDialer(config.Addr, tlsConfig)
+	}
+
+	return &LogstashSink{
+		ReconnectingSink: NewReconnectingSink("logstash "+config.Addr, dial, config.Reconnect...),
+		fields:           config.Fields,
+	}, nil
+}
+
+// Write sends the entry in p as a Logstash event. Like ReconnectingSink.Write,
+// it never fails.
+func (s *LogstashSink) Write(p []byte) (int, error) {
+	_, _ = s.ReconnectingSink.Write(logstashEvent(s.decode(p), s.fields))
+	return len(p), nil
+}
+
+// logstashEvent maps e to a Logstash event, as a JSON line.
+func logstashEvent(e sinkEntry, fields map[string]string) []byte {
+	event := e.Fields
+	for key, value := range fields {
+		if _, ok := event[key]; !ok {
+			event[key] = value
+		}
+	}
+	event["@timestamp"] = e.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	event["@version"] = "1"
+	event["message"] = e.Message
+	event["level"] = e.Level
+	if e.Caller != "" {
+		event["caller"] = e.Caller
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		line, _ = json.Marshal(map[string]interface{}{
+			"@timestamp": event["@timestamp"],
+			"@version":   "1",
+			"message":    e.Message,
+			"level":      e.Level,
+			"error":      err.Error(),
+		})
+	}
+	return append(line, '\n')
+}
+
+// logstashSinkFactory opens LogstashSinks for logstash:// URLs, as in
+// logstash://logstash:5000. A tls=true parameter connects over TLS with the
+// system roots.
+func logstashSinkFactory(u *url.URL) (zapcore.WriteSyncer, error) {
+	config := LogstashConfig{Addr: u.Host}
+	for key, values := range u.Query() {
+		switch key {
+		case "tls":
+			if values[len(values)-1] == "true" {
+				config.TLS = &TLSConfig{}
+			}
+		default:
+			return nil, fmt.Errorf("logstash sink %q: unknown parameter %q", u.String(), key)
+		}
+	}
+	return NewLogstashSink(config)
+}
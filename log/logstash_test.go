@@ -0,0 +1,74 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogstashSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	sink, err := NewLogstashSink(LogstashConfig{
+		Addr:   ln.Addr().String(),
+		Fields: map[string]string{"type": "api", "env": "prod"},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	n, err := sink.Write([]byte(`{"level":"warn","ts":1714557600.5,"caller":"api/user.go:42","msg":"slow","ms":250,"env":"staging"}` + "\n"))
+	require.NoError(t, err)
+	assert.Greater(t, n, 0)
+
+	select {
+	case line := <-lines:
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		assert.Equal(t, map[string]interface{}{
+			"@timestamp": "2024-05-01T10:00:00.500Z",
+			"@version":   "1",
+			"message":    "slow",
+			"level":      "warn",
+			"caller":     "api/user.go:42",
+			"ms":         float64(250),
+			"env":        "staging",
+			"type":       "api",
+		}, event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+}
+
+func TestLogstashSinkURL(t *testing.T) {
+	sink, err := OpenSink("logstash://localhost")
+	require.NoError(t, err)
+	assert.Equal(t, "logstash localhost:5000", sink.(*LogstashSink).Name())
+	assert.NoError(t, sink.(*LogstashSink).Close())
+
+	_, err = OpenSink("logstash://localhost?rotate=true")
+	assert.ErrorContains(t, err, `unknown parameter "rotate"`)
+
+	_, err = NewLogstashSink(LogstashConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+// Package logtestcontainers forwards the output of testcontainers-go
+// containers to the log package, so container logs interleave with the logs
+// of the test driving them.
+package logtestcontainers
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/testcontainers/testcontainers-go"
+
+	"github.com/Stasky745/go-libs/log"
+	"github.com/Stasky745/go-libs/log/logjson"
+)
+
+// Keys of the fields added to forwarded lines.
+const (
+	ContainerKey = "container"
+	StreamKey    = "stream"
+)
+
+// Option customizes a Consumer.
+type Option func(*Consumer)
+
+// WithLogger logs through l instead of the global logger.
+func WithLogger(l *log.Logger) Option {
+	return func(c *Consumer) {
+		c.logger = l
+	}
+}
+
+// WithLevel logs every line at level instead of inferring it.
+func WithLevel(level log.Level) Option {
+	return func(c *Consumer) {
+		c.level = &level
+	}
+}
+
+// Consumer is a testcontainers.LogConsumer logging each line a container
+// writes, with the container name and the stream as fields. Register it in the
+// container request:
+//
+//	LogConsumerCfg: &testcontainers.LogConsumerConfig{
+//		Consumers: []testcontainers.LogConsumer{logtestcontainers.New("postgres")},
+//	}
+type Consumer struct {
+	name   string
+	logger *log.Logger
+	level  *log.Level
+	json   *logjson.Decoder
+}
+
+var _ testcontainers.LogConsumer = (*Consumer)(nil)
+
+// New returns a Consumer for the container called name. Unless WithLevel is
+// given, the level of each line is inferred: from the level field of JSON
+// lines, or else from the first severity word found, like "ERROR" or "warn".
+// Lines without any are logged at Info.
+func New(name string, opts ...Option) *Consumer {
+	c := &Consumer{name: name, json: logjson.NewDecoder(nil)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Accept logs every line of l.
+func (c *Consumer) Accept(l testcontainers.Log) {
+	logger := c.logger
+	if logger == nil {
+		logger = log.GetLogger()
+	}
+	stream := "stdout"
+	if l.LogType == testcontainers.StderrLog {
+		stream = "stderr"
+	}
+
+	for _, line := range bytes.Split(l.Content, []byte{'\n'}) {
+		line = bytes.TrimRight(line, "\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		level := c.inferLevel(line)
+		if c.level != nil {
+			level = *c.level
+		}
+		logger.Log(level, string(line), ContainerKey, c.name, StreamKey, stream)
+	}
+}
+
+// severity finds the first severity word of a line, as written by most
+// servers: "ERROR", "[warn]", "level=debug", "FATAL:"...
+var severity = regexp.MustCompile(`(?i)\b(trace|debug|info|notice|warn|warning|error|err|crit|critical|fatal|panic)\b`)
+
+func (c *Consumer) inferLevel(line []byte) log.Level {
+	if line[0] == '{' {
+		if e := c.json.Decode(line); e.Fields != nil {
+			return clampLevel(log.Level(e.Level))
+		}
+	}
+
+	word := severity.Find(line)
+	if word == nil {
+		return log.InfoLevel
+	}
+	switch strings.ToLower(string(word)) {
+	case "trace", "debug":
+		return log.DebugLevel
+	case "warn", "warning":
+		return log.WarnLevel
+	case "error", "err", "crit", "critical", "fatal", "panic":
+		// A container crashing mustn't take the test process down with it.
+		return log.ErrorLevel
+	}
+	return log.InfoLevel
+}
+
+// clampLevel keeps the levels decoded from JSON lines from panicking or
+// exiting the test process.
+func clampLevel(level log.Level) log.Level {
+	if level > log.ErrorLevel {
+		return log.ErrorLevel
+	}
+	return level
+}
@@ -0,0 +1,71 @@
+package logtestcontainers
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Stasky745/go-libs/log"
+	"github.com/Stasky745/go-libs/log/logjson"
+)
+
+func testLogger(buf *bytes.Buffer) *log.Logger {
+	return log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+}
+
+func entries(t *testing.T, buf *bytes.Buffer) []logjson.Entry {
+	t.Helper()
+
+	var out []logjson.Entry
+	d := logjson.NewDecoder(buf)
+	for {
+		e, err := d.Next()
+		if err == io.EOF {
+			return out
+		}
+		require.NoError(t, err)
+		out = append(out, e)
+	}
+}
+
+func TestConsumerInfersLevels(t *testing.T) {
+	var buf bytes.Buffer
+	c := New("postgres", WithLogger(testLogger(&buf)))
+
+	c.Accept(testcontainers.Log{LogType: testcontainers.StderrLog, Content: []byte(
+		"2024-05-01 10:00:00 UTC [1] LOG:  database system is ready\n" +
+			"2024-05-01 10:00:01 UTC [7] ERROR:  relation \"users\" does not exist\r\n\n")})
+	c.Accept(testcontainers.Log{LogType: testcontainers.StdoutLog, Content: []byte(
+		`{"level":"fatal","msg":"boom"}` + "\n" + `time="..." level=warning msg="slow disk"` + "\n[debug] cache warm\n")})
+
+	got := entries(t, &buf)
+	require.Len(t, got, 5)
+
+	levels := make([]zapcore.Level, len(got))
+	for i, e := range got {
+		levels[i] = e.Level
+		assert.Equal(t, "postgres", e.Fields[ContainerKey])
+	}
+	assert.Equal(t, []zapcore.Level{zapcore.InfoLevel, zapcore.ErrorLevel, zapcore.ErrorLevel, zapcore.WarnLevel, zapcore.DebugLevel}, levels)
+
+	assert.Equal(t, "2024-05-01 10:00:01 UTC [7] ERROR:  relation \"users\" does not exist", got[1].Message)
+	assert.Equal(t, "stderr", got[1].Fields[StreamKey])
+	assert.Equal(t, "stdout", got[2].Fields[StreamKey])
+}
+
+func TestConsumerWithLevel(t *testing.T) {
+	var buf bytes.Buffer
+	c := New("redis", WithLogger(testLogger(&buf)), WithLevel(log.DebugLevel))
+
+	c.Accept(testcontainers.Log{LogType: testcontainers.StdoutLog, Content: []byte("# WARNING overcommit_memory is set to 0")})
+
+	got := entries(t, &buf)
+	require.Len(t, got, 1)
+	assert.Equal(t, zapcore.DebugLevel, got[0].Level)
+}
@@ -0,0 +1,33 @@
+// Package logtoml lets LoadConfig, ReadConfig and WatchConfig of the log
+// package read TOML configuration files, .toml, keeping the TOML parser out
+// of programs that don't need it:
+//
+//	import _ "github.com/Stasky745/go-libs/log/logtoml"
+package logtoml
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func init() {
+	if err := log.RegisterConfigFormat(".toml", Decode); err != nil {
+		panic(err)
+	}
+}
+
+// Decode decodes a TOML configuration file into config, rejecting unknown
+// settings.
+func Decode(data []byte, config *log.Config) error {
+	meta, err := toml.Decode(string(data), config)
+	if err != nil {
+		return err
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("unknown setting %q", undecoded[0].String())
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package logtoml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "app.log")
+	l, err := log.LoadConfig(writeConfig(t, "log.toml", `
+level = "warn"
+outputs = ["`+out+`"]
+time_zone = "Europe/Madrid"
+
+[modules]
+db = "debug"
+
+[sampling]
+initial = 0
+
+[fields]
+service = "api"
+version = 3
+`))
+	require.NoError(t, err)
+	assert.Equal(t, log.WarnLevel, l.GetLevel())
+
+	l.Info("hidden")
+	l.Warn("shown")
+	l.Named("db").Debug("db debug")
+
+	logged, err := os.ReadFile(out)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(logged)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"msg":"shown"`)
+	assert.Contains(t, lines[0], `"service":"api","version":3`)
+	assert.Contains(t, lines[1], `"msg":"db debug"`)
+}
+
+func TestReadConfigUnknownSetting(t *testing.T) {
+	_, err := log.ReadConfig(writeConfig(t, "log.toml", `levle = "debug"`))
+	assert.ErrorContains(t, err, "levle")
+}
@@ -0,0 +1,30 @@
+// Package logyaml lets LoadConfig, ReadConfig and WatchConfig of the log
+// package read YAML configuration files, .yaml or .yml, keeping the YAML
+// parser out of programs that don't need it:
+//
+//	import _ "github.com/Stasky745/go-libs/log/logyaml"
+package logyaml
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func init() {
+	for _, ext := range []string{".yaml", ".yml"} {
+		if err := log.RegisterConfigFormat(ext, Decode); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Decode decodes a YAML configuration file into config, rejecting unknown
+// settings.
+func Decode(data []byte, config *log.Config) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(config)
+}
@@ -0,0 +1,28 @@
+// Package logzstd registers the log.Zstd compression for the HTTP sinks of
+// the log package, keeping the zstd encoder out of programs that don't need
+// it:
+//
+//	import _ "github.com/Stasky745/go-libs/log/logzstd"
+//
+// Without it, sinks configured with log.Zstd fall back to another
+// compression their backend supports.
+package logzstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func init() {
+	if err := log.RegisterCompression(log.Zstd, NewWriter); err != nil {
+		panic(err)
+	}
+}
+
+// NewWriter returns a zstd encoder writing to w.
+func NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
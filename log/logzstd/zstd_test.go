@@ -0,0 +1,58 @@
+package logzstd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func TestHoneycombSinkZstd(t *testing.T) {
+	var mu sync.Mutex
+	var encoding, body string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		encoding = r.Header.Get("Content-Encoding")
+		zr, err := zstd.NewReader(r.Body)
+		require.NoError(t, err)
+		defer zr.Close()
+		raw, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		body = string(raw)
+		_, _ = w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer server.Close()
+
+	sink, err := log.NewHoneycombSink(log.HoneycombConfig{
+		APIKey:  "key",
+		Dataset: "logs",
+		APIHost: server.URL,
+		HTTP:    log.HTTPConfig{Compression: log.Zstd},
+		Batch:   log.BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"info","ts":1714557600,"msg":"compressed"}`))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "zstd", encoding)
+	assert.Contains(t, body, `"msg":"compressed"`)
+}
+
+func TestRegisteredOnce(t *testing.T) {
+	assert.ErrorContains(t, log.RegisterCompression(log.Zstd, NewWriter), `"zstd" is already registered`)
+}
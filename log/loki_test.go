@@ -0,0 +1,147 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLokiSink(t *testing.T) {
+	var mu sync.Mutex
+	var push lokiPush
+	var tenant, user, password, path string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		tenant, path = r.Header.Get("X-Scope-OrgID"), r.URL.Path
+		user, password, _ = r.BasicAuth()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&push))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiConfig{
+		URL:          server.URL + "/",
+		Labels:       []string{"service", "level", "k8s.namespace"},
+		StaticLabels: map[string]string{"job": "api"},
+		TenantID:     "team-a",
+		Username:     "user",
+		Password:     "secret",
+		Batch:        BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	lines := []string{
+		`{"level":"info","ts":1714557600,"msg":"one","service":"users","k8s":{"namespace":"prod"}}`,
+		`{"level":"warn","ts":1714557601,"msg":"two","service":"users"}`,
+		`{"level":"info","ts":1714557602,"msg":"three","service":"users","k8s":{"namespace":"prod"}}`,
+	}
+	for _, line := range lines {
+		_, _ = sink.Write([]byte(line + "\n"))
+	}
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "/loki/api/v1/push", path)
+	assert.Equal(t, "team-a", tenant)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, []lokiStream{
+		{
+			Stream: map[string]string{"job": "api", "service": "users", "level": "info", "k8s_namespace": "prod"},
+			Values: [][2]string{{"1714557600000000000", lines[0]}, {"1714557602000000000", lines[2]}},
+		},
+		{
+			Stream: map[string]string{"job": "api", "service": "users", "level": "warn"},
+			Values: [][2]string{{"1714557601000000000", lines[1]}},
+		},
+	}, push.Streams)
+}
+
+func TestLokiSinkRetries(t *testing.T) {
+	var mu sync.Mutex
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiConfig{URL: server.URL, Backoff: fastBackoff, Batch: BatchConfig{FlushInterval: time.Hour}})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"info","ts":1714557600,"msg":"one"}` + "\n"))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, uint64(1), sink.Stats().Batches)
+}
+
+func TestLokiSinkURL(t *testing.T) {
+	var path, tenant, user, password string
+	pushed := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, tenant = r.URL.Path, r.Header.Get("X-Scope-OrgID")
+		user, password, _ = r.BasicAuth()
+		w.WriteHeader(http.StatusNoContent)
+		pushed <- struct{}{}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	sink, err := OpenSink("loki://user:secret@" + host + "/gateway?tenant=team-a")
+	require.NoError(t, err)
+	_, _ = sink.Write([]byte(`{"level":"info","msg":"one"}` + "\n"))
+	require.NoError(t, sink.Sync())
+	<-pushed
+	require.NoError(t, sink.(*BatchSink).Close())
+
+	assert.Equal(t, "/gateway/loki/api/v1/push", path)
+	assert.Equal(t, "team-a", tenant)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "secret", password)
+
+	for _, rawURL := range []string{"loki:///api", "loki://host?tls=maybe", "loki://host?label=x"} {
+		_, err := OpenSink(rawURL)
+		assert.Error(t, err, rawURL)
+	}
+}
+
+func TestLokiLabelName(t *testing.T) {
+	assert.Equal(t, "k8s_pod_name", lokiLabelName("k8s.pod-name"))
+	assert.Equal(t, "_xx", lokiLabelName("2xx"))
+}
+
+func TestLokiSinkConfig(t *testing.T) {
+	_, err := NewLokiSink(LokiConfig{})
+	assert.Error(t, err)
+
+	sink, err := NewLokiSink(LokiConfig{URL: "http://loki:3100"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"level": "error", "env": "prod"},
+		LokiConfig{Labels: defaultLokiLabels}.labels(decodeSinkEntry([]byte(`{"level":"error","env":"prod","user":1}`), defaultEntryKeys)))
+	assert.NoError(t, sink.Close())
+}
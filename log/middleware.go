@@ -0,0 +1,104 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MiddlewareOption customizes the HTTP middleware.
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	paths      []levelRule
+	userAgents []levelRule
+	bodies     *bodyOptions
+	route      func(*http.Request) string
+
+	redactedKeys []string
+}
+
+// levelRule sets the level of the requests matching pattern; skip drops them.
+type levelRule struct {
+	pattern string
+	level   Level
+	skip    bool
+}
+
+// WithSkipPaths stops logging the requests to paths, such as health checks
+// and metrics endpoints. A path ending in "/*" matches every path below it.
+func WithSkipPaths(paths ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		for _, p := range paths {
+			o.paths = append(o.paths, levelRule{pattern: p, skip: true})
+		}
+	}
+}
+
+// WithPathLevel logs the requests to paths at level instead of the level
+// their status calls for, unless they fail with a 5xx status. Paths are
+// matched as in WithSkipPaths.
+func WithPathLevel(level Level, paths ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		for _, p := range paths {
+			o.paths = append(o.paths, levelRule{pattern: p, level: level})
+		}
+	}
+}
+
+// WithSkipUserAgents stops logging the requests whose User-Agent starts with
+// one of prefixes, such as "kube-probe/".
+func WithSkipUserAgents(prefixes ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		for _, p := range prefixes {
+			o.userAgents = append(o.userAgents, levelRule{pattern: p, skip: true})
+		}
+	}
+}
+
+// WithUserAgentLevel logs the requests whose User-Agent starts with one of
+// prefixes at level, unless they fail with a 5xx status.
+func WithUserAgentLevel(level Level, prefixes ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		for _, p := range prefixes {
+			o.userAgents = append(o.userAgents, levelRule{pattern: p, level: level})
+		}
+	}
+}
+
+// WithRoute logs the route of each request as route, as returned by route
+// once the request is handled, so that requests can be grouped by the
+// pattern they matched, like /users/{id}, rather than by path. Routers set
+// the pattern while routing, so the middleware must run inside the router,
+// as with chi's or gorilla/mux's Use. The logchi and logmux packages provide
+// route for those routers. Requests without a route, like those no pattern
+// matched, are logged without one.
+func WithRoute(route func(r *http.Request) string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.route = route
+	}
+}
+
+// rule returns the first rule matching r, paths first.
+func (o *middlewareOptions) rule(r *http.Request) (levelRule, bool) {
+	for _, rule := range o.paths {
+		if matchPath(rule.pattern, r.URL.Path) {
+			return rule, true
+		}
+	}
+	ua := r.UserAgent()
+	for _, rule := range o.userAgents {
+		if strings.HasPrefix(ua, rule.pattern) {
+			return rule, true
+		}
+	}
+	return levelRule{}, false
+}
+
+func matchPath(pattern, path string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+	return path == pattern
+}
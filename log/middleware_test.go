@@ -0,0 +1,90 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func serve(h http.Handler, path, userAgent string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("User-Agent", userAgent)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestHTTPMiddlewareSkips(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	h := HTTPMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		WithSkipPaths("/healthz", "/metrics/*"),
+		WithSkipUserAgents("kube-probe/"),
+	)
+
+	serve(h, "/healthz", "curl/8.0")
+	serve(h, "/metrics/go", "curl/8.0")
+	serve(h, "/ready", "kube-probe/1.29")
+	serve(h, "/metricsfoo", "curl/8.0") // Not below /metrics
+
+	assert.Equal(t, 1, strings.Count(buf.String(), "http request"))
+	assert.Contains(t, buf.String(), "/metricsfoo")
+}
+
+func TestHTTPMiddlewareDowngrades(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLogger(false, WithLevel(DebugLevel), WithTee(TeeSink{Sink: zapcore.AddSync(&buf), Level: DebugLevel}))
+	require.NoError(t, err)
+
+	failing := false
+	h := l.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}), WithPathLevel(DebugLevel, "/readyz"))
+
+	serve(h, "/readyz", "")
+	failing = true
+	serve(h, "/readyz", "") // Failures stay visible
+
+	var levels []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, "http request", entry["msg"])
+		assert.True(t, strings.HasPrefix(entry["caller"].(string), "log/accesslog.go:"), entry["caller"]) // The middleware, not log.go
+		levels = append(levels, entry["level"].(string))
+	}
+	assert.Equal(t, []string{"debug", "error"}, levels)
+}
+
+func TestHTTPMiddlewareRoute(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	h := HTTPMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		WithRoute(func(r *http.Request) string {
+			if strings.HasPrefix(r.URL.Path, "/users/") {
+				return "/users/{id}"
+			}
+			return ""
+		}),
+	)
+
+	serve(h, "/users/42", "curl/8.0")
+	serve(h, "/nowhere", "curl/8.0")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"path": "/users/42"`)
+	assert.Contains(t, lines[0], `"route": "/users/{id}"`)
+	assert.NotContains(t, lines[1], `"route"`)
+}
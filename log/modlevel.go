@@ -0,0 +1,182 @@
+//go:build !nozap
+
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ParseLevel parses a level name: "debug", "info", "warn", "error",
+// "dpanic", "panic" or "fatal", in any case.
+func ParseLevel(s string) (Level, error) {
+	level, err := zapcore.ParseLevel(s)
+	return Level(level), err
+}
+
+// ParseModuleLevels parses level overrides keyed by logger name, like
+// "db=debug,http=warn", for WithModuleLevels.
+func ParseModuleLevels(spec string) (map[string]Level, error) {
+	levels := map[string]Level{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, levelName, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid module level %q: want name=level", item)
+		}
+		level, err := ParseLevel(strings.TrimSpace(levelName))
+		if err != nil {
+			return nil, fmt.Errorf("invalid module level %q: %w", item, err)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
+
+// WithModuleLevels overrides the level of the loggers created with Named,
+// by name, as parsed by ParseModuleLevels: "db=debug,http=warn" logs Debug
+// entries of the "db" logger and silences Info ones of the "http" logger,
+// whatever the level of the logger. An override applies to the sub-loggers
+// too, "db.pool" included, unless they have their own. SetLevel on a logger
+// with an override changes the override, for every logger it applies to.
+//
+// Overrides only apply to the zap backend.
+func WithModuleLevels(spec string) Option {
+	return func(o *options) {
+		levels, err := ParseModuleLevels(spec)
+		if err != nil {
+			o.err = err
+			return
+		}
+		o.moduleLevels = levels
+	}
+}
+
+// moduleLevels holds the level overrides of a logger and those derived from
+// it. The levels are shared by every logger they apply to, so changing one,
+// with SetLevel or LevelHandler, changes them all.
+type moduleLevels struct {
+	mu     sync.RWMutex
+	levels map[string]zap.AtomicLevel
+}
+
+func newModuleLevels(levels map[string]Level) *moduleLevels {
+	m := &moduleLevels{levels: make(map[string]zap.AtomicLevel, len(levels))}
+	for name, level := range levels {
+		m.levels[name] = zap.NewAtomicLevelAt(zapcore.Level(level))
+	}
+	return m
+}
+
+// lookup returns the override for the logger named name, looking up its
+// parents' names too, and the name it was set for.
+func (m *moduleLevels) lookup(name string) (string, zap.AtomicLevel, bool) {
+	if m == nil {
+		return "", zap.AtomicLevel{}, false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for name != "" {
+		if level, ok := m.levels[name]; ok {
+			return name, level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return "", zap.AtomicLevel{}, false
+}
+
+// get returns the override set for name itself, adding one at level if
+// there's none.
+func (m *moduleLevels) get(name string, level zapcore.Level) zap.AtomicLevel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	atomic, ok := m.levels[name]
+	if !ok {
+		atomic = zap.NewAtomicLevelAt(level)
+		m.levels[name] = atomic
+	}
+	return atomic
+}
+
+// snapshot returns the overrides by name.
+func (m *moduleLevels) snapshot() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	levels := make(map[string]string, len(m.levels))
+	for name, level := range m.levels {
+		levels[name] = level.String()
+	}
+	return levels
+}
+
+// withModuleLevels returns a copy of l whose entries are filtered on the
+// override for its name, looked up at each entry, so overrides added or
+// changed later, with LevelHandler or a reloaded configuration, apply to the
+// loggers created before. Without an override, the level of l applies.
+func (l *Logger) withModuleLevels() *Logger {
+	sugared := l.sugaredLogger.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if mc, ok := core.(*moduleLevelCore); ok { // Named from a named logger
+			core = mc.Core
+		}
+		return &moduleLevelCore{Core: core, modules: l.modules, name: l.name}
+	})).Sugar()
+	return l.derive(sugared)
+}
+
+// levelOf returns the level filtering the entries of l: the override for
+// its name, if any, or its own.
+func (l *Logger) levelOf() zap.AtomicLevel {
+	if _, level, ok := l.modules.lookup(l.name); ok && l.config.Level != (zap.AtomicLevel{}) {
+		return level
+	}
+	return l.config.Level
+}
+
+// moduleLevelCore replaces the level of the cores it wraps with the override
+// for name, below or above theirs, if there's one.
+type moduleLevelCore struct {
+	zapcore.Core
+	modules *moduleLevels
+	name    string
+}
+
+func (c *moduleLevelCore) Enabled(lvl zapcore.Level) bool {
+	if _, level, ok := c.modules.lookup(c.name); ok {
+		return level.Enabled(lvl)
+	}
+	return c.Core.Enabled(lvl)
+}
+
+func (c *moduleLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &moduleLevelCore{Core: c.Core.With(fields), modules: c.modules, name: c.name}
+}
+
+func (c *moduleLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	_, level, ok := c.modules.lookup(c.name)
+	if !ok {
+		return c.Core.Check(ent, ce)
+	}
+	if !level.Enabled(ent.Level) {
+		return ce
+	}
+	if c.Core.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	// Below the wrapped cores' level: write to them directly.
+	return ce.AddCore(ent, c)
+}
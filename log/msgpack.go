@@ -0,0 +1,249 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// The subset of MessagePack the forward-protocol sinks need: encoding the
+// values decoded from JSON entries, and decoding the small messages servers
+// answer with.
+
+// eventTime is Fluentd's EventTime extension type, a time with nanoseconds.
+type eventTime time.Time
+
+func appendMsgpack(buf []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, 0xc0)
+	case bool:
+		if v {
+			return append(buf, 0xc3)
+		}
+		return append(buf, 0xc2)
+	case int:
+		return appendMsgpackInt(buf, int64(v))
+	case int64:
+		return appendMsgpackInt(buf, v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return appendMsgpackInt(buf, int64(v))
+		}
+		buf = append(buf, 0xcb)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(v))
+	case string:
+		return appendMsgpackString(buf, v)
+	case []byte:
+		buf = appendMsgpackHeader(buf, len(v), 0, 0xc4, 0xc5, 0xc6)
+		return append(buf, v...)
+	case []interface{}:
+		buf = appendMsgpackHeader(buf, len(v), 0x90, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			buf = appendMsgpack(buf, item)
+		}
+		return buf
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf = appendMsgpackHeader(buf, len(v), 0x80, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			buf = appendMsgpackString(buf, key)
+			buf = appendMsgpack(buf, v[key])
+		}
+		return buf
+	case eventTime:
+		t := time.Time(v)
+		buf = append(buf, 0xd7, 0x00)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(t.Unix()))
+		return binary.BigEndian.AppendUint32(buf, uint32(t.Nanosecond()))
+	default:
+		return appendMsgpackString(buf, fmt.Sprint(v))
+	}
+}
+
+func appendMsgpackInt(buf []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v < 128:
+		return append(buf, byte(v))
+	case v < 0 && v >= -32:
+		return append(buf, byte(v))
+	}
+	buf = append(buf, 0xd3)
+	return binary.BigEndian.AppendUint64(buf, uint64(v))
+}
+
+func appendMsgpackString(buf []byte, s string) []byte {
+	buf = appendMsgpackHeader(buf, len(s), 0xa0, 0xd9, 0xda, 0xdb)
+	return append(buf, s...)
+}
+
+// appendMsgpackHeader writes the type and length of a string, binary, array
+// or map: fixed is the prefix of the 5 or 4 bit fixed forms (0 if there's
+// none), the others the 8, 16 and 32 bit forms (0 if there's none).
+func appendMsgpackHeader(buf []byte, n int, fixed, b8, b16, b32 byte) []byte {
+	fixedMax := 32
+	if fixed == 0x90 || fixed == 0x80 {
+		fixedMax = 16
+	}
+	switch {
+	case fixed != 0 && n < fixedMax:
+		return append(buf, fixed|byte(n))
+	case b8 != 0 && n <= math.MaxUint8:
+		return append(buf, b8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, b16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, b32), uint32(n))
+	}
+}
+
+var errMsgpack = errors.New("invalid msgpack")
+
+// readMsgpack decodes one value. Maps decode to map[string]interface{} with
+// their keys formatted as strings, strings and binaries to string, integers
+// to int64 and extension types to nil.
+func readMsgpack(r *bufio.Reader) (interface{}, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xe0 == 0xa0:
+		return readMsgpackString(r, int(b&0x1f))
+	case b&0xf0 == 0x90:
+		return readMsgpackArray(r, int(b&0x0f))
+	case b&0xf0 == 0x80:
+		return readMsgpackMap(r, int(b&0x0f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		n, err := readUint(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xc5, 0xda:
+		n, err := readUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xc6, 0xdb:
+		n, err := readUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xca:
+		n, err := readUint(r, 4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := readUint(r, 8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readUint(r, 1<<(b-0xcc))
+		return int64(n), err
+	case 0xd0:
+		n, err := readUint(r, 1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := readUint(r, 2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := readUint(r, 4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := readUint(r, 8)
+		return int64(n), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		_, err := r.Discard(1 + 1<<(b-0xd4))
+		return nil, err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readUint(r, 1<<(b-0xc7))
+		if err != nil {
+			return nil, err
+		}
+		_, err = r.Discard(1 + int(n))
+		return nil, err
+	case 0xdc, 0xdd:
+		n, err := readUint(r, 2<<(b-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, int(n))
+	case 0xde, 0xdf:
+		n, err := readUint(r, 2<<(b-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, int(n))
+	}
+	return nil, fmt.Errorf("%w: unknown type 0x%x", errMsgpack, b)
+}
+
+func readUint(r *bufio.Reader, size int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+func readMsgpackString(r *bufio.Reader, n int) (interface{}, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return string(buf), nil
+}
+
+func readMsgpackArray(r *bufio.Reader, n int) (interface{}, error) {
+	out := make([]interface{}, n)
+	for i := range out {
+		v, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func readMsgpackMap(r *bufio.Reader, n int) (interface{}, error) {
+	out := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		out[fmt.Sprint(key)] = value
+	}
+	return out, nil
+}
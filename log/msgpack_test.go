@@ -0,0 +1,61 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	values := []interface{}{
+		nil, true, false,
+		int64(0), int64(127), int64(-32), int64(-33), int64(1 << 40),
+		1.5, "", "short", long,
+		[]interface{}{int64(1), "two", []interface{}{}},
+		map[string]interface{}{"a": int64(1), "nested": map[string]interface{}{"b": false}},
+	}
+
+	for _, v := range values {
+		r := bufio.NewReader(bytes.NewReader(appendMsgpack(nil, v)))
+		got, err := readMsgpack(r)
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+		_, err = r.ReadByte()
+		assert.Error(t, err, "%v left bytes behind", v)
+	}
+}
+
+func TestMsgpackEncoding(t *testing.T) {
+	// Whole floats from JSON are encoded as integers.
+	assert.Equal(t, []byte{0x2a}, appendMsgpack(nil, float64(42)))
+	assert.Equal(t, []byte{0xa2, 'h', 'i'}, appendMsgpack(nil, "hi"))
+	assert.Equal(t, []byte{0x92, 0xc0, 0xc3}, appendMsgpack(nil, []interface{}{nil, true}))
+
+	at := time.Unix(1714557600, 500)
+	assert.Equal(t, []byte{0xd7, 0x00, 0x66, 0x32, 0x12, 0xa0, 0x00, 0x00, 0x01, 0xf4}, appendMsgpack(nil, eventTime(at)))
+
+	big := make([]interface{}, 20)
+	assert.Equal(t, []byte{0xdc, 0x00, 0x14}, appendMsgpack(nil, big)[:3])
+}
+
+func TestReadMsgpackSkipsExtensions(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(append(appendMsgpack(nil, eventTime(time.Now())), 0x01)))
+	v, err := readMsgpack(r)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = readMsgpack(r)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+
+	_, err = readMsgpack(bufio.NewReader(bytes.NewReader([]byte{0xc1})))
+	assert.ErrorIs(t, err, errMsgpack)
+}
@@ -0,0 +1,151 @@
+//go:build !nozap
+
+package log
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// LoggerNameKey is the key of the logger name, given with Named, in entries
+// of backends other than zap, which names it after its own NameKey.
+const LoggerNameKey = "logger"
+
+// registry holds the loggers handed out by the package-level Named.
+var registry = struct {
+	mu      sync.Mutex
+	loggers map[string]*Logger
+}{loggers: map[string]*Logger{}}
+
+// Named returns the global logger named name, the same one for every call
+// with that name. It resolves the global logger at each entry rather than
+// when it's created, so each module can get its own logger once, at package
+// level, before InitLogger runs, and keep following the global logger when
+// it's replaced:
+//
+//	var logger = log.Named("billing")
+//
+//	func Charge(id string) {
+//		logger.Info("charging", "invoice", id)
+//	}
+//
+// Logging through it before InitLogger panics, as the package-level
+// functions do. Names lists the names handed out.
+func Named(name string) *Logger {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if l, ok := registry.loggers[name]; ok {
+		return l
+	}
+	l := newLazyLogger(name, GetLogger, func(base *Logger) *Logger { return base.Named(name) })
+	registry.loggers[name] = l
+	return l
+}
+
+// Names returns the names given to Named so far, sorted.
+func Names() []string {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	names := make([]string, 0, len(registry.loggers))
+	for name := range registry.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Named returns a logger whose entries carry name, appended to the name of l
+// with a dot as zap does: the Named("db") of a logger named "billing" is
+// "billing.db". The name is written under the encoder's NameKey, "logger" in
+// production, or under LoggerNameKey with other backends. A level set for the
+// name with WithModuleLevels or LevelHandler applies to the logger returned,
+// even if set after it was created.
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+	if l.lazy != nil {
+		return l.lazy.then(joinName(l.name, name), func(parent *Logger) *Logger { return parent.Named(name) })
+	}
+
+	var derived *Logger
+	if _, ok := l.backend.(*zapBackend); ok {
+		derived = l.derive(l.sugaredLogger.Named(name))
+	} else {
+		copied := *l
+		derived = &copied
+	}
+	derived.name = joinName(l.name, name)
+
+	if l.modules != nil && derived.config.Level != (zap.AtomicLevel{}) {
+		derived = derived.withModuleLevels()
+	}
+	return derived
+}
+
+// Name returns the name of l, empty unless given with Named.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// joinName returns the name of the logger named name derived from one named
+// parent.
+func joinName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// lazyLogger derives a logger from another one, like the global logger,
+// when it's first used, and again each time the other one is replaced.
+type lazyLogger struct {
+	from   func() *Logger
+	derive func(*Logger) *Logger
+	cached atomic.Pointer[lazyResult]
+}
+
+type lazyResult struct {
+	from, logger *Logger
+}
+
+// newLazyLogger returns a logger named name, derived with derive from the one
+// returned by from at each entry.
+func newLazyLogger(name string, from func() *Logger, derive func(*Logger) *Logger) *Logger {
+	return &Logger{name: name, lazy: &lazyLogger{from: from, derive: derive}}
+}
+
+// get returns the logger derived from the current one of from, nil if that
+// one is.
+func (z *lazyLogger) get() *Logger {
+	from := z.from()
+	if from == nil {
+		return nil
+	}
+	if cached := z.cached.Load(); cached != nil && cached.from == from {
+		return cached.logger
+	}
+	l := z.derive(from)
+	z.cached.Store(&lazyResult{from: from, logger: l})
+	return l
+}
+
+// then returns a lazy logger named name deriving its loggers with derive from
+// those of z.
+func (z *lazyLogger) then(name string, derive func(*Logger) *Logger) *Logger {
+	return newLazyLogger(name, z.get, derive)
+}
+
+// resolved returns the logger l stands for: l itself, or the logger derived
+// from the current global one for those of the package-level Named.
+func (l *Logger) resolved() *Logger {
+	if l.lazy == nil {
+		return l
+	}
+	return l.lazy.get()
+}
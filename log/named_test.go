@@ -0,0 +1,109 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNamed(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	billing := Named("billing")
+	assert.Same(t, billing, Named("billing"))
+	assert.Contains(t, Names(), "billing")
+
+	billing.Named("db").Info("connected")
+	assert.Contains(t, buf.String(), "\tbilling.db\t")
+	assert.Equal(t, "billing.db", billing.Named("db").Name())
+	assert.Same(t, billing, billing.Named(""))
+
+	// Named loggers follow the global logger when it's replaced.
+	buf2, cleanup2 := setupTestLogger(false)
+	defer cleanup2()
+	assert.Same(t, billing, Named("billing"))
+	billing.Info("charged")
+	assert.NotContains(t, buf.String(), "charged")
+	assert.Contains(t, buf2.String(), "\tbilling\t")
+	assert.Contains(t, buf2.String(), "\tcharged\n")
+}
+
+func TestNamedBeforeInit(t *testing.T) {
+	previous := logger.Swap(nil)
+	defer logger.Store(previous)
+
+	// As in a package-level variable, before InitLogger.
+	sessions := Named("sessions-before-init")
+	store := sessions.Named("store").With("backend", "redis")
+	assert.Equal(t, "sessions-before-init.store", store.Name())
+
+	var buf bytes.Buffer
+	l, err := NewLogger(false, WithSink(zapcore.AddSync(&buf)))
+	require.NoError(t, err)
+	logger.Store(l)
+	store.Info("opened")
+	assert.Contains(t, buf.String(), `"logger":"sessions-before-init.store","caller":"log/named_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"opened","backend":"redis"`)
+	assert.True(t, store.Enabled(InfoLevel))
+	assert.False(t, store.Enabled(DebugLevel))
+}
+
+func TestNamedOtherBackends(t *testing.T) {
+	backend := newRecordingBackend(InfoLevel)
+	l := NewLoggerWithBackend(backend).Named("api").Named("auth")
+
+	l.Info("login", "user", "ana")
+	assert.Equal(t, []string{"0 login [logger api.auth user ana]"}, backend.logged())
+}
+
+func TestModuleLevels(t *testing.T) {
+	buf, cleanup := setupTestLogger(false, WithModuleLevels("db=debug, http=warn"))
+	defer cleanup()
+
+	db := GetLogger().Named("db")
+	db.Debug("db debug")
+	db.Named("pool").Debug("pool debug") // Inherits the override
+	GetLogger().Named("db.conn").Debug("conn debug")
+	Debug("global debug")
+
+	http := GetLogger().Named("http")
+	http.Info("http info")
+	http.Warn("http warn")
+	GetLogger().Named("other").Info("other info")
+
+	out := buf.String()
+	assert.Contains(t, out, "db debug")
+	assert.Contains(t, out, "pool debug")
+	assert.Contains(t, out, "conn debug")
+	assert.NotContains(t, out, "global debug")
+	assert.NotContains(t, out, "http info")
+	assert.Contains(t, out, "http warn")
+	assert.Contains(t, out, "other info")
+
+	// Module levels change independently of the global one.
+	assert.Equal(t, DebugLevel, db.GetLevel())
+	db.SetLevel(ErrorLevel)
+	db.Info("db info")
+	assert.NotContains(t, buf.String(), "db info")
+	assert.Equal(t, InfoLevel, GetLevel())
+}
+
+func TestParseModuleLevels(t *testing.T) {
+	levels, err := ParseModuleLevels("db=debug,,http=WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Level{"db": DebugLevel, "http": WarnLevel}, levels)
+
+	_, err = ParseModuleLevels("db")
+	assert.ErrorContains(t, err, "want name=level")
+	_, err = ParseModuleLevels("db=loud")
+	assert.Error(t, err)
+
+	_, err = NewLogger(false, WithModuleLevels("=debug"))
+	assert.Error(t, err)
+}
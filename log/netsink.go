@@ -0,0 +1,88 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// NewNetSink returns a sink writing entries as newline-delimited JSON to addr
+// over network: "tcp" or "udp", or their "4" and "6" variants. Over UDP each
+// entry is a datagram. The sink reconnects as ReconnectingSink does: entries
+// written while disconnected are kept for the next connection, up to
+// WithRetention, and Dropped counts those lost beyond it.
+func NewNetSink(network, addr string, opts ...ReconnectOption) (*ReconnectingSink, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return nil, fmt.Errorf("unsupported network %q", network)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid %s address: %w", network, err)
+	}
+
+	return NewReconnectingSink(network+"://"+addr, func() (io.WriteCloser, error) {
+		conn, err := net.DialTimeout(network, addr, defaultDialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return lineConn{conn}, nil
+	}, opts...), nil
+}
+
+// lineConn ends every write with a newline, for entries of encoders
+// configured without one.
+type lineConn struct {
+	net.Conn
+}
+
+func (c lineConn) Write(p []byte) (int, error) {
+	if bytes.HasSuffix(p, []byte("\n")) {
+		return c.Conn.Write(p)
+	}
+	if _, err := c.Conn.Write(append(p[:len(p):len(p)], '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// dialSinkFactory opens NewNetSinks to the host of the URL. The retention and
+// overflow parameters set WithRetention and WithOverflow, as in
+// tcp://collector:5170?retention=10000&overflow=drop_newest. overflow is
+// drop_oldest, drop_newest or block.
+func dialSinkFactory(network string) SinkFactory {
+	return func(u *url.URL) (zapcore.WriteSyncer, error) {
+		if u.Host == "" {
+			return nil, fmt.Errorf("%s sink URL %q has no host", network, u.String())
+		}
+
+		var opts []ReconnectOption
+		for key, values := range u.Query() {
+			value := values[len(values)-1]
+			switch key {
+			case "retention":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("%s sink %q: invalid retention %q", network, u.String(), value)
+				}
+				opts = append(opts, WithRetention(n))
+			case "overflow":
+				policy, ok := map[string]OverflowPolicy{"drop_oldest": DropOldest, "drop_newest": DropNewest, "block": Block}[value]
+				if !ok {
+					return nil, fmt.Errorf("%s sink %q: invalid overflow %q", network, u.String(), value)
+				}
+				opts = append(opts, WithOverflow(policy))
+			default:
+				return nil, fmt.Errorf("%s sink %q: unknown parameter %q", network, u.String(), key)
+			}
+		}
+		return NewNetSink(network, u.Host, opts...)
+	}
+}
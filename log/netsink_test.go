@@ -0,0 +1,79 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetSinkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	sink, err := NewNetSink("udp", pc.LocalAddr().String())
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, err = sink.Write([]byte(`{"msg":"one"}` + "\n"))
+	require.NoError(t, err)
+	_, err = sink.Write([]byte(`{"msg":"two"}`))
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	for _, want := range []string{`{"msg":"one"}` + "\n", `{"msg":"two"}` + "\n"} {
+		_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(buf[:n]), "one datagram per entry")
+	}
+}
+
+func TestNetSinkBuffersUntilConnected(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close()) // Nothing listens yet
+
+	sink, err := OpenSink("tcp://" + addr + "?retention=1&overflow=drop_oldest")
+	require.NoError(t, err)
+	rs := sink.(*ReconnectingSink)
+	defer rs.Close()
+
+	_, _ = rs.Write([]byte("lost\n"))
+	_, _ = rs.Write([]byte("kept\n"))
+	assert.Equal(t, uint64(1), rs.Dropped())
+
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer ln.Close()
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "kept\n", line)
+}
+
+func TestNewNetSinkErrors(t *testing.T) {
+	_, err := NewNetSink("unix", "/tmp/log.sock")
+	assert.Error(t, err)
+	_, err = NewNetSink("tcp", "collector")
+	assert.Error(t, err)
+
+	for _, rawURL := range []string{
+		"tcp://collector:5170?retention=-1",
+		"udp://collector:5170?overflow=sometimes",
+		"tcp://collector:5170?buffer=10",
+	} {
+		_, err := OpenSink(rawURL)
+		assert.Error(t, err, rawURL)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+const defaultNewRelicEndpoint = "https://log-api.newrelic.com/log/v1"
+
+// NewRelicConfig configures a sink for the New Relic Log API.
+type NewRelicConfig struct {
+	LicenseKey string
+
+	// Endpoint replaces https://log-api.newrelic.com/log/v1, for instance
+	// with https://log-api.eu.newrelic.com/log/v1 for EU accounts.
+	Endpoint string
+
+	// Linking metadata attached to every entry, which New Relic uses to
+	// connect logs to the APM entity producing them. Hostname defaults to
+	// os.Hostname.
+	EntityGUID string
+	EntityName string
+	Hostname   string
+
+	// TraceIDKey and SpanIDKey name the fields carrying the trace and span
+	// IDs, "trace_id" and "span_id" by default. They're sent as trace.id and
+	// span.id to link entries to distributed traces.
+	TraceIDKey string
+	SpanIDKey  string
+
+	HTTP  HTTPConfig
+	Batch BatchConfig
+}
+
+// NewNewRelicSink returns a sink sending entries to New Relic in batches.
+// Entries must be JSON encoded; their fields become log attributes.
+func NewNewRelicSink(config NewRelicConfig) (*BatchSink, error) {
+	if config.LicenseKey == "" {
+		return nil, errors.New("new relic sink needs a license key")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = defaultNewRelicEndpoint
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	if config.TraceIDKey == "" {
+		config.TraceIDKey = "trace_id"
+	}
+	if config.SpanIDKey == "" {
+		config.SpanIDKey = "span_id"
+	}
+
+	shipper, err := newHTTPShipper(config.HTTP, endpoint, "application/json", Gzip)
+	if err != nil {
+		return nil, err
+	}
+	shipper.header.Set("Api-Key", config.LicenseKey)
+
+	common := map[string]interface{}{}
+	for attribute, value := range map[string]string{
+		"entity.guid": config.EntityGUID,
+		"entity.name": config.EntityName,
+		"hostname":    config.Hostname,
+	} {
+		if value != "" {
+			common[attribute] = value
+		}
+	}
+
+	config.Batch.MaxBytes = capBatchBytes(config.Batch.MaxBytes, oneMBBatchBytes)
+	return newDecodingBatchSink("new relic", config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		logs := make([]newRelicLog, len(batch))
+		for i, line := range batch {
+			logs[i] = config.log(decode(line))
+		}
+		body, err := json.Marshal([]newRelicPayload{{Common: newRelicCommon{Attributes: common}, Logs: logs}})
+		if err != nil {
+			return err
+		}
+		return shipper.ship(body)
+	}), nil
+}
+
+type newRelicPayload struct {
+	Common newRelicCommon `json:"common"`
+	Logs   []newRelicLog  `json:"logs"`
+}
+
+type newRelicCommon struct {
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type newRelicLog struct {
+	Timestamp  int64                  `json:"timestamp"` // Milliseconds since the epoch
+	Message    string                 `json:"message"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+func (c NewRelicConfig) log(e sinkEntry) newRelicLog {
+	attributes := e.Fields
+	attributes["level"] = e.Level
+	if e.Caller != "" {
+		attributes["caller"] = e.Caller
+	}
+	for key, attribute := range map[string]string{c.TraceIDKey: "trace.id", c.SpanIDKey: "span.id"} {
+		if id, ok := attributes[key]; ok {
+			delete(attributes, key)
+			attributes[attribute] = id
+		}
+	}
+	return newRelicLog{Timestamp: e.Time.UnixMilli(), Message: e.Message, Attributes: attributes}
+}
@@ -0,0 +1,78 @@
+//go:build !nozap
+
+package log
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRelicSink(t *testing.T) {
+	var mu sync.Mutex
+	var payloads []newRelicPayload
+	var apiKey string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		apiKey = r.Header.Get("Api-Key")
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		var batch []newRelicPayload
+		require.NoError(t, json.NewDecoder(gz).Decode(&batch))
+		payloads = append(payloads, batch...)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sink, err := NewNewRelicSink(NewRelicConfig{
+		LicenseKey: "license",
+		Endpoint:   server.URL,
+		EntityGUID: "MXxBUE18QVBQTElDQVRJT058MQ",
+		EntityName: "checkout",
+		Hostname:   "web-1",
+		HTTP:       HTTPConfig{Compression: Gzip},
+		Batch:      BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"error","ts":1714557600.25,"caller":"pay.go:9","msg":"declined","trace_id":"t1","span_id":"s1","order":7}`))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "license", apiKey)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, map[string]interface{}{
+		"entity.guid": "MXxBUE18QVBQTElDQVRJT058MQ",
+		"entity.name": "checkout",
+		"hostname":    "web-1",
+	}, payloads[0].Common.Attributes)
+	require.Len(t, payloads[0].Logs, 1)
+	assert.Equal(t, newRelicLog{
+		Timestamp: 1714557600250,
+		Message:   "declined",
+		Attributes: map[string]interface{}{
+			"level":    "error",
+			"caller":   "pay.go:9",
+			"trace.id": "t1",
+			"span.id":  "s1",
+			"order":    float64(7),
+		},
+	}, payloads[0].Logs[0])
+}
+
+func TestNewRelicSinkConfig(t *testing.T) {
+	_, err := NewNewRelicSink(NewRelicConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,187 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NotifyEntry is an entry as handed to the message templates of the chat
+// notifier sinks.
+type NotifyEntry struct {
+	Time    time.Time
+	Level   string
+	Message string
+	Caller  string
+	Fields  map[string]interface{}
+}
+
+// notifyEntries decodes the entries of batch at min or above, the ones a
+// notifier sink delivers.
+func notifyEntries(batch [][]byte, min zapcore.Level, decode func([]byte) sinkEntry) []NotifyEntry {
+	var entries []NotifyEntry
+	for _, line := range batch {
+		e := decode(line)
+		level, err := zapcore.ParseLevel(e.Level)
+		if err != nil || level < min {
+			continue
+		}
+		entries = append(entries, NotifyEntry(e))
+	}
+	return entries
+}
+
+// fieldKeys returns the keys of the fields of e, sorted.
+func (e NotifyEntry) fieldKeys() []string {
+	keys := make([]string, 0, len(e.Fields))
+	for key := range e.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseNotifyTemplate parses text, or fallback if it's empty, with funcs.
+func parseNotifyTemplate(name, text, fallback string, funcs template.FuncMap) (*template.Template, error) {
+	if text == "" {
+		text = fallback
+	}
+	return template.New(name).Funcs(funcs).Parse(text)
+}
+
+// truncateRunes cuts s to at most n runes, ending with an ellipsis if cut.
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// notifyLimiter lets through at most max messages per interval and counts
+// those it holds back, so the next message can mention them.
+type notifyLimiter struct {
+	max      int
+	interval time.Duration
+
+	mu         sync.Mutex
+	start      time.Time // Of the current interval
+	sent       int
+	suppressed int
+}
+
+func newNotifyLimiter(max int, interval time.Duration) *notifyLimiter {
+	return &notifyLimiter{max: max, interval: interval}
+}
+
+// allow reports whether a message may be sent at now and, if so, how many
+// were suppressed since the last one.
+func (l *notifyLimiter) allow(now time.Time) (ok bool, suppressed int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.start) >= l.interval {
+		l.start, l.sent = now, 0
+	}
+	if l.sent >= l.max {
+		l.suppressed++
+		return false, 0
+	}
+	l.sent++
+	suppressed, l.suppressed = l.suppressed, 0
+	return true, suppressed
+}
+
+// notifyDedup suppresses repeats of a message within window of its last
+// delivery, counting them so the next delivery can mention them. With a path,
+// it keeps its state in that file so repeats are recognized across restarts.
+type notifyDedup struct {
+	window time.Duration
+	path   string
+
+	mu   sync.Mutex
+	seen map[string]*dedupState
+}
+
+type dedupState struct {
+	Last       time.Time `json:"last"`
+	Suppressed int       `json:"suppressed"`
+}
+
+// maxDedupKeys bounds the keys a notifyDedup remembers; beyond it, those
+// without suppressed repeats are forgotten once their window is over.
+const maxDedupKeys = 1024
+
+// newNotifyDedup returns a notifyDedup, loading its state from path if set.
+// A missing or unreadable state file starts afresh.
+func newNotifyDedup(window time.Duration, path string) *notifyDedup {
+	d := &notifyDedup{window: window, path: path, seen: make(map[string]*dedupState)}
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(data, &d.seen)
+		}
+	}
+	return d
+}
+
+// allow reports whether the message identified by key may be delivered at
+// now and, if so, how many repeats were suppressed since the last one.
+func (d *notifyDedup) allow(key string, now time.Time) (ok bool, suppressed int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer d.save()
+
+	s := d.seen[key]
+	if s == nil {
+		if len(d.seen) >= maxDedupKeys {
+			d.prune(now)
+		}
+		s = &dedupState{}
+		d.seen[key] = s
+	} else if now.Sub(s.Last) < d.window {
+		s.Suppressed++
+		return false, 0
+	}
+	s.Last = now
+	suppressed, s.Suppressed = s.Suppressed, 0
+	return true, suppressed
+}
+
+func (d *notifyDedup) prune(now time.Time) {
+	for key, s := range d.seen {
+		if s.Suppressed == 0 && now.Sub(s.Last) >= d.window {
+			delete(d.seen, key)
+		}
+	}
+}
+
+// save writes the state to the file, if any, replacing it atomically.
+func (d *notifyDedup) save() {
+	if d.path == "" {
+		return
+	}
+	data, err := json.Marshal(d.seen)
+	if err == nil {
+		tmp := d.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, d.path)
+		}
+	}
+	if err != nil {
+		reportInternal("can't save notifier state", zap.String("path", d.path), zap.Error(err))
+	}
+}
+
+// notifyKey identifies the repeats of an entry: same level, message and
+// caller.
+func notifyKey(e NotifyEntry) string {
+	return e.Level + "\x00" + e.Message + "\x00" + e.Caller
+}
@@ -0,0 +1,88 @@
+//go:build !nozap
+
+package log
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNotifyEntries(t *testing.T) {
+	entries := notifyEntries([][]byte{
+		[]byte(`{"level":"info","msg":"skipped"}`),
+		[]byte(`{"level":"error","msg":"kept","caller":"main.go:3","user":"ana"}`),
+		[]byte(`{"level":"fatal","msg":"also kept"}`),
+		[]byte(`not json`),
+	}, zapcore.ErrorLevel, new(sinkKeys).decode)
+
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "kept", entries[0].Message)
+		assert.Equal(t, "main.go:3", entries[0].Caller)
+		assert.Equal(t, map[string]interface{}{"user": "ana"}, entries[0].Fields)
+		assert.Equal(t, "fatal", entries[1].Level)
+	}
+}
+
+func TestNotifyLimiter(t *testing.T) {
+	l := newNotifyLimiter(2, time.Minute)
+	now := time.Now()
+
+	ok, suppressed := l.allow(now)
+	assert.True(t, ok)
+	assert.Zero(t, suppressed)
+	ok, _ = l.allow(now.Add(time.Second))
+	assert.True(t, ok)
+	ok, _ = l.allow(now.Add(2 * time.Second))
+	assert.False(t, ok)
+	ok, _ = l.allow(now.Add(3 * time.Second))
+	assert.False(t, ok)
+
+	ok, suppressed = l.allow(now.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 2, suppressed)
+}
+
+func TestTruncateRunes(t *testing.T) {
+	assert.Equal(t, "héllo", truncateRunes("héllo", 5))
+	assert.Equal(t, "hé…", truncateRunes("héllo", 3))
+}
+
+func TestNotifyDedup(t *testing.T) {
+	d := newNotifyDedup(time.Minute, "")
+	now := time.Now()
+
+	ok, _ := d.allow("a", now)
+	assert.True(t, ok)
+	ok, _ = d.allow("b", now)
+	assert.True(t, ok, "other keys aren't held back")
+	ok, _ = d.allow("a", now.Add(time.Second))
+	assert.False(t, ok)
+	ok, _ = d.allow("a", now.Add(30*time.Second))
+	assert.False(t, ok)
+
+	ok, suppressed := d.allow("a", now.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 2, suppressed)
+}
+
+func TestNotifyDedupPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Now()
+
+	d := newNotifyDedup(time.Hour, path)
+	ok, _ := d.allow("crash", now)
+	assert.True(t, ok)
+
+	d = newNotifyDedup(time.Hour, path) // After a restart
+	ok, _ = d.allow("crash", now.Add(time.Minute))
+	assert.False(t, ok)
+
+	d = newNotifyDedup(time.Hour, path)
+	ok, suppressed := d.allow("crash", now.Add(time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, 1, suppressed)
+}
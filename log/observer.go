@@ -0,0 +1,27 @@
+//go:build !nozap
+
+package log
+
+import "go.uber.org/zap/zapcore"
+
+// observer is a core that writes nothing and only hands entries to fn. Core
+// wrappers add it to a CheckedEntry to see entries, with their caller and
+// fields resolved, while the wrapped core keeps doing the actual writing.
+type observer struct {
+	fn func(ent zapcore.Entry, fields []zapcore.Field)
+}
+
+func (o observer) Enabled(zapcore.Level) bool { return true }
+
+func (o observer) With([]zapcore.Field) zapcore.Core { return o }
+
+func (o observer) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, o)
+}
+
+func (o observer) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	o.fn(ent, fields)
+	return nil
+}
+
+func (o observer) Sync() error { return nil }
@@ -0,0 +1,236 @@
+//go:build !nozap
+
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option customizes a Logger built by NewLogger or InitLogger.
+type Option func(*options)
+
+type options struct {
+	escalation *escalator
+	alerts     *alertEngine
+	crash      *crashReporter
+
+	noCrashReport bool
+	sinks         []zapcore.WriteSyncer
+
+	fatalTimeout time.Duration
+
+	runtimeStatsEvery time.Duration
+	runtimeStatsLevel zapcore.Level
+
+	dropReportEvery time.Duration
+
+	schemaVersion bool
+	keyNames      KeyNames
+	timeZone      *time.Location
+	sequence      bool
+	prettyFields  bool
+	curl          bool
+	curlBodyBytes int
+	moduleLevels  map[string]Level
+	level         *Level
+	sampling      *zap.SamplingConfig
+	fields        []zap.Field
+	encoding      string
+	noStderr      bool             // Drop the default stderr output, see Config.Outputs
+	atomicLevel   *zap.AtomicLevel // Level to build on, see ConfigWatcher.Reload
+	tee           []TeeSink
+	splitStreams  bool
+	hooks         []hookConfig
+
+	err error // Set by options that failed to apply
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		fatalTimeout: defaultExitTimeout,
+		prettyFields: true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.noCrashReport {
+		o.crashReporter().snapshot = true
+	}
+	return o
+}
+
+// WithSink adds an output written with the same encoding and level as the
+// logger's default one.
+func WithSink(sink zapcore.WriteSyncer) Option {
+	return func(o *options) {
+		o.sinks = append(o.sinks, sink)
+	}
+}
+
+// WithLevel sets the initial minimum level, instead of Debug in development
+// and Info in production.
+func WithLevel(level Level) Option {
+	return func(o *options) {
+		o.level = &level
+	}
+}
+
+// WithSampling sets how many identical entries are written per second:
+// the first initial ones, then every thereafter-th. zap samples production
+// loggers by 100 and 100 by default; an initial of 0 disables sampling.
+func WithSampling(initial, thereafter int) Option {
+	return func(o *options) {
+		o.sampling = &zap.SamplingConfig{Initial: initial, Thereafter: thereafter}
+	}
+}
+
+// WithFields adds keysAndValues to every entry, the sinks' included, such as
+// the service name and version. A last key without a value is ignored.
+func WithFields(keysAndValues ...interface{}) Option {
+	return func(o *options) {
+		for i := 0; i+1 < len(keysAndValues); i += 2 {
+			key, ok := keysAndValues[i].(string)
+			if !ok {
+				key = fmt.Sprint(keysAndValues[i])
+			}
+			o.fields = append(o.fields, zap.Any(key, keysAndValues[i+1]))
+		}
+	}
+}
+
+// configure applies the options that change the zap configuration itself,
+// before the logger is built.
+func (o *options) configure(config *zap.Config) {
+	if o.level != nil {
+		config.Level = zap.NewAtomicLevelAt(zapcore.Level(*o.level))
+	}
+	if o.atomicLevel != nil {
+		config.Level = *o.atomicLevel
+	}
+	if o.encoding != "" {
+		config.Encoding = o.encoding
+	}
+	if o.noStderr {
+		config.OutputPaths = nil
+	}
+	if o.sampling != nil {
+		config.Sampling = o.sampling
+		if o.sampling.Initial <= 0 {
+			config.Sampling = nil
+		}
+	}
+	if config.Sampling != nil {
+		config.Sampling.Hook = func(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped != 0 {
+				recordDropped(DropSampling, 1)
+			}
+		}
+	}
+
+	o.keyNames.apply(&config.EncoderConfig)
+	if o.timeZone != nil {
+		config.EncoderConfig.EncodeTime = inLocation(o.timeZone, config.EncoderConfig.EncodeTime)
+	}
+	if o.prettyFields && config.Development && config.Encoding == "console" {
+		config.Encoding = prettyConsoleEncoding
+	}
+}
+
+// zapOptions translates the configured features into zap options for a
+// logger built from config.
+func (o *options) zapOptions(config zap.Config) []zap.Option {
+	zapOptions := []zap.Option{
+		zap.WithFatalHook(fatalHook{timeout: o.fatalTimeout}),
+		zap.ErrorOutput(internalErrorOutput{}),
+	}
+
+	if len(o.sinks) > 0 || len(o.tee) > 0 || o.splitStreams {
+		zapOptions = append(zapOptions, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			var cores []zapcore.Core
+			if !o.noStderr { // Otherwise core writes nowhere
+				cores = append(cores, core)
+			}
+			for _, sink := range o.sinks {
+				useEntryKeys(sink, config.EncoderConfig)
+				cores = append(cores, zapcore.NewCore(newEncoder(config), monitorSink(sink), config.Level))
+			}
+			for _, sink := range o.tee {
+				cores = append(cores, o.teeCore(config, sink))
+			}
+			if o.splitStreams {
+				cores = append(cores, streamCores(config, stdoutSink, stderrSink)...)
+			}
+			return zapcore.NewTee(cores...)
+		}))
+	}
+
+	// Before the fields, so hooks get them with the others.
+	if len(o.hooks) > 0 {
+		zapOptions = append(zapOptions, zap.WrapCore(wrapHooks(o.hooks)))
+	}
+
+	// After the sinks, so they get the fields too.
+	if o.schemaVersion {
+		zapOptions = append(zapOptions, zap.Fields(schemaField()))
+	}
+	if len(o.fields) > 0 {
+		zapOptions = append(zapOptions, zap.Fields(o.fields...))
+	}
+
+	if o.escalation != nil {
+		zapOptions = append(zapOptions, zap.WrapCore(o.escalation.wrap))
+	}
+
+	if o.crash != nil {
+		zapOptions = append(zapOptions, zap.WrapCore(o.crash.wrap))
+	}
+
+	if o.alerts != nil {
+		zapOptions = append(zapOptions, zap.WrapCore(o.alerts.wrap))
+	}
+
+	return zapOptions
+}
+
+// apply sets the options handled by the Logger itself rather than zap.
+func (o *options) apply(l *Logger) {
+	l.sequence = o.sequence
+	l.curl = o.curl
+	l.curlBodyBytes = o.curlBodyBytes
+	l.modules = newModuleLevels(o.moduleLevels)
+}
+
+// start launches the background work requested by the options once the
+// logger is built, until stop is closed, adding it to running.
+func (o *options) start(l *Logger, stop <-chan struct{}, running *sync.WaitGroup) {
+	if o.runtimeStatsEvery > 0 {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			l.reportRuntimeStats(o.runtimeStatsEvery, o.runtimeStatsLevel, stop)
+		}()
+	}
+	if o.dropReportEvery > 0 {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			l.reportDropped(o.dropReportEvery, stop)
+		}()
+	}
+}
+
+// newEncoder builds the encoder described by config.
+func newEncoder(config zap.Config) zapcore.Encoder {
+	switch config.Encoding {
+	case "console":
+		return zapcore.NewConsoleEncoder(config.EncoderConfig)
+	case prettyConsoleEncoding:
+		return newPrettyConsoleEncoder(config.EncoderConfig)
+	}
+	return zapcore.NewJSONEncoder(config.EncoderConfig)
+}
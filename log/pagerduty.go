@@ -0,0 +1,193 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Defaults of PagerDutyConfig.
+const (
+	defaultPagerDutyURL         = "https://events.pagerduty.com/v2/enqueue"
+	defaultPagerDutyErrorWindow = 5 * time.Minute
+	defaultPagerDutyTimeout     = 5 * time.Second
+	pagerDutyMaxSummary         = 1024
+)
+
+// PagerDutyConfig configures the incidents WithPagerDuty triggers.
+type PagerDutyConfig struct {
+	RoutingKey string // Integration key of an Events API v2 integration
+
+	// Source defaults to os.Hostname. Component, Group and Class are
+	// optional fields of the event.
+	Source    string
+	Component string
+	Group     string
+	Class     string
+
+	// ErrorThreshold, if positive, also triggers an incident once the same
+	// Error entry is logged that many times within ErrorWindow, 5 minutes by
+	// default.
+	ErrorThreshold int
+	ErrorWindow    time.Duration
+
+	// URL replaces the Events API endpoint, for instance with the EU one,
+	// https://events.eu.pagerduty.com/v2/enqueue.
+	URL string
+
+	HTTP HTTPConfig // Timeout defaults to 5 seconds
+}
+
+// WithPagerDuty triggers a PagerDuty incident for every Fatal entry, and
+// optionally for Error entries repeating over a threshold. The dedup key of an
+// incident is the fingerprint of the entry, so PagerDuty groups repeats of an
+// entry, even across restarts, into one incident. Fatal incidents are sent
+// before the process exits; others in the background.
+func WithPagerDuty(config PagerDutyConfig) Option {
+	return func(o *options) {
+		if config.RoutingKey == "" {
+			o.err = errors.New("pagerduty needs a routing key")
+			return
+		}
+		if config.Source == "" {
+			config.Source, _ = os.Hostname()
+		}
+		if config.ErrorWindow <= 0 {
+			config.ErrorWindow = defaultPagerDutyErrorWindow
+		}
+		if config.URL == "" {
+			config.URL = defaultPagerDutyURL
+		}
+		if config.HTTP.Timeout <= 0 {
+			config.HTTP.Timeout = defaultPagerDutyTimeout
+		}
+		shipper, err := newHTTPShipper(config.HTTP, config.URL, "application/json")
+		if err != nil {
+			o.err = err
+			return
+		}
+
+		p := &pagerDuty{config: config, shipper: shipper, counts: make(map[string]*errorCount)}
+		level := FatalLevel
+		if config.ErrorThreshold > 0 {
+			level = ErrorLevel
+		}
+		WithHook(level, p.hook)(o)
+	}
+}
+
+type pagerDuty struct {
+	config  PagerDutyConfig
+	shipper *httpShipper
+
+	mu     sync.Mutex
+	counts map[string]*errorCount // By fingerprint
+}
+
+type errorCount struct {
+	start time.Time
+	count int
+}
+
+func (p *pagerDuty) hook(e HookEntry) {
+	switch {
+	case e.Level >= FatalLevel:
+		p.trigger(e, "critical", 0)
+	case e.Level == ErrorLevel:
+		if n := p.countError(e); n > 0 {
+			go p.trigger(e, "error", n)
+		}
+	}
+}
+
+// countError counts e within the error window and returns the count once it
+// reaches the threshold, starting the count over.
+func (p *pagerDuty) countError(e HookEntry) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c := p.counts[e.Fingerprint]
+	if c == nil || e.Time.Sub(c.start) >= p.config.ErrorWindow {
+		if c == nil && len(p.counts) >= maxDedupKeys {
+			for key, c := range p.counts {
+				if e.Time.Sub(c.start) >= p.config.ErrorWindow {
+					delete(p.counts, key)
+				}
+			}
+		}
+		c = &errorCount{start: e.Time}
+		p.counts[e.Fingerprint] = c
+	}
+	c.count++
+	if c.count < p.config.ErrorThreshold {
+		return 0
+	}
+	delete(p.counts, e.Fingerprint)
+	return c.count
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// trigger sends an incident for e. count is the number of repeats of an
+// Error entry that reached the threshold, 0 for Fatal entries.
+func (p *pagerDuty) trigger(e HookEntry, severity string, count int) {
+	details := make(map[string]interface{}, len(e.Fields)+4)
+	for key, v := range e.Fields {
+		details[key] = v
+	}
+	for key, v := range map[string]string{"logger": e.Logger, "caller": e.Caller, "stacktrace": e.Stack} {
+		if v != "" {
+			details[key] = v
+		}
+	}
+	summary := e.Message
+	if count > 0 {
+		details["count"] = count
+		summary = fmt.Sprintf("%s (logged %d times within %s)", e.Message, count, p.config.ErrorWindow)
+	}
+
+	body, err := json.Marshal(pagerDutyEvent{
+		RoutingKey:  p.config.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    e.Fingerprint,
+		Payload: pagerDutyPayload{
+			Summary:       truncateRunes(summary, pagerDutyMaxSummary),
+			Source:        p.config.Source,
+			Severity:      severity,
+			Timestamp:     e.Time.UTC().Format(time.RFC3339Nano),
+			Component:     p.config.Component,
+			Group:         p.config.Group,
+			Class:         p.config.Class,
+			CustomDetails: details,
+		},
+	})
+	if err == nil {
+		err = p.shipper.ship(body)
+	}
+	if err != nil {
+		reportInternal("can't trigger pagerduty incident", zap.String("dedup_key", e.Fingerprint), zap.Error(err))
+	}
+}
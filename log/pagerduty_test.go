@@ -0,0 +1,85 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWithPagerDuty(t *testing.T) {
+	codes := captureExit(t)
+	var mu sync.Mutex
+	var events []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var event pagerDutyEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	received := func() []pagerDutyEvent {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]pagerDutyEvent(nil), events...)
+	}
+
+	l, err := NewLogger(false,
+		WithSink(zapcore.AddSync(&bytes.Buffer{})),
+		WithPagerDuty(PagerDutyConfig{
+			RoutingKey:     "routing-key",
+			Source:         "web-1",
+			Component:      "api",
+			ErrorThreshold: 3,
+			URL:            server.URL,
+		}),
+	)
+	require.NoError(t, err)
+
+	paymentFailed := func(order int) { l.Error("payment failed", "order", order) } // Same caller each time
+	paymentFailed(0)
+	paymentFailed(1)
+	l.Error("other error")
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, received(), "under the threshold")
+
+	paymentFailed(2)
+	require.Eventually(t, func() bool { return len(received()) == 1 }, time.Second, time.Millisecond)
+	event := received()[0]
+	assert.Equal(t, "routing-key", event.RoutingKey)
+	assert.Equal(t, "trigger", event.EventAction)
+	assert.Len(t, event.DedupKey, 16)
+	assert.Equal(t, "payment failed (logged 3 times within 5m0s)", event.Payload.Summary)
+	assert.Equal(t, "error", event.Payload.Severity)
+	assert.Equal(t, "web-1", event.Payload.Source)
+	assert.Equal(t, "api", event.Payload.Component)
+	assert.Equal(t, float64(3), event.Payload.CustomDetails["count"])
+	assert.Equal(t, float64(2), event.Payload.CustomDetails["order"])
+
+	l.Fatal("out of disk")
+	events = received()
+	require.Len(t, events, 2, "sent before exiting")
+	assert.Equal(t, "critical", events[1].Payload.Severity)
+	assert.Equal(t, "out of disk", events[1].Payload.Summary)
+	assert.Contains(t, events[1].Payload.CustomDetails["caller"], "log/pagerduty_test.go:")
+	assert.NotEqual(t, event.DedupKey, events[1].DedupKey)
+	assert.Equal(t, []int{1}, *codes)
+}
+
+func TestWithPagerDutyNeedsRoutingKey(t *testing.T) {
+	_, err := NewLogger(false, WithPagerDuty(PagerDutyConfig{}))
+	assert.Error(t, err)
+}
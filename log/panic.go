@@ -0,0 +1,67 @@
+//go:build !nozap
+
+package log
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RecoverAndLog recovers a panic in the calling goroutine and logs it through
+// the global logger. See (*Logger).RecoverAndLog.
+func RecoverAndLog(msg string, keysAndValues ...interface{}) {
+	if r := recover(); r != nil {
+		GetLogger().logRecovered(r, msg, keysAndValues)
+	}
+}
+
+// RecoverAndLog recovers a panic in the calling goroutine and logs it at Error
+// with the panic value (see PanicValue) and the stack where it happened. It
+// must be deferred directly:
+//
+//	defer logger.RecoverAndLog("worker crashed", "worker", id)
+func (l *Logger) RecoverAndLog(msg string, keysAndValues ...interface{}) {
+	if r := recover(); r != nil {
+		l.logRecovered(r, msg, keysAndValues)
+	}
+}
+
+func (l *Logger) logRecovered(r interface{}, msg string, keysAndValues []interface{}) {
+	keysAndValues = append(keysAndValues, PanicValue(r), zap.ByteString("stack", debug.Stack()))
+	l.log(ErrorLevel, msg, keysAndValues)
+}
+
+// PanicValue encodes a recovered value under the "panic" key according to
+// what it is: errors get their type and message, fmt.Stringers their String()
+// and anything else its type and Go-syntax representation.
+func PanicValue(v interface{}) zap.Field {
+	return zap.Object("panic", panicValue{v})
+}
+
+type panicValue struct {
+	v interface{}
+}
+
+func (p panicValue) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("type", fmt.Sprintf("%T", p.v))
+
+	switch v := p.v.(type) {
+	case error:
+		enc.AddString("message", v.Error())
+		// Errors carrying their own stack (e.g. github.com/pkg/errors) print
+		// it with %+v.
+		if verbose := fmt.Sprintf("%+v", v); verbose != v.Error() {
+			enc.AddString("details", verbose)
+		}
+	case fmt.Stringer:
+		enc.AddString("value", v.String())
+	case string:
+		enc.AddString("value", v)
+	default:
+		enc.AddString("value", fmt.Sprintf("%#v", v))
+	}
+	return nil
+}
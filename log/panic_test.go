@@ -0,0 +1,53 @@
+//go:build !nozap
+
+package log
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverAndLogError(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	func() {
+		defer RecoverAndLog("worker crashed", "worker", 7)
+		panic(errors.New("boom"))
+	}()
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, "worker crashed")
+	assert.Contains(t, logOutput, `"worker": 7`)
+	assert.Contains(t, logOutput, `"type": "*errors.errorString"`)
+	assert.Contains(t, logOutput, `"message": "boom"`)
+	assert.Contains(t, logOutput, "TestRecoverAndLogError") // Stack of the panic
+}
+
+func TestRecoverAndLogWithoutPanic(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	func() {
+		defer RecoverAndLog("worker crashed")
+	}()
+
+	assert.Empty(t, buf.String())
+}
+
+func TestPanicValueEncoding(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	Info("stringer", PanicValue(net.IPv4(10, 0, 0, 1)))
+	Info("struct", PanicValue(struct{ Code int }{Code: 3}))
+	Info("string", PanicValue("plain"))
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, `"type": "net.IP", "value": "10.0.0.1"`)
+	assert.Contains(t, logOutput, `"type": "struct { Code int }", "value": "struct { Code int }{Code:3}"`)
+	assert.Contains(t, logOutput, `"type": "string", "value": "plain"`)
+}
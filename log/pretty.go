@@ -0,0 +1,184 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// prettyConsoleEncoding names the encoder development loggers use unless
+// WithPrettyFields(false) is given.
+const prettyConsoleEncoding = "pretty-console"
+
+var prettyBufferPool = buffer.NewPool()
+
+// prettyValueWidth is the length beyond which string values are moved under
+// the entry rather than inlined.
+const prettyValueWidth = 80
+
+func init() {
+	_ = zap.RegisterEncoder(prettyConsoleEncoding, func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return newPrettyConsoleEncoder(config), nil
+	})
+}
+
+// WithPrettyFields toggles how development console loggers render nested
+// fields (objects, arrays, namespaces) and long values. Enabled, the default,
+// they're written as indented JSON blocks under the entry; disabled, they stay
+// inlined in the entry's JSON like in zap's console encoder. It doesn't affect
+// JSON-encoded loggers.
+func WithPrettyFields(enabled bool) Option {
+	return func(o *options) {
+		o.prettyFields = enabled
+	}
+}
+
+// prettyConsoleEncoder is zap's console encoder with nested and long fields
+// moved out of the entry line into indented blocks below it:
+//
+//	2024-05-01T10:00:00.000Z	INFO	api/h.go:7	request served	{"status": 200}
+//	    request: {
+//	      "method": "GET",
+//	      "path": "/users"
+//	    }
+type prettyConsoleEncoder struct {
+	zapcore.Encoder
+	blocks []zapcore.Field // Context fields rendered as blocks
+}
+
+func newPrettyConsoleEncoder(config zapcore.EncoderConfig) *prettyConsoleEncoder {
+	return &prettyConsoleEncoder{Encoder: zapcore.NewConsoleEncoder(config)}
+}
+
+func (e *prettyConsoleEncoder) Clone() zapcore.Encoder {
+	return &prettyConsoleEncoder{
+		Encoder: e.Encoder.Clone(),
+		blocks:  e.blocks[:len(e.blocks):len(e.blocks)],
+	}
+}
+
+// The context fields added through With go through these.
+
+func (e *prettyConsoleEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	e.blocks = append(e.blocks, zap.Array(key, arr))
+	return nil
+}
+
+func (e *prettyConsoleEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	e.blocks = append(e.blocks, zap.Object(key, obj))
+	return nil
+}
+
+func (e *prettyConsoleEncoder) AddReflected(key string, value interface{}) error {
+	if isPrettyScalar(value) {
+		return e.Encoder.AddReflected(key, value)
+	}
+	e.blocks = append(e.blocks, zap.Reflect(key, value))
+	return nil
+}
+
+func (e *prettyConsoleEncoder) AddString(key, value string) {
+	if len(value) > prettyValueWidth {
+		e.blocks = append(e.blocks, zap.String(key, value))
+		return
+	}
+	e.Encoder.AddString(key, value)
+}
+
+func (e *prettyConsoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	inline := make([]zapcore.Field, 0, len(fields))
+	blocks := append([]zapcore.Field(nil), e.blocks...)
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			// Everything after a namespace belongs to it.
+			blocks = append(blocks, fields[i:]...)
+			break
+		}
+		if isPrettyBlock(f) {
+			blocks = append(blocks, f)
+		} else {
+			inline = append(inline, f)
+		}
+	}
+
+	buf, err := e.Encoder.EncodeEntry(ent, inline)
+	if err != nil || len(blocks) == 0 {
+		return buf, err
+	}
+
+	// Insert the blocks right after the entry line, before any stack trace.
+	rendered := renderPrettyBlocks(blocks)
+	out := prettyBufferPool.Get()
+	line, rest, _ := bytes.Cut(buf.Bytes(), []byte{'\n'})
+	out.Write(line)
+	out.AppendByte('\n')
+	out.AppendString(rendered)
+	out.Write(rest)
+	buf.Free()
+	return out, nil
+}
+
+// renderPrettyBlocks writes each field as "    key: <indented JSON>".
+func renderPrettyBlocks(fields []zapcore.Field) string {
+	enc := zapcore.NewMapObjectEncoder()
+	var keys []string
+	for _, f := range fields {
+		f.AddTo(enc)
+		if _, ok := enc.Fields[f.Key]; ok && !contains(keys, f.Key) {
+			keys = append(keys, f.Key)
+		}
+	}
+
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString("    ")
+		sb.WriteString(key)
+		sb.WriteString(": ")
+		if s, ok := enc.Fields[key].(string); ok {
+			sb.WriteString(s)
+		} else {
+			value, err := json.MarshalIndent(enc.Fields[key], "    ", "  ")
+			if err != nil {
+				value = []byte(err.Error())
+			}
+			sb.Write(value)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// isPrettyBlock reports whether f is rendered as a block under the entry.
+func isPrettyBlock(f zapcore.Field) bool {
+	switch f.Type {
+	case zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+		return true
+	case zapcore.ReflectType:
+		return !isPrettyScalar(f.Interface)
+	case zapcore.StringType:
+		return len(f.String) > prettyValueWidth
+	}
+	return false
+}
+
+// isPrettyScalar reports whether a reflected value encodes as a JSON scalar,
+// which stays inline.
+func isPrettyScalar(value interface{}) bool {
+	data, err := json.Marshal(value)
+	return err == nil && len(data) > 0 && data[0] != '{' && data[0] != '[' && len(data) <= prettyValueWidth
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
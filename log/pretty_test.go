@@ -0,0 +1,96 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newPrettyTestLogger(buf *bytes.Buffer) *zap.Logger {
+	config := zap.NewDevelopmentEncoderConfig()
+	config.TimeKey = ""
+	config.CallerKey = ""
+	return zap.New(zapcore.NewCore(newPrettyConsoleEncoder(config), zapcore.AddSync(buf), zapcore.DebugLevel))
+}
+
+func TestPrettyConsoleEncoderBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newPrettyTestLogger(&buf)
+
+	logger.Info("request served",
+		zap.Int("status", 200),
+		zap.Any("request", map[string]interface{}{"method": "GET", "path": "/users"}),
+		zap.Strings("tags", []string{"a", "b"}),
+		zap.String("query", strings.Repeat("x", prettyValueWidth+1)),
+		zap.Any("retries", 3),
+	)
+
+	assert.Equal(t, "INFO\trequest served\t{\"status\": 200, \"retries\": 3}\n"+
+		"    request: {\n"+
+		"      \"method\": \"GET\",\n"+
+		"      \"path\": \"/users\"\n"+
+		"    }\n"+
+		"    tags: [\n"+
+		"      \"a\",\n"+
+		"      \"b\"\n"+
+		"    ]\n"+
+		"    query: "+strings.Repeat("x", prettyValueWidth+1)+"\n", buf.String())
+}
+
+func TestPrettyConsoleEncoderContextAndNamespace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newPrettyTestLogger(&buf).With(zap.String("service", "api"), zap.Any("build", map[string]string{"commit": "abc"}))
+
+	logger.Warn("slow", zap.Namespace("db"), zap.String("table", "users"), zap.Int("ms", 900))
+
+	assert.Equal(t, "WARN\tslow\t{\"service\": \"api\"}\n"+
+		"    build: {\n"+
+		"      \"commit\": \"abc\"\n"+
+		"    }\n"+
+		"    db: {\n"+
+		"      \"ms\": 900,\n"+
+		"      \"table\": \"users\"\n"+
+		"    }\n", buf.String())
+}
+
+func TestPrettyConsoleEncoderKeepsStacktraceLast(t *testing.T) {
+	var buf bytes.Buffer
+	config := zap.NewDevelopmentEncoderConfig()
+	config.TimeKey = ""
+	enc := newPrettyConsoleEncoder(config)
+
+	out, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "failed", Stack: "main.main\n\tmain.go:3"},
+		[]zapcore.Field{zap.Error(errors.New("boom")), zap.Any("input", []int{1})})
+	require.NoError(t, err)
+	buf.Write(out.Bytes())
+
+	assert.Equal(t, "ERROR\tfailed\t{\"error\": \"boom\"}\n    input: [\n      1\n    ]\nmain.main\n\tmain.go:3\n", buf.String())
+}
+
+func TestWithPrettyFields(t *testing.T) {
+	config := zap.NewDevelopmentConfig()
+	config.Encoding = "console"
+	newOptions(nil).configure(&config)
+	assert.Equal(t, prettyConsoleEncoding, config.Encoding)
+
+	config = zap.NewDevelopmentConfig()
+	config.Encoding = "console"
+	newOptions([]Option{WithPrettyFields(false)}).configure(&config)
+	assert.Equal(t, "console", config.Encoding)
+
+	config = zap.NewProductionConfig()
+	newOptions(nil).configure(&config)
+	assert.Equal(t, "json", config.Encoding)
+
+	l, err := NewLogger(true)
+	require.NoError(t, err)
+	assert.Equal(t, prettyConsoleEncoding, l.config.Encoding)
+}
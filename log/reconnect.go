@@ -0,0 +1,367 @@
+//go:build !nozap
+
+package log
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// defaultRetainEntries is how many entries a ReconnectingSink keeps while
+// disconnected.
+const defaultRetainEntries = 1000
+
+// Backoff computes exponentially growing delays between retries, randomized
+// by Jitter so many clients don't retry in lockstep.
+type Backoff struct {
+	Initial    time.Duration // Delay before the first retry
+	Max        time.Duration // Upper bound for any delay
+	Multiplier float64       // Growth factor between retries
+	Jitter     float64       // Fraction of the delay randomized, 0 to 1
+}
+
+// DefaultBackoff starts at 100ms and doubles up to 30s, with 20% jitter.
+var DefaultBackoff = Backoff{
+	Initial:    100 * time.Millisecond,
+	Max:        30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Delay returns how long to wait before retry number attempt, starting at 0.
+// A Multiplier of 0 or less counts as 2, so a partly filled Backoff doesn't
+// retry in a hot loop, and the delay is capped by Max, or by the longest
+// time.Duration if Max is unset.
+func (b Backoff) Delay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = DefaultBackoff.Multiplier
+	}
+	limit := float64(math.MaxInt64)
+	if b.Max > 0 {
+		limit = float64(b.Max)
+	}
+
+	delay := math.Min(float64(b.Initial)*math.Pow(multiplier, float64(attempt)), limit)
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*rand.Float64() - 1)
+	}
+	// float64(math.MaxInt64) rounds up past it, so compare with >=.
+	if delay >= float64(math.MaxInt64) {
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+// ReconnectingSink writes entries to a connection opened by a dial function.
+// When a write fails it drops the connection and redials in the background,
+// following its Backoff, while keeping the newest entries in memory to send
+// once reconnected. It is the building block of the network sinks.
+type ReconnectingSink struct {
+	name     string
+	dial     func() (io.WriteCloser, error)
+	backoff  Backoff
+	retain   int
+	overflow OverflowPolicy
+	spool    *Spool
+
+	mu          sync.Mutex
+	drained     *sync.Cond // Signaled when retained entries are sent or the sink closes
+	conn        io.WriteCloser
+	pending     [][]byte
+	dropped     uint64
+	redialing   bool
+	draining    bool // Entries are kept in memory while the spool is sent
+	closed      bool
+	closeSignal chan struct{}
+	redialer    sync.WaitGroup
+}
+
+// ReconnectOption customizes a ReconnectingSink.
+type ReconnectOption func(*ReconnectingSink)
+
+// WithBackoff replaces DefaultBackoff.
+func WithBackoff(b Backoff) ReconnectOption {
+	return func(s *ReconnectingSink) {
+		s.backoff = b
+	}
+}
+
+// WithRetention sets how many entries are kept while disconnected. It
+// defaults to 1000; see WithOverflow for what happens beyond that.
+func WithRetention(entries int) ReconnectOption {
+	return func(s *ReconnectingSink) {
+		s.retain = entries
+	}
+}
+
+// WithOverflow sets what happens to entries written while disconnected once
+// the retention buffer is full. It defaults to DropOldest.
+func WithOverflow(policy OverflowPolicy) ReconnectOption {
+	return func(s *ReconnectingSink) {
+		s.overflow = policy
+	}
+}
+
+// WithSpool keeps the entries written while disconnected in spool instead of
+// memory, so they survive long outages and restarts. Entries found in the
+// spool are sent first whenever a connection is established.
+func WithSpool(spool *Spool) ReconnectOption {
+	return func(s *ReconnectingSink) {
+		s.spool = spool
+	}
+}
+
+// NewReconnectingSink returns a sink writing to connections opened by dial.
+// The first connection is opened lazily, in the background, so an unreachable
+// endpoint at startup doesn't prevent the logger from starting. Connections
+// are written to by concurrent Write calls, as a net.Conn can be.
+func NewReconnectingSink(name string, dial func() (io.WriteCloser, error), opts ...ReconnectOption) *ReconnectingSink {
+	s := &ReconnectingSink{
+		name:        name,
+		dial:        dial,
+		backoff:     DefaultBackoff,
+		retain:      defaultRetainEntries,
+		overflow:    DropOldest,
+		closeSignal: make(chan struct{}),
+	}
+	s.drained = sync.NewCond(&s.mu)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Name identifies the sink in write error reports.
+func (s *ReconnectingSink) Name() string {
+	return s.name
+}
+
+// Write sends p, or keeps it for later if the connection is down. It never
+// fails; failed sends are reported to OnSinkError callbacks instead. Dialing
+// and sending what was kept happen in the background, and s.mu is never held
+// while writing to the connection.
+func (s *ReconnectingSink) Write(p []byte) (int, error) {
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return 0, io.ErrClosedPipe
+		}
+
+		conn := s.conn
+		if conn == nil {
+			if !s.redialing {
+				s.redialing = true
+				s.redialer.Add(1)
+				go s.redial()
+			}
+
+			if s.overflow == Block && (s.spool == nil || s.draining) && s.retain > 0 && len(s.pending) >= s.retain {
+				s.drained.Wait()
+				s.mu.Unlock()
+				continue
+			}
+
+			dropped, err := s.keep(p)
+			s.mu.Unlock()
+			if err != nil {
+				reportSinkError(s.name, err)
+			}
+			if dropped != "" {
+				recordDropped(dropped, 1)
+			}
+			return len(p), nil
+		}
+		s.mu.Unlock()
+
+		_, err := conn.Write(p)
+		if err == nil {
+			return len(p), nil
+		}
+		reportSinkError(s.name, err)
+
+		// Drop the broken connection, unless another Write did already, and
+		// keep p for the next one.
+		s.mu.Lock()
+		if s.conn == conn {
+			_ = conn.Close()
+			s.conn = nil
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Sync is a no-op: entries are sent as soon as they're written.
+func (s *ReconnectingSink) Sync() error {
+	return nil
+}
+
+// Close stops reconnecting, waiting for a dial in progress, and closes the
+// current connection. Entries still waiting for a connection are discarded.
+func (s *ReconnectingSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.closeSignal)
+	s.drained.Broadcast()
+	conn := s.conn
+	s.mu.Unlock()
+
+	s.redialer.Wait()
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+// Dropped returns how many entries were discarded because the retention
+// buffer was full.
+func (s *ReconnectingSink) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// keep retains a copy of p until reconnected, returning the reason p or an
+// older entry was dropped, if one was, and the error of the spool. The caller
+// must hold s.mu.
+func (s *ReconnectingSink) keep(p []byte) (string, error) {
+	if s.spool != nil && !s.draining {
+		if err := s.spool.Append(p); err != nil {
+			s.dropped++
+			return DropSinkError, err
+		}
+		return "", nil
+	}
+
+	if s.retain <= 0 {
+		s.dropped++
+		return DropOverflow, nil
+	}
+	var dropped string
+	if len(s.pending) >= s.retain {
+		s.dropped++
+		if s.overflow == DropNewest {
+			return DropOverflow, nil
+		}
+		s.pending = s.pending[1:]
+		dropped = DropOverflow
+	}
+	s.pending = append(s.pending, append([]byte(nil), p...))
+	return dropped, nil
+}
+
+// redial connects in the background, right away and then following the
+// backoff, until a connection takes everything that was kept.
+func (s *ReconnectingSink) redial() {
+	defer s.redialer.Done()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-s.closeSignal:
+				return
+			case <-time.After(s.backoff.Delay(attempt - 1)):
+			}
+		}
+
+		conn, err := s.dial()
+		if err != nil {
+			reportSinkError(s.name, err)
+			continue
+		}
+		if s.drain(conn) {
+			return
+		}
+		_ = conn.Close()
+
+		select {
+		case <-s.closeSignal:
+			return
+		default:
+		}
+	}
+}
+
+// drain sends the spooled and retained entries over conn, then hands it over
+// to Write. Entries written meanwhile are kept in memory and sent in turn. It
+// returns false if conn failed or the sink was closed.
+func (s *ReconnectingSink) drain(conn io.WriteCloser) bool {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+
+	if s.spool != nil {
+		err := s.spool.Drain(func(entry []byte) error {
+			select {
+			case <-s.closeSignal:
+				return io.ErrClosedPipe
+			default:
+			}
+			_, err := conn.Write(entry)
+			return err
+		})
+		if err != nil {
+			s.stopDraining(nil)
+			if !errors.Is(err, io.ErrClosedPipe) {
+				reportSinkError(s.name, err)
+			}
+			return false
+		}
+	}
+
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.draining = false
+			s.mu.Unlock()
+			return false
+		}
+		if len(s.pending) == 0 {
+			s.conn = conn
+			s.redialing = false
+			s.draining = false
+			s.drained.Broadcast()
+			s.mu.Unlock()
+			return true
+		}
+		batch := s.pending
+		s.pending = nil
+		s.drained.Broadcast() // Blocked writers have room again
+		s.mu.Unlock()
+
+		for i, entry := range batch {
+			if _, err := conn.Write(entry); err != nil {
+				reportSinkError(s.name, err)
+				s.stopDraining(batch[i:])
+				return false
+			}
+		}
+	}
+}
+
+// stopDraining puts unsent back in front of the retained entries, dropping
+// the oldest beyond the retention, once conn failed.
+func (s *ReconnectingSink) stopDraining(unsent [][]byte) {
+	s.mu.Lock()
+	s.draining = false
+	s.pending = append(unsent, s.pending...)
+	var dropped int
+	if s.retain > 0 && len(s.pending) > s.retain {
+		dropped = len(s.pending) - s.retain
+		s.pending = s.pending[dropped:]
+		s.dropped += uint64(dropped)
+	}
+	s.mu.Unlock()
+
+	recordDropped(DropOverflow, uint64(dropped))
+}
@@ -0,0 +1,269 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEndpoint hands out connections that write into a shared buffer and can
+// be made unreachable.
+type fakeEndpoint struct {
+	mu       sync.Mutex
+	received bytes.Buffer
+	down     bool
+	dials    int
+}
+
+type fakeConn struct {
+	endpoint *fakeEndpoint
+}
+
+func (e *fakeEndpoint) dial() (io.WriteCloser, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.dials++
+	if e.down {
+		return nil, errors.New("connection refused")
+	}
+	return &fakeConn{endpoint: e}, nil
+}
+
+func (e *fakeEndpoint) setDown(down bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.down = down
+}
+
+func (e *fakeEndpoint) String() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.received.String()
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) {
+	c.endpoint.mu.Lock()
+	defer c.endpoint.mu.Unlock()
+
+	if c.endpoint.down {
+		return 0, errors.New("broken pipe")
+	}
+	return c.endpoint.received.Write(p)
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+var fastBackoff = Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+
+	assert.Equal(t, 100*time.Millisecond, b.Delay(0))
+	assert.Equal(t, 400*time.Millisecond, b.Delay(2))
+	assert.Equal(t, time.Second, b.Delay(10)) // Capped
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := b.Delay(0)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 150*time.Millisecond)
+	}
+}
+
+func TestBackoffDelayBounds(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond} // Multiplier and Max unset
+	assert.Equal(t, 200*time.Millisecond, b.Delay(1))
+	assert.Equal(t, time.Duration(math.MaxInt64), b.Delay(1000))
+
+	b.Jitter = 1
+	assert.Positive(t, b.Delay(1000)) // Doesn't overflow
+}
+
+func TestReconnectingSinkRetainsDuringOutage(t *testing.T) {
+	captureSinkErrors(t)
+	endpoint := &fakeEndpoint{}
+	sink := NewReconnectingSink("tcp:collector", endpoint.dial, WithBackoff(fastBackoff))
+	defer sink.Close()
+
+	_, err := sink.Write([]byte("before\n"))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return endpoint.String() == "before\n"
+	}, time.Second, time.Millisecond)
+
+	endpoint.setDown(true)
+	_, err = sink.Write([]byte("during 1\n"))
+	require.NoError(t, err)
+	_, err = sink.Write([]byte("during 2\n"))
+	require.NoError(t, err)
+	endpoint.setDown(false)
+
+	assert.Eventually(t, func() bool {
+		return endpoint.String() == "before\nduring 1\nduring 2\n"
+	}, time.Second, time.Millisecond)
+
+	_, err = sink.Write([]byte("after\n"))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return endpoint.String() == "before\nduring 1\nduring 2\nafter\n"
+	}, time.Second, time.Millisecond)
+	assert.Positive(t, SinkErrorCounts()["tcp:collector"])
+}
+
+func TestReconnectingSinkDropsOldestBeyondRetention(t *testing.T) {
+	captureSinkErrors(t)
+	endpoint := &fakeEndpoint{down: true}
+	sink := NewReconnectingSink("tcp:collector", endpoint.dial,
+		WithBackoff(Backoff{Initial: time.Hour}), WithRetention(2))
+	defer sink.Close()
+
+	for _, entry := range []string{"one\n", "two\n", "three\n"} {
+		_, err := sink.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, uint64(1), sink.Dropped())
+	sink.mu.Lock()
+	assert.Equal(t, [][]byte{[]byte("two\n"), []byte("three\n")}, sink.pending)
+	sink.mu.Unlock()
+}
+
+func TestReconnectingSinkClose(t *testing.T) {
+	endpoint := &fakeEndpoint{}
+	sink := NewReconnectingSink("tcp:collector", endpoint.dial)
+
+	require.NoError(t, sink.Close())
+
+	_, err := sink.Write([]byte("entry\n"))
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+func TestReconnectingSinkOverflowDropNewest(t *testing.T) {
+	captureSinkErrors(t)
+	endpoint := &fakeEndpoint{down: true}
+	sink := NewReconnectingSink("tcp:collector", endpoint.dial,
+		WithBackoff(Backoff{Initial: time.Hour}), WithRetention(2), WithOverflow(DropNewest))
+	defer sink.Close()
+
+	for _, entry := range []string{"one\n", "two\n", "three\n"} {
+		_, err := sink.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, uint64(1), sink.Dropped())
+	sink.mu.Lock()
+	assert.Equal(t, [][]byte{[]byte("one\n"), []byte("two\n")}, sink.pending)
+	sink.mu.Unlock()
+}
+
+func TestReconnectingSinkOverflowBlockUnderLoad(t *testing.T) {
+	captureSinkErrors(t)
+	endpoint := &fakeEndpoint{down: true}
+	sink := NewReconnectingSink("tcp:collector", endpoint.dial,
+		WithBackoff(fastBackoff), WithRetention(5), WithOverflow(Block))
+	defer sink.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				_, _ = sink.Write([]byte("x\n"))
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond) // Writers pile up behind the full buffer
+	endpoint.setDown(false)
+	wg.Wait()
+
+	assert.Zero(t, sink.Dropped())
+	assert.Equal(t, 400, bytes.Count([]byte(endpoint.String()), []byte("x\n")))
+}
+
+func TestReconnectingSinkOverflowDropOldestUnderLoad(t *testing.T) {
+	captureSinkErrors(t)
+	endpoint := &fakeEndpoint{down: true}
+	sink := NewReconnectingSink("tcp:collector", endpoint.dial,
+		WithBackoff(Backoff{Initial: time.Hour}), WithRetention(10))
+	defer sink.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				_, _ = sink.Write([]byte("x\n"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(390), sink.Dropped())
+}
+
+func TestReconnectingSinkDialsInBackground(t *testing.T) {
+	captureSinkErrors(t)
+	endpoint := &fakeEndpoint{}
+	unblock := make(chan struct{})
+	sink := NewReconnectingSink("tcp:collector", func() (io.WriteCloser, error) {
+		<-unblock // A slow dial
+		return endpoint.dial()
+	})
+	defer sink.Close()
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		_, _ = sink.Write([]byte("queued\n"))
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("Write waited for the dial")
+	}
+
+	close(unblock)
+	assert.Eventually(t, func() bool {
+		return endpoint.String() == "queued\n"
+	}, time.Second, time.Millisecond)
+}
+
+func TestReconnectingSinkCallbacksRunUnlocked(t *testing.T) {
+	captureSinkErrors(t)
+	endpoint := &fakeEndpoint{}
+	sink := NewReconnectingSink("tcp:collector", endpoint.dial, WithBackoff(Backoff{Initial: time.Hour}))
+	defer sink.Close()
+
+	_, err := sink.Write([]byte("sent\n"))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return endpoint.String() == "sent\n"
+	}, time.Second, time.Millisecond)
+
+	reported := make(chan uint64, 10)
+	OnSinkError(func(string, error) { reported <- sink.Dropped() }) // Deadlocks if called under s.mu
+	endpoint.setDown(true)
+
+	_, err = sink.Write([]byte("failed\n"))
+	require.NoError(t, err)
+	select {
+	case <-reported:
+	case <-time.After(time.Second):
+		t.Fatal("no error reported")
+	}
+}
@@ -0,0 +1,83 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder wraps an http.ResponseWriter to capture the status and
+// size of the response, for middleware logging requests, as HTTPMiddleware
+// does. It keeps the Flush, Hijack and Unwrap methods of the wrapped writer
+// working.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	body   *capturedBody // Captures the body too, if set
+}
+
+// NewResponseRecorder wraps w; pass the recorder to the next handler.
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w}
+}
+
+func (w *ResponseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *ResponseRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	if w.body != nil {
+		w.body.capture(w.Header().Get("Content-Type"), p[:n])
+	}
+	return n, err
+}
+
+// Status returns the status sent, 200 if the handler wrote nothing.
+func (w *ResponseRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Bytes returns the size of the body written so far.
+func (w *ResponseRecorder) Bytes() int64 {
+	return w.bytes
+}
+
+// Flush implements http.Flusher when the wrapped writer does.
+func (w *ResponseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the wrapped writer does, so
+// connection upgrades keep working.
+func (w *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer doesn't support hijacking")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer.
+func (w *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
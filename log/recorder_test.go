@@ -0,0 +1,38 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := NewResponseRecorder(w)
+	assert.Equal(t, http.StatusOK, rec.Status(), "before anything is written")
+
+	rec.WriteHeader(http.StatusCreated)
+	rec.WriteHeader(http.StatusInternalServerError) // Superfluous, ignored
+	_, _ = rec.Write([]byte("hello"))
+	_, _ = rec.Write([]byte(", world"))
+	rec.Flush()
+
+	assert.Equal(t, http.StatusCreated, rec.Status())
+	assert.Equal(t, int64(12), rec.Bytes())
+	assert.Equal(t, "hello, world", w.Body.String())
+	assert.True(t, w.Flushed)
+	assert.Same(t, w, rec.Unwrap())
+}
+
+func TestResponseRecorderImplicitStatus(t *testing.T) {
+	rec := NewResponseRecorder(httptest.NewRecorder())
+	_, _ = rec.Write([]byte("ok"))
+	assert.Equal(t, http.StatusOK, rec.Status())
+
+	_, _, err := rec.Hijack()
+	assert.Error(t, err, "httptest.ResponseRecorder can't be hijacked")
+}
@@ -0,0 +1,71 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+// RecoverMiddleware recovers the panics of next through the logger of the
+// request's context, see FromContext. See (*Logger).RecoverMiddleware.
+func RecoverMiddleware(next http.Handler) http.Handler {
+	return newRecoverMiddleware(func(r *http.Request) *Logger { return FromContext(r.Context()) }, next)
+}
+
+// RecoverMiddleware recovers the panics of next, logs them at Error with the
+// panic value (see PanicValue), the stack and a dump of the request, its
+// sensitive headers redacted as in CurlCommand, and responds with a 500 if
+// nothing was written yet. http.ErrAbortHandler panics, which abort the
+// response on purpose, are passed on.
+func (l *Logger) RecoverMiddleware(next http.Handler) http.Handler {
+	return newRecoverMiddleware(func(*http.Request) *Logger { return l }, next)
+}
+
+func newRecoverMiddleware(logger func(r *http.Request) *Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := NewResponseRecorder(w)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			logger(r).log(ErrorLevel, "http handler panicked", []interface{}{
+				"method", r.Method,
+				"path", r.URL.Path,
+				PanicValue(v),
+				zap.ByteString("stack", debug.Stack()),
+				zap.ByteString("request", dumpRequest(r)),
+			})
+			if rec.status == 0 {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// dumpRequest returns the request line and headers of r, without the body,
+// which the handler may have consumed.
+func dumpRequest(r *http.Request) []byte {
+	redacted := newKeySet(DefaultRedactedKeys)
+	clone := r.Clone(r.Context())
+	for name := range clone.Header {
+		if sensitiveHeaders.has(name) || redacted.has(name) {
+			clone.Header[name] = []string{Redacted}
+		}
+	}
+	clone.Body = nil
+	dump, err := httputil.DumpRequest(clone, false)
+	if err != nil {
+		return []byte(err.Error())
+	}
+	return dump
+}
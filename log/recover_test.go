@@ -0,0 +1,56 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	h := RecoverMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("nil map")
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/orders?id=7", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "error"))
+	assert.Contains(t, out, "http handler panicked")
+	assert.Contains(t, out, `"path": "/orders"`)
+	assert.Contains(t, out, `"panic": {"type": "string", "value": "nil map"}`)
+	assert.Contains(t, out, "recover_test.go")
+	assert.Contains(t, out, `POST /orders?id=7 HTTP/1.1\r\nHost: example.com\r\nAccept: application/json\r\nAuthorization: [REDACTED]`)
+	assert.NotContains(t, out, "secret")
+}
+
+func TestRecoverMiddlewareKeepsWrittenResponse(t *testing.T) {
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	h := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("after the header")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	abort := RecoverMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -0,0 +1,86 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the values of sensitive fields.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactedKeys are the field names whose values are redacted from the
+// payloads this package logs, unless configured otherwise. Names are matched
+// case-insensitively.
+var DefaultRedactedKeys = []string{
+	"password", "passwd", "secret", "token", "access_token", "refresh_token",
+	"api_key", "apikey", "authorization", "client_secret", "private_key",
+}
+
+// keySet is a set of lowercase field names.
+type keySet map[string]bool
+
+func newKeySet(keys []string) keySet {
+	set := make(keySet, len(keys))
+	for _, k := range keys {
+		set[strings.ToLower(k)] = true
+	}
+	return set
+}
+
+func (s keySet) has(key string) bool {
+	return s[strings.ToLower(key)]
+}
+
+// redactJSON replaces the values of the keys in s, at any depth, with
+// Redacted. It fails if body isn't valid JSON.
+func redactJSON(body []byte, s keySet) ([]byte, error) {
+	// Numbers are kept as they're written, as float64 would round large ones.
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: data after the top-level value")
+	}
+	return json.Marshal(s.redactValue(v))
+}
+
+func (s keySet) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range v {
+			if s.has(k) {
+				v[k] = Redacted
+				continue
+			}
+			v[k] = s.redactValue(inner)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = s.redactValue(inner)
+		}
+	}
+	return v
+}
+
+// redactForm replaces the values of the keys in s in a URL-encoded form.
+func redactForm(body []byte, s keySet) ([]byte, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	for k := range values {
+		if s.has(k) {
+			values[k] = []string{Redacted}
+		}
+	}
+	return []byte(values.Encode()), nil
+}
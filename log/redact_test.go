@@ -0,0 +1,34 @@
+//go:build !nozap
+
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactJSON(t *testing.T) {
+	s := newKeySet(DefaultRedactedKeys)
+
+	out, err := redactJSON([]byte(`{"user":"ana","Password":"hunter2","items":[{"token":"t","id":1}]}`), s)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"user":"ana","Password":"[REDACTED]","items":[{"token":"[REDACTED]","id":1}]}`, string(out))
+
+	_, err = redactJSON([]byte(`{"password":"hun`), s)
+	assert.Error(t, err) // Truncated bodies can't be redacted
+
+	out, err = redactJSON([]byte(`{"id":9007199254740993,"amount":0.1,"token":"t"}`), s)
+	require.NoError(t, err)
+	assert.Equal(t, `{"amount":0.1,"id":9007199254740993,"token":"[REDACTED]"}`, string(out)) // Beyond float64 precision
+
+	_, err = redactJSON([]byte(`{"id":1} {"id":2}`), s)
+	assert.Error(t, err)
+}
+
+func TestRedactForm(t *testing.T) {
+	out, err := redactForm([]byte("user=ana&api_key=abc"), newKeySet(DefaultRedactedKeys))
+	require.NoError(t, err)
+	assert.Equal(t, "api_key=%5BREDACTED%5D&user=ana", string(out))
+}
@@ -0,0 +1,363 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ConfigWatcher keeps a logger in line with its configuration file, see
+// WatchConfig.
+type ConfigWatcher struct {
+	path   string
+	opts   []Option
+	logger *Logger
+	core   *swapCore
+
+	mu      sync.Mutex            // Serializes reloads
+	last    []byte                // Contents of the file last loaded
+	outputs []zapcore.WriteSyncer // Sinks opened for the outputs of the file
+
+	// State of the options kept across reloads, so they don't reset alert
+	// cooldowns, escalation windows or the errors of crash reports.
+	escalation *escalator
+	alerts     *alertEngine
+	crash      *crashReporter
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	closing  sync.WaitGroup // Background work and previous sinks being closed
+}
+
+// WatchConfig builds a logger from the configuration file at path, like
+// LoadConfig, and reloads it when the file changes, polling it every poll, or
+// when the process gets SIGHUP. A poll of 0 leaves reloads to SIGHUP and
+// Reload. Polling rather than file system notifications also catches files
+// replaced through symlinks, as Kubernetes does with mounted ConfigMaps.
+//
+// A reload rebuilds the zap core under the logger, and under every logger
+// derived from it, in one atomic swap: entries being written when it happens
+// go to the previous sinks, which are synced and closed once they are written,
+// so none are dropped. Sinks given with WithOutput among opts are opened once
+// and kept across reloads, as is the state of escalations, alerts and crash
+// reports. Background work requested by opts, like WithRuntimeStats, runs
+// until Close. The level and module levels are updated in place, so SetLevel
+// and LevelHandler keep working. Development and the options given here are
+// applied once, at the first load. A configuration that fails to load is
+// reported on the internal output, see SetInternalOutput, and the previous one
+// is kept.
+func WatchConfig(path string, poll time.Duration, opts ...Option) (*ConfigWatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := parseConfig(path, data)
+	if err != nil {
+		return nil, err
+	}
+	base, o, outputs, err := config.build(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	core := &swapCore{}
+	core.swap(base.sugaredLogger.Desugar().Core())
+	w := &ConfigWatcher{
+		path: path,
+		opts: opts,
+		logger: base.derive(base.sugaredLogger.Desugar().WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+			return core
+		})).Sugar()),
+		core:       core,
+		last:       data,
+		outputs:    outputs,
+		escalation: o.escalation,
+		alerts:     o.alerts,
+		crash:      o.crash,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	o.start(w.logger, w.stop, &w.closing)
+	// Subscribe before returning, so no SIGHUP sent afterwards is missed.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go w.watch(hup, poll)
+	return w, nil
+}
+
+// Logger returns the logger following the configuration file.
+func (w *ConfigWatcher) Logger() *Logger {
+	return w.logger
+}
+
+// Reload loads the configuration file again and applies it, if it changed.
+func (w *ConfigWatcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, w.last) {
+		return nil
+	}
+
+	config, err := parseConfig(w.path, data)
+	if err != nil {
+		return err
+	}
+	config.Development = w.logger.config.Development
+	level, err := config.level()
+	if err != nil {
+		return err
+	}
+	// Build the new cores on the level of the logger, so SetLevel keeps
+	// acting on them, and on the state of the first load's options.
+	shared := w.logger.config.Level
+	rebuilt, _, outputs, err := config.build(append(w.opts[:len(w.opts):len(w.opts)], func(o *options) {
+		o.atomicLevel = &shared
+		o.escalation = w.escalation
+		o.alerts = w.alerts
+		o.crash = w.crash
+	}))
+	if err != nil {
+		return err
+	}
+
+	previous := w.core.swap(rebuilt.sugaredLogger.Desugar().Core())
+	shared.SetLevel(zapcore.Level(level))
+	previousOutputs := w.outputs
+	w.outputs = outputs
+	w.closing.Add(1)
+	go func() {
+		defer w.closing.Done()
+		previous.drain(drainTimeout)
+		_ = previous.core.Sync()
+		closeSinks(previousOutputs)
+	}()
+	if err := w.logger.modules.update(config.Modules); err != nil {
+		return err
+	}
+
+	w.last = data
+	w.logger.Info("logging config reloaded", "path", w.path)
+	return nil
+}
+
+// Close stops watching the file and the background work of the options.
+// The logger keeps its last configuration, and its sinks stay open. Calling
+// it again does nothing.
+func (w *ConfigWatcher) Close() error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+	w.closing.Wait()
+	return nil
+}
+
+func (w *ConfigWatcher) watch(hup chan os.Signal, poll time.Duration) {
+	defer close(w.done)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if poll > 0 {
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-hup:
+		case <-tick:
+		}
+		if err := w.Reload(); err != nil {
+			reportInternal("can't reload logging config", zap.String("path", w.path), zap.Error(err))
+		}
+	}
+}
+
+// level returns the level c sets, or the default one for its mode.
+func (c Config) level() (Level, error) {
+	if c.Level != "" {
+		return ParseLevel(c.Level)
+	}
+	if c.Development {
+		return DebugLevel, nil
+	}
+	return InfoLevel, nil
+}
+
+// update sets the module levels given by name, adding the missing ones.
+// Levels missing from levels are left as they are, as loggers may be using
+// them.
+func (m *moduleLevels) update(levels map[string]string) error {
+	if m == nil {
+		return nil
+	}
+	for name, levelName := range levels {
+		level, err := ParseLevel(levelName)
+		if err != nil {
+			return fmt.Errorf("level of module %q: %w", name, err)
+		}
+		m.get(name, zapcore.Level(level)).SetLevel(zapcore.Level(level))
+	}
+	return nil
+}
+
+// drainTimeout bounds the wait for the entries checked on a previous core
+// before its sinks are closed, in case some are never written.
+const drainTimeout = 5 * time.Second
+
+// swapCore is a zapcore.Core whose implementation can be replaced while in
+// use. Cores derived from it with With follow the replacements.
+type swapCore struct {
+	current atomic.Pointer[swapGeneration]
+}
+
+// swapGeneration is one of the cores a swapCore went through, with the
+// number of its entries checked but not written yet.
+type swapGeneration struct {
+	core    zapcore.Core
+	pending atomic.Int64
+}
+
+// swap replaces the core and returns the previous generation.
+func (c *swapCore) swap(core zapcore.Core) *swapGeneration {
+	return c.current.Swap(&swapGeneration{core: core})
+}
+
+// acquire returns the current generation, counting an entry as pending on
+// it. Once swapped out, a generation thus gets no new pending entries.
+func (c *swapCore) acquire() *swapGeneration {
+	for {
+		g := c.current.Load()
+		g.pending.Add(1)
+		if c.current.Load() == g {
+			return g
+		}
+		g.pending.Add(-1)
+	}
+}
+
+// drain waits until the entries pending on g are written, or timeout.
+func (g *swapGeneration) drain(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for g.pending.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// check checks ent on core, derived from the generation g acquired for it,
+// and releases g once the entry is written, or right away if it's not.
+func (g *swapGeneration) check(core zapcore.Core, ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	checked := core.Check(ent, nil)
+	if checked == nil {
+		g.pending.Add(-1)
+		return ce
+	}
+	checked.ErrorOutput = internalErrorOutput{}
+	return ce.AddCore(ent, &pendingCore{generation: g, checked: checked})
+}
+
+// pendingCore writes an entry checked on a generation, then releases it.
+type pendingCore struct {
+	generation *swapGeneration
+	checked    *zapcore.CheckedEntry
+}
+
+func (c *pendingCore) Enabled(zapcore.Level) bool        { return true }
+func (c *pendingCore) With([]zapcore.Field) zapcore.Core { return c }
+func (c *pendingCore) Sync() error                       { return nil }
+
+func (c *pendingCore) Check(_ zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce
+}
+
+func (c *pendingCore) Write(_ zapcore.Entry, fields []zapcore.Field) error {
+	c.checked.Write(fields...)
+	c.generation.pending.Add(-1)
+	return nil
+}
+
+func (c *swapCore) load() *swapGeneration {
+	return c.current.Load()
+}
+
+func (c *swapCore) Enabled(lvl zapcore.Level) bool {
+	return c.load().core.Enabled(lvl)
+}
+
+func (c *swapCore) With(fields []zapcore.Field) zapcore.Core {
+	return &swapFieldsCore{swap: c, fields: fields}
+}
+
+func (c *swapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	g := c.acquire()
+	return g.check(g.core, ent, ce)
+}
+
+func (c *swapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.load().core.Write(ent, fields)
+}
+
+func (c *swapCore) Sync() error {
+	return c.load().core.Sync()
+}
+
+// swapFieldsCore is a swapCore with fields, whose current core With the
+// fields is cached until the next swap.
+type swapFieldsCore struct {
+	swap   *swapCore
+	fields []zapcore.Field
+	cached atomic.Pointer[generationCore]
+}
+
+type generationCore struct {
+	generation *swapGeneration
+	core       zapcore.Core
+}
+
+// coreOf returns the core of g With the fields.
+func (c *swapFieldsCore) coreOf(g *swapGeneration) zapcore.Core {
+	if cached := c.cached.Load(); cached != nil && cached.generation == g {
+		return cached.core
+	}
+	core := g.core.With(c.fields)
+	c.cached.Store(&generationCore{generation: g, core: core})
+	return core
+}
+
+func (c *swapFieldsCore) Enabled(lvl zapcore.Level) bool {
+	return c.coreOf(c.swap.load()).Enabled(lvl)
+}
+
+func (c *swapFieldsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &swapFieldsCore{swap: c.swap, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *swapFieldsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	g := c.swap.acquire()
+	return g.check(c.coreOf(g), ent, ce)
+}
+
+func (c *swapFieldsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.coreOf(c.swap.load()).Write(ent, fields)
+}
+
+func (c *swapFieldsCore) Sync() error {
+	return c.coreOf(c.swap.load()).Sync()
+}
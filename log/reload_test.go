@@ -0,0 +1,245 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+// trackedSinks backs the "tracked" scheme used by the tests, keyed by host,
+// recording how often each sink is opened and whether it's closed.
+var trackedSinks = struct {
+	sync.Mutex
+	sinks map[string]*trackedSink
+}{sinks: map[string]*trackedSink{}}
+
+type trackedSink struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	opened int
+	closed bool
+}
+
+func (s *trackedSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *trackedSink) Sync() error { return nil }
+
+func (s *trackedSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *trackedSink) state() (string, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String(), s.opened, s.closed
+}
+
+func init() {
+	_ = RegisterSink("tracked", func(u *url.URL) (zapcore.WriteSyncer, error) {
+		trackedSinks.Lock()
+		defer trackedSinks.Unlock()
+		s := &trackedSink{opened: 1}
+		if previous, ok := trackedSinks.sinks[u.Host]; ok {
+			s.opened += previous.opened
+		}
+		trackedSinks.sinks[u.Host] = s
+		return s, nil
+	})
+}
+
+// forgetTrackedSinks drops the sinks of earlier runs, so that test counts
+// start over with -count.
+func forgetTrackedSinks() {
+	trackedSinks.Lock()
+	defer trackedSinks.Unlock()
+	trackedSinks.sinks = map[string]*trackedSink{}
+}
+
+func trackedSinkNamed(name string) *trackedSink {
+	trackedSinks.Lock()
+	defer trackedSinks.Unlock()
+	return trackedSinks.sinks[name]
+}
+
+func readLog(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestWatchConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+	config := filepath.Join(dir, "log.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"outputs": ["`+first+`"]}`), 0o600))
+
+	w, err := WatchConfig(config, 0)
+	require.NoError(t, err)
+	defer w.Close()
+
+	l := w.Logger()
+	child := l.With("component", "db")
+	http := l.Named("http") // Named before its level is set
+	child.Debug("debug before")
+	child.Info("info before")
+
+	require.NoError(t, os.WriteFile(config, []byte(`{"level": "debug", "outputs": ["`+second+`"], "modules": {"http": "error"}}`), 0o600))
+	require.NoError(t, w.Reload())
+
+	child.Debug("debug after")
+	http.Warn("http warn")
+	assert.Equal(t, DebugLevel, l.GetLevel())
+
+	assert.Contains(t, readLog(t, first), "info before")
+	assert.NotContains(t, readLog(t, first), "debug before")
+	assert.NotContains(t, readLog(t, first), "after")
+	after := readLog(t, second)
+	assert.Contains(t, after, `"msg":"logging config reloaded"`)
+	assert.Contains(t, after, `"msg":"debug after","component":"db"`)
+	assert.NotContains(t, after, "http warn")
+
+	// SetLevel still acts on the rebuilt cores.
+	l.SetLevel(WarnLevel)
+	child.Info("info hidden")
+	assert.NotContains(t, readLog(t, second), "info hidden")
+}
+
+func TestWatchConfigKeepsAlertAndEscalationState(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "app.log")
+	config := filepath.Join(dir, "log.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"outputs": ["`+out+`"]}`), 0o600))
+
+	var mu sync.Mutex
+	var fired int
+	w, err := WatchConfig(config, 0,
+		WithEscalation(2, time.Minute, time.Minute),
+		WithAlerts(AlertRule{Name: "errors", Level: ErrorLevel, Threshold: 2, Window: time.Minute,
+			Notifier: NotifierFunc(func(alert Alert) error {
+				mu.Lock()
+				defer mu.Unlock()
+				if alert.Firing {
+					fired++
+				}
+				return nil
+			})}),
+	)
+	require.NoError(t, err)
+	defer w.Close()
+
+	l := w.Logger().With(ComponentKey, "db")
+	l.Error("query failed")
+	l.Error("query failed")
+	firings := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fired
+	}
+	require.Eventually(t, func() bool { return firings() == 1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, os.WriteFile(config, []byte(`{"level": "info", "outputs": ["`+out+`"]}`), 0o600))
+	require.NoError(t, w.Reload())
+
+	l.Debug("still escalated")
+	l.Error("query failed") // Within the cooldown, doesn't fire again
+	l.Error("query failed")
+	time.Sleep(10 * time.Millisecond) // Notifiers run in their own goroutine
+
+	assert.Contains(t, readLog(t, out), `"msg":"still escalated"`)
+	assert.Equal(t, 1, firings())
+}
+
+func TestWatchConfigKeepsConfigOnError(t *testing.T) {
+	internal := captureInternal(t)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "app.log")
+	config := filepath.Join(dir, "log.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"outputs": ["`+out+`"]}`), 0o600))
+
+	w, err := WatchConfig(config, time.Millisecond)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(config, []byte(`{"level": "loud", "outputs": ["`+out+`"]}`), 0o600))
+	assert.Error(t, w.Reload())
+	time.Sleep(10 * time.Millisecond) // Let the poller report it
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close()) // Closing twice is fine
+	assert.Contains(t, internal.String(), "can't reload logging config")
+
+	w.Logger().Info("still logging")
+	assert.Contains(t, readLog(t, out), "still logging")
+}
+
+func TestWatchConfigClosesPreviousOutputs(t *testing.T) {
+	forgetTrackedSinks()
+	config := filepath.Join(t.TempDir(), "log.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"outputs": ["tracked://reload-first"]}`), 0o600))
+
+	w, err := WatchConfig(config, 0, WithOutput("tracked://reload-kept"))
+	require.NoError(t, err)
+
+	// An entry checked before the reload and written after it.
+	ce := w.Logger().sugaredLogger.Desugar().Check(zapcore.InfoLevel, "in flight")
+	require.NotNil(t, ce)
+
+	require.NoError(t, os.WriteFile(config, []byte(`{"outputs": ["tracked://reload-second"]}`), 0o600))
+	require.NoError(t, w.Reload())
+	time.Sleep(10 * time.Millisecond)
+	_, _, closed := trackedSinkNamed("reload-first").state()
+	assert.False(t, closed, "closed with an entry pending")
+
+	ce.Write()
+	require.NoError(t, w.Close())
+
+	out, _, closed := trackedSinkNamed("reload-first").state()
+	assert.True(t, closed)
+	assert.Contains(t, out, "in flight")
+	assert.NotContains(t, out, "logging config reloaded")
+
+	out, _, closed = trackedSinkNamed("reload-second").state()
+	assert.False(t, closed)
+	assert.Contains(t, out, "logging config reloaded")
+
+	out, opened, closed := trackedSinkNamed("reload-kept").state()
+	assert.Equal(t, 1, opened) // Not opened again by the reload
+	assert.False(t, closed)
+	assert.Contains(t, out, "in flight")
+	assert.Contains(t, out, "logging config reloaded")
+}
+
+func TestWatchConfigStopsBackgroundWork(t *testing.T) {
+	forgetTrackedSinks()
+	config := filepath.Join(t.TempDir(), "log.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"outputs": ["tracked://reload-stats"]}`), 0o600))
+
+	w, err := WatchConfig(config, 0, WithRuntimeStats(time.Millisecond, InfoLevel))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(config, []byte(`{"level": "warn", "outputs": ["tracked://reload-stats"]}`), 0o600))
+	require.NoError(t, w.Reload())
+	w.Logger().SetLevel(InfoLevel)
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, w.Close())
+
+	before, _, _ := trackedSinkNamed("reload-stats").state()
+	assert.Contains(t, before, "runtime stats")
+	time.Sleep(20 * time.Millisecond)
+	after, _, _ := trackedSinkNamed("reload-stats").state()
+	assert.Equal(t, before, after)
+}
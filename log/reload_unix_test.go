@@ -0,0 +1,28 @@
+//go:build unix && !nozap
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchConfigSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "app.log")
+	config := filepath.Join(dir, "log.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"outputs": ["`+out+`"]}`), 0o600))
+
+	w, err := WatchConfig(config, 0)
+	require.NoError(t, err)
+	defer w.Close()
+
+	require.NoError(t, os.WriteFile(config, []byte(`{"level": "error", "outputs": ["`+out+`"]}`), 0o600))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	require.Eventually(t, func() bool { return w.Logger().GetLevel() == ErrorLevel }, time.Second, time.Millisecond)
+}
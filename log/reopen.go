@@ -0,0 +1,94 @@
+//go:build !nozap
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// openFiles are the FileSinks not closed yet, for ReopenFiles.
+var openFiles = struct {
+	mu    sync.Mutex
+	sinks map[*FileSink]struct{}
+}{sinks: make(map[*FileSink]struct{})}
+
+// Reopen closes the file and opens its path again, creating it if it was
+// moved away. It lets external tools like logrotate rotate the file: once it
+// renamed the file, Reopen has the sink write to a new one instead of the
+// renamed file.
+func (s *FileSink) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Open the new file first, so the sink keeps working if that fails.
+	file, err := s.open()
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("can't stat log file %q: %w", s.path, err)
+	}
+
+	previous := s.file
+	s.file, s.size = file, info.Size()
+	return previous.Close()
+}
+
+// ReopenFiles reopens every FileSink not closed yet, see (*FileSink).Reopen.
+func ReopenFiles() error {
+	openFiles.mu.Lock()
+	sinks := make([]*FileSink, 0, len(openFiles.sinks))
+	for s := range openFiles.sinks {
+		sinks = append(sinks, s)
+	}
+	openFiles.mu.Unlock()
+
+	var errs []error
+	for _, s := range sinks {
+		errs = append(errs, s.Reopen())
+	}
+	return errors.Join(errs...)
+}
+
+// ReopenFilesOnSignal calls ReopenFiles whenever the process gets one of
+// signals, SIGHUP if none are given, as logrotate's postrotate scripts
+// usually send. Failures are reported as sink errors. stop ends it.
+func ReopenFilesOnSignal(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ch:
+				if err := ReopenFiles(); err != nil {
+					reportSinkError("file", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+			<-stopped
+		})
+	}
+}
@@ -0,0 +1,40 @@
+//go:build !nozap
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSinkReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte("before\n"))
+	require.NoError(t, os.Rename(path, path+".1")) // What logrotate does
+	_, _ = sink.Write([]byte("late\n"))
+	require.NoError(t, ReopenFiles())
+	_, _ = sink.Write([]byte("after\n"))
+
+	assert.Equal(t, "before\nlate\n", readLog(t, path+".1"))
+	assert.Equal(t, "after\n", readLog(t, path))
+}
+
+func TestClosedFileSinksNotReopened(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, ReopenFiles())
+	assert.NoFileExists(t, path)
+}
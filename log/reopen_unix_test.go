@@ -0,0 +1,33 @@
+//go:build unix && !nozap
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReopenFilesOnSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	stop := ReopenFilesOnSignal(syscall.SIGUSR1)
+	defer stop()
+
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	stop()
+	stop() // Idempotent
+}
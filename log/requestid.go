@@ -0,0 +1,91 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	// RequestIDHeader is the header RequestIDMiddleware reads and echoes.
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the field holding the request ID in entries.
+	RequestIDKey = "request_id"
+
+	// maxRequestIDLength bounds the IDs accepted from clients.
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// RequestIDMiddleware tags every request with an ID through the global
+// logger. See (*Logger).RequestIDMiddleware.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return newRequestIDMiddleware(GetLogger, next)
+}
+
+// RequestIDMiddleware gives every request handled by next an ID: the one in
+// its X-Request-ID header, if valid, or a new random one. The ID is echoed in
+// the X-Request-ID header of the response, and the request's context carries
+// it, for RequestIDFromContext, along with a logger derived from l adding it
+// to every entry, for FromContext. Wrapping HTTPMiddleware in it adds the ID
+// to the request logs too:
+//
+//	handler = l.RequestIDMiddleware(l.HTTPMiddleware(mux))
+func (l *Logger) RequestIDMiddleware(next http.Handler) http.Handler {
+	return newRequestIDMiddleware(func() *Logger { return l }, next)
+}
+
+func newRequestIDMiddleware(logger func() *Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !ValidRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := ContextWithRequestID(r.Context(), id)
+		ctx = WithContext(ctx, logger().With(RequestIDKey, id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ContextWithRequestID returns a copy of ctx carrying id, for
+// RequestIDFromContext, for transports other than HTTP to tag requests the
+// way RequestIDMiddleware does.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID RequestIDMiddleware gave the request
+// of ctx, or "" if there's none, for instance to pass it on to other
+// services.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ValidRequestID accepts IDs of printable ASCII characters without spaces, up
+// to 128 of them, so clients can't forge entries or response headers with
+// them.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// NewRequestID returns a new random request ID: 16 random bytes in hex.
+func NewRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,61 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	var seen string
+	h := RequestIDMiddleware(HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		FromContext(r.Context()).Info("handling")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(t, "abc-123", seen)
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"request_id": "abc-123"`, "the request-scoped logger")
+	assert.Contains(t, lines[1], `"request_id": "abc-123"`, "the request log")
+}
+
+func TestRequestIDMiddlewareGeneratesIDs(t *testing.T) {
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	var ids []string
+	h := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, RequestIDFromContext(r.Context()))
+	}))
+
+	for _, header := range []string{"", "has spaces", "bad\nid", strings.Repeat("x", 200)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, header)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, ids[len(ids)-1], w.Header().Get(RequestIDHeader))
+	}
+
+	require.Len(t, ids, 4)
+	for _, id := range ids {
+		assert.Len(t, id, 32)
+	}
+	assert.NotEqual(t, ids[0], ids[1])
+	assert.Empty(t, RequestIDFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+}
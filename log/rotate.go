@@ -0,0 +1,260 @@
+//go:build !nozap
+
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat stamps the names of rotated files, like
+// app-2024-05-01T10-04-05.000.log. It sorts chronologically and has no colons,
+// which Windows forbids.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// WithMaxSize rotates the log file once writing to it would make it larger
+// than bytes: the file is renamed after the time, as in
+// app-2024-05-01T10-04-05.000.log, and a new one is started. 0, the default,
+// never rotates.
+func WithMaxSize(bytes int64) FileOption {
+	return func(o *fileOptions) {
+		o.maxSize = bytes
+	}
+}
+
+// WithMaxAge removes rotated files older than age. 0, the default, keeps
+// them regardless of age.
+func WithMaxAge(age time.Duration) FileOption {
+	return func(o *fileOptions) {
+		o.maxAge = age
+	}
+}
+
+// WithMaxBackups keeps at most n rotated files, removing the oldest ones. 0,
+// the default, keeps them all.
+func WithMaxBackups(n int) FileOption {
+	return func(o *fileOptions) {
+		o.maxBackups = n
+	}
+}
+
+// WithCompress gzips rotated files, in the background.
+func WithCompress() FileOption {
+	return func(o *fileOptions) {
+		o.compress = true
+	}
+}
+
+// rotate moves the current file aside and starts a new one, then has the
+// rotated files cleaned up. The caller holds s.mu.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("can't close log file %q for rotation: %w", s.path, err)
+	}
+
+	backup := s.backupName(time.Now())
+	renameErr := os.Rename(s.path, backup)
+	// Reopen even if the rename failed, to keep logging.
+	file, err := s.open()
+	if err != nil {
+		// Keep appending to the previous file rather than a closed one.
+		if renameErr == nil {
+			_ = os.Rename(backup, s.path)
+		}
+		if previous, reopenErr := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0); reopenErr == nil {
+			s.file = previous
+		}
+		return err
+	}
+	s.file, s.size = file, 0
+	if renameErr != nil {
+		return fmt.Errorf("can't rotate log file %q: %w", s.path, renameErr)
+	}
+
+	select {
+	case s.mill <- struct{}{}:
+	default: // A cleanup is already pending and will see this file too
+	}
+	return nil
+}
+
+// backupName returns the name of the file rotated at t. Files rotated within
+// the same millisecond get a counter, as in
+// app-2024-05-01T10-04-05.000-1.log, so none overwrites another.
+func (s *FileSink) backupName(t time.Time) string {
+	dir, prefix, ext := s.backupParts()
+	stamp := t.UTC().Format(backupTimeFormat)
+	name := filepath.Join(dir, prefix+stamp+ext)
+	for n := 1; fileExists(name) || fileExists(name+".gz"); n++ {
+		name = filepath.Join(dir, prefix+stamp+"-"+strconv.Itoa(n)+ext)
+	}
+	return name
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// parseBackupStamp parses the time and counter in the name of a rotated file.
+func parseBackupStamp(stamp string) (t time.Time, counter int, ok bool) {
+	if len(stamp) > len(backupTimeFormat) {
+		digits, ok := strings.CutPrefix(stamp[len(backupTimeFormat):], "-")
+		if !ok || digits == "" || strings.Trim(digits, "0123456789") != "" {
+			return t, 0, false
+		}
+		counter, _ = strconv.Atoi(digits)
+		stamp = stamp[:len(backupTimeFormat)]
+	}
+	t, err := time.Parse(backupTimeFormat, stamp)
+	return t, counter, err == nil
+}
+
+// backupParts splits the path into the directory of the rotated files and
+// the prefix and extension of their names: "/var/log/app.log" gives
+// "/var/log", "app-" and ".log".
+func (s *FileSink) backupParts() (dir, prefix, ext string) {
+	dir, name := filepath.Split(s.path)
+	ext = filepath.Ext(name)
+	return filepath.Clean(dir), strings.TrimSuffix(name, ext) + "-", ext
+}
+
+// runMill cleans up the rotated files after each rotation, until mill is
+// closed.
+func (s *FileSink) runMill() {
+	defer close(s.millDone)
+
+	for range s.mill {
+		if err := s.cleanUp(); err != nil {
+			reportSinkError(s.Name(), err)
+		}
+	}
+}
+
+type backup struct {
+	path    string
+	time    time.Time
+	counter int // Orders the files rotated within the same millisecond
+}
+
+// cleanUp removes the rotated files beyond the retention and compresses the
+// remaining ones if asked to.
+func (s *FileSink) cleanUp() error {
+	backups, err := s.backups()
+	if err != nil {
+		return err
+	}
+
+	var keep []backup
+	cutoff := time.Now().Add(-s.opts.maxAge)
+	for i, b := range backups { // Newest first
+		if (s.opts.maxBackups > 0 && i >= s.opts.maxBackups) || (s.opts.maxAge > 0 && b.time.Before(cutoff)) {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		keep = append(keep, b)
+	}
+
+	if s.opts.compress {
+		for _, b := range keep {
+			if !strings.HasSuffix(b.path, ".gz") {
+				if err := s.compressFile(b.path); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// backups lists the rotated files, newest first.
+func (s *FileSink) backups() ([]backup, error) {
+	dir, prefix, ext := s.backupParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(strings.TrimSuffix(stamp, ".gz"), ext)
+		if !ok {
+			continue
+		}
+		t, counter, ok := parseBackupStamp(stamp)
+		if !ok {
+			continue // Another file sharing the prefix
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, e.Name()), time: t, counter: counter})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].time.Equal(backups[j].time) {
+			return backups[i].time.After(backups[j].time)
+		}
+		return backups[i].counter > backups[j].counter
+	})
+	return backups, nil
+}
+
+// compressFile gzips path into path.gz and removes it.
+func (s *FileSink) compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, s.opts.mode)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("can't compress rotated log file %q: %w", path, err)
+	}
+	return os.Remove(path)
+}
+
+// parseSize parses a size in bytes, with an optional KB, MB or GB suffix
+// (powers of 1024).
+func parseSize(s string) (int64, error) {
+	multiplier := int64(1)
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	for suffix, m := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if trimmed, ok := strings.CutSuffix(upper, suffix); ok {
+			upper, multiplier = strings.TrimSpace(trimmed), m
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
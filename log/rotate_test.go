@@ -0,0 +1,159 @@
+//go:build !nozap
+
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotated lists the rotated files next to path.
+func rotated(t *testing.T, path string) []string {
+	matches, err := filepath.Glob(strings.TrimSuffix(path, ".log") + "-*")
+	require.NoError(t, err)
+	sort.Strings(matches)
+	return matches
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	sink, err := NewFileSink(path, WithMaxSize(10))
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err := sink.Write([]byte(line))
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond) // Distinct backup names
+	}
+	require.NoError(t, sink.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(current))
+
+	backups := rotated(t, path)
+	require.Len(t, backups, 2)
+	assert.Regexp(t, `app-\d{4}-\d\d-\d\dT\d\d-\d\d-\d\d\.\d{3}\.log$`, backups[0])
+	first, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(first))
+}
+
+func TestFileSinkBackupNamesUnique(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	at := time.Date(2024, 5, 1, 10, 4, 5, 0, time.UTC)
+	first := sink.backupName(at)
+	require.NoError(t, os.WriteFile(first, nil, 0o600))
+	second := sink.backupName(at)
+	require.NoError(t, os.WriteFile(second+".gz", nil, 0o600)) // Compressed ones count too
+	third := sink.backupName(at)
+
+	assert.Equal(t, "app-2024-05-01T10-04-05.000.log", filepath.Base(first))
+	assert.Equal(t, "app-2024-05-01T10-04-05.000-1.log", filepath.Base(second))
+	assert.Equal(t, "app-2024-05-01T10-04-05.000-2.log", filepath.Base(third))
+
+	require.NoError(t, os.WriteFile(third, nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "app-2024-05-01T10-04-05.000-x.log"), nil, 0o600))
+	backups, err := sink.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+	assert.Equal(t, third, backups[0].path) // Newest first
+	assert.Equal(t, second+".gz", backups[1].path)
+	assert.Equal(t, first, backups[2].path)
+}
+
+func TestFileSinkOversizedEntryNotSplit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	sink, err := NewFileSink(path, WithMaxSize(4))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, err = sink.Write([]byte("longer than the max\n"))
+	require.NoError(t, err)
+	assert.Empty(t, rotated(t, path)) // An empty file isn't rotated
+}
+
+func TestFileSinkRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	old := filepath.Join(dir, "app-"+time.Now().Add(-48*time.Hour).UTC().Format(backupTimeFormat)+".log")
+	require.NoError(t, os.WriteFile(old, []byte("old\n"), 0o644))
+	unrelated := filepath.Join(dir, "app-notes.log")
+	require.NoError(t, os.WriteFile(unrelated, nil, 0o644))
+
+	sink, err := NewFileSink(path, WithMaxSize(1), WithMaxAge(24*time.Hour), WithMaxBackups(2), WithCompress())
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		_, err := sink.Write([]byte("entry\n"))
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+	}
+	require.NoError(t, sink.Close())
+
+	backups := rotated(t, path)
+	assert.NotContains(t, backups, old) // Too old
+	assert.Contains(t, backups, unrelated)
+
+	var compressed []string
+	for _, b := range backups {
+		if strings.HasSuffix(b, ".log.gz") {
+			compressed = append(compressed, b)
+		}
+	}
+	require.Len(t, compressed, 2) // Three rotations, two kept
+
+	f, err := os.Open(compressed[0])
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "entry\n", string(content))
+}
+
+func TestOpenSinkRotationParameters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	ws, err := OpenSink("file://" + path + "?max_size=1KB&max_age=24h&max_backups=3&compress=true")
+	require.NoError(t, err)
+	sink := ws.(*FileSink)
+	defer sink.Close()
+
+	assert.Equal(t, fileOptions{
+		mode: defaultFileMode, dirMode: defaultDirMode, uid: -1, gid: -1,
+		maxSize: 1024, maxAge: 24 * time.Hour, maxBackups: 3, compress: true,
+	}, sink.opts)
+
+	ws, err = OpenSink("file://" + path + "?rotate=100mb")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100<<20), ws.(*FileSink).opts.maxSize)
+	require.NoError(t, ws.(*FileSink).Close())
+
+	for _, query := range []string{"max_size=big", "rotate=big", "max_age=1", "max_backups=x", "compress=maybe"} {
+		_, err := OpenSink("file://" + path + "?" + query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for in, want := range map[string]int64{"100": 100, "2KB": 2048, "5 mb": 5 << 20, "1GB": 1 << 30} {
+		got, err := parseSize(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := parseSize("-1")
+	assert.Error(t, err)
+}
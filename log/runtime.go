@@ -0,0 +1,101 @@
+//go:build !nozap
+
+package log
+
+import (
+	"os"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// processStart approximates when the process started, for uptime reporting.
+var processStart = time.Now()
+
+// RuntimeSnapshot is a point-in-time view of the process's runtime state.
+type RuntimeSnapshot struct {
+	Goroutines   int
+	HeapAlloc    uint64
+	HeapInuse    uint64
+	HeapSys      uint64
+	NumGC        uint32
+	GCPauseTotal time.Duration
+	LastGCPause  time.Duration
+	OpenFDs      int // -1 when the platform doesn't expose it
+	Uptime       time.Duration
+}
+
+// TakeRuntimeSnapshot collects a RuntimeSnapshot. It briefly stops the world
+// to read the memory statistics, so don't call it on a hot path.
+func TakeRuntimeSnapshot() RuntimeSnapshot {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	s := RuntimeSnapshot{
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		HeapSys:      mem.HeapSys,
+		NumGC:        mem.NumGC,
+		GCPauseTotal: time.Duration(mem.PauseTotalNs),
+		OpenFDs:      openFDs(),
+		Uptime:       time.Since(processStart),
+	}
+	if mem.NumGC > 0 {
+		s.LastGCPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256])
+	}
+	return s
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (s RuntimeSnapshot) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("goroutines", s.Goroutines)
+	enc.AddUint64("heap_alloc", s.HeapAlloc)
+	enc.AddUint64("heap_inuse", s.HeapInuse)
+	enc.AddUint64("heap_sys", s.HeapSys)
+	enc.AddUint32("num_gc", s.NumGC)
+	enc.AddDuration("gc_pause_total", s.GCPauseTotal)
+	enc.AddDuration("last_gc_pause", s.LastGCPause)
+	if s.OpenFDs >= 0 {
+		enc.AddInt("open_fds", s.OpenFDs)
+	}
+	enc.AddDuration("uptime", s.Uptime)
+	return nil
+}
+
+// WithRuntimeStats logs a RuntimeSnapshot at level every interval for as long
+// as the process runs.
+func WithRuntimeStats(every time.Duration, level Level) Option {
+	return func(o *options) {
+		o.runtimeStatsEvery = every
+		o.runtimeStatsLevel = zapcore.Level(level)
+	}
+}
+
+// reportRuntimeStats logs a snapshot every interval until stop is closed.
+func (l *Logger) reportRuntimeStats(every time.Duration, level zapcore.Level, stop <-chan struct{}) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if ce := l.sugaredLogger.Desugar().Check(level, "runtime stats"); ce != nil {
+				ce.Write(zap.Object("runtime", TakeRuntimeSnapshot()))
+			}
+		}
+	}
+}
+
+// openFDs counts the process's open file descriptors where /proc is available.
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,50 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTakeRuntimeSnapshot(t *testing.T) {
+	s := TakeRuntimeSnapshot()
+
+	assert.Positive(t, s.Goroutines)
+	assert.Positive(t, s.HeapSys)
+	assert.NotZero(t, s.OpenFDs)
+}
+
+func TestReportRuntimeStats(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLogger(false, WithTee(TeeSink{Sink: zapcore.AddSync(&buf)}), WithRuntimeStats(5*time.Millisecond, InfoLevel))
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, l.Close())
+
+	logOutput := buf.String()
+	assert.Contains(t, logOutput, "runtime stats")
+	assert.Contains(t, logOutput, "goroutines")
+	assert.Contains(t, logOutput, "heap_alloc")
+
+	// Close stopped the reports.
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, logOutput, buf.String())
+	require.NoError(t, l.Close())
+}
+
+func TestReportRuntimeStatsRespectsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	// Info level, so Debug snapshots are skipped
+	l, err := NewLogger(false, WithTee(TeeSink{Sink: zapcore.AddSync(&buf)}), WithRuntimeStats(5*time.Millisecond, DebugLevel))
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, l.Close())
+
+	assert.NotContains(t, buf.String(), "runtime stats")
+}
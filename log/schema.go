@@ -0,0 +1,29 @@
+//go:build !nozap
+
+package log
+
+import "go.uber.org/zap"
+
+// SchemaKey is the field holding the version of the entry format when
+// WithSchemaVersion is used.
+const SchemaKey = "log_schema"
+
+// SchemaVersion is the version of the format of the entries written by this
+// package. It is bumped whenever the name or structure of a field this
+// package writes changes, so parsers can tell formats apart.
+//
+// Versions:
+//
+//	1: initial version
+const SchemaVersion = 1
+
+// WithSchemaVersion stamps SchemaKey with SchemaVersion on every entry.
+func WithSchemaVersion() Option {
+	return func(o *options) {
+		o.schemaVersion = true
+	}
+}
+
+func schemaField() zap.Field {
+	return zap.Int(SchemaKey, SchemaVersion)
+}
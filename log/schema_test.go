@@ -0,0 +1,32 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	var sink bytes.Buffer
+	buf, cleanup := setupTestLogger(false, WithSchemaVersion(), WithSink(zapcore.AddSync(&sink)))
+	defer cleanup()
+
+	Info("stamped")
+	GetLogger().NewRequestBuffer(1, 0).Info("derived")
+
+	assert.Contains(t, buf.String(), `"log_schema": 1`)
+	assert.Equal(t, 2, bytes.Count(sink.Bytes(), []byte(`"log_schema":1`))) // Sinks get it too
+}
+
+func TestSchemaVersionIsOptional(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	Info("plain")
+
+	assert.NotContains(t, buf.String(), "log_schema")
+}
@@ -0,0 +1,23 @@
+package log
+
+import "sync/atomic"
+
+// SequenceKey is the field holding the sequence number of entries when
+// WithSequence is used.
+const SequenceKey = "seq"
+
+var sequence atomic.Uint64
+
+// WithSequence stamps every entry with a sequence number, increasing
+// monotonically across all the loggers of the process, so their order can be
+// recovered when timestamps collide or sinks reorder batches. Numbers are
+// taken before sampling, so sampled out entries leave gaps.
+func WithSequence() Option {
+	return func(o *options) {
+		o.sequence = true
+	}
+}
+
+func nextSequence() uint64 {
+	return sequence.Add(1)
+}
@@ -0,0 +1,52 @@
+//go:build !nozap
+
+package log
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var seqPattern = regexp.MustCompile(`"seq": (\d+)`)
+
+func TestSequence(t *testing.T) {
+	buf, cleanup := setupTestLogger(false, WithSequence())
+	defer cleanup()
+
+	for i := 0; i < 10; i++ {
+		Info("numbered", "i", i)
+	}
+	Debug("disabled, no number taken")
+	Infof("formatted %d", 1)
+	GetLogger().NewRequestBuffer(1, 0).Warn("derived")
+
+	matches := seqPattern.FindAllStringSubmatch(buf.String(), -1)
+	require.Len(t, matches, 12)
+
+	seen := make(map[uint64]bool)
+	var last uint64
+	for _, m := range matches {
+		n, err := strconv.ParseUint(m[1], 10, 64)
+		require.NoError(t, err)
+		assert.False(t, seen[n], "duplicate sequence number %d", n)
+		seen[n] = true
+		last = max(last, n)
+	}
+	first := last - 11
+	for n := first; n <= last; n++ {
+		assert.True(t, seen[n], "missing sequence number %d", n)
+	}
+}
+
+func TestSequenceIsOptional(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	Info("plain")
+
+	assert.NotContains(t, buf.String(), SequenceKey)
+}
@@ -0,0 +1,79 @@
+//go:build !nozap
+
+package log
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var sinkErrors = struct {
+	mu        sync.Mutex
+	callbacks []func(sink string, err error)
+	counts    map[string]uint64
+}{counts: make(map[string]uint64)}
+
+// OnSinkError registers fn to be called with the sink's name every time a
+// sink fails to write an entry. fn runs on the logging goroutine, so it should
+// be quick and must not log through the failing sink.
+func OnSinkError(fn func(sink string, err error)) {
+	sinkErrors.mu.Lock()
+	defer sinkErrors.mu.Unlock()
+
+	sinkErrors.callbacks = append(sinkErrors.callbacks, fn)
+}
+
+// SinkErrorCounts returns how many writes failed so far, per sink name.
+func SinkErrorCounts() map[string]uint64 {
+	sinkErrors.mu.Lock()
+	defer sinkErrors.mu.Unlock()
+
+	counts := make(map[string]uint64, len(sinkErrors.counts))
+	for name, n := range sinkErrors.counts {
+		counts[name] = n
+	}
+	return counts
+}
+
+func reportSinkError(sink string, err error) {
+	sinkErrors.mu.Lock()
+	sinkErrors.counts[sink]++
+	callbacks := sinkErrors.callbacks
+	sinkErrors.mu.Unlock()
+
+	reportInternal("log sink failed", zap.String("sink", sink), zap.Error(err))
+	for _, fn := range callbacks {
+		fn(sink, err)
+	}
+}
+
+// sinkName identifies ws in SinkErrorCounts and OnSinkError callbacks: its
+// Name method if it has one, its type otherwise.
+func sinkName(ws zapcore.WriteSyncer) string {
+	if named, ok := ws.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("%T", ws)
+}
+
+// monitoredSink reports the write errors of the wrapped sink.
+type monitoredSink struct {
+	zapcore.WriteSyncer
+	name string
+}
+
+func monitorSink(ws zapcore.WriteSyncer) zapcore.WriteSyncer {
+	return &monitoredSink{WriteSyncer: ws, name: sinkName(ws)}
+}
+
+func (s *monitoredSink) Write(p []byte) (int, error) {
+	n, err := s.WriteSyncer.Write(p)
+	if err != nil {
+		recordDropped(DropSinkError, 1)
+		reportSinkError(s.name, err)
+	}
+	return n, err
+}
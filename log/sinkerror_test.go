@@ -0,0 +1,61 @@
+//go:build !nozap
+
+package log
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureSinkErrors resets the sink error state for the test and returns the
+// errors reported through OnSinkError.
+func captureSinkErrors(t *testing.T) *[]string {
+	var mu sync.Mutex // Sinks of earlier tests may still report from the background
+	var reported []string
+	resetSinkErrors()
+	OnSinkError(func(sink string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, sink+": "+err.Error())
+	})
+	t.Cleanup(resetSinkErrors)
+	return &reported
+}
+
+// resetSinkErrors forgets the callbacks and counts, under the lock as sinks
+// may still report from the background.
+func resetSinkErrors() {
+	sinkErrors.mu.Lock()
+	defer sinkErrors.mu.Unlock()
+
+	sinkErrors.callbacks = nil
+	sinkErrors.counts = make(map[string]uint64)
+}
+
+func TestSinkErrorsAreReported(t *testing.T) {
+	reported := captureSinkErrors(t)
+	_, cleanup := setupTestLogger(false, WithSink(&failingWriter{failing: true}))
+	defer cleanup()
+
+	Info("first")
+	Info("second")
+
+	assert.Equal(t, []string{
+		"*log.failingWriter: no space left on device",
+		"*log.failingWriter: no space left on device",
+	}, *reported)
+	assert.Equal(t, map[string]uint64{"*log.failingWriter": 2}, SinkErrorCounts())
+}
+
+func TestFallbackSinkReportsPrimaryErrors(t *testing.T) {
+	reported := captureSinkErrors(t)
+	sink := NewFallbackSink(&failingWriter{failing: true}, &failingWriter{})
+
+	_, err := sink.Write([]byte("entry\n"))
+
+	assert.NoError(t, err) // Saved by the fallback, but still reported
+	assert.Len(t, *reported, 1)
+	assert.Equal(t, uint64(1), SinkErrorCounts()["*log.failingWriter"])
+}
@@ -0,0 +1,185 @@
+//go:build !nozap
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SinkFactory opens the sink described by a URL.
+type SinkFactory func(u *url.URL) (zapcore.WriteSyncer, error)
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = make(map[string]SinkFactory)
+)
+
+func init() {
+	for scheme, factory := range map[string]SinkFactory{
+		"tcp":      dialSinkFactory("tcp"),
+		"udp":      dialSinkFactory("udp"),
+		"logstash": logstashSinkFactory,
+		"loki":     lokiSinkFactory,
+	} {
+		// Fails if another package registered the scheme with zap first.
+		if err := RegisterSink(scheme, factory); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterSink makes sinks of the given URL scheme available to OpenSink,
+// WithOutput and zap's own OutputPaths. It fails if the scheme is already
+// registered.
+func RegisterSink(scheme string, factory SinkFactory) error {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+
+	if _, ok := sinkFactories[scheme]; ok {
+		return fmt.Errorf("sink scheme %q is already registered", scheme)
+	}
+
+	err := zap.RegisterSink(scheme, func(u *url.URL) (zap.Sink, error) {
+		ws, err := factory(u)
+		if err != nil {
+			return nil, err
+		}
+		return closableSink{ws}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sinkFactories[scheme] = factory
+	return nil
+}
+
+// OpenSink opens the sink described by rawURL: a file path or file:// URL,
+// "stdout", "stderr", or a URL of a scheme added with RegisterSink. tcp://,
+// udp://, logstash:// and loki:// are registered by default. File URLs take
+// rotation parameters, see fileOptionsOf.
+func OpenSink(rawURL string) (zapcore.WriteSyncer, error) {
+	switch rawURL {
+	case "stdout":
+		return zapcore.Lock(os.Stdout), nil
+	case "stderr":
+		return zapcore.Lock(os.Stderr), nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse sink URL %q: %w", rawURL, err)
+	}
+
+	if u.Scheme == "" || u.Scheme == "file" {
+		opts, err := fileOptionsOf(u.Query())
+		if err != nil {
+			return nil, fmt.Errorf("file sink %q: %w", rawURL, err)
+		}
+		return NewFileSink(u.Path, opts...)
+	}
+
+	sinkFactoriesMu.RLock()
+	factory, ok := sinkFactories[u.Scheme]
+	sinkFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no sink registered for scheme %q", u.Scheme)
+	}
+	return factory(u)
+}
+
+// fileOptionsOf translates the parameters of a file URL into FileOptions:
+// rotate, or its alias max_size (bytes, or with a KB, MB or GB suffix),
+// max_age (a duration), max_backups and compress (a boolean), as in
+// file:///var/log/app.log?rotate=100mb&max_backups=5&compress=true.
+func fileOptionsOf(query url.Values) ([]FileOption, error) {
+	var opts []FileOption
+	for key, values := range query {
+		value := values[len(values)-1]
+		switch key {
+		case "rotate", "max_size":
+			size, err := parseSize(value)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithMaxSize(size))
+		case "max_age":
+			age, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithMaxAge(age))
+		case "max_backups":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithMaxBackups(n))
+		case "compress":
+			compress, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, err
+			}
+			if compress {
+				opts = append(opts, WithCompress())
+			}
+		default:
+			return nil, fmt.Errorf("unknown parameter %q", key)
+		}
+	}
+	return opts, nil
+}
+
+// WithOutput adds the sink described by rawURL, as opened by OpenSink. If it
+// can't be opened, NewLogger returns the error. The sink is opened once, the
+// first time the option is applied, and shared by the loggers built with it
+// after, so a ConfigWatcher reloading its configuration doesn't open it again.
+func WithOutput(rawURL string) Option {
+	var (
+		once sync.Once
+		sink zapcore.WriteSyncer
+		err  error
+	)
+	return func(o *options) {
+		once.Do(func() { sink, err = OpenSink(rawURL) })
+		if err != nil {
+			o.err = errors.Join(o.err, err)
+			return
+		}
+		o.sinks = append(o.sinks, sink)
+	}
+}
+
+// closeSinks closes the sinks that can be, reporting failures on the internal
+// output. Those without a Close method, like stdout, are left as they are.
+func closeSinks(sinks []zapcore.WriteSyncer) {
+	for _, sink := range sinks {
+		if c, ok := sink.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				reportInternal("can't close sink", zap.Error(err))
+			}
+		}
+	}
+}
+
+// closableSink adds a Close method to sinks lacking one, as zap requires.
+type closableSink struct {
+	zapcore.WriteSyncer
+}
+
+func (s closableSink) Close() error {
+	if c, ok := s.WriteSyncer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
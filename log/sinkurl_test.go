@@ -0,0 +1,93 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// memorySinks backs the "memory" scheme used by the tests, keyed by host.
+var memorySinks = map[string]*bytes.Buffer{}
+
+func init() {
+	_ = RegisterSink("memory", func(u *url.URL) (zapcore.WriteSyncer, error) {
+		buf := &bytes.Buffer{}
+		memorySinks[u.Host] = buf
+		return zapcore.AddSync(buf), nil
+	})
+}
+
+func TestRegisterSinkRejectsDuplicates(t *testing.T) {
+	err := RegisterSink("memory", func(*url.URL) (zapcore.WriteSyncer, error) { return nil, nil })
+	assert.ErrorContains(t, err, `"memory" is already registered`)
+}
+
+func TestRegisteredSinkInZapOutputPaths(t *testing.T) {
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"memory://zap"}
+	zapLogger, err := config.Build()
+	require.NoError(t, err)
+
+	zapLogger.Info("through zap")
+	assert.Contains(t, memorySinks["zap"].String(), "through zap")
+}
+
+func TestWithOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := NewLogger(false, WithOutput("memory://logger"), WithOutput("file://"+path))
+	require.NoError(t, err)
+
+	l.Info("to every output")
+
+	assert.Contains(t, memorySinks["logger"].String(), "to every output")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "to every output")
+}
+
+func TestWithOutputErrors(t *testing.T) {
+	_, err := NewLogger(false, WithOutput("nope://host"))
+	assert.ErrorContains(t, err, `no sink registered for scheme "nope"`)
+
+	_, err = NewLogger(false, WithOutput("tcp://"))
+	assert.ErrorContains(t, err, "has no host")
+
+	_, err = OpenSink("file:///tmp/app.log?rotate_daily=true")
+	assert.ErrorContains(t, err, `unknown parameter "rotate_daily"`)
+}
+
+func TestTCPSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	sink, err := OpenSink("tcp://" + ln.Addr().String())
+	require.NoError(t, err)
+	defer sink.(*ReconnectingSink).Close()
+
+	_, err = sink.Write([]byte("over tcp\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "over tcp\n", <-received)
+}
@@ -0,0 +1,138 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of SlackConfig.
+const (
+	defaultSlackRateLimit  = 10
+	defaultSlackRateWindow = time.Minute
+	defaultSlackMaxEntries = 5
+	defaultSlackTemplate   = "*{{.Level}}* {{escape .Message}}{{if .Caller}} `{{.Caller}}`{{end}}{{range $k, $v := .Fields}}\n• {{$k}}: {{escape $v}}{{end}}"
+	slackMaxEntryRunes     = 3000
+)
+
+// SlackConfig configures a sink posting entries to a Slack incoming webhook.
+type SlackConfig struct {
+	WebhookURL string
+
+	// Username and IconEmoji override those of the webhook, as in
+	// ":rotating_light:".
+	Username  string
+	IconEmoji string
+
+	// Template renders each entry, a NotifyEntry, in Slack's mrkdwn. The
+	// escape function escapes &, < and >. The default shows the level,
+	// message, caller and fields.
+	Template string
+
+	// At most RateLimit messages are posted per RateWindow, 10 per minute by
+	// default; the next message posted says how many were suppressed.
+	RateLimit  int
+	RateWindow time.Duration
+
+	// MaxEntries is how many entries a message shows, 5 by default. A batch
+	// holding more mentions how many were left out.
+	MaxEntries int
+
+	HTTP  HTTPConfig
+	Batch BatchConfig
+}
+
+// NewSlackSink returns a sink posting the Error, Panic and Fatal entries
+// written to it to Slack, one message per batch. Lower entries are skipped,
+// so the sink can take the logger's whole output; entries must be JSON
+// encoded. For instance:
+//
+//	slack, err := log.NewSlackSink(log.SlackConfig{WebhookURL: url})
+//	...
+//	log.NewLogger(false, log.WithSink(slack))
+func NewSlackSink(config SlackConfig) (*BatchSink, error) {
+	if config.WebhookURL == "" {
+		return nil, errors.New("slack sink needs a webhook URL")
+	}
+	if config.RateLimit <= 0 {
+		config.RateLimit = defaultSlackRateLimit
+	}
+	if config.RateWindow <= 0 {
+		config.RateWindow = defaultSlackRateWindow
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultSlackMaxEntries
+	}
+	tmpl, err := parseNotifyTemplate("slack", config.Template, defaultSlackTemplate, template.FuncMap{"escape": slackEscape})
+	if err != nil {
+		return nil, fmt.Errorf("invalid slack template: %w", err)
+	}
+
+	shipper, err := newHTTPShipper(config.HTTP, config.WebhookURL, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	limiter := newNotifyLimiter(config.RateLimit, config.RateWindow)
+
+	return newDecodingBatchSink("slack", config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		entries := notifyEntries(batch, zapcore.ErrorLevel, decode)
+		if len(entries) == 0 {
+			return nil
+		}
+		ok, suppressed := limiter.allow(time.Now())
+		if !ok {
+			return nil
+		}
+
+		text, err := config.text(tmpl, entries, suppressed)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(slackMessage{Text: text, Username: config.Username, IconEmoji: config.IconEmoji})
+		if err != nil {
+			return err
+		}
+		return shipper.ship(body)
+	}), nil
+}
+
+type slackMessage struct {
+	Text      string `json:"text"`
+	Username  string `json:"username,omitempty"`
+	IconEmoji string `json:"icon_emoji,omitempty"`
+}
+
+// text renders up to MaxEntries entries, separated by blank lines.
+func (c SlackConfig) text(tmpl *template.Template, entries []NotifyEntry, suppressed int) (string, error) {
+	var b strings.Builder
+	for i, e := range entries {
+		if i == c.MaxEntries {
+			fmt.Fprintf(&b, "\n\n_…and %d more_", len(entries)-i)
+			break
+		}
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		var entry strings.Builder
+		if err := tmpl.Execute(&entry, e); err != nil {
+			return "", fmt.Errorf("can't render slack message: %w", err)
+		}
+		b.WriteString(truncateRunes(entry.String(), slackMaxEntryRunes))
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(&b, "\n\n_%d earlier messages were suppressed by rate limiting_", suppressed)
+	}
+	return b.String(), nil
+}
+
+// slackEscape escapes the characters mrkdwn gives a meaning to.
+func slackEscape(v interface{}) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(fmt.Sprint(v))
+}
@@ -0,0 +1,75 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlackSink(t *testing.T) {
+	var mu sync.Mutex
+	var messages []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var msg slackMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		messages = append(messages, msg)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	sink, err := NewSlackSink(SlackConfig{
+		WebhookURL: server.URL,
+		Username:   "alerts",
+		RateLimit:  1,
+		RateWindow: time.Hour,
+		Batch:      BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"info","msg":"ignored"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"error","msg":"payment <failed>","caller":"pay.go:12","order":42}` + "\n"))
+	require.NoError(t, sink.Sync())
+	_, _ = sink.Write([]byte(`{"level":"error","msg":"rate limited"}` + "\n"))
+	require.NoError(t, sink.Sync())
+	_, _ = sink.Write([]byte(`{"level":"info","msg":"nothing to post"}` + "\n"))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "alerts", messages[0].Username)
+	assert.Equal(t, "*error* payment &lt;failed&gt; `pay.go:12`\n• order: 42", messages[0].Text)
+}
+
+func TestSlackSinkTemplate(t *testing.T) {
+	_, err := NewSlackSink(SlackConfig{WebhookURL: "http://localhost", Template: "{{.Nope"})
+	assert.Error(t, err)
+	_, err = NewSlackSink(SlackConfig{})
+	assert.Error(t, err)
+
+	config := SlackConfig{MaxEntries: 2}
+	tmpl, err := parseNotifyTemplate("slack", "{{.Level}}: {{.Message}}", "", nil)
+	require.NoError(t, err)
+	entries := []NotifyEntry{{Level: "error", Message: "a"}, {Level: "fatal", Message: "b"}, {Level: "error", Message: "c"}}
+	text, err := config.text(tmpl, entries, 3)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"error: a",
+		"fatal: b",
+		"_…and 1 more_",
+		"_3 earlier messages were suppressed by rate limiting_",
+	}, "\n\n"), text)
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// Levels slog has no equivalent for. Handlers render them as ERROR+4 and
+// ERROR+8.
+const (
+	slogPanicLevel = slog.LevelError + 4
+	slogFatalLevel = slog.LevelError + 8
+)
+
+// UseSlogBackend replaces the global logger with one writing through the
+// handler of slog's default logger. See NewSlogBackend.
+//
+// Together with the nozap build tag, it's the zero-dependency mode of the
+// package: entries go through slog only, and neither zap nor any other
+// third-party module is compiled in.
+func UseSlogBackend() {
+	UseBackend(NewSlogBackend(slog.Default().Handler()))
+}
+
+// NewSlogBackend returns a Backend writing through handler, using only the
+// standard library. The Options configuring zap cores don't apply to it. It's
+// also the backend of NewLogger in builds with the nozap tag.
+func NewSlogBackend(handler slog.Handler) Backend {
+	return &slogBackend{handler: handler}
+}
+
+type slogBackend struct {
+	handler slog.Handler
+}
+
+func (b *slogBackend) Log(level Level, msg string, keysAndValues []interface{}) {
+	lvl := slogLevel(level)
+	if b.handler.Enabled(context.Background(), lvl) {
+		// Skip runtime.Callers, this method, Logger.log and the exported
+		// function that called it.
+		var pcs [1]uintptr
+		runtime.Callers(4, pcs[:])
+
+		r := slog.NewRecord(time.Now(), lvl, msg, pcs[0])
+		r.Add(keysAndValues...)
+		_ = b.handler.Handle(context.Background(), r)
+	}
+	Terminate(level, msg)
+}
+
+func (b *slogBackend) With(keysAndValues []interface{}) Backend {
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(keysAndValues...)
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return &slogBackend{handler: b.handler.WithAttrs(attrs)}
+}
+
+func (b *slogBackend) Enabled(level Level) bool {
+	return b.handler.Enabled(context.Background(), slogLevel(level))
+}
+
+func (b *slogBackend) Sync() error {
+	return nil
+}
+
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	case PanicLevel:
+		return slogPanicLevel
+	case FatalLevel:
+		return slogFatalLevel
+	default:
+		return slog.LevelError
+	}
+}
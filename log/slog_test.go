@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTextHandler(buf *bytes.Buffer) slog.Handler {
+	return slog.NewTextHandler(buf, &slog.HandlerOptions{
+		AddSource: true,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{} // Drop the timestamp to keep the output stable
+			}
+			return a
+		},
+	})
+}
+
+func TestSlogBackend(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLoggerWithBackend(NewSlogBackend(newTextHandler(&buf)))
+
+	l.Debug("hidden") // slog's default level is Info
+	l.Info("hello", "user", "ana")
+	assert.Panics(t, func() { l.Panic("boom") })
+
+	logOutput := buf.String()
+	assert.NotContains(t, logOutput, "hidden")
+	assert.Contains(t, logOutput, `level=INFO source=`)
+	assert.Contains(t, logOutput, `slog_test.go:`) // Points at the caller, not the backend
+	assert.Contains(t, logOutput, `msg=hello user=ana`)
+	assert.Contains(t, logOutput, `level=ERROR+4`)
+}
+
+func TestSlogBackendWith(t *testing.T) {
+	var buf bytes.Buffer
+	b := NewSlogBackend(slog.NewTextHandler(&buf, nil)).With([]interface{}{"component", "api"})
+
+	b.Log(WarnLevel, "slow", []interface{}{"ms", 1200})
+
+	assert.Contains(t, buf.String(), `level=WARN msg=slow component=api ms=1200`)
+}
+
+func TestUseSlogBackend(t *testing.T) {
+	previousLogger := logger.Load()
+	defer logger.Store(previousLogger)
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(newTextHandler(&buf)))
+	UseSlogBackend()
+
+	Errorf("failed %d times", 3)
+	assert.Contains(t, buf.String(), `level=ERROR`)
+	assert.Contains(t, buf.String(), `msg="failed 3 times"`)
+}
@@ -0,0 +1,143 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SlogHandler returns a slog.Handler writing through l, so that code logging
+// with log/slog, third-party libraries included, goes through the same
+// pipeline as the rest of the program:
+//
+//	slog.SetDefault(slog.New(l.SlogHandler()))
+//
+// slog levels map to the closest level at or below them: below Info to
+// Debug, below Warn to Info, below Error to Warn and the rest to Error, so a
+// slog record never panics or exits. Attributes become fields, LogValuers
+// resolved; those of groups, from slog.Group or WithGroup, are keyed by their
+// dotted path, like "request.method". With the zap backend, entries keep the
+// time and caller of their record.
+//
+// l must not write through slog's default handler, as with UseSlogBackend,
+// once the handler is made the default: its entries would loop back to it.
+func (l *Logger) SlogHandler() slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+type slogHandler struct {
+	logger *Logger
+	prefix string // Dotted path of the groups opened with WithGroup
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(levelOfSlog(level))
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	level := levelOfSlog(r.Level)
+	keysAndValues := make([]interface{}, 0, 2*r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		keysAndValues = appendSlogAttr(keysAndValues, h.prefix, a)
+		return true
+	})
+
+	l := h.logger.resolved()
+	if _, ok := l.backend.(*zapBackend); !ok {
+		l.log(level, r.Message, keysAndValues)
+		return nil
+	}
+
+	ce := l.sugaredLogger.Desugar().Check(zapcore.Level(level), r.Message)
+	if ce == nil {
+		return nil
+	}
+	if !r.Time.IsZero() {
+		ce.Time = r.Time
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		if ce.Caller.Defined {
+			ce.Caller = zapcore.EntryCaller{Defined: true, PC: frame.PC, File: frame.File, Line: frame.Line, Function: frame.Function}
+		}
+		ce.Stack = trimStack(ce.Stack, frame.Function)
+	}
+	if l.sequence {
+		keysAndValues = append(keysAndValues, SequenceKey, nextSequence())
+	}
+	fields := make([]zap.Field, 0, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields = append(fields, zap.Any(keysAndValues[i].(string), keysAndValues[i+1]))
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	keysAndValues := make([]interface{}, 0, 2*len(attrs))
+	for _, a := range attrs {
+		keysAndValues = appendSlogAttr(keysAndValues, h.prefix, a)
+	}
+	return &slogHandler{logger: h.logger.With(keysAndValues...), prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+// appendSlogAttr appends the key and resolved value of a to keysAndValues,
+// the key prefixed with prefix, or those of its attributes if it's a group.
+// Empty attributes and groups are skipped, as slog handlers should.
+func appendSlogAttr(keysAndValues []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		if a.Equal(slog.Attr{}) {
+			return keysAndValues
+		}
+		return append(keysAndValues, prefix+a.Key, a.Value.Any())
+	}
+
+	if a.Key != "" { // Inlined otherwise
+		prefix += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		keysAndValues = appendSlogAttr(keysAndValues, prefix, ga)
+	}
+	return keysAndValues
+}
+
+// trimStack drops the frames of stack above function's, those of slog and of
+// the handler, so the stack starts where the record was logged.
+func trimStack(stack, function string) string {
+	if strings.HasPrefix(stack, function+"\n") {
+		return stack
+	}
+	if i := strings.Index(stack, "\n"+function+"\n"); i >= 0 {
+		return stack[i+1:]
+	}
+	return stack
+}
+
+// levelOfSlog returns the level of the entries written for slog records at
+// level.
+func levelOfSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
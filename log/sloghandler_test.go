@@ -0,0 +1,54 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userValue struct{ name string }
+
+func (u userValue) LogValue() slog.Value { return slog.StringValue("user:" + u.name) }
+
+func TestSlogHandler(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	sl := slog.New(GetLogger().SlogHandler())
+	sl.Debug("hidden")
+	sl.Info("hello", "user", userValue{"ana"}, slog.Group("request", "method", "GET", slog.Group("", "path", "/x")))
+	sl.With("component", "db").WithGroup("query").Warn("slow", "took", time.Second, slog.Attr{})
+	sl.Log(nil, slog.LevelError+4, "not a panic", "err", errors.New("boom"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Greater(t, len(lines), 3) // The Error entry has a stack
+
+	// Callers and stacks start at the caller of slog.
+	assert.Equal(t, "github.com/Stasky745/go-libs/log.TestSlogHandler", lines[3])
+	assert.True(t, strings.HasPrefix(lines[0], "info\tlog/sloghandler_test.go:"), lines[0])
+	assert.Contains(t, lines[0], `{"user": "user:ana", "request.method": "GET", "request.path": "/x"}`)
+	assert.True(t, strings.HasPrefix(lines[1], "warn"))
+	assert.Contains(t, lines[1], `{"component": "db", "query.took": 1}`)
+	assert.True(t, strings.HasPrefix(lines[2], "error"))
+	assert.Contains(t, lines[2], `"err": "boom"`)
+}
+
+func TestSlogHandlerOtherBackend(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLoggerWithBackend(NewSlogBackend(newTextHandler(&buf)))
+
+	sl := slog.New(l.SlogHandler()).WithGroup("job")
+	sl.Debug("hidden")
+	sl.Info("done", "id", 7)
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "msg=done job.id=7")
+}
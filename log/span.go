@@ -0,0 +1,144 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Keys of the fields logged by (*Span).End.
+const (
+	SpanNameKey     = "span.name"
+	SpanIDKey       = "span.id"
+	SpanParentIDKey = "span.parent_id"       // Left out for root spans
+	SpanDurationKey = "span.duration"        // Time between StartSpan and End
+	SpanChildrenKey = "span.children"        // Spans started under this one
+	SpanFailedKey   = "span.failed_children" // Children that ended with an error
+)
+
+// Span is a scoped operation, logged once as a single record when it ends.
+// Spans started from the context of another one are its children, so the
+// records form a tree even without a tracing backend.
+type Span struct {
+	logger   *Logger
+	ctx      context.Context
+	name     string
+	id       string
+	parent   *Span
+	start    time.Time
+	children atomic.Int64
+	failed   atomic.Int64
+
+	mu     sync.Mutex
+	fields []interface{}
+	ended  bool
+}
+
+type spanKey struct{}
+
+// StartSpan starts a span logged through the global logger.
+// See (*Logger).StartSpan.
+func StartSpan(ctx context.Context, name string, keysAndValues ...interface{}) *Span {
+	return GetLogger().StartSpan(ctx, name, keysAndValues...)
+}
+
+// StartSpan starts a span named name, a child of the span in ctx if any,
+// with keysAndValues as its first fields. Pass sp.Context() down to start
+// children, and call End once the operation is over:
+//
+//	sp := log.StartSpan(ctx, "import")
+//	defer func() { sp.End(err) }()
+func (l *Logger) StartSpan(ctx context.Context, name string, keysAndValues ...interface{}) *Span {
+	sp := &Span{
+		logger: l,
+		name:   name,
+		id:     newSpanID(),
+		start:  time.Now(),
+		fields: append([]interface{}(nil), keysAndValues...),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		sp.parent = parent
+		parent.children.Add(1)
+	}
+	sp.ctx = context.WithValue(ctx, spanKey{}, sp)
+	return sp
+}
+
+// SpanFromContext returns the span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	sp, _ := ctx.Value(spanKey{}).(*Span)
+	return sp
+}
+
+// Context returns a context carrying the span, for its children.
+func (sp *Span) Context() context.Context {
+	return sp.ctx
+}
+
+// ID returns the span ID, 16 hex digits.
+func (sp *Span) ID() string {
+	return sp.id
+}
+
+// Set adds fields to the span record. It's safe to call concurrently.
+func (sp *Span) Set(keysAndValues ...interface{}) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	sp.fields = append(sp.fields, keysAndValues...)
+}
+
+// Fields returns the span name and IDs as key-value pairs, to correlate the
+// other entries logged during the span with its record.
+func (sp *Span) Fields() []interface{} {
+	keysAndValues := []interface{}{SpanNameKey, sp.name, SpanIDKey, sp.id}
+	if sp.parent != nil {
+		keysAndValues = append(keysAndValues, SpanParentIDKey, sp.parent.id)
+	}
+	return keysAndValues
+}
+
+// End logs the span record: its fields, duration and child counts, and err
+// if not nil. The record is logged at Info, or at Error when err is not nil.
+// Calls after the first do nothing.
+func (sp *Span) End(err error) {
+	sp.mu.Lock()
+	if sp.ended {
+		sp.mu.Unlock()
+		return
+	}
+	sp.ended = true
+	keysAndValues := append(sp.Fields(), sp.fields...)
+	sp.mu.Unlock()
+
+	keysAndValues = append(keysAndValues,
+		SpanDurationKey, time.Since(sp.start),
+		SpanChildrenKey, sp.children.Load(),
+	)
+	if failed := sp.failed.Load(); failed > 0 {
+		keysAndValues = append(keysAndValues, SpanFailedKey, failed)
+	}
+
+	level := InfoLevel
+	if err != nil {
+		level = ErrorLevel
+		keysAndValues = append(keysAndValues, "error", err)
+		if sp.parent != nil {
+			sp.parent.failed.Add(1)
+		}
+	}
+	sp.logger.log(level, "span finished", keysAndValues)
+}
+
+// newSpanID returns 8 random bytes in hex, like OpenTelemetry span IDs.
+func newSpanID() string {
+	var id [8]byte
+	binary.LittleEndian.PutUint64(id[:], rand.Uint64())
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,83 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spanRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+func TestSpanTree(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLoggerWithBackend(NewSlogBackend(slog.NewJSONHandler(&buf, nil)))
+
+	root := l.StartSpan(context.Background(), "import", "file", "users.csv")
+	for i := 0; i < 2; i++ {
+		child := l.StartSpan(root.Context(), "batch", "n", i)
+		assert.Same(t, child, SpanFromContext(child.Context()))
+		if i == 1 {
+			child.End(assert.AnError)
+		} else {
+			child.End(nil)
+		}
+	}
+	root.Set("rows", 42)
+	root.End(nil)
+	root.End(nil) // Logged once
+
+	records := spanRecords(t, &buf)
+	require.Len(t, records, 3)
+
+	assert.Equal(t, "span finished", records[0]["msg"])
+	assert.Equal(t, "batch", records[0][SpanNameKey])
+	assert.Equal(t, root.ID(), records[0][SpanParentIDKey])
+	assert.Equal(t, "INFO", records[0]["level"])
+	assert.Equal(t, "ERROR", records[1]["level"])
+	assert.Equal(t, assert.AnError.Error(), records[1]["error"])
+	assert.NotEqual(t, records[0][SpanIDKey], records[1][SpanIDKey])
+
+	rec := records[2]
+	assert.Equal(t, "import", rec[SpanNameKey])
+	assert.Equal(t, root.ID(), rec[SpanIDKey])
+	assert.NotContains(t, rec, SpanParentIDKey)
+	assert.Equal(t, "users.csv", rec["file"])
+	assert.Equal(t, float64(42), rec["rows"])
+	assert.Equal(t, float64(2), rec[SpanChildrenKey])
+	assert.Equal(t, float64(1), rec[SpanFailedKey])
+	assert.Contains(t, rec, SpanDurationKey)
+}
+
+func TestSpanFields(t *testing.T) {
+	l := NewLoggerWithBackend(newRecordingBackend(InfoLevel))
+	root := l.StartSpan(context.Background(), "import")
+	child := l.StartSpan(root.Context(), "batch")
+
+	assert.Len(t, root.ID(), 16)
+	assert.Equal(t, []interface{}{SpanNameKey, "batch", SpanIDKey, child.ID(), SpanParentIDKey, root.ID()}, child.Fields())
+	assert.Nil(t, SpanFromContext(context.Background()))
+}
+
+func TestSpanReportsCaller(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLoggerWithBackend(NewSlogBackend(newTextHandler(&buf)))
+
+	l.StartSpan(context.Background(), "op").End(nil)
+	assert.Contains(t, buf.String(), "span_test.go:")
+}
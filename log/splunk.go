@@ -0,0 +1,99 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"strings"
+)
+
+// SplunkConfig configures a sink for the Splunk HTTP Event Collector (HEC).
+type SplunkConfig struct {
+	// URL of the collector, as in https://splunk.example.com:8088. The event
+	// endpoint path is added to it.
+	URL   string
+	Token string // HEC token
+
+	// Index, Source and SourceType of the events, when not left to the
+	// defaults of the token. Host defaults to os.Hostname.
+	Index      string
+	Source     string
+	SourceType string
+	Host       string
+
+	// MaxRetries is how many times a batch failing with a transient error, a
+	// network error, 429 Too Many Requests or a 5xx status, is sent again,
+	// following Backoff. It defaults to 3; a negative value disables
+	// retries.
+	MaxRetries int
+	Backoff    Backoff // DefaultBackoff if unset
+
+	HTTP  HTTPConfig // Compression may be Gzip, which HEC accepts
+	Batch BatchConfig
+}
+
+// NewSplunkSink returns a sink sending entries to Splunk HEC in batches.
+// Entries must be JSON encoded: ts becomes the event time, and msg, level,
+// caller and the other fields make up the event.
+func NewSplunkSink(config SplunkConfig) (*BatchSink, error) {
+	if config.URL == "" || config.Token == "" {
+		return nil, errors.New("splunk sink needs a URL and a token")
+	}
+	if config.Host == "" {
+		config.Host, _ = os.Hostname()
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultHTTPRetries
+	}
+	if config.Backoff == (Backoff{}) {
+		config.Backoff = DefaultBackoff
+	}
+
+	endpoint := strings.TrimSuffix(config.URL, "/") + "/services/collector/event"
+	shipper, err := newHTTPShipper(config.HTTP, endpoint, "application/json", Gzip)
+	if err != nil {
+		return nil, err
+	}
+	shipper.header.Set("Authorization", "Splunk "+config.Token)
+
+	return newDecodingBatchSink("splunk "+config.URL, config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		var body bytes.Buffer
+		enc := json.NewEncoder(&body)
+		for _, line := range batch {
+			if err := enc.Encode(config.event(decode(line))); err != nil {
+				return err
+			}
+		}
+		return shipper.shipRetrying(body.Bytes(), config.MaxRetries, config.Backoff)
+	}), nil
+}
+
+type splunkEvent struct {
+	Time       float64                `json:"time"`
+	Host       string                 `json:"host,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	SourceType string                 `json:"sourcetype,omitempty"`
+	Event      map[string]interface{} `json:"event"`
+}
+
+func (c SplunkConfig) event(e sinkEntry) splunkEvent {
+	event := e.Fields
+	event["message"] = e.Message
+	event["level"] = e.Level
+	if e.Caller != "" {
+		event["caller"] = e.Caller
+	}
+	return splunkEvent{
+		Time:       math.Round(float64(e.Time.UnixNano())/1e6) / 1e3, // Milliseconds, as HEC keeps
+		Host:       c.Host,
+		Index:      c.Index,
+		Source:     c.Source,
+		SourceType: c.SourceType,
+		Event:      event,
+	}
+}
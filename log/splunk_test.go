@@ -0,0 +1,111 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplunkSink(t *testing.T) {
+	captureInternal(t)
+	var mu sync.Mutex
+	var requests int
+	var events []splunkEvent
+	var auth, path string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // Server busy
+			return
+		}
+		auth, path = r.Header.Get("Authorization"), r.URL.Path
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		scanner := bufio.NewScanner(gz)
+		for scanner.Scan() {
+			var event splunkEvent
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+			events = append(events, event)
+		}
+		_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSplunkSink(SplunkConfig{
+		URL:        server.URL,
+		Token:      "hec-token",
+		Index:      "apps",
+		SourceType: "_json",
+		Host:       "web-1",
+		Backoff:    Backoff{Initial: time.Millisecond, Multiplier: 1},
+		HTTP:       HTTPConfig{Compression: Gzip},
+		Batch:      BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"warn","ts":1714557600.25,"caller":"api/user.go:42","msg":"slow","ms":250}`))
+	_, _ = sink.Write([]byte(`{"level":"info","ts":1714557601,"msg":"done"}`))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, requests, "retried after 503")
+	assert.Equal(t, "Splunk hec-token", auth)
+	assert.Equal(t, "/services/collector/event", path)
+	require.Len(t, events, 2)
+	assert.Equal(t, splunkEvent{
+		Time:       1714557600.25,
+		Host:       "web-1",
+		Index:      "apps",
+		SourceType: "_json",
+		Event:      map[string]interface{}{"message": "slow", "level": "warn", "caller": "api/user.go:42", "ms": float64(250)},
+	}, events[0])
+	assert.Equal(t, uint64(1), sink.Stats().Batches)
+}
+
+func TestSplunkSinkDoesntRetryRejections(t *testing.T) {
+	captureInternal(t)
+	var mu sync.Mutex
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests++
+		w.WriteHeader(http.StatusForbidden) // Invalid token
+	}))
+	defer server.Close()
+
+	sink, err := NewSplunkSink(SplunkConfig{URL: server.URL, Token: "wrong", Batch: BatchConfig{FlushInterval: time.Hour}})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"msg":"lost"}`))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, uint64(1), sink.Stats().Failed)
+}
+
+func TestSplunkSinkConfig(t *testing.T) {
+	_, err := NewSplunkSink(SplunkConfig{URL: "https://splunk:8088"})
+	assert.Error(t, err)
+}
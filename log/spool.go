@@ -0,0 +1,394 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Size caps applied by a Spool unless overridden.
+const (
+	defaultSegmentSize  = 16 << 20
+	defaultSpoolMaxSize = 256 << 20
+)
+
+const (
+	segmentExt = ".seg"
+	cursorFile = "cursor"
+)
+
+// Spool is a disk-backed queue of entries made of append-only segment files.
+// Remote sinks spool the entries they can't deliver and drain the spool once
+// the remote end is back, so entries survive collector outages and even
+// process restarts. When the spool outgrows its size cap the oldest segment is
+// discarded.
+type Spool struct {
+	dir         string
+	segmentSize int64
+	maxSize     int64
+
+	drainMu sync.Mutex // One Drain at a time, so entries are delivered once
+
+	mu       sync.Mutex
+	segments []uint64 // ids of the segment files, oldest first
+	sizes    map[uint64]int64
+	active   *os.File // newest segment, open for appending
+	offset   int64    // read position within the oldest segment
+	dropped  uint64
+}
+
+// SpoolOption customizes a Spool.
+type SpoolOption func(*Spool)
+
+// WithSegmentSize sets the size at which a new segment file is started, 16MiB
+// by default.
+func WithSegmentSize(bytes int64) SpoolOption {
+	return func(s *Spool) {
+		s.segmentSize = bytes
+	}
+}
+
+// WithSpoolMaxSize caps the total size of the segment files, 256MiB by
+// default.
+func WithSpoolMaxSize(bytes int64) SpoolOption {
+	return func(s *Spool) {
+		s.maxSize = bytes
+	}
+}
+
+// OpenSpool opens the spool kept in dir, creating the directory if needed and
+// picking up any entries left by a previous run.
+func OpenSpool(dir string, opts ...SpoolOption) (*Spool, error) {
+	s := &Spool{
+		dir:         dir,
+		segmentSize: defaultSegmentSize,
+		maxSize:     defaultSpoolMaxSize,
+		sizes:       make(map[uint64]int64),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := mkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("can't create spool directory %q: %w", dir, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't read spool directory %q: %w", dir, err)
+	}
+	for _, entry := range entries {
+		id, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), segmentExt), 10, 64)
+		if err != nil || !strings.HasSuffix(entry.Name(), segmentExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, id)
+		s.sizes[id] = info.Size()
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i] < s.segments[j] })
+
+	if len(s.segments) > 0 {
+		s.offset = s.readCursor(s.segments[0])
+	}
+	return s, nil
+}
+
+// Append adds an entry at the end of the spool.
+func (s *Spool) Append(entry []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := int64(4 + len(entry))
+	if record > s.segmentSize {
+		return fmt.Errorf("can't append to spool %q: entry of %d bytes exceeds the segment size", s.dir, len(entry))
+	}
+	if s.active != nil && s.sizes[s.newest()]+record > s.segmentSize {
+		if err := s.seal(); err != nil {
+			return err
+		}
+	}
+	if s.active == nil {
+		if err := s.startSegment(); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, record)
+	binary.BigEndian.PutUint32(buf, uint32(len(entry)))
+	copy(buf[4:], entry)
+	if _, err := s.active.Write(buf); err != nil {
+		return fmt.Errorf("can't append to spool %q: %w", s.dir, err)
+	}
+	s.sizes[s.newest()] += record
+
+	return s.enforceMaxSize()
+}
+
+// Drain hands every spooled entry to deliver, oldest first, discarding the
+// ones delivered. It stops at the first error, which it returns, leaving that
+// entry and the following ones in the spool. The spool isn't locked while
+// deliver runs, so appending doesn't wait for the remote end, and the read
+// position is saved after each entry, so a crash doesn't deliver the drained
+// ones again.
+func (s *Spool) Drain(deliver func(entry []byte) error) error {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+
+	s.mu.Lock()
+	if len(s.segments) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	// Entries appended meanwhile go to a new segment, left to the next Drain.
+	err := s.seal()
+	last := s.newest()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for {
+		s.mu.Lock()
+		if len(s.segments) == 0 || s.segments[0] > last {
+			s.mu.Unlock()
+			return nil
+		}
+		id := s.segments[0]
+		entries, err := s.readSegment(id)
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		if err := s.deliverSegment(id, entries, deliver); err != nil {
+			return err
+		}
+	}
+}
+
+// deliverSegment hands entries, read from segment id, to deliver and removes
+// the segment once they're all delivered.
+func (s *Spool) deliverSegment(id uint64, entries [][]byte, deliver func([]byte) error) error {
+	for _, entry := range entries {
+		if err := deliver(entry); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		if len(s.segments) == 0 || s.segments[0] != id {
+			// Discarded meanwhile to respect the size cap.
+			s.mu.Unlock()
+			return nil
+		}
+		s.offset += int64(4 + len(entry))
+		err := s.writeCursor(id)
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.segments) == 0 || s.segments[0] != id {
+		return nil
+	}
+	if err := os.Remove(s.segmentPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	s.dropOldest()
+	return nil
+}
+
+// Len returns the size in bytes of the spooled entries, framing included.
+func (s *Spool) Len() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total int64
+	for _, id := range s.segments {
+		total += s.sizes[id]
+	}
+	return total - s.offset
+}
+
+// Dropped returns how many entries were discarded to respect the size cap.
+func (s *Spool) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// Close closes the active segment. Spooled entries stay on disk.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.seal()
+}
+
+// readSegment returns the entries of segment id not drained yet.
+func (s *Spool) readSegment(id uint64) ([][]byte, error) {
+	f, err := os.Open(s.segmentPath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var entries [][]byte
+	r := bufio.NewReader(f)
+	for {
+		entry, err := readRecord(r, s.segmentSize)
+		if err != nil {
+			// io.EOF, or a torn write at the end of the segment; nothing more
+			// to read either way.
+			return entries, nil
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// readRecord reads an entry and its length header. A length over limit can
+// only come from a torn or corrupt header, read as io.ErrUnexpectedEOF.
+func readRecord(r io.Reader, limit int64) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := int64(binary.BigEndian.Uint32(header[:]))
+	if 4+size > limit {
+		return nil, io.ErrUnexpectedEOF
+	}
+	entry := make([]byte, size)
+	if _, err := io.ReadFull(r, entry); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return entry, nil
+}
+
+// enforceMaxSize discards the oldest segments until the spool fits its cap,
+// never touching the active one.
+func (s *Spool) enforceMaxSize() error {
+	var total int64
+	for _, id := range s.segments {
+		total += s.sizes[id]
+	}
+
+	for total > s.maxSize && len(s.segments) > 1 {
+		id := s.segments[0]
+		n := s.countRecords(id)
+		s.dropped += n
+		recordDropped(DropOverflow, n)
+		total -= s.sizes[id]
+		if err := os.Remove(s.segmentPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		s.dropOldest()
+	}
+	return nil
+}
+
+// countRecords counts the entries not yet drained from segment id.
+func (s *Spool) countRecords(id uint64) uint64 {
+	f, err := os.Open(s.segmentPath(id))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	if id == s.segments[0] {
+		if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+			return 0
+		}
+	}
+
+	var n uint64
+	r := bufio.NewReader(f)
+	for {
+		if _, err := readRecord(r, s.segmentSize); err != nil {
+			return n
+		}
+		n++
+	}
+}
+
+func (s *Spool) startSegment() error {
+	var id uint64
+	if len(s.segments) > 0 {
+		id = s.newest() + 1
+	}
+
+	f, err := os.OpenFile(s.segmentPath(id), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("can't create spool segment: %w", err)
+	}
+	s.active = f
+	s.segments = append(s.segments, id)
+	s.sizes[id] = 0
+	return nil
+}
+
+// seal closes the active segment; the next Append starts a new one.
+func (s *Spool) seal() error {
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+func (s *Spool) dropOldest() {
+	delete(s.sizes, s.segments[0])
+	s.segments = s.segments[1:]
+	s.offset = 0
+	_ = os.Remove(filepath.Join(s.dir, cursorFile))
+}
+
+func (s *Spool) newest() uint64 {
+	return s.segments[len(s.segments)-1]
+}
+
+func (s *Spool) segmentPath(id uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// writeCursor persists the read position within segment id, so a restarted
+// process doesn't deliver the same entries twice.
+func (s *Spool) writeCursor(id uint64) error {
+	return os.WriteFile(filepath.Join(s.dir, cursorFile), []byte(fmt.Sprintf("%d %d", id, s.offset)), 0o600)
+}
+
+func (s *Spool) readCursor(id uint64) int64 {
+	content, err := os.ReadFile(filepath.Join(s.dir, cursorFile))
+	if err != nil {
+		return 0
+	}
+
+	var cursorID uint64
+	var offset int64
+	if _, err := fmt.Sscanf(string(content), "%d %d", &cursorID, &offset); err != nil || cursorID != id {
+		return 0
+	}
+	return offset
+}
@@ -0,0 +1,179 @@
+//go:build !nozap
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func drainAll(t *testing.T, s *Spool) []string {
+	var got []string
+	require.NoError(t, s.Drain(func(entry []byte) error {
+		got = append(got, string(entry))
+		return nil
+	}))
+	return got
+}
+
+func TestSpoolAppendAndDrain(t *testing.T) {
+	s, err := OpenSpool(t.TempDir(), WithSegmentSize(32))
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Append([]byte(fmt.Sprintf("entry %d", i))))
+	}
+	assert.Greater(t, len(s.segments), 1) // Rolled over the small segments
+
+	assert.Equal(t, []string{"entry 0", "entry 1", "entry 2", "entry 3", "entry 4"}, drainAll(t, s))
+	assert.Zero(t, s.Len())
+	assert.Empty(t, drainAll(t, s))
+}
+
+func TestSpoolDrainStopsAtFailureAndResumes(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSpool(dir)
+	require.NoError(t, err)
+
+	for _, entry := range []string{"one", "two", "three"} {
+		require.NoError(t, s.Append([]byte(entry)))
+	}
+
+	var delivered []string
+	err = s.Drain(func(entry []byte) error {
+		if string(entry) == "two" {
+			return errors.New("collector down")
+		}
+		delivered = append(delivered, string(entry))
+		return nil
+	})
+	require.Error(t, err)
+	assert.Equal(t, []string{"one"}, delivered)
+	require.NoError(t, s.Close())
+
+	// A restarted process picks up where the last one stopped
+	reopened, err := OpenSpool(dir)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, []string{"two", "three"}, drainAll(t, reopened))
+}
+
+func TestSpoolDrainSavesPositionAfterEachEntry(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSpool(dir)
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, entry := range []string{"one", "two", "three"} {
+		require.NoError(t, s.Append([]byte(entry)))
+	}
+
+	var afterCrash []string
+	require.NoError(t, s.Drain(func(entry []byte) error {
+		if string(entry) == "two" {
+			// What a process crashing now would find on restart
+			restarted, err := OpenSpool(dir)
+			require.NoError(t, err)
+			afterCrash = drainAll(t, restarted)
+		}
+		return nil
+	}))
+	assert.Equal(t, []string{"two", "three"}, afterCrash)
+}
+
+func TestSpoolAppendDuringDrain(t *testing.T) {
+	s, err := OpenSpool(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Append([]byte("spooled")))
+
+	delivering, release := make(chan struct{}), make(chan struct{})
+	drained := make(chan error)
+	go func() {
+		drained <- s.Drain(func([]byte) error {
+			close(delivering)
+			<-release
+			return nil
+		})
+	}()
+	<-delivering
+
+	appended := make(chan error)
+	go func() { appended <- s.Append([]byte("meanwhile")) }()
+	select {
+	case err := <-appended:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Append waited for the delivery")
+	}
+
+	close(release)
+	require.NoError(t, <-drained)
+	assert.Equal(t, []string{"meanwhile"}, drainAll(t, s))
+}
+
+func TestSpoolCorruptHeader(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSpool(dir, WithSegmentSize(64))
+	require.NoError(t, err)
+	require.NoError(t, s.Append([]byte("kept")))
+	require.NoError(t, s.Close())
+
+	f, err := os.OpenFile(s.segmentPath(0), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0xff, 0xff, 0xff, 0xff, 'x'}) // Claims a 4GiB entry
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	reopened, err := OpenSpool(dir, WithSegmentSize(64))
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, []string{"kept"}, drainAll(t, reopened))
+
+	assert.Error(t, reopened.Append(make([]byte, 64))) // Wouldn't fit a segment
+}
+
+func TestSpoolMaxSizeDropsOldestSegment(t *testing.T) {
+	s, err := OpenSpool(t.TempDir(), WithSegmentSize(20), WithSpoolMaxSize(40))
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, s.Append([]byte(fmt.Sprintf("entry %d", i)))) // 11 bytes framed
+	}
+
+	got := drainAll(t, s)
+	assert.Positive(t, s.Dropped())
+	assert.Equal(t, 6, len(got)+int(s.Dropped()))
+	assert.Equal(t, "entry 5", got[len(got)-1]) // The newest entries are kept
+}
+
+func TestReconnectingSinkWithSpool(t *testing.T) {
+	captureSinkErrors(t)
+	spool, err := OpenSpool(t.TempDir())
+	require.NoError(t, err)
+	defer spool.Close()
+
+	endpoint := &fakeEndpoint{down: true}
+	sink := NewReconnectingSink("tcp:collector", endpoint.dial, WithBackoff(fastBackoff), WithSpool(spool))
+	defer sink.Close()
+
+	_, err = sink.Write([]byte("spooled 1\n"))
+	require.NoError(t, err)
+	_, err = sink.Write([]byte("spooled 2\n"))
+	require.NoError(t, err)
+	assert.Positive(t, spool.Len())
+
+	endpoint.setDown(false)
+	assert.Eventually(t, func() bool {
+		return endpoint.String() == "spooled 1\nspooled 2\n"
+	}, time.Second, time.Millisecond)
+	assert.Zero(t, spool.Len())
+}
@@ -0,0 +1,347 @@
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// Open opens a database like sql.Open, through the driver registered as
+// driverName wrapped with Wrap.
+func Open(driverName, dataSourceName string, opts ...Option) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	_ = db.Close() // It has no connection yet
+
+	connector, err := Wrap(d, opts...).(driver.DriverContext).OpenConnector(dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// Wrap returns a driver logging every query and statement run through d once
+// it completes, with the query, its arguments, duration and error, and the
+// number of rows affected by statements. Transactions are logged as BEGIN,
+// COMMIT and ROLLBACK queries.
+//
+// Arguments named like log.DefaultRedactedKeys, or the names set with
+// WithRedactedArgs, are redacted. driver.ErrSkip, with which drivers ask
+// database/sql for another way to run a query, isn't logged.
+func Wrap(d driver.Driver, opts ...Option) driver.Driver {
+	return &loggedDriver{Driver: d, opts: newOptions(opts)}
+}
+
+type loggedDriver struct {
+	driver.Driver
+	opts *options
+}
+
+func (d *loggedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedConn{Conn: conn, opts: d.opts}, nil
+}
+
+func (d *loggedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &loggedConnector{Connector: connector, driver: d}, nil
+	}
+	return &loggedConnector{driver: d, name: name}, nil
+}
+
+// loggedConnector wraps the Connector of a driver, or opens connections
+// with the driver's Open if it has none.
+type loggedConnector struct {
+	driver.Connector
+	driver *loggedDriver
+	name   string
+}
+
+func (c *loggedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.Connector == nil {
+		return c.driver.Open(c.name)
+	}
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedConn{Conn: conn, opts: c.driver.opts}, nil
+}
+
+func (c *loggedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// loggedConn implements every optional interface of driver.Conn, falling
+// back to what database/sql does without them when the wrapped connection
+// doesn't.
+type loggedConn struct {
+	driver.Conn
+	opts *options
+}
+
+func (c *loggedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *loggedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = pc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		c.opts.logQuery(ctx, query, nil, 0, nil, err)
+		return nil, err
+	}
+	return &loggedStmt{Stmt: stmt, query: query, opts: c.opts}, nil
+}
+
+func (c *loggedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *loggedConn) BeginTx(ctx context.Context, txOpts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var tx driver.Tx
+	var err error
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = bc.BeginTx(ctx, txOpts)
+	} else {
+		tx, err = c.Conn.Begin() //nolint:staticcheck // Drivers without BeginTx
+	}
+	c.opts.logQuery(ctx, "BEGIN", nil, time.Since(start), nil, err)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedTx{Tx: tx, ctx: ctx, opts: c.opts}, nil
+}
+
+func (c *loggedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := ec.ExecContext(ctx, query, args)
+	c.opts.logQuery(ctx, query, args, time.Since(start), result, err)
+	return result, err
+}
+
+func (c *loggedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	c.opts.logQuery(ctx, query, args, time.Since(start), nil, err)
+	return rows, err
+}
+
+func (c *loggedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *loggedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *loggedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *loggedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type loggedStmt struct {
+	driver.Stmt
+	query string
+	opts  *options
+}
+
+func (s *loggedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *loggedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *loggedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = ec.ExecContext(ctx, args)
+	} else if values, verr := plainValues(args); verr != nil {
+		err = verr
+	} else {
+		result, err = s.Stmt.Exec(values) //nolint:staticcheck // Drivers without ExecContext
+	}
+	s.opts.logQuery(ctx, s.query, args, time.Since(start), result, err)
+	return result, err
+}
+
+func (s *loggedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else if values, verr := plainValues(args); verr != nil {
+		err = verr
+	} else {
+		rows, err = s.Stmt.Query(values) //nolint:staticcheck // Drivers without QueryContext
+	}
+	s.opts.logQuery(ctx, s.query, args, time.Since(start), nil, err)
+	return rows, err
+}
+
+func (s *loggedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type loggedTx struct {
+	driver.Tx
+	ctx  context.Context
+	opts *options
+}
+
+func (t *loggedTx) Commit() error {
+	start := time.Now()
+	err := t.Tx.Commit()
+	t.opts.logQuery(t.ctx, "COMMIT", nil, time.Since(start), nil, err)
+	return err
+}
+
+func (t *loggedTx) Rollback() error {
+	start := time.Now()
+	err := t.Tx.Rollback()
+	t.opts.logQuery(t.ctx, "ROLLBACK", nil, time.Since(start), nil, err)
+	return err
+}
+
+// logQuery logs a query once it has run. result, if set, gives the number
+// of rows affected.
+func (o *options) logQuery(ctx context.Context, query string, args []driver.NamedValue, duration time.Duration, result driver.Result, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	level := o.level
+	switch {
+	case err != nil:
+		level = log.ErrorLevel
+	case o.slowThreshold > 0 && duration >= o.slowThreshold:
+		level = log.WarnLevel
+	}
+	l := o.log(ctx)
+	if l == nil || !l.Enabled(level) {
+		return
+	}
+
+	keysAndValues := []interface{}{"query", query, "duration", duration}
+	if o.logArgs && len(args) > 0 {
+		keysAndValues = append(keysAndValues, "args", o.args(args))
+	}
+	if result != nil && err == nil {
+		if n, rerr := result.RowsAffected(); rerr == nil {
+			keysAndValues = append(keysAndValues, "rows_affected", n)
+		}
+	}
+	if err != nil {
+		keysAndValues = append(keysAndValues, "error", err)
+	}
+	l.Log(level, "sql query", keysAndValues...)
+}
+
+// args returns the values of args for logging, redacted and capped.
+func (o *options) args(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = o.arg(arg)
+	}
+	return values
+}
+
+func (o *options) arg(arg driver.NamedValue) interface{} {
+	if arg.Name != "" {
+		for _, name := range o.redactedArgs {
+			if strings.EqualFold(arg.Name, name) {
+				return log.Redacted
+			}
+		}
+	}
+	switch v := arg.Value.(type) {
+	case []byte:
+		return fmt.Sprintf("[%d bytes]", len(v))
+	case string:
+		if len(v) > o.maxArgBytes {
+			cut := o.maxArgBytes
+			for cut > 0 && !utf8.RuneStart(v[cut]) {
+				cut--
+			}
+			return v[:cut] + "...[truncated]"
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// plainValues returns the values of args for drivers without context
+// support, which don't support named arguments either.
+func plainValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("sql driver doesn't support named argument %q", arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,199 @@
+package sqllog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// fakeDriver runs any query: those containing "fail" fail, those containing
+// "slow" take 20ms, and each statement affects one row. Its prepared
+// statements only support the driver interfaces without context.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := run(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := run(query); err != nil {
+		return nil, err
+	}
+	return fakeRows{}, nil
+}
+
+func run(query string) error {
+	if strings.Contains(query, "slow") {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if strings.Contains(query, "fail") {
+		return errors.New("syntax error")
+	}
+	return nil
+}
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), run(s.query)
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) { return fakeRows{}, run(s.query) }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return nil }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func testLogger(buf *bytes.Buffer) *log.Logger {
+	return log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+}
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+func openDB(t *testing.T, opts ...Option) *sql.DB {
+	connector, err := Wrap(fakeDriver{}, opts...).(driver.DriverContext).OpenConnector("")
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestWrap(t *testing.T) {
+	var buf bytes.Buffer
+	db := openDB(t, WithLogger(testLogger(&buf)), WithArgs(), WithMaxArgBytes(4))
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, "UPDATE users SET name = ?, password = ?, avatar = ? WHERE id = ?",
+		"annabel", sql.Named("password", "hunter2"), []byte{1, 2, 3}, 7)
+	require.NoError(t, err)
+	_, err = db.QueryContext(ctx, "SELECT fail")
+	require.Error(t, err)
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 2)
+	assert.Equal(t, "DEBUG", logged[0]["level"])
+	assert.Equal(t, "sql query", logged[0]["msg"])
+	assert.Equal(t, "UPDATE users SET name = ?, password = ?, avatar = ? WHERE id = ?", logged[0]["query"])
+	assert.Equal(t, []interface{}{"anna...[truncated]", "[REDACTED]", "[3 bytes]", float64(7)}, logged[0]["args"])
+	assert.Equal(t, float64(1), logged[0]["rows_affected"])
+	assert.Equal(t, "ERROR", logged[1]["level"])
+	assert.Equal(t, "syntax error", logged[1]["error"])
+}
+
+func TestWrapArgBytes(t *testing.T) {
+	for _, tc := range []struct {
+		max  int
+		want string
+	}{
+		{-1, "...[truncated]"},
+		{3, "zo...[truncated]"}, // Not inside the ë
+		{4, "zoë...[truncated]"},
+	} {
+		var buf bytes.Buffer
+		db := openDB(t, WithLogger(testLogger(&buf)), WithArgs(), WithMaxArgBytes(tc.max))
+		_, err := db.Exec("INSERT INTO users VALUES (?)", "zoë smith")
+		require.NoError(t, err)
+
+		logged := entries(t, &buf)
+		require.Len(t, logged, 1)
+		assert.Equal(t, []interface{}{tc.want}, logged[0]["args"], "max %d", tc.max)
+	}
+}
+
+func TestWrapPreparedAndTx(t *testing.T) {
+	var buf bytes.Buffer
+	db := openDB(t, WithLogger(testLogger(&buf)))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	stmt, err := tx.Prepare("INSERT INTO events VALUES (?)")
+	require.NoError(t, err)
+	_, err = stmt.Exec("login")
+	require.NoError(t, err)
+	require.NoError(t, stmt.Close())
+	require.NoError(t, tx.Commit())
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 3)
+	assert.Equal(t, "BEGIN", logged[0]["query"])
+	assert.Equal(t, "INSERT INTO events VALUES (?)", logged[1]["query"])
+	assert.NotContains(t, logged[1], "args")
+	assert.Equal(t, float64(1), logged[1]["rows_affected"])
+	assert.Equal(t, "COMMIT", logged[2]["query"])
+}
+
+func TestSlowThreshold(t *testing.T) {
+	var buf bytes.Buffer
+	db := openDB(t, WithLogger(testLogger(&buf)), WithLevel(log.InfoLevel), WithSlowThreshold(10*time.Millisecond))
+
+	_, _ = db.Exec("SELECT 1")
+	_, _ = db.Exec("SELECT slow")
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 2)
+	assert.Equal(t, "INFO", logged[0]["level"])
+	assert.Equal(t, "WARN", logged[1]["level"])
+}
+
+// registerFake registers fakeDriver for Open once, as tests may run more than
+// once in the same process.
+var registerFake sync.Once
+
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	registerFake.Do(func() { sql.Register("sqllog-fake", fakeDriver{}) })
+	db, err := Open("sqllog-fake", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := log.WithContext(context.Background(), testLogger(&buf).With("request_id", "abc"))
+	_, err = db.ExecContext(ctx, "DELETE FROM sessions")
+	require.NoError(t, err)
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "abc", logged[0]["request_id"])
+}
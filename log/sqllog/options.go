@@ -0,0 +1,101 @@
+// Package sqllog logs the queries of any database/sql driver through the log
+// package, with their duration and error, and their arguments if asked to.
+//
+//	db, err := sqllog.Open("postgres", dsn, sqllog.WithSlowThreshold(200*time.Millisecond))
+//
+// or, with a driver value at hand:
+//
+//	sql.Register("postgres-logged", sqllog.Wrap(&pq.Driver{}))
+package sqllog
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// Option customizes the logging of a wrapped driver.
+type Option func(*options)
+
+type options struct {
+	logger *log.Logger
+
+	level         log.Level
+	slowThreshold time.Duration
+	logArgs       bool
+	redactedArgs  []string
+	maxArgBytes   int
+}
+
+const defaultMaxArgBytes = 256
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		level:        log.DebugLevel,
+		redactedArgs: log.DefaultRedactedKeys,
+		maxArgBytes:  defaultMaxArgBytes,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithLogger logs through l. By default queries are logged through the
+// logger of their context, see log.FromContext, so they carry the fields of
+// the request that made them.
+func WithLogger(l *log.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithLevel logs successful queries at level, Debug by default. Failed
+// queries are logged at Error.
+func WithLevel(level log.Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithSlowThreshold logs the successful queries lasting d or more at Warn.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(o *options) {
+		o.slowThreshold = d
+	}
+}
+
+// WithArgs logs the arguments of queries, which are left out by default as
+// they may hold secrets: named ones, as in sql.Named("password", p), are
+// redacted after their name, see WithRedactedArgs, but positional ones have
+// no name to go by and are logged as they are.
+func WithArgs() Option {
+	return func(o *options) {
+		o.logArgs = true
+	}
+}
+
+// WithRedactedArgs replaces log.DefaultRedactedKeys as the names of the
+// named arguments whose values are redacted, see WithArgs.
+func WithRedactedArgs(names ...string) Option {
+	return func(o *options) {
+		o.redactedArgs = names
+	}
+}
+
+// WithMaxArgBytes caps the strings logged as arguments, 256 bytes by
+// default; longer ones are truncated, on a character boundary. A negative n
+// counts as 0. Byte slices are logged as their size only.
+func WithMaxArgBytes(n int) Option {
+	return func(o *options) {
+		o.maxArgBytes = max(n, 0)
+	}
+}
+
+func (o *options) log(ctx context.Context) *log.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return log.FromContext(ctx)
+}
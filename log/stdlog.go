@@ -0,0 +1,111 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"regexp"
+)
+
+// stdLogSkip is the number of frames between the caller of a standard
+// library logger and stdLogWriter.Write: the method called, like Printf, and
+// the logger's output method.
+const stdLogSkip = 2
+
+// NewStdLogger returns a standard library logger writing through the global
+// logger, whichever it is at the time of each entry. See
+// (*Logger).NewStdLogger.
+func NewStdLogger(level Level) *stdlog.Logger {
+	return stdlog.New(&stdLogWriter{logger: GetLogger, level: level}, "", 0)
+}
+
+// NewStdLogger returns a standard library logger writing each of its lines
+// as an entry of l at level, for libraries that only accept a *log.Logger.
+// Its prefix, if set, is part of the message.
+func (l *Logger) NewStdLogger(level Level) *stdlog.Logger {
+	return stdlog.New(&stdLogWriter{logger: func() *Logger { return l }, level: level}, "", 0)
+}
+
+// RedirectStdLog sends the output of the standard library's log package to
+// the global logger at Info. See (*Logger).RedirectStdLog.
+func RedirectStdLog() (restore func()) {
+	return redirectStdLog(&stdLogWriter{logger: GetLogger, level: InfoLevel})
+}
+
+// RedirectStdLog sends the output of the standard library's log package, as
+// in log.Printf, to l at Info, so that code and libraries using it end up in
+// the same entries. The returned function restores the previous output,
+// flags and prefix.
+func (l *Logger) RedirectStdLog() (restore func()) {
+	return redirectStdLog(&stdLogWriter{logger: func() *Logger { return l }, level: InfoLevel})
+}
+
+// HTTPErrorLog returns a standard library logger for the ErrorLog of
+// http.Server and httputil.ReverseProxy writing through the global logger.
+// See (*Logger).HTTPErrorLog.
+func HTTPErrorLog() *stdlog.Logger {
+	return stdlog.New(&stdLogWriter{logger: GetLogger, level: WarnLevel, parse: parseHTTPError}, "", 0)
+}
+
+// HTTPErrorLog returns a standard library logger for the ErrorLog of
+// http.Server and httputil.ReverseProxy, writing their errors, like failed
+// TLS handshakes, as entries of l at Warn:
+//
+//	server := &http.Server{Addr: ":443", Handler: mux, ErrorLog: l.HTTPErrorLog()}
+//
+// The address of the peer becomes the remote_addr field and the cause the
+// error field, so "http: TLS handshake error from 192.0.2.1:51234: EOF" is
+// logged as "http: TLS handshake error" with remote_addr 192.0.2.1:51234
+// and error EOF. Panics recovered by the server are logged at Error.
+func (l *Logger) HTTPErrorLog() *stdlog.Logger {
+	return stdlog.New(&stdLogWriter{logger: func() *Logger { return l }, level: WarnLevel, parse: parseHTTPError}, "", 0)
+}
+
+var (
+	httpPanicLine = regexp.MustCompile(`(?s)^(http: panic serving) (\S+?): (.*)$`)
+	httpErrorLine = regexp.MustCompile(`(?s)^(.+?) from (\S+?): (.*)$`)
+)
+
+// parseHTTPError splits the lines of net/http's error log in a message and
+// fields, see (*Logger).HTTPErrorLog.
+func parseHTTPError(line string, level Level) (string, Level, []interface{}) {
+	if m := httpPanicLine.FindStringSubmatch(line); m != nil {
+		return m[1], ErrorLevel, []interface{}{"remote_addr", m[2], "error", m[3]}
+	}
+	if m := httpErrorLine.FindStringSubmatch(line); m != nil {
+		return m[1], level, []interface{}{"remote_addr", m[2], "error", m[3]}
+	}
+	return line, level, nil
+}
+
+func redirectStdLog(w *stdLogWriter) func() {
+	flags, prefix, output := stdlog.Flags(), stdlog.Prefix(), stdlog.Writer()
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(w)
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(output)
+	}
+}
+
+// stdLogWriter writes the lines of a standard library logger, one per
+// Write, as entries. parse, if set, turns a line in the message, level and
+// fields of its entry.
+type stdLogWriter struct {
+	logger func() *Logger
+	level  Level
+	parse  func(line string, level Level) (string, Level, []interface{})
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg, level := string(bytes.TrimSuffix(p, []byte("\n"))), w.level
+	var keysAndValues []interface{}
+	if w.parse != nil {
+		msg, level, keysAndValues = w.parse(msg, level)
+	}
+	w.logger().WithCallerSkip(stdLogSkip).log(level, msg, keysAndValues)
+	return len(p), nil
+}
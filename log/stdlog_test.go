@@ -0,0 +1,93 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	stdlog "log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLogger(false, WithSink(zapcore.AddSync(&buf)))
+	require.NoError(t, err)
+
+	std := l.NewStdLogger(WarnLevel)
+	std.SetPrefix("proxy: ")
+	_, _, line, _ := runtime.Caller(0)
+	std.Printf("upstream %s unreachable", "10.0.0.1")
+
+	out := buf.String()
+	assert.Contains(t, out, `"level":"warn"`)
+	assert.Contains(t, out, fmt.Sprintf(`"caller":"log/stdlog_test.go:%d"`, line+1))
+	assert.Contains(t, out, `"msg":"proxy: upstream 10.0.0.1 unreachable"`)
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLogger(false, WithSink(zapcore.AddSync(&buf)))
+	require.NoError(t, err)
+
+	flags := stdlog.Flags()
+	restore := l.RedirectStdLog()
+	_, _, line, _ := runtime.Caller(0)
+	stdlog.Println("from the standard library")
+	restore()
+	output := stdlog.Writer()
+	stdlog.SetOutput(&bytes.Buffer{}) // Keep the test's output clean
+	stdlog.Println("not redirected")
+	stdlog.SetOutput(output)
+
+	out := buf.String()
+	assert.Equal(t, 1, strings.Count(out, "\n"))
+	assert.Contains(t, out, `"level":"info"`)
+	assert.Contains(t, out, fmt.Sprintf(`"caller":"log/stdlog_test.go:%d"`, line+1))
+	assert.Contains(t, out, `"msg":"from the standard library"`)
+	assert.Equal(t, flags, stdlog.Flags())
+}
+
+func TestHTTPErrorLog(t *testing.T) {
+	backend := newRecordingBackend(DebugLevel)
+	errorLog := NewLoggerWithBackend(backend).HTTPErrorLog()
+
+	errorLog.Printf("http: TLS handshake error from %s: %v", "192.0.2.1:51234", "EOF")
+	errorLog.Printf("http: panic serving [::1]:5000: boom\ngoroutine 7 [running]:")
+	errorLog.Printf("http: Accept error: too many open files; retrying in 5ms")
+
+	assert.Equal(t, []string{
+		"1 http: TLS handshake error [remote_addr 192.0.2.1:51234 error EOF]",
+		"2 http: panic serving [remote_addr [::1]:5000 error boom\ngoroutine 7 [running]:]",
+		"1 http: Accept error: too many open files; retrying in 5ms []",
+	}, backend.logged())
+}
+
+func TestHTTPErrorLogServer(t *testing.T) {
+	backend := newRecordingBackend(DebugLevel)
+
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.Config.ErrorLog = NewLoggerWithBackend(backend).HTTPErrorLog()
+	server.StartTLS()
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	_, _ = conn.Write([]byte("not a handshake\r\n\r\n"))
+	_, _ = io.ReadAll(conn)
+	_ = conn.Close()
+
+	require.Eventually(t, func() bool { return len(backend.logged()) > 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, strings.HasPrefix(backend.logged()[0], "1 http: TLS handshake error [remote_addr 127.0.0.1:"), backend.logged()[0])
+}
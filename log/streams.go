@@ -0,0 +1,45 @@
+//go:build !nozap
+
+package log
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithSplitStreams writes Warn entries and above to stderr and the others to
+// stdout, instead of everything to stderr, for the container log collectors
+// that tell severities apart by stream. Both use the logger's encoding.
+func WithSplitStreams() Option {
+	return func(o *options) {
+		o.noStderr = true
+		o.splitStreams = true
+	}
+}
+
+// streamCores returns the cores of WithSplitStreams, writing to stdout and
+// stderr. Each keeps to its side of Warn on Write as well, for the entries
+// written past the logger's level.
+func streamCores(config zap.Config, stdout, stderr zapcore.WriteSyncer) []zapcore.Core {
+	below := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool { return lvl < zapcore.WarnLevel })
+	above := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool { return lvl >= zapcore.WarnLevel })
+	return []zapcore.Core{
+		streamCore(config, stdout, below),
+		streamCore(config, stderr, above),
+	}
+}
+
+func streamCore(config zap.Config, sink zapcore.WriteSyncer, accept zapcore.LevelEnabler) zapcore.Core {
+	level := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return accept.Enabled(lvl) && config.Level.Enabled(lvl)
+	})
+	return &filterCore{Core: zapcore.NewCore(newEncoder(config), monitorSink(sink), level), accept: accept}
+}
+
+// The streams of WithSplitStreams, replaced in tests.
+var (
+	stdoutSink zapcore.WriteSyncer = zapcore.Lock(os.Stdout)
+	stderrSink zapcore.WriteSyncer = zapcore.Lock(os.Stderr)
+)
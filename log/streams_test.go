@@ -0,0 +1,57 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWithSplitStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	defer func(out, err zapcore.WriteSyncer) { stdoutSink, stderrSink = out, err }(stdoutSink, stderrSink)
+	stdoutSink, stderrSink = zapcore.AddSync(&stdout), zapcore.AddSync(&stderr)
+
+	l, err := NewLogger(false, WithLevel(DebugLevel), WithSplitStreams())
+	require.NoError(t, err)
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+
+	assert.Contains(t, stdout.String(), `"msg":"debug"`)
+	assert.Contains(t, stdout.String(), `"msg":"info"`)
+	assert.NotContains(t, stdout.String(), "warn")
+	assert.Contains(t, stderr.String(), `"msg":"warn"`)
+	assert.Contains(t, stderr.String(), `"msg":"error"`)
+	assert.NotContains(t, stderr.String(), `"msg":"info"`)
+
+	l.SetLevel(ErrorLevel)
+	l.Warn("hidden")
+	assert.NotContains(t, stderr.String(), "hidden")
+}
+
+func TestWithSplitStreamsRequestBuffer(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	defer func(out, err zapcore.WriteSyncer) { stdoutSink, stderrSink = out, err }(stdoutSink, stderrSink)
+	stdoutSink, stderrSink = zapcore.AddSync(&stdout), zapcore.AddSync(&stderr)
+
+	l, err := NewLogger(false, WithSplitStreams())
+	require.NoError(t, err)
+
+	req := l.NewRequestBuffer(10, 0)
+	req.Debug("buffered")
+	req.Warn("slow")
+	req.End(errors.New("request failed"))
+
+	assert.Contains(t, stdout.String(), `"msg":"buffered"`)
+	assert.NotContains(t, stderr.String(), "buffered")
+	assert.Contains(t, stderr.String(), `"msg":"slow"`)
+	assert.NotContains(t, stdout.String(), "slow")
+}
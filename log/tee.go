@@ -0,0 +1,111 @@
+//go:build !nozap
+
+package log
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Encodings of a TeeSink.
+const (
+	JSONEncoding         = "json"
+	ConsoleEncoding      = "console"
+	ColorConsoleEncoding = "color-console" // Console with colored levels
+)
+
+// TeeSink is an output of WithTee, with its own level and encoding.
+type TeeSink struct {
+	Sink     zapcore.WriteSyncer
+	Level    Level  // Minimum level of the entries written to Sink
+	Encoding string // JSONEncoding, the default, ConsoleEncoding or ColorConsoleEncoding
+}
+
+// WithTee writes entries to each of sinks, replacing the default stderr
+// output, each with its own level and encoding, for instance JSON to a file
+// at Info and colored console to stderr at Debug:
+//
+//	log.NewLogger(false, log.WithLevel(log.DebugLevel), log.WithTee(
+//		log.TeeSink{Sink: file, Level: log.InfoLevel},
+//		log.TeeSink{Sink: zapcore.Lock(os.Stderr), Level: log.DebugLevel, Encoding: log.ColorConsoleEncoding},
+//	))
+//
+// A sink's level filters on top of the logger's, which SetLevel changes, so
+// the logger's level must be as low as the lowest sink's. The other settings
+// of the encoder, like the key names, are the logger's.
+func WithTee(sinks ...TeeSink) Option {
+	return func(o *options) {
+		for _, s := range sinks {
+			switch s.Encoding {
+			case "", JSONEncoding, ConsoleEncoding, ColorConsoleEncoding:
+			default:
+				o.err = fmt.Errorf("unsupported tee sink encoding %q", s.Encoding)
+				return
+			}
+		}
+		o.noStderr = true
+		o.tee = append(o.tee, sinks...)
+	}
+}
+
+// teeCore returns the core writing to s, for a logger built from config.
+func (o *options) teeCore(config zap.Config, s TeeSink) zapcore.Core {
+	encoderConfig := config.EncoderConfig
+	switch s.Encoding {
+	case ConsoleEncoding:
+		config.Encoding = "console"
+	case ColorConsoleEncoding:
+		config.Encoding = "console"
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	default:
+		config.Encoding = "json"
+	}
+	if o.prettyFields && config.Development && config.Encoding == "console" {
+		config.Encoding = prettyConsoleEncoding
+	}
+	config.EncoderConfig = encoderConfig
+
+	level := teeLevel{logger: config.Level, min: zapcore.Level(s.Level)}
+	useEntryKeys(s.Sink, encoderConfig)
+	core := zapcore.NewCore(newEncoder(config), monitorSink(s.Sink), level)
+	return &filterCore{Core: core, accept: zapcore.Level(s.Level)}
+}
+
+// teeLevel enables the levels enabled by the logger's level, from min.
+type teeLevel struct {
+	logger zapcore.LevelEnabler
+	min    zapcore.Level
+}
+
+func (l teeLevel) Enabled(lvl zapcore.Level) bool {
+	return lvl >= l.min && l.logger.Enabled(lvl)
+}
+
+// filterCore drops on Write the entries accept doesn't enable. Module levels,
+// request buffers and escalation write straight to the cores below them for
+// levels the logger doesn't enable, skipping Check, so a sink's own level has
+// to hold on Write too.
+type filterCore struct {
+	zapcore.Core
+	accept zapcore.LevelEnabler
+}
+
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &filterCore{Core: c.Core.With(fields), accept: c.accept}
+}
+
+func (c *filterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *filterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.accept.Enabled(ent.Level) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
@@ -0,0 +1,85 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWithTee(t *testing.T) {
+	var file, console bytes.Buffer
+	l, err := NewLogger(false, WithLevel(DebugLevel), WithTee(
+		TeeSink{Sink: zapcore.AddSync(&file), Level: InfoLevel},
+		TeeSink{Sink: zapcore.AddSync(&console), Level: DebugLevel, Encoding: ColorConsoleEncoding},
+	))
+	require.NoError(t, err)
+
+	l.Debug("details", "n", 1)
+	l.Info("started")
+
+	assert.Equal(t, 1, strings.Count(file.String(), "\n"))
+	assert.Contains(t, file.String(), `"msg":"started"`)
+	assert.Contains(t, console.String(), "\x1b[35mDEBUG\x1b[0m\t")
+	assert.Contains(t, console.String(), "details\t{\"n\": 1}")
+	assert.Contains(t, console.String(), "started")
+
+	// The logger's level applies on top of the sinks'.
+	l.SetLevel(WarnLevel)
+	l.Info("hidden")
+	assert.NotContains(t, console.String(), "hidden")
+}
+
+func TestWithTeeEncodingError(t *testing.T) {
+	_, err := NewLogger(false, WithTee(TeeSink{Sink: zapcore.AddSync(&bytes.Buffer{}), Encoding: "xml"}))
+	assert.ErrorContains(t, err, `unsupported tee sink encoding "xml"`)
+}
+
+func TestWithTeeSinkLevelHoldsPastLoggerLevel(t *testing.T) {
+	// Each of these writes a Debug entry the logger's Info level doesn't
+	// enable, past the tee's Check.
+	tests := []struct {
+		name string
+		opt  Option
+		log  func(l *Logger)
+	}{
+		{"module level", WithModuleLevels("db=debug"), func(l *Logger) {
+			l.Named("db").Debug("debug entry")
+		}},
+		{"request buffer", nil, func(l *Logger) {
+			req := l.NewRequestBuffer(10, 0)
+			req.Debug("debug entry")
+			req.End(errors.New("request failed"))
+		}},
+		{"escalation", WithEscalation(1, time.Minute, time.Hour), func(l *Logger) {
+			l.Error("charge failed", ComponentKey, "payments")
+			l.Debug("debug entry", ComponentKey, "payments")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs, all bytes.Buffer
+			opts := []Option{WithTee(
+				TeeSink{Sink: zapcore.AddSync(&errs), Level: ErrorLevel},
+				TeeSink{Sink: zapcore.AddSync(&all), Level: DebugLevel},
+			)}
+			if tt.opt != nil {
+				opts = append(opts, tt.opt)
+			}
+			l, err := NewLogger(false, opts...)
+			require.NoError(t, err)
+
+			tt.log(l)
+
+			assert.Contains(t, all.String(), "debug entry")
+			assert.NotContains(t, errs.String(), "debug entry")
+		})
+	}
+}
@@ -0,0 +1,144 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of TelegramConfig, and the message size limit of the Bot API.
+const (
+	defaultTelegramAPIHost     = "https://api.telegram.org"
+	defaultTelegramDedupWindow = time.Hour
+	telegramMaxMessageRunes    = 4096
+	telegramOmittedRoom        = 32 // For the note about omitted fields
+)
+
+// TelegramConfig configures a sink sending critical entries to a Telegram
+// chat through a bot.
+type TelegramConfig struct {
+	Token  string // Bot token, from @BotFather
+	ChatID string // Numeric ID, or @username of a public channel
+
+	// MessageThreadID sends to a topic of a forum supergroup.
+	MessageThreadID int
+
+	// Repeats of an entry, with the same level, message and caller, are
+	// suppressed within DedupWindow of its last notification, an hour by
+	// default; the next notification says how many were. The state is kept
+	// in StateFile, so that a process crashing in a restart loop notifies
+	// once. StateFile defaults to a file named after the bot and chat in
+	// os.TempDir().
+	DedupWindow time.Duration
+	StateFile   string
+
+	// APIHost replaces https://api.telegram.org, for instance with a local
+	// Bot API server.
+	APIHost string
+
+	HTTP  HTTPConfig
+	Batch BatchConfig
+}
+
+// NewTelegramSink returns a sink sending the Panic and Fatal entries written
+// to it to a Telegram chat, one message each. Lower entries are skipped, so
+// the sink can take the logger's whole output; entries must be JSON encoded.
+// Panic and Fatal entries sync the sinks before the process goes down, so
+// their messages are sent first.
+func NewTelegramSink(config TelegramConfig) (*BatchSink, error) {
+	if config.Token == "" || config.ChatID == "" {
+		return nil, errors.New("telegram sink needs a bot token and a chat ID")
+	}
+	if config.DedupWindow <= 0 {
+		config.DedupWindow = defaultTelegramDedupWindow
+	}
+	if config.StateFile == "" {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(config.Token + "\x00" + config.ChatID))
+		config.StateFile = filepath.Join(os.TempDir(), fmt.Sprintf("telegram-notifier-%016x.json", h.Sum64()))
+	}
+	host := config.APIHost
+	if host == "" {
+		host = defaultTelegramAPIHost
+	}
+
+	endpoint := strings.TrimSuffix(host, "/") + "/bot" + config.Token + "/sendMessage"
+	shipper, err := newHTTPShipper(config.HTTP, endpoint, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	dedup := newNotifyDedup(config.DedupWindow, config.StateFile)
+
+	return newDecodingBatchSink("telegram "+config.ChatID, config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		var errs []error
+		for _, e := range notifyEntries(batch, zapcore.DPanicLevel, decode) {
+			ok, repeats := dedup.allow(notifyKey(e), time.Now())
+			if !ok {
+				continue
+			}
+			body, err := json.Marshal(telegramMessage{
+				ChatID:                config.ChatID,
+				MessageThreadID:       config.MessageThreadID,
+				Text:                  telegramText(e, repeats),
+				ParseMode:             "HTML",
+				DisableWebPagePreview: true,
+			})
+			if err == nil {
+				err = shipper.ship(body)
+			}
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}), nil
+}
+
+type telegramMessage struct {
+	ChatID                string `json:"chat_id"`
+	MessageThreadID       int    `json:"message_thread_id,omitempty"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+// telegramText formats e as HTML: the level and message in bold, then the
+// caller and fields. Fields that would go over the size limit of a message
+// are left out, since cutting through the markup would get it rejected.
+func telegramText(e NotifyEntry, repeats int) string {
+	head := fmt.Sprintf("<b>%s: %s</b>", strings.ToUpper(e.Level), html.EscapeString(truncateRunes(e.Message, telegramMaxMessageRunes/2)))
+	if e.Caller != "" {
+		head += fmt.Sprintf("\n<code>%s</code>", html.EscapeString(e.Caller))
+	}
+	var tail string
+	if repeats > 0 {
+		tail = fmt.Sprintf("\n<i>%d repeats suppressed since the last notification</i>", repeats)
+	}
+
+	keys := e.fieldKeys()
+
+	var b strings.Builder
+	b.WriteString(head)
+	size := utf8.RuneCountInString(head) + utf8.RuneCountInString(tail)
+	for i, key := range keys {
+		line := fmt.Sprintf("\n%s: %s", html.EscapeString(key), html.EscapeString(fmt.Sprint(e.Fields[key])))
+		n := utf8.RuneCountInString(line)
+		if size+n > telegramMaxMessageRunes-telegramOmittedRoom {
+			fmt.Fprintf(&b, "\n<i>%d more fields</i>", len(keys)-i)
+			break
+		}
+		b.WriteString(line)
+		size += n
+	}
+	b.WriteString(tail)
+	return b.String()
+}
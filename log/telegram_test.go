@@ -0,0 +1,74 @@
+//go:build !nozap
+
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegramSink(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	var messages []telegramMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var msg telegramMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		paths = append(paths, r.URL.Path)
+		messages = append(messages, msg)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	config := TelegramConfig{
+		Token:     "123:abc",
+		ChatID:    "-100200",
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		APIHost:   server.URL,
+		Batch:     BatchConfig{FlushInterval: time.Hour},
+	}
+	line := []byte(`{"level":"fatal","msg":"can't open <db>","caller":"main.go:20","attempt":3}` + "\n")
+	for i := 0; i < 2; i++ { // The process restarting after the crash
+		sink, err := NewTelegramSink(config)
+		require.NoError(t, err)
+		_, _ = sink.Write([]byte(`{"level":"error","msg":"not critical"}` + "\n"))
+		_, _ = sink.Write(line)
+		require.NoError(t, sink.Close())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, messages, 1, "the restart doesn't notify again")
+	assert.Equal(t, "/bot123:abc/sendMessage", paths[0])
+	assert.Equal(t, telegramMessage{
+		ChatID:                "-100200",
+		Text:                  "<b>FATAL: can&#39;t open &lt;db&gt;</b>\n<code>main.go:20</code>\nattempt: 3",
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+	}, messages[0])
+}
+
+func TestTelegramText(t *testing.T) {
+	text := telegramText(NotifyEntry{Level: "panic", Message: "boom"}, 2)
+	assert.Equal(t, "<b>PANIC: boom</b>\n<i>2 repeats suppressed since the last notification</i>", text)
+
+	fields := map[string]interface{}{"a": strings.Repeat("x", 3000), "b": strings.Repeat("y", 3000), "c": "z"}
+	text = telegramText(NotifyEntry{Level: "fatal", Message: "big", Fields: fields}, 0)
+	assert.LessOrEqual(t, len(text), telegramMaxMessageRunes)
+	assert.True(t, strings.HasSuffix(text, "\n<i>2 more fields</i>"))
+
+	_, err := NewTelegramSink(TelegramConfig{Token: "123:abc"})
+	assert.Error(t, err)
+}
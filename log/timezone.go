@@ -0,0 +1,28 @@
+//go:build !nozap
+
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// WithTimeZone sets the time zone of the timestamps. It defaults to UTC in
+// production, so every host of a fleet agrees, and to the local time zone in
+// development.
+func WithTimeZone(loc *time.Location) Option {
+	return func(o *options) {
+		o.timeZone = loc
+	}
+}
+
+// inLocation converts times to loc before encoding them with encode.
+func inLocation(loc *time.Location, encode zapcore.TimeEncoder) zapcore.TimeEncoder {
+	if encode == nil {
+		return nil
+	}
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		encode(t.In(loc), enc)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !nozap
+
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func encodeTime(t *testing.T, opts []Option, at time.Time) string {
+	config := zap.NewDevelopmentConfig()
+	newOptions(opts).configure(&config)
+
+	buf, err := zapcore.NewJSONEncoder(config.EncoderConfig).EncodeEntry(zapcore.Entry{Time: at}, nil)
+	require.NoError(t, err)
+	return buf.String()
+}
+
+func TestTimeZone(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*3600)
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, tokyo)
+
+	assert.Contains(t, encodeTime(t, nil, at), "2024-05-01T12:00:00.000+0900") // Left alone by default
+	assert.Contains(t, encodeTime(t, []Option{WithTimeZone(time.UTC)}, at), "2024-05-01T03:00:00.000Z")
+}
@@ -0,0 +1,73 @@
+//go:build !nozap
+
+package log
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// defaultDialTimeout bounds how long the network sinks wait for a connection.
+const defaultDialTimeout = 10 * time.Second
+
+// TLSConfig holds the TLS settings shared by every TLS-capable sink,
+// including client certificates for collectors requiring mutual TLS.
+type TLSConfig struct {
+	CertFile   string // Client certificate, PEM encoded
+	KeyFile    string // Key of the client certificate, PEM encoded
+	CAFile     string // CA bundle verifying the server; the system pool if empty
+	ServerName string // Overrides the name verified against the server certificate
+
+	// InsecureSkipVerify disables server certificate verification. Only meant
+	// for development against self-signed collectors.
+	InsecureSkipVerify bool
+}
+
+// Build loads the certificates and returns the matching *tls.Config.
+func (c TLSConfig) Build() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate %q: %w", c.CertFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA bundle %q: %w", c.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %q", c.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// TLSDialer returns a dial function for NewReconnectingSink opening TLS
+// connections to addr.
+func TLSDialer(addr string, config *tls.Config) func() (io.WriteCloser, error) {
+	return func() (io.WriteCloser, error) {
+		dialer := &net.Dialer{Timeout: defaultDialTimeout}
+		return tls.DialWithDialer(dialer, "tcp", addr, config)
+	}
+}
@@ -0,0 +1,138 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// issueCert creates a certificate signed by parent, or self-signed if nil.
+func issueCert(t *testing.T, name string, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// writePEM writes the certificate and key of c, returning their paths.
+func (c *testCert) writePEM(t *testing.T, dir, name string) (certFile, keyFile string) {
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestTLSConfigMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, "test-ca", nil, true)
+	server := issueCert(t, "collector.internal", ca, false)
+	client := issueCert(t, "app", ca, false)
+
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	certFile, keyFile := client.writePEM(t, dir, "client")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.der}, PrivateKey: server.key}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	config, err := TLSConfig{
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		CAFile:     caFile,
+		ServerName: "collector.internal", // Dialing an IP, so the name must be overridden
+	}.Build()
+	require.NoError(t, err)
+
+	sink := NewReconnectingSink("tls:collector", TLSDialer(listener.Addr().String(), config))
+	defer sink.Close()
+	_, err = sink.Write([]byte("over mtls\n"))
+	require.NoError(t, err)
+
+	select {
+	case line := <-received:
+		assert.Equal(t, "over mtls\n", line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector received nothing")
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	_, err := TLSConfig{CertFile: "client.crt"}.Build()
+	assert.ErrorContains(t, err, "must be set together")
+
+	_, err = TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}.Build()
+	assert.ErrorContains(t, err, "missing.pem")
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, nil, 0o600))
+	_, err = TLSConfig{CAFile: empty}.Build()
+	assert.ErrorContains(t, err, "no certificates found")
+}
+
+func TestTLSConfigInsecureSkipVerify(t *testing.T) {
+	config, err := TLSConfig{InsecureSkipVerify: true}.Build()
+	require.NoError(t, err)
+	assert.True(t, config.InsecureSkipVerify)
+}
@@ -0,0 +1,66 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ForTrace returns the global logger scoped to the trace in ctx.
+// See (*Logger).ForTrace.
+func ForTrace(ctx context.Context) *Logger {
+	return GetLogger().ForTrace(ctx)
+}
+
+// ForTrace returns a logger whose Debug entries are written exactly when the
+// OpenTelemetry trace in ctx is sampled, whatever the level of l. Requests
+// that get traced thus also get detailed logs, and the others don't pay for
+// them. If ctx carries no valid span context, l is returned as is.
+func (l *Logger) ForTrace(ctx context.Context) *Logger {
+	l = l.resolved()
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return l
+	}
+
+	sampled := spanContext.IsSampled()
+	sugared := l.sugaredLogger.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &traceDebugCore{Core: core, sampled: sampled}
+	})).Sugar()
+	return l.derive(sugared)
+}
+
+// traceDebugCore writes Debug entries if the trace is sampled and drops them
+// otherwise, leaving other levels to the wrapped core.
+type traceDebugCore struct {
+	zapcore.Core
+	sampled bool
+}
+
+func (c *traceDebugCore) Enabled(lvl zapcore.Level) bool {
+	if lvl == zapcore.DebugLevel {
+		return c.sampled
+	}
+	return c.Core.Enabled(lvl)
+}
+
+func (c *traceDebugCore) With(fields []zapcore.Field) zapcore.Core {
+	return &traceDebugCore{Core: c.Core.With(fields), sampled: c.sampled}
+}
+
+func (c *traceDebugCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level != zapcore.DebugLevel {
+		return c.Core.Check(ent, ce)
+	}
+	if !c.sampled {
+		return ce
+	}
+	if c.Core.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce.AddCore(ent, c)
+}
@@ -0,0 +1,47 @@
+//go:build !nozap
+
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func traceContext(flags trace.TraceFlags) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: flags,
+	}))
+}
+
+func TestForTraceSampled(t *testing.T) {
+	buf, cleanup := setupTestLogger(false) // Info level
+	defer cleanup()
+
+	ForTrace(traceContext(trace.FlagsSampled)).Debug("sampled request detail")
+
+	assert.Contains(t, buf.String(), "sampled request detail")
+}
+
+func TestForTraceNotSampled(t *testing.T) {
+	buf, cleanup := setupTestLogger(true) // Debug level
+	defer cleanup()
+
+	l := ForTrace(traceContext(0))
+	l.Debug("unsampled request detail")
+	l.Info("still logged")
+
+	assert.NotContains(t, buf.String(), "unsampled request detail")
+	assert.Contains(t, buf.String(), "still logged")
+}
+
+func TestForTraceWithoutSpan(t *testing.T) {
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	assert.Same(t, GetLogger(), ForTrace(context.Background()))
+}
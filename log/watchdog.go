@@ -0,0 +1,187 @@
+//go:build !nozap
+
+package log
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// stalledAfter is how many missed intervals turn a late component into a
+// stalled one.
+const stalledAfter = 3
+
+// defaultWatchdogCheck is the check interval of a Watchdog given none.
+const defaultWatchdogCheck = time.Second
+
+// Watchdog watches components that are expected to call Beat periodically
+// and logs when one of them stops doing so. A component that misses its
+// interval is logged at Warn; one that misses stalledAfter intervals is
+// logged at Error along with the stacks of every goroutine.
+type Watchdog struct {
+	logger     *Logger
+	checkEvery time.Duration
+	now        func() time.Time
+
+	mu         sync.Mutex
+	heartbeats map[string]*Heartbeat
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Heartbeat is a component registered with a Watchdog.
+type Heartbeat struct {
+	logger   *Logger
+	name     string
+	interval time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	lastSeen time.Time
+	reported int // missed intervals already reported
+}
+
+// NewWatchdog starts a Watchdog on the global logger. See (*Logger).NewWatchdog.
+func NewWatchdog(checkEvery time.Duration) *Watchdog {
+	return GetLogger().NewWatchdog(checkEvery)
+}
+
+// NewWatchdog starts a Watchdog that checks its components every checkEvery,
+// every second if it's not positive. Call Stop to release it.
+func (l *Logger) NewWatchdog(checkEvery time.Duration) *Watchdog {
+	w := l.newWatchdog(checkEvery, time.Now)
+	ticker := time.NewTicker(w.checkEvery)
+	go func() {
+		defer ticker.Stop()
+		w.run(ticker.C)
+	}()
+	return w
+}
+
+// newWatchdog returns a Watchdog reading the time with now, left to check
+// its components when run is.
+func (l *Logger) newWatchdog(checkEvery time.Duration, now func() time.Time) *Watchdog {
+	if checkEvery <= 0 {
+		checkEvery = defaultWatchdogCheck
+	}
+	return &Watchdog{
+		logger:     l,
+		checkEvery: checkEvery,
+		now:        now,
+		heartbeats: make(map[string]*Heartbeat),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Register adds a component expected to beat at least once per interval, or
+// per check interval of the Watchdog if interval isn't positive. Registering
+// an existing name replaces it.
+func (w *Watchdog) Register(name string, interval time.Duration) *Heartbeat {
+	if interval <= 0 {
+		interval = w.checkEvery
+	}
+	hb := &Heartbeat{logger: w.logger, name: name, interval: interval, now: w.now, lastSeen: w.now()}
+
+	w.mu.Lock()
+	w.heartbeats[name] = hb
+	w.mu.Unlock()
+
+	return hb
+}
+
+// Unregister stops watching the named component.
+func (w *Watchdog) Unregister(name string) {
+	w.mu.Lock()
+	delete(w.heartbeats, name)
+	w.mu.Unlock()
+}
+
+// Stop stops the Watchdog and waits for its goroutine to exit. Calling it
+// again does nothing.
+func (w *Watchdog) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// Beat records that the component is alive. A component that recovers after
+// being reported late is logged at Info.
+func (h *Heartbeat) Beat() {
+	h.mu.Lock()
+	lastSeen, reported := h.lastSeen, h.reported
+	h.lastSeen = h.now()
+	h.reported = 0
+	h.mu.Unlock()
+
+	if reported > 0 {
+		h.logger.Info("component recovered", ComponentKey, h.name, "last_seen", lastSeen)
+	}
+}
+
+// run checks the components at each tick until Stop.
+func (w *Watchdog) run(tick <-chan time.Time) {
+	defer close(w.done)
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-tick:
+			w.check(w.now())
+		}
+	}
+}
+
+func (w *Watchdog) check(now time.Time) {
+	w.mu.Lock()
+	heartbeats := make([]*Heartbeat, 0, len(w.heartbeats))
+	for _, hb := range w.heartbeats {
+		heartbeats = append(heartbeats, hb)
+	}
+	w.mu.Unlock()
+
+	for _, hb := range heartbeats {
+		hb.mu.Lock()
+		lastSeen, reported := hb.lastSeen, hb.reported
+		missed := int(now.Sub(lastSeen) / hb.interval)
+
+		switch {
+		case missed >= stalledAfter && reported < stalledAfter:
+			hb.reported = stalledAfter
+		case missed >= 1 && reported < 1:
+			hb.reported = 1
+		default:
+			hb.mu.Unlock()
+			continue
+		}
+		hb.mu.Unlock()
+
+		keysAndValues := []interface{}{
+			ComponentKey, hb.name,
+			"last_seen", lastSeen,
+			"interval", hb.interval,
+			"missed_intervals", missed,
+		}
+		if missed >= stalledAfter {
+			w.logger.Error("component stalled", append(keysAndValues, "stacks", goroutineStacks())...)
+		} else {
+			w.logger.Warn("component missed heartbeat", keysAndValues...)
+		}
+	}
+}
+
+// goroutineStacks returns the stacks of all goroutines, growing the buffer
+// until the whole dump fits.
+func goroutineStacks() string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,108 @@
+//go:build !nozap
+
+package log
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock moved forward by the tests, ticking the Watchdog
+// watching it on each move.
+type fakeClock struct {
+	mu   sync.Mutex
+	time time.Time
+	tick chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), tick: make(chan time.Time)}
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.time
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.time = c.time.Add(d)
+	c.mu.Unlock()
+	c.tick <- c.time
+}
+
+// fakeWatchdog starts a Watchdog on the global logger ticking with clock. Its
+// checks are done once the next one is received, so tests sync on Stop.
+func fakeWatchdog(clock *fakeClock) *Watchdog {
+	w := GetLogger().newWatchdog(time.Second, clock.now)
+	go w.run(clock.tick)
+	return w
+}
+
+func TestWatchdogReportsMissedAndStalledComponents(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	clock := newFakeClock()
+	w := fakeWatchdog(clock)
+	w.Register("consumer", 10*time.Second)
+	for i := 0; i < 6; i++ {
+		clock.advance(5 * time.Second)
+	}
+	w.Stop()
+
+	logOutput := buf.String()
+	assert.Equal(t, 1, strings.Count(logOutput, "component missed heartbeat")) // Reported once
+	assert.Equal(t, 1, strings.Count(logOutput, "component stalled"))
+	assert.Contains(t, logOutput, "consumer")
+	assert.Contains(t, logOutput, "goroutine ") // Stack dump attached
+}
+
+func TestWatchdogQuietWhileBeating(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	clock := newFakeClock()
+	w := fakeWatchdog(clock)
+	hb := w.Register("consumer", 50*time.Second)
+	for i := 0; i < 10; i++ {
+		hb.Beat()
+		clock.advance(5 * time.Second)
+	}
+	w.Stop()
+
+	assert.Empty(t, buf.String())
+}
+
+func TestWatchdogLogsRecovery(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	clock := newFakeClock()
+	w := fakeWatchdog(clock)
+	hb := w.Register("consumer", 10*time.Second)
+	clock.advance(15 * time.Second)
+	clock.advance(time.Second) // Waits for the check of the previous tick
+	hb.Beat()
+	w.Stop()
+
+	assert.Contains(t, buf.String(), "component missed heartbeat")
+	assert.Contains(t, buf.String(), "component recovered")
+}
+
+func TestWatchdogDefaults(t *testing.T) {
+	_, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	w := NewWatchdog(0)
+	assert.Equal(t, defaultWatchdogCheck, w.checkEvery)
+	assert.Equal(t, defaultWatchdogCheck, w.Register("consumer", 0).interval)
+	w.check(time.Now().Add(time.Hour)) // No division by zero
+	w.Stop()
+	w.Stop()
+}
@@ -0,0 +1,105 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultWebhookTemplate = "{{json .Entries}}"
+
+// WebhookConfig configures a sink sending entries to any HTTP endpoint, with a
+// body rendered from a template.
+type WebhookConfig struct {
+	URL    string
+	Method string // POST by default
+
+	// Headers are added to every request, like an Authorization header.
+	Headers     map[string]string
+	ContentType string // application/json by default
+
+	// Template renders the body of a request from a WebhookPayload. The json
+	// function encodes its argument as JSON, for instance
+	//
+	//	{"text": {{json (index .Entries 0).Message}}}
+	//
+	// By default the body is the JSON array of the entries.
+	Template string
+
+	// Level is the minimum level of the entries sent, as ParseLevel accepts.
+	// All entries are sent by default.
+	Level string
+
+	HTTP  HTTPConfig // Compression is used as set, the endpoint must accept it
+	Batch BatchConfig
+}
+
+// WebhookPayload is what the template of a webhook sink renders: the entries
+// of a batch, oldest first. Setting Batch.MaxEntries to 1 sends one request
+// per entry.
+type WebhookPayload struct {
+	Entries []NotifyEntry
+}
+
+// NewWebhookSink returns a sink sending entries to config.URL in batches, one
+// request per batch rendered from config.Template, so that alerting and chat
+// systems without a sink of their own can be integrated. Entries must be
+// JSON encoded.
+func NewWebhookSink(config WebhookConfig) (*BatchSink, error) {
+	if config.URL == "" {
+		return nil, errors.New("webhook sink needs a URL")
+	}
+	if config.Method == "" {
+		config.Method = http.MethodPost
+	}
+	if config.ContentType == "" {
+		config.ContentType = "application/json"
+	}
+	level := DebugLevel
+	if config.Level != "" {
+		var err error
+		if level, err = ParseLevel(config.Level); err != nil {
+			return nil, err
+		}
+	}
+	tmpl, err := parseNotifyTemplate("webhook", config.Template, defaultWebhookTemplate, template.FuncMap{"json": webhookJSON})
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+
+	shipper, err := newHTTPShipper(config.HTTP, config.URL, config.ContentType, config.HTTP.Compression)
+	if err != nil {
+		return nil, err
+	}
+	shipper.method = strings.ToUpper(config.Method)
+	for key, value := range config.Headers {
+		shipper.header.Set(key, value)
+	}
+
+	return newDecodingBatchSink("webhook "+config.URL, config.Batch, func(batch [][]byte, decode func([]byte) sinkEntry) error {
+		entries := notifyEntries(batch, zapcore.Level(level), decode)
+		if len(entries) == 0 {
+			return nil
+		}
+		var body bytes.Buffer
+		if err := tmpl.Execute(&body, WebhookPayload{Entries: entries}); err != nil {
+			return fmt.Errorf("can't render webhook body: %w", err)
+		}
+		return shipper.ship(body.Bytes())
+	}), nil
+}
+
+// webhookJSON encodes v as JSON for templates, strings included, so they can
+// be embedded in JSON bodies safely.
+func webhookJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
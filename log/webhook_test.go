@@ -0,0 +1,82 @@
+//go:build !nozap
+
+package log
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	var method, contentType, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		method, contentType, auth = r.Method, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookConfig{
+		URL:      server.URL,
+		Method:   "put",
+		Headers:  map[string]string{"Authorization": "Bearer secret"},
+		Template: `{"alerts":[{{range $i, $e := .Entries}}{{if $i}},{{end}}{"summary":{{json $e.Message}},"user":{{json (index $e.Fields "user")}}}{{end}}]}`,
+		Level:    "warn",
+		Batch:    BatchConfig{FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"info","msg":"skipped"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"warn","msg":"disk \"almost\" full","user":"ana"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"level":"error","msg":"disk full"}` + "\n"))
+	require.NoError(t, sink.Sync())
+	_, _ = sink.Write([]byte(`{"level":"debug","msg":"nothing to send"}` + "\n"))
+	require.NoError(t, sink.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{`{"alerts":[{"summary":"disk \"almost\" full","user":"ana"},{"summary":"disk full","user":null}]}`}, bodies)
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, "Bearer secret", auth)
+}
+
+func TestWebhookSinkDefaultTemplate(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookConfig{URL: server.URL, Batch: BatchConfig{FlushInterval: time.Hour}})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, _ = sink.Write([]byte(`{"level":"debug","ts":"2024-05-01T10:00:00Z","msg":"hello","caller":"main.go:1","n":1}` + "\n"))
+	require.NoError(t, sink.Sync())
+	assert.JSONEq(t, `[{"Time":"2024-05-01T10:00:00Z","Level":"debug","Message":"hello","Caller":"main.go:1","Fields":{"n":1}}]`, string(body))
+}
+
+func TestNewWebhookSink(t *testing.T) {
+	for name, config := range map[string]WebhookConfig{
+		"no url":         {},
+		"invalid level":  {URL: "http://localhost", Level: "loud"},
+		"invalid syntax": {URL: "http://localhost", Template: "{{.Entries"},
+	} {
+		_, err := NewWebhookSink(config)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,108 @@
+//go:build !nozap
+
+package log
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// WebSocket close codes from RFC 6455 that get special treatment.
+const (
+	wsCloseNormal    = 1000
+	wsCloseGoingAway = 1001
+	wsCloseAbnormal  = 1006 // The connection dropped without a close frame
+)
+
+// WebSocket logs the lifecycle of a WebSocket connection: its upgrade, its
+// traffic and how it ended. It works with any WebSocket library; the handler
+// reports messages and the close. It is safe for concurrent use, so reader and
+// writer goroutines can share it.
+type WebSocket struct {
+	logger  *Logger
+	context []interface{}
+	start   time.Time
+
+	received, sent           atomic.Uint64
+	bytesReceived, bytesSent atomic.Uint64
+	closed                   atomic.Bool
+}
+
+// TrackWebSocket starts tracking an upgraded connection through the global
+// logger. See (*Logger).TrackWebSocket.
+func TrackWebSocket(r *http.Request, keysAndValues ...interface{}) *WebSocket {
+	return GetLogger().TrackWebSocket(r, keysAndValues...)
+}
+
+// TrackWebSocket starts tracking the connection upgraded from r and logs it
+// at Info with the request's path, remote IP, user agent, origin and
+// subprotocols. keysAndValues, such as a connection or user ID, are added to
+// every entry about the connection.
+func (l *Logger) TrackWebSocket(r *http.Request, keysAndValues ...interface{}) *WebSocket {
+	ws := &WebSocket{
+		logger: l,
+		context: append([]interface{}{
+			"path", r.URL.Path,
+			"remote_ip", remoteIP(r),
+		}, keysAndValues...),
+		start: time.Now(),
+	}
+
+	l.log(InfoLevel, "websocket connected", append(ws.fields(),
+		"user_agent", r.UserAgent(),
+		"origin", r.Header.Get("Origin"),
+		"subprotocols", r.Header.Get("Sec-WebSocket-Protocol"),
+	))
+	return ws
+}
+
+// Received counts a message of size bytes read from the connection.
+func (ws *WebSocket) Received(size int) {
+	ws.received.Add(1)
+	ws.bytesReceived.Add(uint64(size))
+}
+
+// Sent counts a message of size bytes written to the connection.
+func (ws *WebSocket) Sent(size int) {
+	ws.sent.Add(1)
+	ws.bytesSent.Add(uint64(size))
+}
+
+// Close logs the end of the connection with its duration, traffic and close
+// code and reason. Normal closures (1000 and 1001) are logged at Info. Other
+// codes, and a non-nil err, are logged at Warn as abnormal terminations; a
+// code of 0 with an error stands for a connection that dropped without a
+// close frame (1006). Only the first call logs anything.
+func (ws *WebSocket) Close(code int, reason string, err error) {
+	if !ws.closed.CompareAndSwap(false, true) {
+		return
+	}
+	if code == 0 && err != nil {
+		code = wsCloseAbnormal
+	}
+
+	keysAndValues := append(ws.fields(),
+		"duration", time.Since(ws.start),
+		"messages_received", ws.received.Load(),
+		"messages_sent", ws.sent.Load(),
+		"bytes_received", ws.bytesReceived.Load(),
+		"bytes_sent", ws.bytesSent.Load(),
+		"close_code", code,
+		"close_reason", reason,
+	)
+	if err != nil {
+		keysAndValues = append(keysAndValues, "error", err)
+	}
+
+	if err == nil && (code == wsCloseNormal || code == wsCloseGoingAway) {
+		ws.logger.log(InfoLevel, "websocket closed", keysAndValues)
+		return
+	}
+	ws.logger.log(WarnLevel, "websocket closed abnormally", keysAndValues)
+}
+
+// fields returns a copy of the fields shared by every entry.
+func (ws *WebSocket) fields() []interface{} {
+	return append([]interface{}{}, ws.context...)
+}
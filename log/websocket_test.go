@@ -0,0 +1,55 @@
+//go:build !nozap
+
+package log
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func upgradeRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Sec-WebSocket-Protocol", "chat")
+	return r
+}
+
+func TestWebSocketLifecycle(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	ws := TrackWebSocket(upgradeRequest(), "conn_id", "c1")
+	ws.Received(10)
+	ws.Received(5)
+	ws.Sent(100)
+	ws.Close(1000, "bye", nil)
+	ws.Close(1000, "twice", nil) // Ignored
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "websocket connected")
+	assert.Contains(t, lines[0], `"path": "/ws", "remote_ip": "192.0.2.1", "conn_id": "c1"`)
+	assert.Contains(t, lines[0], `"origin": "https://app.example.com", "subprotocols": "chat"`)
+	assert.True(t, strings.HasPrefix(lines[1], "info"))
+	assert.Contains(t, lines[1], `"conn_id": "c1"`)
+	assert.Contains(t, lines[1], `"messages_received": 2, "messages_sent": 1, "bytes_received": 15, "bytes_sent": 100, "close_code": 1000, "close_reason": "bye"`)
+}
+
+func TestWebSocketAbnormalClose(t *testing.T) {
+	buf, cleanup := setupTestLogger(false)
+	defer cleanup()
+
+	TrackWebSocket(upgradeRequest()).Close(0, "", errors.New("connection reset by peer"))
+	TrackWebSocket(upgradeRequest()).Close(1011, "internal error", nil)
+
+	logOutput := buf.String()
+	assert.Equal(t, 2, strings.Count(logOutput, "websocket closed abnormally"))
+	assert.Contains(t, logOutput, `"close_code": 1006`)
+	assert.Contains(t, logOutput, `"error": "connection reset by peer"`)
+	assert.Contains(t, logOutput, `"close_code": 1011`)
+}
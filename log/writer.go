@@ -0,0 +1,90 @@
+//go:build !nozap
+
+package log
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxWriterLine bounds the lines buffered by the writers of Writer: longer
+// lines are split in entries of that size.
+const maxWriterLine = 64 << 10
+
+// Writer returns a writer logging each line through the global logger,
+// whichever it is at the time of each line. See (*Logger).Writer.
+func Writer(level Level) io.WriteCloser {
+	return &lineWriter{logger: GetLogger, level: level}
+}
+
+// Writer returns a writer logging each line written to it as an entry of l
+// at level, for the output of commands and libraries that only accept an
+// io.Writer:
+//
+//	w := l.With("cmd", "rsync").Writer(log.InfoLevel)
+//	defer w.Close()
+//	cmd.Stdout = w
+//
+// Lines may be written in any number of writes; a line is logged once its
+// newline is written, without it or a carriage return before it, and empty
+// lines are skipped. Close logs the last line if it has no newline. The
+// writer is safe for concurrent use.
+func (l *Logger) Writer(level Level) io.WriteCloser {
+	return &lineWriter{logger: func() *Logger { return l }, level: level}
+}
+
+type lineWriter struct {
+	logger func() *Logger
+	level  Level
+
+	mu  sync.Mutex
+	buf []byte // The partial line
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			w.logOverflow()
+			return n, nil
+		}
+		w.buf = append(w.buf, p[:i]...)
+		w.logOverflow()
+		w.logLine(w.buf)
+		w.buf = w.buf[:0]
+		p = p[i+1:]
+	}
+}
+
+// logOverflow logs the beginning of the partial line while it's longer than
+// maxWriterLine.
+func (w *lineWriter) logOverflow() {
+	for len(w.buf) > maxWriterLine {
+		w.logLine(w.buf[:maxWriterLine])
+		w.buf = w.buf[maxWriterLine:]
+	}
+}
+
+// Close logs the partial line, if any.
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.logLine(w.buf)
+	w.buf = nil
+	return nil
+}
+
+func (w *lineWriter) logLine(line []byte) {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) == 0 {
+		return
+	}
+	w.logger().log(w.level, string(line), nil)
+}
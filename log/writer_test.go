@@ -0,0 +1,55 @@
+//go:build !nozap
+
+package log
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	backend := newRecordingBackend(DebugLevel)
+	w := NewLoggerWithBackend(backend).Writer(WarnLevel)
+
+	_, _ = fmt.Fprint(w, "first li")
+	_, _ = fmt.Fprint(w, "ne\r\n\nsecond line\nthird")
+	assert.Equal(t, []string{"1 first line []", "1 second line []"}, backend.logged())
+
+	require.NoError(t, w.Close())
+	assert.Equal(t, "1 third []", backend.logged()[2])
+}
+
+func TestWriterLongLine(t *testing.T) {
+	backend := newRecordingBackend(DebugLevel)
+	w := NewLoggerWithBackend(backend).Writer(InfoLevel)
+
+	_, _ = w.Write([]byte(strings.Repeat("x", maxWriterLine+10)))
+	require.NoError(t, w.Close())
+
+	logged := backend.logged()
+	require.Len(t, logged, 2)
+	assert.Len(t, logged[0], len("0 ")+maxWriterLine+len(" []"))
+	assert.Equal(t, "0 xxxxxxxxxx []", logged[1])
+}
+
+func TestWriterCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell")
+	}
+	backend := newRecordingBackend(DebugLevel)
+	l := NewLoggerWithBackend(backend)
+
+	cmd := exec.Command("sh", "-c", "echo out; echo err >&2")
+	stdout, stderr := l.Writer(InfoLevel), l.Writer(ErrorLevel)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	require.NoError(t, cmd.Run())
+	require.NoError(t, stdout.Close())
+	require.NoError(t, stderr.Close())
+
+	assert.ElementsMatch(t, []string{"0 out []", "2 err []"}, backend.logged())
+}
@@ -0,0 +1,65 @@
+// Package zerologbackend writes log entries through zerolog, for programs
+// standardized on its output format that still want the helpers of the log
+// package.
+//
+//	log.UseBackend(zerologbackend.New(zerolog.New(os.Stderr).With().Timestamp().Logger()))
+package zerologbackend
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// Backend is a log.Backend writing through a zerolog.Logger.
+type Backend struct {
+	logger zerolog.Logger
+}
+
+// New returns a Backend writing through logger. Its level, hooks and context
+// apply to every entry.
+func New(logger zerolog.Logger) *Backend {
+	return &Backend{logger: logger}
+}
+
+// Log writes an entry. WithLevel is used for every level so zerolog neither
+// panics nor exits by itself; log.Terminate does it, running the package's
+// hooks.
+func (b *Backend) Log(level log.Level, msg string, keysAndValues []interface{}) {
+	b.logger.WithLevel(zerologLevel(level)).Fields(keysAndValues).Msg(msg)
+	log.Terminate(level, msg)
+}
+
+// With returns a Backend adding keysAndValues to every entry.
+func (b *Backend) With(keysAndValues []interface{}) log.Backend {
+	return &Backend{logger: b.logger.With().Fields(keysAndValues).Logger()}
+}
+
+// Enabled reports whether both the logger and zerolog's global level let
+// entries at level through.
+func (b *Backend) Enabled(level log.Level) bool {
+	lvl := zerologLevel(level)
+	return lvl >= b.logger.GetLevel() && lvl >= zerolog.GlobalLevel()
+}
+
+// Sync does nothing, as zerolog doesn't buffer entries.
+func (b *Backend) Sync() error {
+	return nil
+}
+
+func zerologLevel(level log.Level) zerolog.Level {
+	switch level {
+	case log.DebugLevel:
+		return zerolog.DebugLevel
+	case log.InfoLevel:
+		return zerolog.InfoLevel
+	case log.WarnLevel:
+		return zerolog.WarnLevel
+	case log.PanicLevel:
+		return zerolog.PanicLevel
+	case log.FatalLevel:
+		return zerolog.FatalLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
@@ -0,0 +1,107 @@
+package zerologbackend
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestBackendWritesZerologJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := log.NewLoggerWithBackend(New(zerolog.New(&buf).Level(zerolog.InfoLevel)))
+
+	l.Debug("hidden")
+	l.Info("hello", "user", "ana", "attempt", 2)
+	l.Warnf("disk at %d%%", 91)
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"level": "info", "user": "ana", "attempt": float64(2), "message": "hello"}, entries[0])
+	assert.Equal(t, map[string]interface{}{"level": "warn", "message": "disk at 91%"}, entries[1])
+}
+
+func TestBackendKeepsHelpers(t *testing.T) {
+	var buf bytes.Buffer
+	log.UseBackend(New(zerolog.New(&buf)))
+
+	assert.True(t, log.CheckErr(assert.AnError, false, "request failed", "url", "/x"))
+	assert.Panics(t, func() { log.Panic("boom") })
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "error", entries[0]["level"])
+	assert.Equal(t, assert.AnError.Error(), entries[0]["error"])
+	assert.Equal(t, "/x", entries[0]["url"])
+	assert.Equal(t, "panic", entries[1]["level"])
+}
+
+func TestBackendWith(t *testing.T) {
+	var buf bytes.Buffer
+	b := New(zerolog.New(&buf)).With([]interface{}{"component", "api"})
+
+	b.Log(log.InfoLevel, "ready", nil)
+
+	assert.Equal(t, "api", decodeLines(t, &buf)[0]["component"])
+}
+
+// TestHelpersWithoutZap runs the helpers adding zap fields to their entries
+// through backends other than zap, which must get plain key-value pairs.
+func TestHelpersWithoutZap(t *testing.T) {
+	backends := map[string]func(*bytes.Buffer) log.Backend{
+		"zerolog": func(buf *bytes.Buffer) log.Backend { return New(zerolog.New(buf)) },
+		"slog": func(buf *bytes.Buffer) log.Backend {
+			return log.NewSlogBackend(slog.NewJSONHandler(buf, nil))
+		},
+	}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := log.NewLoggerWithBackend(backend(&buf)).With(zap.String("service", "billing"))
+
+			func() {
+				defer l.RecoverAndLog("worker crashed", "worker", 3)
+				panic("boom")
+			}()
+			l.LogDiagnostics()
+			l.RecoverMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				panic("handler boom")
+			})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+
+			assert.NotContains(t, buf.String(), "BADKEY")
+			entries := decodeLines(t, &buf)
+			require.Len(t, entries, 3)
+			for _, entry := range entries {
+				assert.Equal(t, "billing", entry["service"])
+			}
+			assert.Equal(t, map[string]interface{}{"type": "string", "value": "boom"}, entries[0]["panic"])
+			assert.Equal(t, map[string]interface{}{"type": "string", "value": "handler boom"}, entries[2]["panic"])
+			assert.Equal(t, float64(3), entries[0]["worker"])
+			assert.Contains(t, entries[0]["stack"], "runtime/debug.Stack")
+			assert.Contains(t, entries[1], "runtime")
+			assert.Contains(t, entries[1], "config")
+			assert.Contains(t, entries[1], "stacks")
+			assert.Contains(t, entries[2]["request"], "GET /x")
+		})
+	}
+}
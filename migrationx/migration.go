@@ -0,0 +1,77 @@
+// Package migrationx applies ordered SQL migrations, usually embedded in the
+// binary with embed.FS, recording the applied ones in a table of the
+// database itself.
+package migrationx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration is one SQL script, applied once.
+type Migration struct {
+	Version  int64
+	Name     string
+	SQL      string
+	Checksum string // Hex SHA-256 of SQL, to detect migrations edited once applied
+}
+
+// Load reads the migrations in dir of fsys, ordered by version. Files are
+// named "<version>_<name>.sql", like "0001_create_users.sql"; ".up.sql" is
+// accepted too, while ".down.sql" files and other files are ignored. Versions
+// must be unique.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := map[int64]string{}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+
+		version, label, err := parseFileName(name)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", name, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: label, SQL: string(data), Checksum: checksum(data)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// parseFileName splits "0001_create_users.sql" into 1 and "create_users".
+func parseFileName(name string) (int64, string, error) {
+	base := strings.TrimSuffix(strings.TrimSuffix(name, ".sql"), ".up")
+	digits, label, _ := strings.Cut(base, "_")
+	version, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || version < 0 {
+		return 0, "", fmt.Errorf("migration %s: file name must start with a version number", name)
+	}
+	return version, label, nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,39 @@
+package migrationx
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0010_add_email.up.sql":   {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"migrations/0010_add_email.down.sql": {Data: []byte("ALTER TABLE users DROP email;")},
+		"migrations/0002_create_users.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+		"migrations/README.md":               {Data: []byte("ignored")},
+	}
+
+	migrations, err := Load(fsys, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(2), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Equal(t, "CREATE TABLE users (id INT);", migrations[0].SQL)
+	assert.Len(t, migrations[0].Checksum, 64)
+	assert.Equal(t, int64(10), migrations[1].Version)
+	assert.Equal(t, "add_email", migrations[1].Name)
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	_, err := Load(fstest.MapFS{"create_users.sql": {}}, ".")
+	assert.ErrorContains(t, err, "must start with a version number")
+
+	_, err = Load(fstest.MapFS{"1_a.sql": {}, "01_b.sql": {}}, ".")
+	assert.ErrorContains(t, err, "share version 1")
+
+	_, err = Load(fstest.MapFS{}, "missing")
+	assert.Error(t, err)
+}
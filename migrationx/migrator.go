@@ -0,0 +1,314 @@
+package migrationx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"time"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// ErrChecksumMismatch is returned when an applied migration was edited since.
+var ErrChecksumMismatch = errors.New("applied migration changed")
+
+// Dialect holds what differs between databases: how to write query
+// placeholders and how to take the advisory lock keeping two instances from
+// migrating at once. Lock is nil for databases without advisory locks.
+type Dialect struct {
+	Placeholder func(n int) string // The n-th placeholder, from 1
+	Lock        func(ctx context.Context, conn *sql.Conn, key int64) error
+	Unlock      func(ctx context.Context, conn *sql.Conn, key int64) error
+}
+
+// Postgres locks with pg_advisory_lock.
+var Postgres = Dialect{
+	Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+	Lock: func(ctx context.Context, conn *sql.Conn, key int64) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key)
+		return err
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn, key int64) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key)
+		return err
+	},
+}
+
+// MySQL locks with GET_LOCK, waiting as long as it takes. Migrations holding
+// several statements need multiStatements=true in the DSN.
+var MySQL = Dialect{
+	Placeholder: func(int) string { return "?" },
+	Lock: func(ctx context.Context, conn *sql.Conn, key int64) error {
+		var got sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, -1)", mysqlLockName(key)).Scan(&got); err != nil {
+			return err
+		}
+		if got.Int64 != 1 {
+			return errors.New("GET_LOCK failed")
+		}
+		return nil
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn, key int64) error {
+		_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", mysqlLockName(key))
+		return err
+	},
+}
+
+// SQLite has no advisory locks; its database-wide write lock serializes the
+// migrations instead.
+var SQLite = Dialect{
+	Placeholder: func(int) string { return "?" },
+}
+
+func mysqlLockName(key int64) string {
+	return fmt.Sprintf("migrationx:%d", key)
+}
+
+// Option customizes a Migrator.
+type Option func(*options)
+
+type options struct {
+	dir     string
+	table   string
+	dialect Dialect
+	lockKey int64
+	dryRun  bool
+	logger  *log.Logger
+}
+
+// WithDir reads the migrations from dir of the file system, "." by default.
+func WithDir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+	}
+}
+
+// WithTable records the applied migrations in table, "schema_migrations" by
+// default.
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithDialect sets the database dialect, Postgres by default.
+func WithDialect(d Dialect) Option {
+	return func(o *options) {
+		o.dialect = d
+	}
+}
+
+// WithLockKey sets the advisory lock key, derived from the table name by
+// default.
+func WithLockKey(key int64) Option {
+	return func(o *options) {
+		o.lockKey = key
+	}
+}
+
+// WithDryRun makes Up log the pending migrations without applying them.
+func WithDryRun(dryRun bool) Option {
+	return func(o *options) {
+		o.dryRun = dryRun
+	}
+}
+
+// WithLogger logs through l instead of the global logger.
+func WithLogger(l *log.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// Migrator applies the migrations of a file system to a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	options
+}
+
+// New loads the migrations of fsys, see Load, to apply them to db.
+func New(db *sql.DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	o := options{dir: ".", table: "schema_migrations", dialect: Postgres}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.lockKey == 0 {
+		h := fnv.New64a()
+		h.Write([]byte("migrationx:" + o.table))
+		o.lockKey = int64(h.Sum64())
+	}
+	if o.logger == nil {
+		o.logger = log.GetLogger()
+	}
+
+	migrations, err := Load(fsys, o.dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, options: o}, nil
+}
+
+// Migrations returns the migrations loaded, ordered by version.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies the pending migrations in order, each in its own transaction,
+// holding the advisory lock throughout. It stops at the first failure,
+// leaving the migrations before it applied. Every migration is logged with
+// its version, name, checksum and duration. Up returns the migrations
+// applied, or the ones that would be with WithDryRun.
+//
+// Up fails with ErrChecksumMismatch, before applying anything, if an applied
+// migration was edited since.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if m.dialect.Lock != nil {
+		if err := m.dialect.Lock(ctx, conn, m.lockKey); err != nil {
+			return nil, fmt.Errorf("taking migration lock: %w", err)
+		}
+		defer func() {
+			// Unlock even if ctx is done, or the lock lives on with the
+			// pooled connection.
+			if err := m.dialect.Unlock(context.WithoutCancel(ctx), conn, m.lockKey); err != nil {
+				m.logger.Warn("can't release migration lock", "error", err)
+			}
+		}()
+	}
+
+	if !m.dryRun {
+		if _, err := conn.ExecContext(ctx, m.createTableSQL()); err != nil {
+			return nil, fmt.Errorf("creating %s: %w", m.table, err)
+		}
+	}
+	pending, err := m.pending(ctx, conn, m.dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	var applied []Migration
+	for _, mig := range pending {
+		if m.dryRun {
+			m.logger.Info("migration pending (dry run)", "version", mig.Version, "name", mig.Name, "checksum", mig.Checksum)
+			applied = append(applied, mig)
+			continue
+		}
+
+		migStart := time.Now()
+		if err := m.apply(ctx, conn, mig); err != nil {
+			m.logger.Error("migration failed", "version", mig.Version, "name", mig.Name, "checksum", mig.Checksum,
+				"duration", time.Since(migStart), "error", err)
+			return applied, fmt.Errorf("migration %d %s: %w", mig.Version, mig.Name, err)
+		}
+		m.logger.Info("migration applied", "version", mig.Version, "name", mig.Name, "checksum", mig.Checksum,
+			"duration", time.Since(migStart))
+		applied = append(applied, mig)
+	}
+
+	m.logger.Info("migrations done", "applied", len(applied), "pending", len(pending), "dry_run", m.dryRun,
+		"duration", time.Since(start))
+	return applied, nil
+}
+
+// Pending returns the migrations not applied yet, without locking. Like a
+// dry run, it assumes none were applied if the table can't be read.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return m.pending(ctx, conn, true)
+}
+
+// pending compares the migrations with the applied ones, checking their
+// checksums.
+func (m *Migrator) pending(ctx context.Context, conn *sql.Conn, readOnly bool) ([]Migration, error) {
+	applied, err := m.applied(ctx, conn, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, mig := range m.migrations {
+		sum, ok := applied[mig.Version]
+		if !ok {
+			pending = append(pending, mig)
+			continue
+		}
+		if sum != mig.Checksum {
+			return nil, fmt.Errorf("%w: %d %s was applied with checksum %s, now %s",
+				ErrChecksumMismatch, mig.Version, mig.Name, sum, mig.Checksum)
+		}
+		delete(applied, mig.Version)
+	}
+	for version := range applied {
+		m.logger.Warn("applied migration not found", "version", version)
+	}
+	return pending, nil
+}
+
+// applied returns the checksums of the applied migrations by version.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn, readOnly bool) (map[int64]string, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum FROM "+m.table)
+	if err != nil && readOnly {
+		// Read-only runs don't create the table, and how querying a missing table
+		// fails differs between databases.
+		m.logger.Warn("can't read applied migrations, assuming none", "table", m.table, "error", err)
+		return map[int64]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", m.table, err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]string{}
+	for rows.Next() {
+		var version int64
+		var sum string
+		if err := rows.Scan(&version, &sum); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", m.table, err)
+		}
+		applied[version] = sum
+	}
+	return applied, rows.Err()
+}
+
+// apply runs a migration and records it in one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // A no-op once committed
+
+	if _, err := tx.ExecContext(ctx, mig.SQL); err != nil {
+		return err
+	}
+	p := m.dialect.Placeholder
+	if _, err := tx.ExecContext(ctx, "INSERT INTO "+m.table+" (version, name, checksum) VALUES ("+p(1)+", "+p(2)+", "+p(3)+")",
+		mig.Version, mig.Name, mig.Checksum); err != nil {
+		return fmt.Errorf("recording migration: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) createTableSQL() string {
+	return "CREATE TABLE IF NOT EXISTS " + m.table + ` (
+	version BIGINT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+}
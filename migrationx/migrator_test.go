@@ -0,0 +1,234 @@
+package migrationx
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Stasky745/go-libs/log"
+)
+
+// fakeDB understands the statements of the Migrator, and records the others
+// as migrations run. Statements containing "FAIL" fail.
+type fakeDB struct {
+	mu       sync.Mutex
+	table    bool
+	rows     [][2]interface{} // version, checksum
+	executed []string
+	locks    int
+	unlocks  int
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: d}, nil }
+func (d *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTx
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.tx = &fakeTx{c: c}
+	return c.tx, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "FAIL"):
+		return nil, errors.New("syntax error")
+	case strings.Contains(query, "pg_advisory_lock"):
+		c.db.locks++
+	case strings.Contains(query, "pg_advisory_unlock"):
+		c.db.unlocks++
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		c.db.table = true
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		c.tx.rows = append(c.tx.rows, [2]interface{}{args[0].Value, args[2].Value})
+	default:
+		c.tx.executed = append(c.tx.executed, query)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if !c.db.table {
+		return nil, errors.New("no such table: schema_migrations")
+	}
+	return &fakeRows{rows: append([][2]interface{}(nil), c.db.rows...)}, nil
+}
+
+type fakeTx struct {
+	c        *fakeConn
+	rows     [][2]interface{}
+	executed []string
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.c.db.mu.Lock()
+	defer tx.c.db.mu.Unlock()
+
+	tx.c.db.rows = append(tx.c.db.rows, tx.rows...)
+	tx.c.db.executed = append(tx.c.db.executed, tx.executed...)
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	rows [][2]interface{}
+}
+
+func (r *fakeRows) Columns() []string { return []string{"version", "checksum"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[0][0], r.rows[0][1]
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func openFake(t *testing.T) (*sql.DB, *fakeDB) {
+	fake := &fakeDB{}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// testLogger returns a logger writing JSON lines to buf, at every level.
+func testLogger(buf *bytes.Buffer) *log.Logger {
+	return log.NewLoggerWithBackend(log.NewSlogBackend(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+}
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+var testMigrations = fstest.MapFS{
+	"sql/0001_create_users.sql": {Data: []byte("CREATE TABLE users (id INT)")},
+	"sql/0002_add_email.sql":    {Data: []byte("ALTER TABLE users ADD email TEXT")},
+}
+
+func TestUp(t *testing.T) {
+	db, fake := openFake(t)
+	var buf bytes.Buffer
+
+	m, err := New(db, testMigrations, WithDir("sql"), WithLogger(testLogger(&buf)))
+	require.NoError(t, err)
+
+	applied, err := m.Up(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.Equal(t, []string{"CREATE TABLE users (id INT)", "ALTER TABLE users ADD email TEXT"}, fake.executed)
+	assert.Equal(t, 1, fake.locks)
+	assert.Equal(t, 1, fake.unlocks)
+
+	logged := entries(t, &buf)
+	require.Len(t, logged, 3)
+	assert.Equal(t, "migration applied", logged[0]["msg"])
+	assert.Equal(t, float64(1), logged[0]["version"])
+	assert.Equal(t, "create_users", logged[0]["name"])
+	assert.Equal(t, m.Migrations()[0].Checksum, logged[0]["checksum"])
+	assert.Contains(t, logged[0], "duration")
+	assert.Equal(t, "migrations done", logged[2]["msg"])
+
+	// Nothing left to apply the second time.
+	applied, err = m.Up(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.Len(t, fake.executed, 2)
+}
+
+func TestUpDryRun(t *testing.T) {
+	db, fake := openFake(t)
+	var buf bytes.Buffer
+
+	m, err := New(db, testMigrations, WithDir("sql"), WithDryRun(true), WithLogger(testLogger(&buf)))
+	require.NoError(t, err)
+
+	pending, err := m.Up(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+	assert.Empty(t, fake.executed)
+	assert.False(t, fake.table) // Nothing written, not even the table
+	assert.Equal(t, 2, strings.Count(buf.String(), "migration pending (dry run)"))
+}
+
+func TestUpStopsAtFailure(t *testing.T) {
+	db, fake := openFake(t)
+	var buf bytes.Buffer
+	fsys := fstest.MapFS{
+		"1_ok.sql":     {Data: []byte("CREATE TABLE a (id INT)")},
+		"2_broken.sql": {Data: []byte("FAIL")},
+		"3_later.sql":  {Data: []byte("CREATE TABLE c (id INT)")},
+	}
+
+	m, err := New(db, fsys, WithLogger(testLogger(&buf)))
+	require.NoError(t, err)
+
+	applied, err := m.Up(context.Background())
+	assert.ErrorContains(t, err, "migration 2 broken: syntax error")
+	assert.Len(t, applied, 1)
+	assert.Equal(t, []string{"CREATE TABLE a (id INT)"}, fake.executed)
+	assert.Len(t, fake.rows, 1)
+	assert.Equal(t, 1, fake.unlocks) // Released despite the failure
+	assert.Contains(t, buf.String(), `"msg":"migration failed"`)
+}
+
+func TestUpDetectsEditedMigrations(t *testing.T) {
+	db, fake := openFake(t)
+	fake.table = true
+	fake.rows = [][2]interface{}{{int64(1), "0000"}}
+
+	m, err := New(db, testMigrations, WithDir("sql"), WithLogger(testLogger(&bytes.Buffer{})))
+	require.NoError(t, err)
+
+	_, err = m.Up(context.Background())
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Empty(t, fake.executed)
+}
+
+func TestPending(t *testing.T) {
+	db, fake := openFake(t)
+
+	m, err := New(db, testMigrations, WithDir("sql"), WithDialect(SQLite), WithLogger(testLogger(&bytes.Buffer{})))
+	require.NoError(t, err)
+
+	pending, err := m.Pending(context.Background()) // Before the table exists
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	fake.table = true
+	fake.rows = [][2]interface{}{{int64(1), m.Migrations()[0].Checksum}}
+	pending, err = m.Pending(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "add_email", pending[0].Name)
+}
@@ -0,0 +1,260 @@
+package semverx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a set of version ranges, parsed by ParseConstraint.
+type Constraint struct {
+	raw  string
+	sets [][]comparator // A version must pass every comparator of any set
+}
+
+type comparator struct {
+	op string // One of "=", "!=", ">", ">=", "<", "<="
+	v  Version
+}
+
+// ParseConstraint parses a constraint made of comparators joined by spaces or
+// commas, all of which must hold, and alternatives separated by "||":
+//
+//	=1.2.3 or 1.2.3   exactly 1.2.3
+//	!=1.2.3           anything else
+//	>1.2.3 >=1.2.3 <2 <=2.1
+//	1.2.x, 1.2, 1.*   any 1.2.z, any 1.y.z
+//	~1.2.3            >=1.2.3 <1.3.0, patch updates
+//	^1.2.3            >=1.2.3 <2.0.0, updates not changing the first non-zero component
+//	1.2 - 1.4.5       >=1.2.0 <=1.4.5
+//
+// Versions in constraints may have a "v" prefix and omit trailing components.
+// As with npm, pre-releases only match comparators of the same
+// major.minor.patch that name a pre-release: ">=1.2.0-rc.1" accepts
+// 1.2.0-rc.2 but ">=1.1.0" doesn't.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: s}
+	for _, alternative := range strings.Split(s, "||") {
+		set, err := parseSet(alternative)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid constraint %q: %w", s, err)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+// MustParseConstraint is like ParseConstraint but panics on invalid
+// constraints. It's meant for constants.
+func MustParseConstraint(s string) Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Satisfies parses version and constraint and reports whether the version
+// satisfies the constraint.
+func Satisfies(version, constraint string) (bool, error) {
+	v, err := Parse(version)
+	if err != nil {
+		return false, err
+	}
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(v), nil
+}
+
+// String returns the constraint as given to ParseConstraint.
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Check reports whether v satisfies the constraint.
+func (c Constraint) Check(v Version) bool {
+	for _, set := range c.sets {
+		if checkSet(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Highest returns the highest of versions satisfying the constraint, and
+// false if none does.
+func (c Constraint) Highest(versions []Version) (Version, bool) {
+	var highest Version
+	found := false
+	for _, v := range versions {
+		if c.Check(v) && (!found || highest.Less(v)) {
+			highest, found = v, true
+		}
+	}
+	return highest, found
+}
+
+func checkSet(set []comparator, v Version) bool {
+	for _, cmp := range set {
+		if !cmp.check(v) {
+			return false
+		}
+	}
+	if !v.IsPrerelease() {
+		return true
+	}
+
+	// Pre-releases need a comparator opting into their release.
+	for _, cmp := range set {
+		if cmp.v.IsPrerelease() && cmp.v.Major == v.Major && cmp.v.Minor == v.Minor && cmp.v.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (c comparator) check(v Version) bool {
+	cmp := v.Compare(c.v)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	}
+	return cmp <= 0
+}
+
+func parseSet(s string) ([]comparator, error) {
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty range")
+	}
+
+	if len(fields) == 3 && fields[1] == "-" {
+		lower, err := parse(fields[0], true)
+		if err != nil {
+			return nil, err
+		}
+		upper, err := parse(fields[2], true)
+		if err != nil {
+			return nil, err
+		}
+		set := []comparator{{">=", lower.Version}}
+		return append(set, upperBound("<=", upper)...), nil
+	}
+
+	var set []comparator
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		// Accept an operator separated from its version: ">= 1.2".
+		if strings.Trim(field, "=!<>~^") == "" && i+1 < len(fields) {
+			i++
+			field += fields[i]
+		}
+		comparators, err := parseComparator(field)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+func parseComparator(s string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range []string{"~>", ">=", "<=", "!=", "=", ">", "<", "~", "^"} {
+		if strings.HasPrefix(s, candidate) {
+			op, s = candidate, s[len(candidate):]
+			break
+		}
+	}
+	p, err := parse(s, true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "", "=":
+		if p.parts == 0 {
+			return []comparator{{">=", Version{}}}, nil
+		}
+		if p.parts == 3 {
+			return []comparator{{"=", p.Version}}, nil
+		}
+		return []comparator{{">=", p.Version}, {"<", p.next(p.parts)}}, nil
+	case "!=":
+		if p.parts < 3 {
+			return nil, fmt.Errorf("!= needs a full version, got %q", s)
+		}
+		return []comparator{{"!=", p.Version}}, nil
+	case ">":
+		if p.parts == 0 {
+			return nil, fmt.Errorf("nothing is greater than %q", s)
+		}
+		if p.parts == 3 {
+			return []comparator{{">", p.Version}}, nil
+		}
+		return []comparator{{">=", p.next(p.parts)}}, nil
+	case ">=":
+		return []comparator{{">=", p.Version}}, nil
+	case "<":
+		if p.parts == 0 {
+			return nil, fmt.Errorf("nothing is lower than %q", s)
+		}
+		return []comparator{{"<", p.Version}}, nil
+	case "<=":
+		return upperBound("<=", p), nil
+	case "~", "~>":
+		parts := p.parts
+		if parts > 2 {
+			parts = 2
+		}
+		if parts == 0 {
+			return []comparator{{">=", Version{}}}, nil
+		}
+		return []comparator{{">=", p.Version}, {"<", p.next(parts)}}, nil
+	}
+
+	// "^": the first non-zero component given is the one that can't change.
+	parts := 1
+	switch {
+	case p.Major == 0 && p.Minor == 0 && p.parts == 3:
+		parts = 3
+	case p.Major == 0 && p.parts >= 2:
+		parts = 2
+	}
+	if p.parts == 0 {
+		return []comparator{{">=", Version{}}}, nil
+	}
+	return []comparator{{">=", p.Version}, {"<", p.next(parts)}}, nil
+}
+
+// upperBound turns "<=1.2" into "<1.3.0" and "<=1.2.3" into "<=1.2.3".
+func upperBound(op string, p partial) []comparator {
+	switch p.parts {
+	case 0:
+		return nil
+	case 3:
+		return []comparator{{op, p.Version}}
+	}
+	return []comparator{{"<", p.next(p.parts)}}
+}
+
+// next returns the lowest version after every version sharing the first
+// parts components of p: 1.2.3 with parts 2 gives 1.3.0.
+func (p partial) next(parts int) Version {
+	switch parts {
+	case 1:
+		return Version{Major: p.Major + 1}
+	case 2:
+		return Version{Major: p.Major, Minor: p.Minor + 1}
+	}
+	return Version{Major: p.Major, Minor: p.Minor, Patch: p.Patch + 1}
+}
@@ -0,0 +1,78 @@
+package semverx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matches    []string
+		rejects    []string
+	}{
+		{"1.2.3", []string{"1.2.3", "v1.2.3+build"}, []string{"1.2.4", "1.2.3-rc.1"}},
+		{"=v1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0", "1.1.9"}},
+		{"1.x", []string{"1.0.0", "1.99.0"}, []string{"2.0.0", "0.9.0"}},
+		{"*", []string{"0.0.0", "5.0.0"}, []string{"5.0.0-rc.1"}},
+		{"!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{">1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{">= 1.2, < 2", []string{"1.2.0", "1.9.9"}, []string{"2.0.0", "1.1.0", "2.0.0-rc.1"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~>1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{"~1", []string{"1.9.0"}, []string{"2.0.0"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"2.0.0", "1.2.2"}},
+		{"^0.2.3", []string{"0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^0", []string{"0.9.0"}, []string{"1.0.0"}},
+		{"1.2 - 1.4.5", []string{"1.2.0", "1.4.5"}, []string{"1.4.6", "1.1.9"}},
+		{"1.2.3 - 2", []string{"2.9.9"}, []string{"3.0.0"}},
+		{"<1.0.0 || >=2.0.0", []string{"0.5.0", "2.1.0"}, []string{"1.5.0"}},
+		{">=1.2.0-rc.1", []string{"1.2.0-rc.2", "1.2.0", "1.3.0"}, []string{"1.2.0-beta", "1.3.0-rc.1"}},
+		{"^1.2.0-beta.2 || 2.0.0-rc.1", []string{"1.2.0-beta.3", "2.0.0-rc.1"}, []string{"1.3.0-beta.1", "2.0.0-rc.2"}},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		require.NoError(t, err, tt.constraint)
+		assert.Equal(t, tt.constraint, c.String())
+
+		for _, v := range tt.matches {
+			assert.True(t, c.Check(MustParse(v)), "%s should match %s", tt.constraint, v)
+		}
+		for _, v := range tt.rejects {
+			assert.False(t, c.Check(MustParse(v)), "%s shouldn't match %s", tt.constraint, v)
+		}
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, invalid := range []string{"", ">= ", "1.2.3 ||", "!=1.2", ">*", "<x", "~1.2.3.4", "^latest", "1.2-rc.1"} {
+		_, err := ParseConstraint(invalid)
+		assert.Error(t, err, invalid)
+	}
+	assert.Panics(t, func() { MustParseConstraint("nope") })
+}
+
+func TestSatisfiesAndHighest(t *testing.T) {
+	ok, err := Satisfies("v1.4.2", "^1.2")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = Satisfies("1.4", "^1.2")
+	assert.Error(t, err)
+	_, err = Satisfies("1.4.0", ">>1")
+	assert.Error(t, err)
+
+	c := MustParseConstraint("~1.2")
+	highest, found := c.Highest([]Version{MustParse("1.2.1"), MustParse("1.3.0"), MustParse("1.2.7"), MustParse("1.2.8-rc.1")})
+	assert.True(t, found)
+	assert.Equal(t, "1.2.7", highest.String())
+
+	_, found = c.Highest([]Version{MustParse("2.0.0")})
+	assert.False(t, found)
+}
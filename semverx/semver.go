@@ -0,0 +1,283 @@
+// Package semverx parses, compares, sorts and matches semantic versions
+// against constraints. Versions follow Semantic Versioning 2.0.0, tolerating
+// a leading "v" as in Go module and git tag versions.
+package semverx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidVersion is returned by Parse for strings that aren't semantic
+// versions.
+var ErrInvalidVersion = errors.New("invalid semantic version")
+
+// Version is a parsed semantic version.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string // Dot-separated identifiers after "-", like ["rc", "1"]
+	Build      string   // Metadata after "+", ignored in comparisons
+}
+
+// Parse parses a version like "1.2.3", "v1.2.3-rc.1" or "1.2.3+build.5".
+func Parse(s string) (Version, error) {
+	v, err := parse(s, false)
+	if err != nil {
+		return Version{}, err
+	}
+	return v.Version, nil
+}
+
+// MustParse is like Parse but panics on invalid versions. It's meant for
+// constants.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String returns the canonical form of v, without a "v" prefix.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// IsPrerelease reports whether v has pre-release identifiers.
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare returns -1, 0 or +1 as v is lower than, equal to or greater than o,
+// by semantic versioning precedence: pre-releases come before their release
+// and build metadata is ignored.
+func (v Version) Compare(o Version) int {
+	if c := compareUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// Less reports whether v has a lower precedence than o.
+func (v Version) Less(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+// Equal reports whether v and o have the same precedence, which ignores
+// build metadata.
+func (v Version) Equal(o Version) bool {
+	return v.Compare(o) == 0
+}
+
+// Compare parses and compares two versions. See Version.Compare.
+func Compare(a, b string) (int, error) {
+	va, err := Parse(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := Parse(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+// Sort sorts versions from lowest to highest precedence.
+func Sort(versions []Version) {
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].Less(versions[j]) })
+}
+
+// SortStrings sorts version strings from lowest to highest precedence,
+// keeping their original spelling. It fails on the first invalid version,
+// leaving versions untouched.
+func SortStrings(versions []string) error {
+	parsed := make([]Version, len(versions))
+	for i, s := range versions {
+		v, err := Parse(s)
+		if err != nil {
+			return err
+		}
+		parsed[i] = v
+	}
+
+	order := make([]int, len(versions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return parsed[order[i]].Less(parsed[order[j]]) })
+
+	sorted := make([]string, len(versions))
+	for i, idx := range order {
+		sorted[i] = versions[idx]
+	}
+	copy(versions, sorted)
+	return nil
+}
+
+// Latest returns the highest of versions, ignoring pre-releases unless
+// withPrereleases is set. It reports false if none qualifies.
+func Latest(versions []Version, withPrereleases bool) (Version, bool) {
+	var latest Version
+	found := false
+	for _, v := range versions {
+		if v.IsPrerelease() && !withPrereleases {
+			continue
+		}
+		if !found || latest.Less(v) {
+			latest, found = v, true
+		}
+	}
+	return latest, found
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease orders pre-release identifiers: a release is greater
+// than any pre-release, numeric identifiers compare numerically and below
+// alphanumeric ones, and a longer list wins when all shared ones are equal.
+func comparePrerelease(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		na, errA := strconv.ParseUint(a[i], 10, 64)
+		nb, errB := strconv.ParseUint(b[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if c := compareUint(na, nb); c != 0 {
+				return c
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareUint(uint64(len(a)), uint64(len(b)))
+}
+
+// partial is a version parsed from a constraint, where trailing components
+// may be missing or wildcards: "1", "1.2", "1.2.x", "*".
+type partial struct {
+	Version
+	parts int // How many of major, minor and patch were given
+}
+
+func parse(s string, allowPartial bool) (partial, error) {
+	invalid := func() (partial, error) {
+		return partial{}, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+
+	str := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	var p partial
+	if i := strings.IndexByte(str, '+'); i >= 0 {
+		p.Build = str[i+1:]
+		str = str[:i]
+		if !validIdentifiers(p.Build, false) {
+			return invalid()
+		}
+	}
+	if i := strings.IndexByte(str, '-'); i >= 0 {
+		if !validIdentifiers(str[i+1:], true) {
+			return invalid()
+		}
+		p.Prerelease = strings.Split(str[i+1:], ".")
+		str = str[:i]
+	}
+
+	numbers := strings.Split(str, ".")
+	if len(numbers) > 3 || (!allowPartial && len(numbers) != 3) {
+		return invalid()
+	}
+	dst := []*uint64{&p.Major, &p.Minor, &p.Patch}
+	for i, n := range numbers {
+		if allowPartial && (n == "x" || n == "X" || n == "*") {
+			break
+		}
+		if !validNumber(n) {
+			return invalid()
+		}
+		value, err := strconv.ParseUint(n, 10, 64)
+		if err != nil {
+			return invalid()
+		}
+		*dst[i] = value
+		p.parts++
+	}
+	if p.parts < 3 && (p.Prerelease != nil || p.Build != "") {
+		return invalid() // "1.2-rc.1" is ambiguous
+	}
+	return p, nil
+}
+
+func validNumber(s string) bool {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validIdentifiers checks dot-separated identifiers of ASCII alphanumerics
+// and hyphens. Numeric pre-release identifiers can't have leading zeros.
+func validIdentifiers(s string, prerelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		numeric := true
+		for _, c := range id {
+			switch {
+			case c >= '0' && c <= '9':
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-':
+				numeric = false
+			default:
+				return false
+			}
+		}
+		if prerelease && numeric && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
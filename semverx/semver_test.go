@@ -0,0 +1,87 @@
+package semverx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2.3-rc.1+build.5")
+	require.NoError(t, err)
+	assert.Equal(t, Version{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}, Build: "build.5"}, v)
+	assert.Equal(t, "1.2.3-rc.1+build.5", v.String())
+	assert.True(t, v.IsPrerelease())
+
+	v, err = Parse(" 10.20.30 ")
+	require.NoError(t, err)
+	assert.Equal(t, "10.20.30", v.String())
+
+	for _, invalid := range []string{"", "1", "1.2", "1.2.3.4", "01.2.3", "1.2.3-", "1.2.3-01", "1.2.3-rc..1", "1.2.3+", "1.2.x", "a.b.c", "1.2.3-rc_1"} {
+		_, err := Parse(invalid)
+		assert.ErrorIs(t, err, ErrInvalidVersion, invalid)
+	}
+
+	assert.Panics(t, func() { MustParse("latest") })
+}
+
+func TestCompare(t *testing.T) {
+	// In increasing precedence, from the specification.
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0", "10.0.0",
+	}
+	for i := 1; i < len(ordered); i++ {
+		c, err := Compare(ordered[i-1], ordered[i])
+		require.NoError(t, err)
+		assert.Equal(t, -1, c, "%s < %s", ordered[i-1], ordered[i])
+
+		c, err = Compare(ordered[i], ordered[i-1])
+		require.NoError(t, err)
+		assert.Equal(t, 1, c)
+	}
+
+	assert.True(t, MustParse("1.0.0+a").Equal(MustParse("v1.0.0+b")), "build metadata is ignored")
+	_, err := Compare("1.0.0", "one")
+	assert.Error(t, err)
+}
+
+func TestSort(t *testing.T) {
+	versions := []Version{MustParse("1.10.0"), MustParse("1.2.0"), MustParse("1.2.0-rc.1"), MustParse("0.9.9")}
+	Sort(versions)
+	assert.Equal(t, "0.9.9 1.2.0-rc.1 1.2.0 1.10.0", join(versions))
+
+	tags := []string{"v1.10.0", "v1.2.0", "1.9.0"}
+	require.NoError(t, SortStrings(tags))
+	assert.Equal(t, []string{"v1.2.0", "1.9.0", "v1.10.0"}, tags)
+
+	tags = []string{"v2.0.0", "latest", "v1.0.0"}
+	assert.Error(t, SortStrings(tags))
+	assert.Equal(t, []string{"v2.0.0", "latest", "v1.0.0"}, tags)
+}
+
+func TestLatest(t *testing.T) {
+	versions := []Version{MustParse("1.2.0"), MustParse("1.3.0-rc.1"), MustParse("1.1.0")}
+
+	latest, ok := Latest(versions, false)
+	assert.True(t, ok)
+	assert.Equal(t, "1.2.0", latest.String())
+
+	latest, _ = Latest(versions, true)
+	assert.Equal(t, "1.3.0-rc.1", latest.String())
+
+	_, ok = Latest(nil, true)
+	assert.False(t, ok)
+}
+
+func join(versions []Version) string {
+	s := ""
+	for i, v := range versions {
+		if i > 0 {
+			s += " "
+		}
+		s += v.String()
+	}
+	return s
+}